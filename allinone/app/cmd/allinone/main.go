@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/allinone/internal/category"
+	"github.com/ohdaddyplease/notes/allinone/internal/config"
+	"github.com/ohdaddyplease/notes/allinone/internal/inproc"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/ohdaddyplease/notes/api_service/pkg/shutdown"
+	"github.com/ohdaddyplease/notes/api_service/router"
+	filelogging "github.com/ohdaddyplease/notes/file_service/pkg/logging"
+	filerouter "github.com/ohdaddyplease/notes/file_service/router"
+	notelogging "gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	noterouter "gitlab.konstweb.ru/ow/arch/notes/note_service/router"
+	taglogging "gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	tagrouter "gitlab.konstweb.ru/ow/arch/notes/tag_service/router"
+	userlogging "gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	userrouter "gitlab.konstweb.ru/ow/arch/notes/user_service/router"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// hosts of the inproc:// service URLs in config.yml
+const (
+	noteServiceHost     = "note_service"
+	tagServiceHost      = "tag_service"
+	userServiceHost     = "user_service"
+	fileServiceHost     = "file_service"
+	categoryServiceHost = "category_service"
+)
+
+func main() {
+	logging.Init()
+	logger := logging.GetLogger()
+
+	cfg := config.GetConfig()
+	apiCfg := router.GetConfig()
+
+	ctx := context.Background()
+	transport := inproc.NewTransport()
+
+	noteHandler, err := noterouter.New(ctx, &cfg.Services.Notes, notelogging.Logger{Entry: logger.WithField("service", noteServiceHost)})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	transport.Mount(noteServiceHost, noteHandler)
+
+	tagHandler, err := tagrouter.New(ctx, &cfg.Services.Tags, taglogging.Logger{Entry: logger.WithField("service", tagServiceHost)})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	transport.Mount(tagServiceHost, tagHandler)
+
+	userHandler, err := userrouter.New(ctx, &cfg.Services.Users, userlogging.Logger{Entry: logger.WithField("service", userServiceHost)})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	transport.Mount(userServiceHost, userHandler)
+
+	fileHandler, err := filerouter.New(ctx, &cfg.Services.Files, filelogging.Logger{Entry: logger.WithField("service", fileServiceHost)})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	transport.Mount(fileServiceHost, fileHandler)
+
+	categoryRouter := httprouter.New()
+	categoriesHandler := category.Handler{
+		Logger:  logging.Logger{Entry: logger.WithField("service", categoryServiceHost)},
+		Storage: category.NewMemoryStorage(),
+	}
+	categoriesHandler.Register(categoryRouter)
+	transport.Mount(categoryServiceHost, categoryRouter)
+
+	// the clients of the gateway send through the default transport, so inproc:// URLs reach the handlers above
+	http.DefaultTransport.(*http.Transport).RegisterProtocol(inproc.Scheme, transport)
+
+	handler, err := router.New(ctx, apiCfg, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Println("start application")
+	start(handler, logger, apiCfg)
+}
+
+func start(handler http.Handler, logger logging.Logger, cfg *router.Config) {
+	var server *http.Server
+	var listener net.Listener
+
+	if cfg.Listen.Type == "sock" {
+		appDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		socketPath := path.Join(appDir, "app.sock")
+		logger.Infof("socket path: %s", socketPath)
+
+		listener, err = net.Listen("unix", socketPath)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	} else {
+		logger.Infof("bind application to host: %s and port: %s", cfg.Listen.BindIP, cfg.Listen.Port)
+
+		var err error
+
+		listener, err = net.Listen("tcp", fmt.Sprintf("%s:%s", cfg.Listen.BindIP, cfg.Listen.Port))
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	server = &http.Server{
+		Handler:      handler,
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+	}
+
+	go shutdown.Graceful([]os.Signal{syscall.SIGABRT, syscall.SIGQUIT, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM},
+		server)
+
+	logger.Println("application inited and started")
+
+	if err := server.Serve(listener); err != nil {
+		switch {
+		case errors.Is(err, http.ErrServerClosed):
+			logger.Warn("server shutdown")
+		default:
+			logger.Fatal(err)
+		}
+	}
+}
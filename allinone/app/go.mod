@@ -0,0 +1,24 @@
+module github.com/ohdaddyplease/notes/allinone
+
+go 1.19
+
+require (
+	github.com/google/uuid v1.3.0
+	github.com/ilyakaznacheev/cleanenv v1.4.1
+	github.com/julienschmidt/httprouter v1.3.0
+	github.com/ohdaddyplease/notes/api_service v0.0.0
+	github.com/ohdaddyplease/notes/file_service v0.0.0
+	github.com/sirupsen/logrus v1.9.0
+	gitlab.konstweb.ru/ow/arch/notes/note_service v0.0.0
+	gitlab.konstweb.ru/ow/arch/notes/tag_service v0.0.0
+	gitlab.konstweb.ru/ow/arch/notes/user_service v0.0.0
+)
+
+// the services are built from this tree
+replace (
+	github.com/ohdaddyplease/notes/api_service => ../../api_service/app
+	github.com/ohdaddyplease/notes/file_service => ../../file_service/app
+	gitlab.konstweb.ru/ow/arch/notes/note_service => ../../note_service/app
+	gitlab.konstweb.ru/ow/arch/notes/tag_service => ../../tag_service/app
+	gitlab.konstweb.ru/ow/arch/notes/user_service => ../../user_service/app
+)
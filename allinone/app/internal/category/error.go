@@ -0,0 +1,72 @@
+package category
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// codes and messages are the ones of category_service, the gateway passes them on to clients
+var (
+	ErrNotFound     = newAppError("category not found", "CS-00008", "")
+	ErrUserNotFound = newAppError("user not found", "CS-00009", "")
+)
+
+type appError struct {
+	Code             string `json:"code"`
+	Message          string `json:"message"`
+	DeveloperMessage string `json:"developer_message"`
+}
+
+func newAppError(message, code, developerMessage string) *appError {
+	return &appError{
+		Code:             code,
+		Message:          message,
+		DeveloperMessage: developerMessage,
+	}
+}
+
+func (e *appError) Error() string {
+	return e.Message
+}
+
+func (e *appError) marshal() []byte {
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return nil
+	}
+	return bytes
+}
+
+func validationError(developerMessage string) *appError {
+	return newAppError("validation error", "CS-00010", developerMessage)
+}
+
+func systemError(developerMessage string) *appError {
+	return newAppError("system error", "CS-00001", developerMessage)
+}
+
+type appHandler func(http.ResponseWriter, *http.Request) error
+
+func middleware(h appHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		if err == nil {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		var appErr *appError
+		if !errors.As(err, &appErr) {
+			w.WriteHeader(418)
+			w.Write(systemError(err.Error()).marshal())
+			return
+		}
+		if appErr == ErrNotFound || appErr == ErrUserNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write(appErr.marshal())
+	}
+}
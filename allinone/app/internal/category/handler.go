@@ -0,0 +1,130 @@
+package category
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"net/http"
+)
+
+// the routes of category_service, the gateway talks to this handler the way it talks to the service
+const (
+	categoriesURL = "/api/categories"
+	categoryURL   = "/api/categories/:uuid"
+	// ownerURL is called by the gateway when the account of the owner is deleted
+	ownerURL = "/api/owners/:uuid"
+)
+
+type Handler struct {
+	Logger  logging.Logger
+	Storage Storage
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, categoriesURL, middleware(h.GetCategories))
+	router.HandlerFunc(http.MethodPost, categoriesURL, middleware(h.CreateCategory))
+	router.HandlerFunc(http.MethodPatch, categoryURL, middleware(h.UpdateCategory))
+	router.HandlerFunc(http.MethodDelete, categoryURL, middleware(h.DeleteCategory))
+	router.HandlerFunc(http.MethodDelete, ownerURL, middleware(h.DeleteOwnerCategories))
+}
+
+func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) error {
+	userUUID := r.URL.Query().Get("user_uuid")
+	if userUUID == "" {
+		return validationError("user_uuid query parameter is required")
+	}
+
+	categories, err := h.Storage.FindByOwner(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+
+	categoriesBytes, err := json.Marshal(tree(categories))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(categoriesBytes)
+
+	return nil
+}
+
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) error {
+	var dto CreateCategoryDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return validationError(fmt.Sprintf("invalid JSON body: %v", err))
+	}
+	if dto.Name == "" || dto.UserUUID == "" {
+		return validationError("name and user_uuid are required")
+	}
+
+	categoryUUID, err := h.Storage.Create(r.Context(), dto.UserUUID, Category{Name: dto.Name, ParentUUID: dto.ParentUUID})
+	if err != nil {
+		return err
+	}
+	h.Logger.Debugf("category %s of user %s created", categoryUUID, dto.UserUUID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", categoriesURL, categoryUUID))
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto UpdateCategoryDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return validationError(fmt.Sprintf("invalid JSON body: %v", err))
+	}
+	if dto.Name == "" || dto.UserUUID == "" {
+		return validationError("name and user_uuid are required")
+	}
+
+	if err := h.Storage.Rename(r.Context(), dto.UserUUID, params.ByName("uuid"), dto.Name); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto DeleteCategoryDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return validationError(fmt.Sprintf("invalid JSON body: %v", err))
+	}
+	if dto.UserUUID == "" {
+		return validationError("user_uuid is required")
+	}
+
+	if err := h.Storage.Delete(r.Context(), dto.UserUUID, params.ByName("uuid")); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteOwnerCategories(w http.ResponseWriter, r *http.Request) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	if err := h.Storage.DeleteByOwner(r.Context(), params.ByName("uuid")); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package category
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+func newRouter() *httprouter.Router {
+	router := httprouter.New()
+	h := Handler{Logger: logging.Logger{Entry: logrus.NewEntry(logrus.New())}, Storage: NewMemoryStorage()}
+	h.Register(router)
+	return router
+}
+
+func do(t *testing.T, router http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return rec
+}
+
+func create(t *testing.T, router http.Handler, body string) string {
+	t.Helper()
+	rec := do(t, router, http.MethodPost, "/api/categories", body)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("create %s: %d %s", body, rec.Code, rec.Body)
+	}
+	return path.Base(rec.Header().Get("Location"))
+}
+
+func categories(t *testing.T, router http.Handler, userUUID string) []Category {
+	t.Helper()
+	rec := do(t, router, http.MethodGet, "/api/categories?user_uuid="+userUUID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get categories of %s: %d %s", userUUID, rec.Code, rec.Body)
+	}
+	var tree []Category
+	if err := json.Unmarshal(rec.Body.Bytes(), &tree); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestCategoriesAreATreeOfTheirOwner(t *testing.T) {
+	router := newRouter()
+
+	work := create(t, router, `{"name":"work","user_uuid":"alice"}`)
+	projects := create(t, router, `{"name":"projects","user_uuid":"alice","parent_uuid":"`+work+`"}`)
+	create(t, router, `{"name":"archive","user_uuid":"alice","parent_uuid":"`+projects+`"}`)
+	create(t, router, `{"name":"home","user_uuid":"alice"}`)
+
+	if rec := do(t, router, http.MethodPost, "/api/categories",
+		`{"name":"mine","user_uuid":"bob","parent_uuid":"`+work+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("category under a parent of another user answered %d", rec.Code)
+	}
+	if rec := do(t, router, http.MethodPost, "/api/categories", `{"user_uuid":"alice"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("category without a name answered %d", rec.Code)
+	}
+
+	tree := categories(t, router, "alice")
+	if len(tree) != 2 || tree[0].Name != "work" || tree[1].Name != "home" {
+		t.Fatalf("roots %+v, want work and home", tree)
+	}
+	if len(tree[0].Children) != 1 || tree[0].Children[0].ParentUUID != work ||
+		len(tree[0].Children[0].Children) != 1 || tree[0].Children[0].Children[0].Name != "archive" {
+		t.Fatalf("work subtree %+v", tree[0])
+	}
+
+	if rec := do(t, router, http.MethodPatch, "/api/categories/"+work, `{"name":"job","user_uuid":"alice"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("rename: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, router, http.MethodDelete, "/api/categories/"+work, `{"user_uuid":"bob"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("delete by another user answered %d", rec.Code)
+	}
+	if tree := categories(t, router, "alice"); tree[0].Name != "job" {
+		t.Errorf("renamed root is %q", tree[0].Name)
+	}
+
+	if rec := do(t, router, http.MethodDelete, "/api/categories/"+work, `{"user_uuid":"alice"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if tree := categories(t, router, "alice"); len(tree) != 1 || tree[0].Name != "home" || tree[0].Children != nil {
+		t.Errorf("after deleting the work subtree %+v", tree)
+	}
+}
+
+func TestDeletedOwnerIsNotFound(t *testing.T) {
+	router := newRouter()
+	create(t, router, `{"name":"work","user_uuid":"alice"}`)
+
+	for i := 0; i < 2; i++ {
+		if rec := do(t, router, http.MethodDelete, "/api/owners/alice", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete owner, attempt %d: %d %s", i+1, rec.Code, rec.Body)
+		}
+	}
+
+	rec := do(t, router, http.MethodGet, "/api/categories?user_uuid=alice", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("categories of a deleted owner answered %d", rec.Code)
+	}
+	var appErr appError
+	if err := json.Unmarshal(rec.Body.Bytes(), &appErr); err != nil || appErr.Code != "CS-00009" {
+		t.Errorf("error %s, want code CS-00009", rec.Body)
+	}
+}
@@ -0,0 +1,47 @@
+package category
+
+// Category is answered as a tree, the roots are the categories without a parent
+type Category struct {
+	UUID       string     `json:"uuid"`
+	Name       string     `json:"name"`
+	ParentUUID string     `json:"parent_uuid,omitempty"`
+	Children   []Category `json:"children,omitempty"`
+}
+
+type CreateCategoryDTO struct {
+	Name       string `json:"name"`
+	UserUUID   string `json:"user_uuid"`
+	ParentUUID string `json:"parent_uuid"`
+}
+
+type UpdateCategoryDTO struct {
+	Name     string `json:"name"`
+	UserUUID string `json:"user_uuid"`
+}
+
+type DeleteCategoryDTO struct {
+	UserUUID string `json:"user_uuid"`
+}
+
+// tree nests the categories under their parents keeping their order, parents come before children
+func tree(categories []Category) []Category {
+	children := make(map[string][]Category)
+	for _, c := range categories {
+		children[c.ParentUUID] = append(children[c.ParentUUID], c)
+	}
+
+	var nest func(parentUUID string) []Category
+	nest = func(parentUUID string) []Category {
+		level := children[parentUUID]
+		for i := range level {
+			level[i].Children = nest(level[i].UUID)
+		}
+		return level
+	}
+
+	roots := nest("")
+	if roots == nil {
+		roots = []Category{}
+	}
+	return roots
+}
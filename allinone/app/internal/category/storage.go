@@ -0,0 +1,145 @@
+package category
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+)
+
+type Storage interface {
+	// FindByOwner returns the categories of the owner in the order they were created, ErrUserNotFound
+	// when the owner never created one or was deleted
+	FindByOwner(ctx context.Context, ownerUUID string) ([]Category, error)
+	// Create returns the uuid of the new category, the parent must be a category of the same owner
+	Create(ctx context.Context, ownerUUID string, c Category) (string, error)
+	Rename(ctx context.Context, ownerUUID, categoryUUID, name string) error
+	// Delete removes the category and its subtree
+	Delete(ctx context.Context, ownerUUID, categoryUUID string) error
+	// DeleteByOwner removes every category of the owner, an unknown owner is not an error
+	DeleteByOwner(ctx context.Context, ownerUUID string) error
+}
+
+var _ Storage = &memoryStorage{}
+
+type storedCategory struct {
+	Category
+	owner string
+	seq   int
+}
+
+// memoryStorage keeps categories in process memory, they are gone after a restart
+type memoryStorage struct {
+	mu         sync.RWMutex
+	categories map[string]storedCategory
+	owners     map[string]bool
+	lastSeq    int
+}
+
+func NewMemoryStorage() Storage {
+	return &memoryStorage{
+		categories: make(map[string]storedCategory),
+		owners:     make(map[string]bool),
+	}
+}
+
+func (s *memoryStorage) FindByOwner(ctx context.Context, ownerUUID string) ([]Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.owners[ownerUUID] {
+		return nil, ErrUserNotFound
+	}
+
+	var stored []storedCategory
+	for _, c := range s.categories {
+		if c.owner == ownerUUID {
+			stored = append(stored, c)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].seq < stored[j].seq })
+
+	categories := make([]Category, 0, len(stored))
+	for _, c := range stored {
+		categories = append(categories, c.Category)
+	}
+	return categories, nil
+}
+
+func (s *memoryStorage) Create(ctx context.Context, ownerUUID string, c Category) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.ParentUUID != "" {
+		if _, err := s.owned(ownerUUID, c.ParentUUID); err != nil {
+			return "", err
+		}
+	}
+
+	s.lastSeq++
+	c.UUID = uuid.New().String()
+	s.categories[c.UUID] = storedCategory{Category: c, owner: ownerUUID, seq: s.lastSeq}
+	s.owners[ownerUUID] = true
+
+	return c.UUID, nil
+}
+
+func (s *memoryStorage) Rename(ctx context.Context, ownerUUID, categoryUUID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.owned(ownerUUID, categoryUUID)
+	if err != nil {
+		return err
+	}
+	c.Name = name
+	s.categories[categoryUUID] = c
+
+	return nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, ownerUUID, categoryUUID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.owned(ownerUUID, categoryUUID); err != nil {
+		return err
+	}
+
+	subtree := []string{categoryUUID}
+	for i := 0; i < len(subtree); i++ {
+		for _, c := range s.categories {
+			if c.ParentUUID == subtree[i] {
+				subtree = append(subtree, c.UUID)
+			}
+		}
+	}
+	for _, id := range subtree {
+		delete(s.categories, id)
+	}
+
+	return nil
+}
+
+func (s *memoryStorage) DeleteByOwner(ctx context.Context, ownerUUID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, c := range s.categories {
+		if c.owner == ownerUUID {
+			delete(s.categories, id)
+		}
+	}
+	delete(s.owners, ownerUUID)
+
+	return nil
+}
+
+// owned returns the category if it belongs to the owner, categories of others are not found
+func (s *memoryStorage) owned(ownerUUID, categoryUUID string) (storedCategory, error) {
+	c, ok := s.categories[categoryUUID]
+	if !ok || c.owner != ownerUUID {
+		return c, ErrNotFound
+	}
+	return c, nil
+}
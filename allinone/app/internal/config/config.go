@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	filerouter "github.com/ohdaddyplease/notes/file_service/router"
+	noterouter "gitlab.konstweb.ru/ow/arch/notes/note_service/router"
+	tagrouter "gitlab.konstweb.ru/ow/arch/notes/tag_service/router"
+	userrouter "gitlab.konstweb.ru/ow/arch/notes/user_service/router"
+	"sync"
+)
+
+// Config holds the configs of the services served in process, the gateway reads its own keys from
+// the same config.yml
+type Config struct {
+	Services struct {
+		Notes noterouter.Config `yaml:"notes"`
+		Tags  tagrouter.Config  `yaml:"tags"`
+		Users userrouter.Config `yaml:"users"`
+		Files filerouter.Config `yaml:"files"`
+	} `yaml:"services"`
+}
+
+var instance *Config
+var once sync.Once
+
+func GetConfig() *Config {
+	once.Do(func() {
+		logger := logging.GetLogger()
+		instance = &Config{}
+		if err := cleanenv.ReadConfig("config.yml", instance); err != nil {
+			help, _ := cleanenv.GetDescription(instance, nil)
+			logger.Info(help)
+			logger.Fatal(err)
+		}
+	})
+	return instance
+}
@@ -0,0 +1,104 @@
+// Package inproc carries http requests to handlers of the same process, no sockets are involved
+package inproc
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// Scheme is the URL scheme of the transport, inproc://note_service/api reaches the handler mounted
+// for note_service
+const Scheme = "inproc"
+
+// Transport serves each request with the handler mounted for the host of its URL
+type Transport struct {
+	handlers map[string]http.Handler
+}
+
+func NewTransport() *Transport {
+	return &Transport{handlers: make(map[string]http.Handler)}
+}
+
+// Mount makes handler serve the requests to host, everything is mounted before the first request
+func (t *Transport) Mount(host string, handler http.Handler) {
+	t.handlers[host] = handler
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	handler, ok := t.handlers[req.URL.Host]
+	if !ok {
+		return nil, fmt.Errorf("no handler is mounted for host %q", req.URL.Host)
+	}
+
+	// the handler gets the request the way a server would pass it
+	in := req.Clone(req.Context())
+	in.URL = &url.URL{Path: req.URL.Path, RawPath: req.URL.RawPath, RawQuery: req.URL.RawQuery}
+	in.RequestURI = in.URL.RequestURI()
+	in.Host = req.URL.Host
+	in.RemoteAddr = "127.0.0.1:0"
+	if in.Body == nil {
+		in.Body = http.NoBody
+	}
+
+	// a server recovers panics of a handler, so does the transport instead of taking the process down
+	defer func() {
+		if p := recover(); p != nil {
+			resp, err = nil, fmt.Errorf("handler of %s panicked: %v", req.URL.Host, p)
+		}
+	}()
+
+	rec := &recorder{header: make(http.Header)}
+	handler.ServeHTTP(rec, in)
+
+	return rec.response(req), nil
+}
+
+// recorder keeps what the handler answers, the header is frozen once the status is written
+type recorder struct {
+	header http.Header
+	sent   http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status != 0 {
+		return
+	}
+	r.status = status
+	r.sent = r.header.Clone()
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) response(req *http.Request) *http.Response {
+	r.WriteHeader(http.StatusOK)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		StatusCode:    r.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.sent,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.body.Bytes())),
+		ContentLength: int64(r.body.Len()),
+		Request:       req,
+	}
+}
@@ -0,0 +1,80 @@
+package inproc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newClient(t *Transport) *http.Client {
+	transport := &http.Transport{}
+	transport.RegisterProtocol(Scheme, t)
+	return &http.Client{Transport: transport}
+}
+
+func TestRequestsReachTheHandlerOfTheirHost(t *testing.T) {
+	transport := NewTransport()
+	transport.Mount("note_service", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Location", "/api/notes/1")
+		w.WriteHeader(http.StatusCreated)
+		// set after the status, a server would not send it
+		w.Header().Set("X-Late", "1")
+		w.Write([]byte(r.Method + " " + r.RequestURI + " " + r.Header.Get("X-User-UUID") + " " + string(body)))
+	}))
+	transport.Mount("tag_service", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tags"))
+	}))
+	client := newClient(transport)
+
+	req, err := http.NewRequest(http.MethodPost, "inproc://note_service/api/notes?x=1", strings.NewReader("note"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-User-UUID", "alice")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	if got, want := string(body), "POST /api/notes?x=1 alice note"; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+	if location, err := resp.Location(); err != nil || location.Path != "/api/notes/1" {
+		t.Errorf("location %v, %v", location, err)
+	}
+	if resp.Header.Get("X-Late") != "" {
+		t.Error("header set after the status was sent")
+	}
+
+	resp, err = client.Get("inproc://tag_service/api/tags")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "tags" {
+		t.Errorf("tag_service answered %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestUnknownHostsAndPanicsFailTheRequest(t *testing.T) {
+	transport := NewTransport()
+	transport.Mount("note_service", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	client := newClient(transport)
+
+	if _, err := client.Get("inproc://user_service/api/users"); err == nil {
+		t.Error("request to a host without a handler succeeded")
+	}
+	if _, err := client.Get("inproc://note_service/api/notes"); err == nil {
+		t.Error("request to a panicking handler succeeded")
+	}
+}
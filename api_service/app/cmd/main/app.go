@@ -1,23 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"github.com/julienschmidt/httprouter"
-	"github.com/ohdaddyplease/notes/api_service/internal/client/category_service"
-	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
-	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
-	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
 	"github.com/ohdaddyplease/notes/api_service/internal/config"
-	"github.com/ohdaddyplease/notes/api_service/internal/handlers/auth"
-	"github.com/ohdaddyplease/notes/api_service/internal/handlers/categories"
-	"github.com/ohdaddyplease/notes/api_service/internal/handlers/notes"
-	"github.com/ohdaddyplease/notes/api_service/internal/handlers/tags"
-	"github.com/ohdaddyplease/notes/api_service/pkg/cache/freecache"
-	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
-	"github.com/ohdaddyplease/notes/api_service/pkg/metrics"
 	"github.com/ohdaddyplease/notes/api_service/pkg/shutdown"
+	"github.com/ohdaddyplease/notes/api_service/router"
 	"net"
 	"net/http"
 	"os"
@@ -33,36 +23,16 @@ func main() {
 
 	cfg := config.GetConfig()
 
-	router := httprouter.New()
-
-	refreshTokenCache := freecache.NewCacheRepo(104857600) // 100MB
-
-	jwtHelper := jwt.NewHelper(refreshTokenCache, logger)
-
-	metricHandler := metric.Handler{Logger: logger}
-	metricHandler.Register(router)
-
-	userService := user_service.NewService(cfg.UserService.URL, "/users", logger)
-	authHandler := auth.Handler{JWTHelper: jwtHelper, UserService: userService, Logger: logger}
-	authHandler.Register(router)
-
-	categoryService := category_service.NewService(cfg.CategoryService.URL, "/categories", logger)
-	categoriesHandler := categories.Handler{CategoryService: categoryService, Logger: logger}
-	categoriesHandler.Register(router)
-
-	noteService := note_service.NewService(cfg.NoteService.URL, "/notes", logger)
-	notesHandler := notes.Handler{NoteService: noteService, Logger: logger}
-	notesHandler.Register(router)
-
-	tagService := tag_service.NewService(cfg.TagService.URL, "/tags", logger)
-	tagsHandler := tags.Handler{TagService: tagService, Logger: logger}
-	tagsHandler.Register(router)
+	handler, err := router.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	logger.Println("start application")
-	start(router, logger, cfg)
+	start(handler, logger, cfg)
 }
 
-func start(router *httprouter.Router, logger logging.Logger, cfg *config.Config) {
+func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
 	var server *http.Server
 	var listener net.Listener
 
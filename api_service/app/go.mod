@@ -4,7 +4,7 @@ go 1.15
 
 require (
 	github.com/coocood/freecache v1.1.1
-	github.com/cristalhq/jwt/v3 v3.1.0 // indirect
+	github.com/cristalhq/jwt/v3 v3.1.0
 	github.com/fatih/structs v1.1.0
 	github.com/google/uuid v1.2.0
 	github.com/ilyakaznacheev/cleanenv v1.2.5
@@ -3,6 +3,7 @@ package apperror
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 )
 
 var (
@@ -11,9 +12,13 @@ var (
 
 type AppError struct {
 	Err              error  `json:"-"`
+	Status           int    `json:"-"`
 	Message          string `json:"message,omitempty"`
 	DeveloperMessage string `json:"developer_message,omitempty"`
 	Code             string `json:"code,omitempty"`
+	ConflictingID    int    `json:"conflicting_id,omitempty"`
+	// RetryAfter is sent as the Retry-After header, in seconds
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 func NewAppError(message, code, developerMessage string) *AppError {
@@ -43,6 +48,27 @@ func UnauthorizedError(message string) *AppError {
 	return NewAppError(message, "NS-000003", "")
 }
 
+// QuotaExceededError is answered with 403 Forbidden
+func QuotaExceededError(message string) *AppError {
+	appErr := NewAppError(message, "NS-000011", "quota of the user plan is exhausted")
+	appErr.Status = http.StatusForbidden
+	return appErr
+}
+
+// UnverifiedError is answered with 403 Forbidden until the user confirms the email
+func UnverifiedError(message string) *AppError {
+	appErr := NewAppError(message, "NS-000012", "email of the user is not verified")
+	appErr.Status = http.StatusForbidden
+	return appErr
+}
+
+// ForbiddenError is answered with 403 Forbidden
+func ForbiddenError(message string) *AppError {
+	appErr := NewAppError(message, "NS-000013", "")
+	appErr.Status = http.StatusForbidden
+	return appErr
+}
+
 func BadRequestError(message string) *AppError {
 	return NewAppError(message, "NS-000002", "some thing wrong with user data")
 }
@@ -51,6 +77,10 @@ func systemError(developerMessage string) *AppError {
 	return NewAppError("system error", "NS-000001", developerMessage)
 }
 
-func APIError(code, message, developerMessage string) *AppError {
-	return NewAppError(message, code, developerMessage)
+// APIError converts error response of an upstream service. status is the upstream HTTP status,
+// client errors (4xx) are passed to the caller as is.
+func APIError(status int, code, message, developerMessage string) *AppError {
+	appErr := NewAppError(message, code, developerMessage)
+	appErr.Status = status
+	return appErr
 }
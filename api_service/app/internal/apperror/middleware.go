@@ -3,6 +3,7 @@ package apperror
 import (
 	"errors"
 	"net/http"
+	"strconv"
 )
 
 type appHandler func(http.ResponseWriter, *http.Request) error
@@ -18,9 +19,16 @@ func Middleware(h appHandler) http.HandlerFunc {
 					w.Write(ErrNotFound.Marshal())
 					return
 				}
-				err := err.(*AppError)
+				if appErr.Status >= http.StatusBadRequest && appErr.Status < http.StatusInternalServerError {
+					if appErr.RetryAfter > 0 {
+						w.Header().Set("Retry-After", strconv.Itoa(appErr.RetryAfter))
+					}
+					w.WriteHeader(appErr.Status)
+					w.Write(appErr.Marshal())
+					return
+				}
 				w.WriteHeader(http.StatusBadRequest)
-				w.Write(err.Marshal())
+				w.Write(appErr.Marshal())
 				return
 			}
 			w.WriteHeader(418)
@@ -37,6 +37,8 @@ type CategoryService interface {
 	CreateCategory(ctx context.Context, dto CreateCategoryDTO) (string, error)
 	UpdateCategory(ctx context.Context, uuid string, dto UpdateCategoryDTO) error
 	DeleteCategory(ctx context.Context, dto DeleteCategoryDTO) error
+	// DeleteByOwner removes everything the user keeps in the service, the account is being deleted
+	DeleteByOwner(ctx context.Context, ownerUUID string) error
 }
 
 func (c *client) GetUserCategories(ctx context.Context, userUuid string) ([]byte, error) {
@@ -76,7 +78,7 @@ func (c *client) GetUserCategories(ctx context.Context, userUuid string) ([]byte
 		}
 		return categories, nil
 	}
-	return nil, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) CreateCategory(ctx context.Context, dto CreateCategoryDTO) (string, error) {
@@ -119,7 +121,7 @@ func (c *client) CreateCategory(ctx context.Context, dto CreateCategoryDTO) (str
 		categoryUuid = splitCategoryURL[len(splitCategoryURL)-1]
 		return categoryUuid, nil
 	}
-	return categoryUuid, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return categoryUuid, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) UpdateCategory(ctx context.Context, uuid string, dto UpdateCategoryDTO) error {
@@ -152,7 +154,7 @@ func (c *client) UpdateCategory(ctx context.Context, uuid string, dto UpdateCate
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) DeleteCategory(ctx context.Context, dto DeleteCategoryDTO) error {
@@ -186,5 +188,32 @@ func (c *client) DeleteCategory(ctx context.Context, dto DeleteCategoryDTO) erro
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) DeleteByOwner(ctx context.Context, ownerUUID string) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("/owners/%s", ownerUUID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	// the service walks all data of the owner, a timed out purge is resumed by the next attempt
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
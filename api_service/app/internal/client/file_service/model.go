@@ -0,0 +1,22 @@
+package file_service
+
+// File is a downloaded attachment
+type File struct {
+	Name  string
+	Bytes []byte
+}
+
+// UploadFileDTO is one attachment of a note, Size is taken from the multipart header
+type UploadFileDTO struct {
+	NoteUUID string
+	Name     string
+	Size     int64
+	Bytes    []byte
+}
+
+// Usage is what one user keeps in file_service
+type Usage struct {
+	OwnerUUID string `json:"owner_uuid"`
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
+}
@@ -0,0 +1,288 @@
+package file_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/ohdaddyplease/notes/api_service/pkg/rest"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+var _ FileService = &client{}
+
+type client struct {
+	Resource string
+	base     rest.BaseClient
+}
+
+func NewService(baseURL string, resource string, logger logging.Logger) FileService {
+	return &client{
+		Resource: resource,
+		base: rest.BaseClient{
+			BaseURL: baseURL,
+			HTTPClient: &http.Client{
+				Timeout: 30 * time.Second,
+			},
+			Logger: logger,
+		},
+	}
+}
+
+type FileService interface {
+	GetByNoteUUID(ctx context.Context, noteUUID string) ([]byte, error)
+	GetFile(ctx context.Context, noteUUID, fileID string) (File, error)
+	Upload(ctx context.Context, ownerUUID string, dto UploadFileDTO) error
+	Delete(ctx context.Context, noteUUID, fileID string) error
+	GetUsage(ctx context.Context, ownerUUID string) (Usage, error)
+	// DeleteByOwner removes everything the user keeps in the service, the account is being deleted
+	DeleteByOwner(ctx context.Context, ownerUUID string) error
+}
+
+func (c *client) GetByNoteUUID(ctx context.Context, noteUUID string) ([]byte, error) {
+	var files []byte
+
+	filters := []rest.FilterOptions{
+		{
+			Field:  "note_uuid",
+			Values: []string{noteUUID},
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(c.Resource, filters)
+	if err != nil {
+		return files, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return files, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return files, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("read body")
+		files, err = response.ReadBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body")
+		}
+		return files, nil
+	}
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) GetFile(ctx context.Context, noteUUID, fileID string) (File, error) {
+	var f File
+
+	filters := []rest.FilterOptions{
+		{
+			Field:  "note_uuid",
+			Values: []string{noteUUID},
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s", c.Resource, fileID), filters)
+	if err != nil {
+		return f, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return f, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return f, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		if _, params, err := mime.ParseMediaType(response.Header().Get("Content-Disposition")); err == nil {
+			f.Name = params["filename"]
+		}
+		c.base.Logger.Debug("read body")
+		f.Bytes, err = response.ReadBody()
+		if err != nil {
+			return f, fmt.Errorf("failed to read body")
+		}
+		return f, nil
+	}
+	return f, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Upload(ctx context.Context, ownerUUID string, dto UploadFileDTO) error {
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(c.Resource, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("write multipart form")
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err = form.WriteField("note_uuid", dto.NoteUUID); err != nil {
+		return fmt.Errorf("failed to write form field due to error: %v", err)
+	}
+	part, err := form.CreateFormFile("file", dto.Name)
+	if err != nil {
+		return fmt.Errorf("failed to write form file due to error: %v", err)
+	}
+	if _, err = part.Write(dto.Bytes); err != nil {
+		return fmt.Errorf("failed to write form file due to error: %v", err)
+	}
+	if err = form.Close(); err != nil {
+		return fmt.Errorf("failed to close form due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("POST", uri, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Delete(ctx context.Context, noteUUID, fileID string) error {
+	filters := []rest.FilterOptions{
+		{
+			Field:  "note_uuid",
+			Values: []string{noteUUID},
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s", c.Resource, fileID), filters)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) GetUsage(ctx context.Context, ownerUUID string) (Usage, error) {
+	var usage Usage
+
+	filters := []rest.FilterOptions{
+		{
+			Field:  "owner_uuid",
+			Values: []string{ownerUUID},
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL("/usage", filters)
+	if err != nil {
+		return usage, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return usage, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return usage, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("decode body")
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&usage); err != nil {
+			return usage, fmt.Errorf("failed to decode body due to error %v", err)
+		}
+		return usage, nil
+	}
+	return usage, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) DeleteByOwner(ctx context.Context, ownerUUID string) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("/owners/%s", ownerUUID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	// the service walks all data of the owner, a timed out purge is resumed by the next attempt
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
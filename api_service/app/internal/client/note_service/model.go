@@ -1,16 +1,108 @@
 package note_service
 
+import "time"
+
 type CreateNoteDTO struct {
 	Header       string `json:"header"`
 	Body         string `json:"body"`
 	ShortBody    string `json:"short_body,omitempty"`
 	Tags         []int  `json:"tags,omitempty"`
 	CategoryUUID string `json:"category_uuid"`
+	// TagNames are paths or aliases of tags, the gateway resolves them into Tags
+	TagNames []string `json:"tag_names,omitempty"`
 }
 
 type UpdateNoteDTO struct {
-	Header       string `json:"header,omitempty"`
-	Body         string `json:"body,omitempty"`
-	Tags         []int  `json:"tags,omitempty"`
+	Header       string   `json:"header,omitempty"`
+	Body         string   `json:"body,omitempty"`
+	Tags         []int    `json:"tags,omitempty"`
+	CategoryUUID string   `json:"category_uuid,omitempty"`
+	TagNames     []string `json:"tag_names,omitempty"`
+}
+
+// Editor identifies the user and the session that act on a note
+type Editor struct {
+	UserUUID  string
+	SessionID string
+}
+
+type CommentAnchor struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// CommentAuthor is filled by the gateway from user_service
+type CommentAuthor struct {
+	UUID  string `json:"uuid"`
+	Email string `json:"email,omitempty"`
+}
+
+type Comment struct {
+	UUID       string         `json:"uuid"`
+	NoteUUID   string         `json:"note_uuid"`
+	ParentUUID string         `json:"parent_uuid,omitempty"`
+	AuthorUUID string         `json:"author_uuid"`
+	Author     *CommentAuthor `json:"author,omitempty"`
+	Body       string         `json:"body"`
+	Anchor     *CommentAnchor `json:"anchor,omitempty"`
+	Resolved   bool           `json:"resolved"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+type CommentThread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+type CreateCommentDTO struct {
+	ParentUUID string         `json:"parent_uuid,omitempty"`
+	Body       string         `json:"body"`
+	Anchor     *CommentAnchor `json:"anchor,omitempty"`
+}
+
+type UpdateCommentDTO struct {
+	Body     string `json:"body,omitempty"`
+	Resolved *bool  `json:"resolved,omitempty"`
+}
+
+// TagUsage tells how many notes of the owner reference the tag and when one of them was last written
+type TagUsage struct {
+	TagID      int        `json:"tag_id"`
+	Notes      int        `json:"notes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// ReplaceTagsDTO asks note_service to reference TargetID instead of SourceIDs in every note
+type ReplaceTagsDTO struct {
+	SourceIDs []int `json:"source_ids"`
+	TargetID  int   `json:"target_id"`
+}
+
+type ReplaceTagsResult struct {
+	NotesChanged int `json:"notes_changed"`
+}
+
+// Usage is what one user keeps in note_service
+type Usage struct {
+	OwnerUUID string `json:"owner_uuid"`
+	Notes     int    `json:"notes"`
+	BodyBytes int64  `json:"body_bytes"`
+}
+
+// CreateRuleDTO defines a rule tagging notes with TagID when their body matches BodyPattern
+// and they are in CategoryUUID, at least one of the conditions is required
+type CreateRuleDTO struct {
+	Name         string `json:"name"`
+	BodyPattern  string `json:"body_pattern,omitempty"`
 	CategoryUUID string `json:"category_uuid,omitempty"`
+	TagID        int    `json:"tag_id"`
+}
+
+// UpdateRuleDTO changes the fields that are set, an empty condition removes it
+type UpdateRuleDTO struct {
+	Name         string  `json:"name,omitempty"`
+	BodyPattern  *string `json:"body_pattern,omitempty"`
+	CategoryUUID *string `json:"category_uuid,omitempty"`
+	TagID        int     `json:"tag_id,omitempty"`
 }
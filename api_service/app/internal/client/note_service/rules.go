@@ -0,0 +1,116 @@
+package note_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	rulesResource    = "/rules"
+	ruleJobsResource = "/rule-jobs"
+	tagRulesResource = "/tag-rules"
+)
+
+func (c *client) GetRules(ctx context.Context, ownerUUID string) ([]byte, error) {
+	body, _, err := c.sendRuleRequest(ctx, "GET", rulesResource, ownerUUID, nil)
+	return body, err
+}
+
+func (c *client) GetRule(ctx context.Context, ownerUUID, uuid string) ([]byte, error) {
+	body, _, err := c.sendRuleRequest(ctx, "GET", fmt.Sprintf("%s/%s", rulesResource, uuid), ownerUUID, nil)
+	return body, err
+}
+
+func (c *client) CreateRule(ctx context.Context, ownerUUID string, dto CreateRuleDTO) (string, error) {
+	_, location, err := c.sendRuleRequest(ctx, "POST", rulesResource, ownerUUID, dto)
+	return lastSegment(location), err
+}
+
+func (c *client) UpdateRule(ctx context.Context, ownerUUID, uuid string, dto UpdateRuleDTO) error {
+	_, _, err := c.sendRuleRequest(ctx, "PATCH", fmt.Sprintf("%s/%s", rulesResource, uuid), ownerUUID, dto)
+	return err
+}
+
+func (c *client) DeleteRule(ctx context.Context, ownerUUID, uuid string) error {
+	_, _, err := c.sendRuleRequest(ctx, "DELETE", fmt.Sprintf("%s/%s", rulesResource, uuid), ownerUUID, nil)
+	return err
+}
+
+func (c *client) DryRunRule(ctx context.Context, ownerUUID, uuid string) ([]byte, error) {
+	body, _, err := c.sendRuleRequest(ctx, "GET", fmt.Sprintf("%s/%s/dry-run", rulesResource, uuid), ownerUUID, nil)
+	return body, err
+}
+
+func (c *client) ApplyRule(ctx context.Context, ownerUUID, uuid string) (string, error) {
+	_, location, err := c.sendRuleRequest(ctx, "POST", fmt.Sprintf("%s/%s/apply", rulesResource, uuid), ownerUUID, nil)
+	return lastSegment(location), err
+}
+
+func (c *client) GetRuleJob(ctx context.Context, ownerUUID, uuid string) ([]byte, error) {
+	body, _, err := c.sendRuleRequest(ctx, "GET", fmt.Sprintf("%s/%s", ruleJobsResource, uuid), ownerUUID, nil)
+	return body, err
+}
+
+func (c *client) DeleteTagRules(ctx context.Context, ownerUUID string, tagID int) error {
+	_, _, err := c.sendRuleRequest(ctx, "DELETE", fmt.Sprintf("%s/%d", tagRulesResource, tagID), ownerUUID, nil)
+	return err
+}
+
+// sendRuleRequest sends a request on behalf of the owner of the rules and returns the body and
+// the Location header of the response, a failed response is turned into an error
+func (c *client) sendRuleRequest(ctx context.Context, method, resource, ownerUUID string, dto interface{}) ([]byte, string, error) {
+	c.base.Logger.Debug("build url with resource")
+	uri, err := c.base.BuildURL(resource, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	var body io.Reader
+	if dto != nil {
+		dataBytes, err := json.Marshal(dto)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal dto")
+		}
+		body = bytes.NewBuffer(dataBytes)
+	}
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	c.base.Logger.Debug("send request")
+	// dry runs read every note of the owner
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("read body")
+		responseBody, err := response.ReadBody()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read body")
+		}
+		return responseBody, response.Header().Get("Location"), nil
+	}
+	return nil, "", apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func lastSegment(location string) string {
+	segments := strings.Split(location, "/")
+	return segments[len(segments)-1]
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
 	"github.com/ohdaddyplease/notes/api_service/pkg/rest"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -35,23 +36,57 @@ func NewService(baseURL string, resource string, logger logging.Logger) NoteServ
 }
 
 type NoteService interface {
-	GetByCategoryUUID(ctx context.Context, categoryUUID string) ([]byte, error)
+	// GetByCategoryUUID returns notes of the category that reference any of tagIDs, all of them
+	// when tagIDs are empty
+	GetByCategoryUUID(ctx context.Context, categoryUUID string, tagIDs []int) ([]byte, error)
 	GetByUUID(ctx context.Context, uuid string) ([]byte, error)
-	Create(ctx context.Context, note CreateNoteDTO) (string, error)
-	Update(ctx context.Context, uuid string, note UpdateNoteDTO) error
+	GetUsage(ctx context.Context, ownerUUID string) (Usage, error)
+	GetTagUsage(ctx context.Context, ownerUUID string) ([]TagUsage, error)
+	// ReplaceTags rewrites tags of all notes referencing any of sourceIDs, it returns the number of
+	// notes changed
+	ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error)
+	Create(ctx context.Context, owner Editor, note CreateNoteDTO) (string, error)
+	Update(ctx context.Context, uuid string, editor Editor, note UpdateNoteDTO) error
 	Delete(ctx context.Context, uuid string) error
+	Lock(ctx context.Context, uuid string, editor Editor) ([]byte, error)
+	Unlock(ctx context.Context, uuid string, editor Editor) error
+	GetComments(ctx context.Context, noteUUID string) ([]CommentThread, error)
+	CreateComment(ctx context.Context, noteUUID string, author Editor, dto CreateCommentDTO) (string, error)
+	UpdateComment(ctx context.Context, noteUUID, commentUUID string, editor Editor, dto UpdateCommentDTO) error
+	DeleteComment(ctx context.Context, noteUUID, commentUUID string, editor Editor) error
+	GetRules(ctx context.Context, ownerUUID string) ([]byte, error)
+	GetRule(ctx context.Context, ownerUUID, uuid string) ([]byte, error)
+	CreateRule(ctx context.Context, ownerUUID string, dto CreateRuleDTO) (string, error)
+	UpdateRule(ctx context.Context, ownerUUID, uuid string, dto UpdateRuleDTO) error
+	DeleteRule(ctx context.Context, ownerUUID, uuid string) error
+	// DryRunRule lists the notes of the owner the rule would tag
+	DryRunRule(ctx context.Context, ownerUUID, uuid string) ([]byte, error)
+	// ApplyRule starts tagging the existing notes of the owner and returns the job uuid
+	ApplyRule(ctx context.Context, ownerUUID, uuid string) (string, error)
+	GetRuleJob(ctx context.Context, ownerUUID, uuid string) ([]byte, error)
+	// DeleteTagRules deletes the rules of the owner tagging with a deleted tag
+	DeleteTagRules(ctx context.Context, ownerUUID string, tagID int) error
+	// DeleteByOwner removes everything the user keeps in the service, the account is being deleted
+	DeleteByOwner(ctx context.Context, ownerUUID string) error
 }
 
-func (c *client) GetByCategoryUUID(ctx context.Context, categoryUUID string) ([]byte, error) {
+func (c *client) GetByCategoryUUID(ctx context.Context, categoryUUID string, tagIDs []int) ([]byte, error) {
 	var notes []byte
 
-	c.base.Logger.Debug("add email and password to filter options")
+	c.base.Logger.Debug("add category and tags to filter options")
 	filters := []rest.FilterOptions{
 		{
 			Field:  "category_uuid",
 			Values: []string{categoryUUID},
 		},
 	}
+	if len(tagIDs) > 0 {
+		var tags []string
+		for _, id := range tagIDs {
+			tags = append(tags, strconv.Itoa(id))
+		}
+		filters = append(filters, rest.FilterOptions{Field: "tag", Values: tags})
+	}
 
 	c.base.Logger.Debug("build url with resource and filter")
 	uri, err := c.base.BuildURL(c.Resource, filters)
@@ -83,7 +118,7 @@ func (c *client) GetByCategoryUUID(ctx context.Context, categoryUUID string) ([]
 		}
 		return notes, nil
 	}
-	return nil, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) GetByUUID(ctx context.Context, uuid string) ([]byte, error) {
@@ -119,10 +154,137 @@ func (c *client) GetByUUID(ctx context.Context, uuid string) ([]byte, error) {
 		}
 		return note, nil
 	}
-	return nil, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) GetTagUsage(ctx context.Context, ownerUUID string) ([]TagUsage, error) {
+	var usage []TagUsage
+
+	filters := []rest.FilterOptions{
+		{
+			Field:  "owner_uuid",
+			Values: []string{ownerUUID},
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL("/tag-usage", filters)
+	if err != nil {
+		return usage, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return usage, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return usage, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("decode body")
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&usage); err != nil {
+			return usage, fmt.Errorf("failed to decode body due to error %v", err)
+		}
+		return usage, nil
+	}
+	return usage, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error) {
+	var result ReplaceTagsResult
+
+	c.base.Logger.Debug("build url with resource")
+	uri, err := c.base.BuildURL("/tag-merges", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	dataBytes, err := json.Marshal(ReplaceTagsDTO{SourceIDs: sourceIDs, TargetID: targetID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal dto")
+	}
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("POST", uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("decode body")
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&result); err != nil {
+			return 0, fmt.Errorf("failed to decode body due to error %v", err)
+		}
+		return result.NotesChanged, nil
+	}
+	return 0, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) GetUsage(ctx context.Context, ownerUUID string) (Usage, error) {
+	var usage Usage
+
+	filters := []rest.FilterOptions{
+		{
+			Field:  "owner_uuid",
+			Values: []string{ownerUUID},
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL("/usage", filters)
+	if err != nil {
+		return usage, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return usage, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return usage, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("decode body")
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&usage); err != nil {
+			return usage, fmt.Errorf("failed to decode body due to error %v", err)
+		}
+		return usage, nil
+	}
+	return usage, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
-func (c *client) Create(ctx context.Context, note CreateNoteDTO) (string, error) {
+func (c *client) Create(ctx context.Context, owner Editor, note CreateNoteDTO) (string, error) {
 	var noteUUID string
 
 	c.base.Logger.Debug("build url with resource and filter")
@@ -147,6 +309,7 @@ func (c *client) Create(ctx context.Context, note CreateNoteDTO) (string, error)
 	if err != nil {
 		return noteUUID, fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	setEditorHeaders(req, owner)
 
 	c.base.Logger.Debug("send request")
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -169,10 +332,10 @@ func (c *client) Create(ctx context.Context, note CreateNoteDTO) (string, error)
 		noteUUID = splitCategoryURL[len(splitCategoryURL)-1]
 		return noteUUID, nil
 	}
-	return noteUUID, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return noteUUID, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
-func (c *client) Update(ctx context.Context, uuid string, note UpdateNoteDTO) error {
+func (c *client) Update(ctx context.Context, uuid string, editor Editor, note UpdateNoteDTO) error {
 	c.base.Logger.Debug("build url with resource and filter")
 	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s", c.Resource, uuid), nil)
 	if err != nil {
@@ -195,6 +358,7 @@ func (c *client) Update(ctx context.Context, uuid string, note UpdateNoteDTO) er
 	if err != nil {
 		return fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	setEditorHeaders(req, editor)
 
 	c.base.Logger.Debug("send request")
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -208,7 +372,7 @@ func (c *client) Update(ctx context.Context, uuid string, note UpdateNoteDTO) er
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) Delete(ctx context.Context, uuid string) error {
@@ -237,5 +401,261 @@ func (c *client) Delete(ctx context.Context, uuid string) error {
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Lock(ctx context.Context, uuid string, editor Editor) ([]byte, error) {
+	var lease []byte
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/lock", c.Resource, uuid), nil)
+	if err != nil {
+		return lease, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("POST", uri, nil)
+	if err != nil {
+		return lease, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	setEditorHeaders(req, editor)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return lease, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("read body")
+		lease, err = response.ReadBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body")
+		}
+		return lease, nil
+	}
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Unlock(ctx context.Context, uuid string, editor Editor) error {
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/lock", c.Resource, uuid), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	setEditorHeaders(req, editor)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) GetComments(ctx context.Context, noteUUID string) ([]CommentThread, error) {
+	var threads []CommentThread
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/comments", c.Resource, noteUUID), nil)
+	if err != nil {
+		return threads, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return threads, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return threads, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("decode body")
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&threads); err != nil {
+			return nil, fmt.Errorf("failed to decode body due to error %v", err)
+		}
+		return threads, nil
+	}
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) CreateComment(ctx context.Context, noteUUID string, author Editor, dto CreateCommentDTO) (string, error) {
+	var commentUUID string
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/comments", c.Resource, noteUUID), nil)
+	if err != nil {
+		return commentUUID, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("convert dto to map")
+	structs.DefaultTagName = "json"
+	data := structs.Map(dto)
+
+	c.base.Logger.Debug("marshal map to bytes")
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return commentUUID, fmt.Errorf("failed to marshal dto")
+	}
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("POST", uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return commentUUID, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	setEditorHeaders(req, author)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return commentUUID, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		c.base.Logger.Debug("parse location header")
+		commentURL, err := response.Location()
+		if err != nil {
+			return commentUUID, fmt.Errorf("failed to get Location header")
+		}
+		c.base.Logger.Tracef("Location: %s", commentURL.String())
+
+		splitCommentURL := strings.Split(commentURL.String(), "/")
+		commentUUID = splitCommentURL[len(splitCommentURL)-1]
+		return commentUUID, nil
+	}
+	return commentUUID, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) UpdateComment(ctx context.Context, noteUUID, commentUUID string, editor Editor, dto UpdateCommentDTO) error {
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/comments/%s", c.Resource, noteUUID, commentUUID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("convert dto to map")
+	structs.DefaultTagName = "json"
+	data := structs.Map(dto)
+
+	c.base.Logger.Debug("marshal map to bytes")
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dto")
+	}
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("PATCH", uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	setEditorHeaders(req, editor)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) DeleteComment(ctx context.Context, noteUUID, commentUUID string, editor Editor) error {
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/comments/%s", c.Resource, noteUUID, commentUUID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	setEditorHeaders(req, editor)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func setEditorHeaders(req *http.Request, editor Editor) {
+	req.Header.Set("X-User-UUID", editor.UserUUID)
+	req.Header.Set("X-Session-ID", editor.SessionID)
+}
+
+func (c *client) DeleteByOwner(ctx context.Context, ownerUUID string) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("/owners/%s", ownerUUID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	// the service walks all data of the owner, a timed out purge is resumed by the next attempt
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
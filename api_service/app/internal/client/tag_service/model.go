@@ -1,15 +1,52 @@
 package tag_service
 
+// CreateTagDTO names the tag by path, e.g. work/clients/acme, or relative to ParentID
 type CreateTagDTO struct {
 	ID       int    `json:"_id,omitempty" bson:"_id"`
 	Name     string `json:"name" bson:"name"`
 	Color    string `json:"color" bson:"color"`
-	UserUUID string `json:"user_uuid" bson:"user_uuid"`
+	ParentID int    `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
 }
 
+// UpdateTagDTO renames the tag or moves it with its subtree under ParentID, 0 is the root
 type UpdateTagDTO struct {
 	ID       int    `json:"_id,omitempty" bson:"_id,omitempty"`
 	Name     string `json:"name,omitempty" bson:"name,omitempty"`
 	Color    string `json:"color,omitempty" bson:"color,omitempty"`
-	UserUUID string `json:"user_uuid,omitempty" bson:"user_uuid,omitempty"`
+	ParentID *int   `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+}
+
+type Tag struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	ParentID int    `json:"parent_id,omitempty"`
+	Path     string `json:"path"`
+	// Aliases are other names of the tag that resolve to it
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Suggestion is a tag completing a query, lower Rank is a better match
+type Suggestion struct {
+	Tag
+	Match string `json:"match"`
+	// Alias is set when the query matched an alias of the tag rather than its name
+	Alias string `json:"alias,omitempty"`
+	Rank  int    `json:"rank"`
+}
+
+// TagNode is a tag with its children, the shape of the tag tree
+type TagNode struct {
+	Tag
+	Children []TagNode `json:"children"`
+}
+
+type AliasDTO struct {
+	Alias string `json:"alias"`
+}
+
+// ResolvedName is a tag found by its path or one of its aliases
+type ResolvedName struct {
+	Name string `json:"name"`
+	Tag  Tag    `json:"tag"`
 }
@@ -10,6 +10,8 @@ import (
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
 	"github.com/ohdaddyplease/notes/api_service/pkg/rest"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -34,15 +36,31 @@ func NewService(baseURL string, resource string, logger logging.Logger) TagServi
 	}
 }
 
+// TagService acts on behalf of the owner, the tag service hides tags of other users.
 type TagService interface {
-	GetOne(ctx context.Context, id int) ([]byte, error)
-	GetMany(ctx context.Context, ids []int) ([]byte, error)
-	Create(ctx context.Context, tag CreateTagDTO) (string, error)
-	Update(ctx context.Context, uuid string, tag UpdateTagDTO) error
-	Delete(ctx context.Context, id string) error
+	GetOne(ctx context.Context, ownerUUID string, id int) ([]byte, error)
+	GetMany(ctx context.Context, ownerUUID string, ids []int) ([]byte, error)
+	// GetAll returns the tag tree of the owner
+	GetAll(ctx context.Context, ownerUUID string) ([]byte, error)
+	// Suggest returns up to limit tags of the owner completing the query, with ties also the tags
+	// ranked like the last one
+	Suggest(ctx context.Context, ownerUUID string, query string, limit int, ties bool) ([]Suggestion, error)
+	// GetSubtrees returns the tags and all their descendants
+	GetSubtrees(ctx context.Context, ownerUUID string, ids []int) ([]Tag, error)
+	// Create creates the tag, onConflict is passed to tag_service as is. When the owner already has
+	// the tag and onConflict asks for it, existing is the tag and nothing is created.
+	Create(ctx context.Context, ownerUUID string, tag CreateTagDTO, onConflict string) (tagID string, existing []byte, err error)
+	Update(ctx context.Context, ownerUUID string, uuid string, tag UpdateTagDTO) error
+	Delete(ctx context.Context, ownerUUID string, id string) error
+	AddAlias(ctx context.Context, ownerUUID string, id string, alias string) error
+	RemoveAlias(ctx context.Context, ownerUUID string, id string, alias string) error
+	// Resolve finds the tags named by paths or aliases, unknown names are left out
+	Resolve(ctx context.Context, ownerUUID string, names []string) ([]ResolvedName, error)
+	// DeleteByOwner removes everything the user keeps in the service, the account is being deleted
+	DeleteByOwner(ctx context.Context, ownerUUID string) error
 }
 
-func (c *client) GetOne(ctx context.Context, id int) ([]byte, error) {
+func (c *client) GetOne(ctx context.Context, ownerUUID string, id int) ([]byte, error) {
 	var tags []byte
 
 	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%d", c.resource, id), nil)
@@ -55,6 +73,7 @@ func (c *client) GetOne(ctx context.Context, id int) ([]byte, error) {
 	if err != nil {
 		return tags, fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	req.Header.Set("X-User-UUID", ownerUUID)
 
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -71,10 +90,10 @@ func (c *client) GetOne(ctx context.Context, id int) ([]byte, error) {
 		}
 		return tags, nil
 	}
-	return nil, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
-func (c *client) GetMany(ctx context.Context, ids []int) ([]byte, error) {
+func (c *client) GetMany(ctx context.Context, ownerUUID string, ids []int) ([]byte, error) {
 	var tags []byte
 
 	filters := []rest.FilterOptions{
@@ -94,6 +113,7 @@ func (c *client) GetMany(ctx context.Context, ids []int) ([]byte, error) {
 	if err != nil {
 		return tags, fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	req.Header.Set("X-User-UUID", ownerUUID)
 
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -110,15 +130,142 @@ func (c *client) GetMany(ctx context.Context, ids []int) ([]byte, error) {
 		}
 		return tags, nil
 	}
-	return nil, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
-func (c *client) Create(ctx context.Context, tag CreateTagDTO) (string, error) {
-	var tagUUID string
+func (c *client) Suggest(ctx context.Context, ownerUUID string, query string, limit int, ties bool) ([]Suggestion, error) {
+	filters := []rest.FilterOptions{
+		{
+			Field:  "q",
+			Values: []string{query},
+		},
+		{
+			Field:  "limit",
+			Values: []string{strconv.Itoa(limit)},
+		},
+		{
+			Field:  "ties",
+			Values: []string{strconv.FormatBool(ties)},
+		},
+	}
+
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/suggest", c.resource), filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if !response.IsOk {
+		return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	}
+	var suggestions []Suggestion
+	defer response.Body().Close()
+	if err = json.NewDecoder(response.Body()).Decode(&suggestions); err != nil {
+		return nil, fmt.Errorf("failed to decode body due to error %v", err)
+	}
+	return suggestions, nil
+}
+
+func (c *client) GetSubtrees(ctx context.Context, ownerUUID string, ids []int) ([]Tag, error) {
+	filters := []rest.FilterOptions{
+		{
+			Field:  "id",
+			Values: strings.Split(strings.Trim(fmt.Sprint(ids), "[]"), " "),
+		},
+		{
+			Field:  "descendants",
+			Values: []string{"true"},
+		},
+	}
+
+	uri, err := c.base.BuildURL(c.resource, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if !response.IsOk {
+		return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	}
+	var tags []Tag
+	defer response.Body().Close()
+	if err = json.NewDecoder(response.Body()).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode body due to error %v", err)
+	}
+	return tags, nil
+}
+
+func (c *client) GetAll(ctx context.Context, ownerUUID string) ([]byte, error) {
+	var tags []byte
 
 	uri, err := c.base.BuildURL(c.resource, nil)
 	if err != nil {
-		return tagUUID, fmt.Errorf("failed to build URL. error: %v", err)
+		return tags, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return tags, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return tags, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		tags, err = response.ReadBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body")
+		}
+		return tags, nil
+	}
+	return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Create(ctx context.Context, ownerUUID string, tag CreateTagDTO, onConflict string) (string, []byte, error) {
+	var tagUUID string
+
+	var filters []rest.FilterOptions
+	if onConflict != "" {
+		filters = append(filters, rest.FilterOptions{Field: "on_conflict", Values: []string{onConflict}})
+	}
+	uri, err := c.base.BuildURL(c.resource, filters)
+	if err != nil {
+		return tagUUID, nil, fmt.Errorf("failed to build URL. error: %v", err)
 	}
 	c.base.Logger.Tracef("url: %s", uri)
 
@@ -127,37 +274,49 @@ func (c *client) Create(ctx context.Context, tag CreateTagDTO) (string, error) {
 
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
-		return tagUUID, fmt.Errorf("failed to marshal dto")
+		return tagUUID, nil, fmt.Errorf("failed to marshal dto")
 	}
 
 	req, err := http.NewRequest("POST", uri, bytes.NewBuffer(dataBytes))
 	if err != nil {
-		return tagUUID, fmt.Errorf("failed to create new request due to error: %v", err)
+		return tagUUID, nil, fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	req.Header.Set("X-User-UUID", ownerUUID)
 
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	req = req.WithContext(reqCtx)
 	response, err := c.base.SendRequest(req)
 	if err != nil {
-		return tagUUID, fmt.Errorf("failed to send request due to error: %v", err)
+		return tagUUID, nil, fmt.Errorf("failed to send request due to error: %v", err)
 	}
 
 	if response.IsOk {
 		tagURL, err := response.Location()
 		if err != nil {
-			return tagUUID, fmt.Errorf("failed to get Location header")
+			return tagUUID, nil, fmt.Errorf("failed to get Location header")
 		}
 		c.base.Logger.Tracef("Location: %s", tagURL.String())
 
 		splitCategoryURL := strings.Split(tagURL.String(), "/")
 		tagUUID = splitCategoryURL[len(splitCategoryURL)-1]
-		return tagUUID, nil
+
+		// 200 instead of 201 answers an exact duplicate with the existing tag
+		if response.StatusCode() == http.StatusOK {
+			existing, err := response.ReadBody()
+			if err != nil {
+				return tagUUID, nil, fmt.Errorf("failed to read body")
+			}
+			return tagUUID, existing, nil
+		}
+		return tagUUID, nil, nil
 	}
-	return tagUUID, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr := apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr.ConflictingID = response.Error.ConflictingID
+	return tagUUID, nil, appErr
 }
 
-func (c *client) Update(ctx context.Context, uuid string, tag UpdateTagDTO) error {
+func (c *client) Update(ctx context.Context, ownerUUID string, uuid string, tag UpdateTagDTO) error {
 	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s", c.resource, uuid), nil)
 	if err != nil {
 		return fmt.Errorf("failed to build URL. error: %v", err)
@@ -176,6 +335,7 @@ func (c *client) Update(ctx context.Context, uuid string, tag UpdateTagDTO) erro
 	if err != nil {
 		return fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	req.Header.Set("X-User-UUID", ownerUUID)
 
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -188,10 +348,12 @@ func (c *client) Update(ctx context.Context, uuid string, tag UpdateTagDTO) erro
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr := apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr.ConflictingID = response.Error.ConflictingID
+	return appErr
 }
 
-func (c *client) Delete(ctx context.Context, id string) error {
+func (c *client) Delete(ctx context.Context, ownerUUID string, id string) error {
 	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s", c.resource, id), nil)
 	if err != nil {
 		return fmt.Errorf("failed to build URL. error: %v", err)
@@ -202,6 +364,39 @@ func (c *client) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create new request due to error: %v", err)
 	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) AddAlias(ctx context.Context, ownerUUID string, id string, alias string) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/aliases", c.resource, id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	dataBytes, err := json.Marshal(AliasDTO{Alias: alias})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dto")
+	}
+
+	req, err := http.NewRequest("POST", uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
 
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -214,5 +409,95 @@ func (c *client) Delete(ctx context.Context, id string) error {
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr := apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr.ConflictingID = response.Error.ConflictingID
+	return appErr
+}
+
+func (c *client) RemoveAlias(ctx context.Context, ownerUUID string, id string, alias string) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/aliases/%s", c.resource, id, url.PathEscape(alias)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Resolve(ctx context.Context, ownerUUID string, names []string) ([]ResolvedName, error) {
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/resolve", c.resource), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	// names may contain commas, so they go as repeated parameters rather than a filter
+	uri += "?" + url.Values{"name": names}.Encode()
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+	req.Header.Set("X-User-UUID", ownerUUID)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if !response.IsOk {
+		return nil, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	}
+	var resolved []ResolvedName
+	defer response.Body().Close()
+	if err = json.NewDecoder(response.Body()).Decode(&resolved); err != nil {
+		return nil, fmt.Errorf("failed to decode body due to error %v", err)
+	}
+	return resolved, nil
+}
+
+func (c *client) DeleteByOwner(ctx context.Context, ownerUUID string) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("/owners/%s", ownerUUID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %v", err)
+	}
+
+	// the service walks all data of the owner, a timed out purge is resumed by the next attempt
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %v", err)
+	}
+
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
@@ -0,0 +1,74 @@
+package user_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/pkg/rest"
+	"net/http"
+	"time"
+)
+
+const deletionsResource = "/deletions"
+
+func (c *client) StartDeletion(ctx context.Context, uuid string, steps []string) (d Deletion, err error) {
+	err = c.sendDeletionRequest(ctx, http.MethodPost, deletionsResource, nil, StartDeletionDTO{UserUUID: uuid, Steps: steps}, &d)
+	return d, err
+}
+
+func (c *client) GetDeletion(ctx context.Context, uuid string) (d Deletion, err error) {
+	err = c.sendDeletionRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%s", deletionsResource, uuid), nil, nil, &d)
+	return d, err
+}
+
+func (c *client) RunningDeletions(ctx context.Context) (deletions []Deletion, err error) {
+	filters := []rest.FilterOptions{{Field: "status", Values: []string{DeletionRunning}}}
+	err = c.sendDeletionRequest(ctx, http.MethodGet, deletionsResource, filters, nil, &deletions)
+	return deletions, err
+}
+
+func (c *client) ReportDeletionStep(ctx context.Context, uuid, step, stepErr string) (d Deletion, err error) {
+	dto := ReportDeletionStepDTO{Step: step, Error: stepErr}
+	err = c.sendDeletionRequest(ctx, http.MethodPatch, fmt.Sprintf("%s/%s", deletionsResource, uuid), nil, dto, &d)
+	return d, err
+}
+
+// sendDeletionRequest sends dto to the deletions resource and decodes the answer into out
+func (c *client) sendDeletionRequest(ctx context.Context, method, resource string, filters []rest.FilterOptions, dto interface{}, out interface{}) error {
+	uri, err := c.base.BuildURL(resource, filters)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	var body bytes.Buffer
+	if dto != nil {
+		if err = json.NewEncoder(&body).Encode(dto); err != nil {
+			return fmt.Errorf("failed to marshal dto")
+		}
+	}
+
+	req, err := http.NewRequest(method, uri, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %w", err)
+	}
+
+	if response.IsOk {
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode body due to error %w", err)
+		}
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
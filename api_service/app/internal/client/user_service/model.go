@@ -1,9 +1,48 @@
 package user_service
 
+import (
+	"time"
+)
+
 type User struct {
 	UUID     string `json:"uuid" bson:"_id"`
 	Email    string `json:"email" bson:"email"`
 	Password string `json:"-" bson:"password,omitempty"`
+	Verified bool   `json:"verified" bson:"verified"`
+	// TOTPEnabled makes sign in ask for a one-time code after the password
+	TOTPEnabled bool `json:"totp_enabled" bson:"totp_enabled"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TOTPCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+type DisableTOTPDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type ResendVerificationDTO struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordDTO struct {
+	Token          string `json:"token"`
+	Password       string `json:"password"`
+	RepeatPassword string `json:"repeat_password"`
 }
 
 type SigninUserDTO struct {
@@ -19,7 +58,38 @@ type CreateUserDTO struct {
 
 type UpdateUserDTO struct {
 	Email       string `json:"email,omitempty"`
-	Password    string `json:"password,omitempty"`
 	OldPassword string `json:"old_password,omitempty"`
 	NewPassword string `json:"new_password,omitempty"`
 }
+
+// Deletion removes the account together with the data of the user in every service
+type Deletion struct {
+	UserUUID    string         `json:"user_uuid"`
+	Status      string         `json:"status"`
+	Steps       []DeletionStep `json:"steps"`
+	RequestedAt time.Time      `json:"requested_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	FinishedAt  *time.Time     `json:"finished_at,omitempty"`
+}
+
+const (
+	DeletionRunning = "running"
+	DeletionDone    = "done"
+)
+
+type DeletionStep struct {
+	Name     string `json:"name"`
+	Done     bool   `json:"done"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+type StartDeletionDTO struct {
+	UserUUID string   `json:"user_uuid"`
+	Steps    []string `json:"steps"`
+}
+
+type ReportDeletionStepDTO struct {
+	Step  string `json:"step"`
+	Error string `json:"error,omitempty"`
+}
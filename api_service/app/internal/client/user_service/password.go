@@ -0,0 +1,69 @@
+package user_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/structs"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"net/http"
+	"time"
+)
+
+const passwordResetsResource = "/password-resets"
+
+func (c *client) ForgotPassword(ctx context.Context, email string) error {
+	uri, err := c.base.BuildURL(passwordResetsResource, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	dataBytes, err := json.Marshal(map[string]string{"email": email})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dto")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	return c.sendBodilessRequest(ctx, req)
+}
+
+func (c *client) ResetPassword(ctx context.Context, dto ResetPasswordDTO) (u User, err error) {
+	uri, err := c.base.BuildURL(passwordResetsResource, nil)
+	if err != nil {
+		return u, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	structs.DefaultTagName = "json"
+	dataBytes, err := json.Marshal(structs.Map(dto))
+	if err != nil {
+		return u, fmt.Errorf("failed to marshal dto")
+	}
+
+	req, err := http.NewRequest(http.MethodPut, uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return u, fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return u, fmt.Errorf("failed to send request due to error: %w", err)
+	}
+
+	if response.IsOk {
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&u); err != nil {
+			return u, fmt.Errorf("failed to decode body due to error %w", err)
+		}
+		return u, nil
+	}
+	return u, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
@@ -36,14 +36,40 @@ func NewService(baseURL string, resource string, logger logging.Logger) UserServ
 }
 
 type UserService interface {
-	GetByEmailAndPassword(ctx context.Context, email, password string) (User, error)
+	// GetByEmailAndPassword signs the user in, clientIP is where the attempt comes from, failed
+	// attempts from it are throttled
+	GetByEmailAndPassword(ctx context.Context, email, password, clientIP string) (User, error)
 	GetByUUID(ctx context.Context, uuid string) (User, error)
 	Create(ctx context.Context, dto CreateUserDTO) (User, error)
 	Update(ctx context.Context, uuid string, dto UpdateUserDTO) error
 	Delete(ctx context.Context, uuid string) error
+	// Verify activates the account the verification token was mailed for
+	Verify(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	// ForgotPassword mails a reset token, unknown emails are not reported
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword sets a new password by a reset token and returns the user it belongs to
+	ResetPassword(ctx context.Context, dto ResetPasswordDTO) (User, error)
+	// Unlock lifts sign in throttling of the email and of the client address
+	Unlock(ctx context.Context, email, clientIP string) error
+	EnrollTOTP(ctx context.Context, uuid string) (TOTPEnrollment, error)
+	// ConfirmTOTP enables the second factor with the first code and returns recovery codes
+	ConfirmTOTP(ctx context.Context, uuid, code string) (RecoveryCodes, error)
+	// VerifyTOTP checks a one-time or a recovery code, each is accepted once. Wrong codes are
+	// throttled like wrong passwords, per account and per clientIP.
+	VerifyTOTP(ctx context.Context, uuid, code, clientIP string) error
+	DisableTOTP(ctx context.Context, uuid string, dto DisableTOTPDTO) error
+	// StartDeletion disables the user and records the deletion steps, a deletion in progress is
+	// returned as it is
+	StartDeletion(ctx context.Context, uuid string, steps []string) (Deletion, error)
+	GetDeletion(ctx context.Context, uuid string) (Deletion, error)
+	RunningDeletions(ctx context.Context) ([]Deletion, error)
+	// ReportDeletionStep records an attempt of the step, it failed when stepErr is not empty.
+	// The user is removed once every step is done.
+	ReportDeletionStep(ctx context.Context, uuid, step, stepErr string) (Deletion, error)
 }
 
-func (c *client) GetByEmailAndPassword(ctx context.Context, email, password string) (u User, err error) {
+func (c *client) GetByEmailAndPassword(ctx context.Context, email, password, clientIP string) (u User, err error) {
 	filters := []rest.FilterOptions{
 		{
 			Field:  "email",
@@ -65,6 +91,7 @@ func (c *client) GetByEmailAndPassword(ctx context.Context, email, password stri
 	if err != nil {
 		return u, fmt.Errorf("failed to create new request due to error: %w", err)
 	}
+	req.Header.Set("X-Client-IP", clientIP)
 
 	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -80,7 +107,9 @@ func (c *client) GetByEmailAndPassword(ctx context.Context, email, password stri
 		}
 		return u, nil
 	}
-	return u, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr := apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	appErr.RetryAfter = response.Error.RetryAfter
+	return u, appErr
 }
 
 func (c *client) GetByUUID(ctx context.Context, uuid string) (User, error) {
@@ -112,7 +141,7 @@ func (c *client) GetByUUID(ctx context.Context, uuid string) (User, error) {
 		}
 		return u, nil
 	}
-	return u, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return u, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) Create(ctx context.Context, dto CreateUserDTO) (User, error) {
@@ -161,7 +190,7 @@ func (c *client) Create(ctx context.Context, dto CreateUserDTO) (User, error) {
 		}
 		return u, nil
 	}
-	return u, apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return u, apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) Update(ctx context.Context, uuid string, dto UpdateUserDTO) error {
@@ -194,7 +223,7 @@ func (c *client) Update(ctx context.Context, uuid string, dto UpdateUserDTO) err
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
 
 func (c *client) Delete(ctx context.Context, uuid string) error {
@@ -220,5 +249,5 @@ func (c *client) Delete(ctx context.Context, uuid string) error {
 	if response.IsOk {
 		return nil
 	}
-	return apperror.APIError(response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
 }
@@ -0,0 +1,74 @@
+package user_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"net/http"
+	"time"
+)
+
+func (c *client) EnrollTOTP(ctx context.Context, uuid string) (e TOTPEnrollment, err error) {
+	err = c.sendTOTPRequest(ctx, http.MethodPost, uuid, "", "", nil, &e)
+	return e, err
+}
+
+func (c *client) ConfirmTOTP(ctx context.Context, uuid, code string) (codes RecoveryCodes, err error) {
+	err = c.sendTOTPRequest(ctx, http.MethodPut, uuid, "", "", TOTPCodeDTO{Code: code}, &codes)
+	return codes, err
+}
+
+func (c *client) VerifyTOTP(ctx context.Context, uuid, code, clientIP string) error {
+	return c.sendTOTPRequest(ctx, http.MethodPost, uuid, "/verify", clientIP, TOTPCodeDTO{Code: code}, nil)
+}
+
+func (c *client) DisableTOTP(ctx context.Context, uuid string, dto DisableTOTPDTO) error {
+	return c.sendTOTPRequest(ctx, http.MethodPost, uuid, "/disable", "", dto, nil)
+}
+
+// sendTOTPRequest sends dto to the totp resource of the user and decodes the answer into out
+// when it is not nil, clientIP is passed on when it is set
+func (c *client) sendTOTPRequest(ctx context.Context, method, uuid, action, clientIP string, dto interface{}, out interface{}) error {
+	uri, err := c.base.BuildURL(fmt.Sprintf("%s/%s/totp%s", c.Resource, uuid, action), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	var body bytes.Buffer
+	if dto != nil {
+		if err = json.NewEncoder(&body).Encode(dto); err != nil {
+			return fmt.Errorf("failed to marshal dto")
+		}
+	}
+
+	req, err := http.NewRequest(method, uri, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	if clientIP != "" {
+		req.Header.Set("X-Client-IP", clientIP)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %w", err)
+	}
+
+	if response.IsOk {
+		defer response.Body().Close()
+		if out == nil {
+			return nil
+		}
+		if err = json.NewDecoder(response.Body()).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode body due to error %w", err)
+		}
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
@@ -0,0 +1,87 @@
+package user_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/pkg/rest"
+	"net/http"
+	"time"
+)
+
+const verificationsResource = "/verifications"
+
+func (c *client) Verify(ctx context.Context, token string) error {
+	filters := []rest.FilterOptions{
+		{
+			Field:  "token",
+			Values: []string{token},
+		},
+	}
+	uri, err := c.base.BuildURL(verificationsResource, filters)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	return c.sendBodilessRequest(ctx, req)
+}
+
+func (c *client) ResendVerification(ctx context.Context, email string) error {
+	uri, err := c.base.BuildURL(verificationsResource, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	dataBytes, err := json.Marshal(map[string]string{"email": email})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dto")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	return c.sendBodilessRequest(ctx, req)
+}
+
+func (c *client) Unlock(ctx context.Context, email, clientIP string) error {
+	var filters []rest.FilterOptions
+	if email != "" {
+		filters = append(filters, rest.FilterOptions{Field: "email", Values: []string{email}})
+	}
+	if clientIP != "" {
+		filters = append(filters, rest.FilterOptions{Field: "ip", Values: []string{clientIP}})
+	}
+	uri, err := c.base.BuildURL("/lockouts", filters)
+	if err != nil {
+		return fmt.Errorf("failed to build URL. error: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	return c.sendBodilessRequest(ctx, req)
+}
+
+// sendBodilessRequest sends requests the service answers without a body
+func (c *client) sendBodilessRequest(ctx context.Context, req *http.Request) error {
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request due to error: %w", err)
+	}
+	if response.IsOk {
+		return nil
+	}
+	return apperror.APIError(response.StatusCode(), response.Error.ErrorCode, response.Error.Message, response.Error.DeveloperMessage)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
 	"sync"
+	"time"
 )
 
 type Config struct {
@@ -11,6 +12,13 @@ type Config struct {
 	JWT     struct {
 		Secret string `yaml:"secret" env-required:"true"`
 	}
+	Auth struct {
+		// TrustForwardedFor takes the client address of sign ins from X-Forwarded-For, enable it
+		// behind a proxy only
+		TrustForwardedFor bool `yaml:"trust_forwarded_for"`
+		// Admins are uuids of users allowed to lift sign in lockouts
+		Admins []string `yaml:"admins"`
+	} `yaml:"auth"`
 	Listen struct {
 		Type   string `yaml:"type" env-default:"port"`
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
@@ -28,6 +36,34 @@ type Config struct {
 	TagService struct {
 		URL string `yaml:"url" env-required:"true"`
 	} `yaml:"tag_service" env-required:"true"`
+	FileService struct {
+		URL string `yaml:"url" env-required:"true"`
+	} `yaml:"file_service" env-required:"true"`
+	Quota struct {
+		DefaultPlan string               `yaml:"default_plan" env-default:"free"`
+		Plans       map[string]QuotaPlan `yaml:"plans"`
+		// Users assigns plans other than the default one, user uuid to plan name
+		Users map[string]string `yaml:"users"`
+	} `yaml:"quota"`
+	Deletion struct {
+		// RetryInterval is how often unfinished account deletions are resumed
+		RetryInterval time.Duration `yaml:"retry_interval" env-default:"1m"`
+	} `yaml:"deletion"`
+	Export struct {
+		// Dir keeps the archives, instances serving the same users share it
+		Dir string `yaml:"dir" env-default:"exports"`
+		// TTL is how long an archive can be downloaded once it is done
+		TTL time.Duration `yaml:"ttl" env-default:"24h"`
+		// CleanInterval is how often expired archives are removed
+		CleanInterval time.Duration `yaml:"clean_interval" env-default:"10m"`
+	} `yaml:"export"`
+}
+
+// QuotaPlan limits what one user may keep, zero means unlimited
+type QuotaPlan struct {
+	MaxNotes           int   `yaml:"max_notes"`
+	MaxBodyBytes       int64 `yaml:"max_body_bytes"`
+	MaxAttachmentBytes int64 `yaml:"max_attachment_bytes"`
 }
 
 var instance *Config
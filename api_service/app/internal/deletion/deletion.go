@@ -0,0 +1,155 @@
+package deletion
+
+import (
+	"context"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"sync"
+	"time"
+)
+
+// Step removes the data of a user from one service. Purge must be safe to run again, a step
+// that failed half way is retried from the start.
+type Step struct {
+	Name  string
+	Purge func(ctx context.Context, userUUID string) error
+}
+
+var _ Service = &service{}
+
+// Service runs account deletions. The progress is kept by user_service, so a deletion cut short
+// by a failure or a restart is resumed by any gateway instance.
+type Service interface {
+	// Start records the deletion and runs it in the background, a deletion in progress is
+	// returned as it is
+	Start(ctx context.Context, userUUID string) (user_service.Deletion, error)
+	Get(ctx context.Context, userUUID string) (user_service.Deletion, error)
+	// Resume runs the unfinished deletions again, those running in this process are skipped
+	Resume(ctx context.Context) error
+	// ResumeEvery calls Resume every interval until ctx is done
+	ResumeEvery(ctx context.Context, interval time.Duration)
+}
+
+type service struct {
+	users  user_service.UserService
+	steps  []Step
+	logger logging.Logger
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewService runs steps in the given order, user_service removes the user after the last one
+func NewService(users user_service.UserService, steps []Step, logger logging.Logger) Service {
+	return &service{
+		users:   users,
+		steps:   steps,
+		logger:  logger,
+		running: make(map[string]bool),
+	}
+}
+
+func (s *service) Start(ctx context.Context, userUUID string) (user_service.Deletion, error) {
+	names := make([]string, 0, len(s.steps))
+	for _, step := range s.steps {
+		names = append(names, step.Name)
+	}
+	d, err := s.users.StartDeletion(ctx, userUUID, names)
+	if err != nil {
+		return d, err
+	}
+	s.runAsync(d)
+	return d, nil
+}
+
+func (s *service) Get(ctx context.Context, userUUID string) (user_service.Deletion, error) {
+	return s.users.GetDeletion(ctx, userUUID)
+}
+
+func (s *service) Resume(ctx context.Context) error {
+	deletions, err := s.users.RunningDeletions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get running deletions. error: %w", err)
+	}
+	for _, d := range deletions {
+		s.runAsync(d)
+	}
+	return nil
+}
+
+func (s *service) ResumeEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Resume(ctx); err != nil {
+			s.logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runAsync runs the deletion unless it is done or runs already in this process
+func (s *service) runAsync(d user_service.Deletion) {
+	if d.Status != user_service.DeletionRunning {
+		return
+	}
+	s.mu.Lock()
+	if s.running[d.UserUUID] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[d.UserUUID] = true
+	s.mu.Unlock()
+
+	// the deletion outlives the request that started it
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, d.UserUUID)
+			s.mu.Unlock()
+		}()
+		s.run(context.Background(), d)
+	}()
+}
+
+// run takes the steps that are not done in the recorded order and stops at the first failure,
+// the next Resume goes on from there
+func (s *service) run(ctx context.Context, d user_service.Deletion) {
+	for _, recorded := range d.Steps {
+		if recorded.Done {
+			continue
+		}
+		step, ok := s.step(recorded.Name)
+		if !ok {
+			s.logger.Errorf("deletion of user %s has unknown step %s", d.UserUUID, recorded.Name)
+			return
+		}
+
+		var stepErr string
+		if err := step.Purge(ctx, d.UserUUID); err != nil {
+			stepErr = err.Error()
+		}
+		if _, err := s.users.ReportDeletionStep(ctx, d.UserUUID, step.Name, stepErr); err != nil {
+			s.logger.Errorf("failed to report step %s of the deletion of user %s due to error %v", step.Name, d.UserUUID, err)
+			return
+		}
+		if stepErr != "" {
+			s.logger.Warnf("deletion of user %s failed at step %s: %s", d.UserUUID, step.Name, stepErr)
+			return
+		}
+	}
+}
+
+func (s *service) step(name string) (Step, bool) {
+	for _, step := range s.steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
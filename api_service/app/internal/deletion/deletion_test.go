@@ -0,0 +1,151 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+// fakeUsers keeps deletions the way user_service does
+type fakeUsers struct {
+	user_service.UserService
+	mu        sync.Mutex
+	deletions map[string]user_service.Deletion
+	reports   chan string
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{deletions: make(map[string]user_service.Deletion), reports: make(chan string, 16)}
+}
+
+func (f *fakeUsers) StartDeletion(ctx context.Context, uuid string, steps []string) (user_service.Deletion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d, ok := f.deletions[uuid]; ok {
+		return d, nil
+	}
+	d := user_service.Deletion{UserUUID: uuid, Status: user_service.DeletionRunning}
+	for _, name := range steps {
+		d.Steps = append(d.Steps, user_service.DeletionStep{Name: name})
+	}
+	f.deletions[uuid] = d
+	return d, nil
+}
+
+func (f *fakeUsers) RunningDeletions(ctx context.Context) (deletions []user_service.Deletion, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.deletions {
+		if d.Status == user_service.DeletionRunning {
+			deletions = append(deletions, d)
+		}
+	}
+	return deletions, nil
+}
+
+func (f *fakeUsers) ReportDeletionStep(ctx context.Context, uuid, step, stepErr string) (user_service.Deletion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.deletions[uuid]
+	done := true
+	steps := make([]user_service.DeletionStep, len(d.Steps))
+	for i, s := range d.Steps {
+		if s.Name == step {
+			s.Attempts++
+			s.Error = stepErr
+			s.Done = stepErr == ""
+		}
+		done = done && s.Done
+		steps[i] = s
+	}
+	d.Steps = steps
+	if done {
+		d.Status = user_service.DeletionDone
+	}
+	f.deletions[uuid] = d
+	f.reports <- step + ":" + stepErr
+	return d, nil
+}
+
+func (f *fakeUsers) waitReport(t *testing.T) string {
+	t.Helper()
+	select {
+	case r := <-f.reports:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("no step was reported")
+		return ""
+	}
+}
+
+func TestDeletionResumesAfterFailedStep(t *testing.T) {
+	users := newFakeUsers()
+	var mu sync.Mutex
+	tagsDown := true
+	purged := map[string]int{}
+	purge := func(name string) func(ctx context.Context, userUUID string) error {
+		return func(ctx context.Context, userUUID string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if name == "tags" && tagsDown {
+				return errors.New("tag_service is down")
+			}
+			purged[name]++
+			return nil
+		}
+	}
+	s := NewService(users, []Step{{"notes", purge("notes")}, {"tags", purge("tags")}, {"categories", purge("categories")}}, testLogger)
+
+	if _, err := s.Start(context.Background(), "alice"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if r := users.waitReport(t); r != "notes:" {
+		t.Fatalf("first report %q", r)
+	}
+	if r := users.waitReport(t); r != "tags:tag_service is down" {
+		t.Fatalf("second report %q", r)
+	}
+
+	mu.Lock()
+	tagsDown = false
+	mu.Unlock()
+	// the failed run may still be leaving, Resume skips deletions running in the process
+	deadline := time.Now().Add(time.Second)
+	for {
+		if err := s.Resume(context.Background()); err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		select {
+		case r := <-users.reports:
+			if r != "tags:" {
+				t.Fatalf("resumed report %q", r)
+			}
+		case <-time.After(10 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("deletion was not resumed")
+			}
+			continue
+		}
+		break
+	}
+	if r := users.waitReport(t); r != "categories:" {
+		t.Fatalf("last report %q", r)
+	}
+
+	users.mu.Lock()
+	status := users.deletions["alice"].Status
+	users.mu.Unlock()
+	mu.Lock()
+	defer mu.Unlock()
+	if status != user_service.DeletionDone || purged["notes"] != 1 || purged["tags"] != 1 || purged["categories"] != 1 {
+		t.Fatalf("status %s, purged %v", status, purged)
+	}
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// category is what the archive needs of the category tree
+type category struct {
+	UUID     string     `json:"uuid"`
+	Children []category `json:"children"`
+}
+
+type noteRef struct {
+	UUID string `json:"uuid"`
+}
+
+type fileRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// collect writes the archive:
+//
+//	profile.json                      the user
+//	categories.json                   the category tree
+//	tags.json                         the tag tree
+//	notes/<note uuid>.json            every note of every category
+//	files/<note uuid>/<id>-<name>     the attachments of the notes
+func (s *service) collect(ctx context.Context, userUUID string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	u, err := s.clients.Users.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get profile. error: %w", err)
+	}
+	profile, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile. error: %w", err)
+	}
+	if err = writeEntry(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	categoriesBytes, err := s.clients.Categories.GetUserCategories(ctx, userUUID)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to get categories. error: %w", err)
+	}
+	var categories []category
+	if len(categoriesBytes) > 0 {
+		if err = json.Unmarshal(categoriesBytes, &categories); err != nil {
+			return fmt.Errorf("failed to unmarshal categories. error: %w", err)
+		}
+	} else {
+		categoriesBytes = []byte("[]")
+	}
+	if err = writeEntry(zw, "categories.json", categoriesBytes); err != nil {
+		return err
+	}
+
+	tags, err := s.clients.Tags.GetAll(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get tags. error: %w", err)
+	}
+	if err = writeEntry(zw, "tags.json", tags); err != nil {
+		return err
+	}
+
+	for _, categoryUUID := range categoryUUIDs(categories) {
+		if err = s.collectNotes(ctx, zw, categoryUUID); err != nil {
+			return err
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive. error: %w", err)
+	}
+	return nil
+}
+
+func (s *service) collectNotes(ctx context.Context, zw *zip.Writer, categoryUUID string) error {
+	notesBytes, err := s.clients.Notes.GetByCategoryUUID(ctx, categoryUUID, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get notes of category %s. error: %w", categoryUUID, err)
+	}
+	var notes []noteRef
+	if err = json.Unmarshal(notesBytes, &notes); err != nil {
+		return fmt.Errorf("failed to unmarshal notes of category %s. error: %w", categoryUUID, err)
+	}
+
+	for _, n := range notes {
+		// the list may carry short bodies only
+		note, err := s.clients.Notes.GetByUUID(ctx, n.UUID)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get note %s. error: %w", n.UUID, err)
+		}
+		if err = writeEntry(zw, "notes/"+n.UUID+".json", note); err != nil {
+			return err
+		}
+		if err = s.collectFiles(ctx, zw, n.UUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) collectFiles(ctx context.Context, zw *zip.Writer, noteUUID string) error {
+	filesBytes, err := s.clients.Files.GetByNoteUUID(ctx, noteUUID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get files of note %s. error: %w", noteUUID, err)
+	}
+	var files []fileRef
+	if err = json.Unmarshal(filesBytes, &files); err != nil {
+		return fmt.Errorf("failed to unmarshal files of note %s. error: %w", noteUUID, err)
+	}
+
+	for _, ref := range files {
+		f, err := s.clients.Files.GetFile(ctx, noteUUID, ref.ID)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get file %s of note %s. error: %w", ref.ID, noteUUID, err)
+		}
+		name := ref.Name
+		if f.Name != "" {
+			name = f.Name
+		}
+		if err = writeEntry(zw, fmt.Sprintf("files/%s/%s-%s", noteUUID, ref.ID, entryName(name)), f.Bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEntry(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive. error: %w", name, err)
+	}
+	if _, err = w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to archive. error: %w", name, err)
+	}
+	return nil
+}
+
+// categoryUUIDs flattens the tree, parents go before their children
+func categoryUUIDs(categories []category) (uuids []string) {
+	for _, c := range categories {
+		uuids = append(uuids, c.UUID)
+		uuids = append(uuids, categoryUUIDs(c.Children)...)
+	}
+	return uuids
+}
+
+// entryName keeps an uploaded name from leaving its directory in the archive
+func entryName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+func isNotFound(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Status == http.StatusNotFound
+}
@@ -0,0 +1,306 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/category_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/file_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+
+	archiveExt = ".zip"
+	// partExt marks an archive being written, it is renamed once complete
+	partExt = ".part"
+)
+
+type Export struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	RequestedAt time.Time  `json:"requested_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// ExpiresAt is when the archive is removed, it is set once the export is done
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+}
+
+// Clients are the services the data of the user is collected from
+type Clients struct {
+	Users      user_service.UserService
+	Categories category_service.CategoryService
+	Notes      note_service.NoteService
+	Tags       tag_service.TagService
+	Files      file_service.FileService
+}
+
+var _ Service = &service{}
+
+// Service builds archives with everything kept about a user. Archives are files in dir named by
+// the user and the export, so finished exports survive a restart and are served by every gateway
+// instance sharing dir. Exports in progress and failures are known to the instance running them only.
+type Service interface {
+	// Start collects the data in the background, an export of the user in progress is returned as it is
+	Start(ctx context.Context, userUUID string) (Export, error)
+	Get(ctx context.Context, userUUID, id string) (Export, error)
+	// Open returns the archive of a done export, the caller closes it
+	Open(ctx context.Context, userUUID, id string) (*os.File, Export, error)
+	// RemoveExpired removes archives and failures older than the time to live
+	RemoveExpired() error
+	// RemoveExpiredEvery calls RemoveExpired every interval until ctx is done
+	RemoveExpiredEvery(ctx context.Context, interval time.Duration)
+	// DeleteByOwner removes every archive of the user, it fails while an export of the user runs
+	// in this process so the caller tries again once it is done
+	DeleteByOwner(ctx context.Context, userUUID string) error
+}
+
+type service struct {
+	clients Clients
+	dir     string
+	ttl     time.Duration
+	logger  logging.Logger
+
+	mu sync.Mutex
+	// jobs are the exports of this process not done yet, by id
+	jobs map[string]*job
+}
+
+type job struct {
+	userUUID string
+	export   Export
+}
+
+// NewService keeps archives in dir for ttl after they are done
+func NewService(clients Clients, dir string, ttl time.Duration, logger logging.Logger) (Service, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create export dir %s. error: %w", dir, err)
+	}
+	return &service{
+		clients: clients,
+		dir:     dir,
+		ttl:     ttl,
+		logger:  logger,
+		jobs:    make(map[string]*job),
+	}, nil
+}
+
+func (s *service) Start(ctx context.Context, userUUID string) (Export, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.userUUID == userUUID && j.export.Status == StatusRunning {
+			return j.export, nil
+		}
+	}
+
+	j := &job{userUUID: userUUID, export: Export{
+		ID:          uuid.New().String(),
+		Status:      StatusRunning,
+		RequestedAt: time.Now().UTC(),
+	}}
+	s.jobs[j.export.ID] = j
+
+	// the export outlives the request that started it
+	go s.run(context.Background(), j)
+
+	return j.export, nil
+}
+
+func (s *service) Get(ctx context.Context, userUUID, id string) (Export, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return Export{}, apperror.ErrNotFound
+	}
+
+	s.mu.Lock()
+	var j job
+	if running, ok := s.jobs[id]; ok {
+		j = *running
+	}
+	s.mu.Unlock()
+	if j.userUUID != "" {
+		if j.userUUID != userUUID {
+			return Export{}, apperror.ErrNotFound
+		}
+		return j.export, nil
+	}
+
+	info, err := os.Stat(s.archivePath(userUUID, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Export{}, apperror.ErrNotFound
+		}
+		return Export{}, fmt.Errorf("failed to stat archive of export %s. error: %w", id, err)
+	}
+	finishedAt := info.ModTime().UTC()
+	expiresAt := finishedAt.Add(s.ttl)
+	if time.Now().After(expiresAt) {
+		return Export{}, apperror.ErrNotFound
+	}
+	return Export{
+		ID:         id,
+		Status:     StatusDone,
+		FinishedAt: &finishedAt,
+		ExpiresAt:  &expiresAt,
+		Size:       info.Size(),
+	}, nil
+}
+
+func (s *service) Open(ctx context.Context, userUUID, id string) (*os.File, Export, error) {
+	e, err := s.Get(ctx, userUUID, id)
+	if err != nil || e.Status != StatusDone {
+		return nil, e, err
+	}
+	f, err := os.Open(s.archivePath(userUUID, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, e, apperror.ErrNotFound
+		}
+		return nil, e, fmt.Errorf("failed to open archive of export %s. error: %w", id, err)
+	}
+	return f, e, nil
+}
+
+func (s *service) RemoveExpired() error {
+	now := time.Now()
+
+	running := make(map[string]bool)
+	s.mu.Lock()
+	for id, j := range s.jobs {
+		if j.export.Status == StatusRunning {
+			running[j.userUUID] = true
+		} else if now.After(j.export.FinishedAt.Add(s.ttl)) {
+			delete(s.jobs, id)
+		}
+	}
+	s.mu.Unlock()
+
+	users, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read export dir. error: %w", err)
+	}
+	for _, u := range users {
+		if !u.IsDir() {
+			continue
+		}
+		userDir := filepath.Join(s.dir, u.Name())
+		archives, err := ioutil.ReadDir(userDir)
+		if err != nil {
+			return fmt.Errorf("failed to read export dir of user %s. error: %w", u.Name(), err)
+		}
+		left := len(archives)
+		for _, a := range archives {
+			// a part file this old belongs to an export cut short by a restart
+			if now.Before(a.ModTime().Add(s.ttl)) {
+				continue
+			}
+			if err = os.Remove(filepath.Join(userDir, a.Name())); err != nil {
+				return fmt.Errorf("failed to remove expired archive %s. error: %w", a.Name(), err)
+			}
+			left--
+		}
+		if left == 0 && !running[u.Name()] {
+			os.Remove(userDir)
+		}
+	}
+	return nil
+}
+
+func (s *service) RemoveExpiredEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RemoveExpired(); err != nil {
+			s.logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) DeleteByOwner(ctx context.Context, userUUID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, j := range s.jobs {
+		if j.userUUID != userUUID {
+			continue
+		}
+		if j.export.Status == StatusRunning {
+			return fmt.Errorf("export %s of user %s is running", id, userUUID)
+		}
+		delete(s.jobs, id)
+	}
+
+	if err := os.RemoveAll(filepath.Join(s.dir, userUUID)); err != nil {
+		return fmt.Errorf("failed to remove archives of user %s. error: %w", userUUID, err)
+	}
+	return nil
+}
+
+func (s *service) run(ctx context.Context, j *job) {
+	size, err := s.build(ctx, j.userUUID, j.export.ID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.logger.Errorf("export %s of user %s failed due to error %v", j.export.ID, j.userUUID, err)
+		finishedAt := time.Now().UTC()
+		j.export.Status = StatusFailed
+		j.export.Error = err.Error()
+		j.export.FinishedAt = &finishedAt
+		return
+	}
+	s.logger.Infof("export %s of user %s is done, %d bytes", j.export.ID, j.userUUID, size)
+	// the archive on disk answers from now on
+	delete(s.jobs, j.export.ID)
+}
+
+// build writes the archive under a part name and renames it once complete
+func (s *service) build(ctx context.Context, userUUID, id string) (int64, error) {
+	path := s.archivePath(userUUID, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, fmt.Errorf("failed to create export dir of the user. error: %w", err)
+	}
+	f, err := os.OpenFile(path+partExt, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create archive. error: %w", err)
+	}
+	err = s.collect(ctx, userUUID, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return 0, err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return 0, fmt.Errorf("failed to complete archive. error: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat archive. error: %w", err)
+	}
+	return info.Size(), nil
+}
+
+func (s *service) archivePath(userUUID, id string) string {
+	// the user uuid comes from a signed access token, the id is checked to be a uuid by Get
+	return filepath.Join(s.dir, userUUID, id+archiveExt)
+}
@@ -0,0 +1,228 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/category_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/file_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+type fakeUsers struct{ user_service.UserService }
+
+func (fakeUsers) GetByUUID(ctx context.Context, uuid string) (user_service.User, error) {
+	return user_service.User{UUID: uuid, Email: "a@b.c", Password: "hash"}, nil
+}
+
+type fakeCategories struct {
+	category_service.CategoryService
+}
+
+func (fakeCategories) GetUserCategories(ctx context.Context, userUuid string) ([]byte, error) {
+	return []byte(`[{"uuid":"work","name":"work","children":[{"uuid":"acme","name":"acme","parent_uuid":"work"}]}]`), nil
+}
+
+type fakeTags struct{ tag_service.TagService }
+
+func (fakeTags) GetAll(ctx context.Context, ownerUUID string) ([]byte, error) {
+	return []byte(`[{"id":1,"name":"todo"}]`), nil
+}
+
+type fakeNotes struct{ note_service.NoteService }
+
+func (fakeNotes) GetByCategoryUUID(ctx context.Context, categoryUUID string, tagIDs []int) ([]byte, error) {
+	if categoryUUID == "acme" {
+		return []byte(`[{"uuid":"n1","header":"plan"},{"uuid":"gone"}]`), nil
+	}
+	return nil, apperror.APIError(http.StatusNotFound, "NS-000003", "not found", "")
+}
+
+func (fakeNotes) GetByUUID(ctx context.Context, uuid string) ([]byte, error) {
+	if uuid == "gone" {
+		return nil, apperror.APIError(http.StatusNotFound, "NS-000003", "not found", "")
+	}
+	return []byte(`{"uuid":"` + uuid + `","header":"plan","body":"the whole body"}`), nil
+}
+
+type fakeFiles struct {
+	file_service.FileService
+	down bool
+}
+
+func (f fakeFiles) GetByNoteUUID(ctx context.Context, noteUUID string) ([]byte, error) {
+	if f.down {
+		return nil, errors.New("file_service is down")
+	}
+	return []byte(`[{"id":"f1","name":"../../etc/passwd","size":3}]`), nil
+}
+
+func (fakeFiles) GetFile(ctx context.Context, noteUUID, fileID string) (file_service.File, error) {
+	return file_service.File{Bytes: []byte("abc")}, nil
+}
+
+func newService(t *testing.T, files fakeFiles, ttl time.Duration) Service {
+	t.Helper()
+	s, err := NewService(Clients{
+		Users:      fakeUsers{},
+		Categories: fakeCategories{},
+		Notes:      fakeNotes{},
+		Tags:       fakeTags{},
+		Files:      files,
+	}, t.TempDir(), ttl, testLogger)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	return s
+}
+
+func wait(t *testing.T, s Service, userUUID, id string) Export {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		e, err := s.Get(context.Background(), userUUID, id)
+		if err != nil {
+			t.Fatalf("get export: %v", err)
+		}
+		if e.Status != StatusRunning {
+			return e
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("export is still running")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExportArchivesEverything(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, fakeFiles{}, time.Hour)
+
+	e, err := s.Start(ctx, "alice")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if e = wait(t, s, "alice", e.ID); e.Status != StatusDone || e.ExpiresAt == nil {
+		t.Fatalf("export = %+v", e)
+	}
+	if _, err = s.Get(ctx, "bob", e.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("export of alice for bob: %v", err)
+	}
+
+	f, _, err := s.Open(ctx, "alice", e.ID)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	zr, err := zip.NewReader(f, e.Size)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	entries := make(map[string]string)
+	var names []string
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", zf.Name, err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		entries[zf.Name] = string(data)
+		names = append(names, zf.Name)
+	}
+	sort.Strings(names)
+	want := []string{"categories.json", "files/n1/f1-passwd", "notes/n1.json", "profile.json", "tags.json"}
+	if len(names) != len(want) {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("entries = %v, want %v", names, want)
+		}
+	}
+	if entries["files/n1/f1-passwd"] != "abc" || entries["notes/n1.json"] != `{"uuid":"n1","header":"plan","body":"the whole body"}` {
+		t.Fatalf("entries = %v", entries)
+	}
+}
+
+func TestFailedExportAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, fakeFiles{down: true}, time.Hour)
+
+	e, err := s.Start(ctx, "alice")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if e = wait(t, s, "alice", e.ID); e.Status != StatusFailed || e.Error == "" {
+		t.Fatalf("export = %+v, want failed", e)
+	}
+	if f, got, err := s.Open(ctx, "alice", e.ID); f != nil || err != nil || got.Status != StatusFailed {
+		t.Fatalf("open failed export = %v, %+v, %v", f, got, err)
+	}
+
+	s = newService(t, fakeFiles{}, time.Millisecond)
+	if e, err = s.Start(ctx, "alice"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	// the archive may expire before the wait sees it done
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err = s.Get(ctx, "alice", e.ID); errors.Is(err, apperror.ErrNotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("export did not expire: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err = s.RemoveExpired(); err != nil {
+		t.Fatalf("remove expired: %v", err)
+	}
+	dir := s.(*service).dir
+	if left, _ := ioutil.ReadDir(dir); len(left) != 0 {
+		t.Fatalf("left in export dir: %v", left)
+	}
+}
+
+func TestDeleteByOwnerRemovesArchives(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, fakeFiles{}, time.Hour)
+
+	alice, err := s.Start(ctx, "alice")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	bob, err := s.Start(ctx, "bob")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	wait(t, s, "alice", alice.ID)
+	wait(t, s, "bob", bob.ID)
+
+	if err = s.DeleteByOwner(ctx, "alice"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = s.Get(ctx, "alice", alice.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("export of alice after delete: %v", err)
+	}
+	if e, err := s.Get(ctx, "bob", bob.ID); err != nil || e.Status != StatusDone {
+		t.Fatalf("export of bob = %+v, %v", e, err)
+	}
+	// nothing left is deleted again
+	if err = s.DeleteByOwner(ctx, "alice"); err != nil {
+		t.Fatalf("second delete: %v", err)
+	}
+}
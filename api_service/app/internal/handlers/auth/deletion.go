@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"net/http"
+)
+
+const (
+	meURL         = "/api/me"
+	meDeletionURL = "/api/me/deletion"
+)
+
+type DeleteAccountDTO struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccount confirms the password and starts deleting the account with all data of the user.
+// It answers 202 Accepted, the progress is at GET /api/me/deletion until the access token expires.
+func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+
+	defer r.Body.Close()
+	var dto DeleteAccountDTO
+	if err = json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+	if dto.Password == "" {
+		return apperror.BadRequestError("password is required")
+	}
+
+	u, err := h.UserService.GetByUUID(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	// a wrong password counts as a failed sign in, guesses are throttled the same way
+	if _, err = h.UserService.GetByEmailAndPassword(r.Context(), u.Email, dto.Password, h.clientIP(r)); err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Status == http.StatusNotFound {
+			return apperror.ForbiddenError("password is wrong")
+		}
+		return err
+	}
+
+	d, err := h.Deletions.Start(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	// data written from now on would outlive the purge
+	jwt.RevokeAccess(userUUID)
+	revoked := h.JWTHelper.RevokeUserTokens(userUUID)
+	h.Logger.Infof("deletion of user %s requested, %d refresh tokens revoked", userUUID, revoked)
+
+	w.Header().Set("Location", meDeletionURL)
+	return writeJSON(w, http.StatusAccepted, d)
+}
+
+func (h *Handler) GetDeletion(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	d, err := h.Deletions.Get(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, d)
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/deletion"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeDeletions struct {
+	deletion.Service
+	started []string
+}
+
+func (f *fakeDeletions) Start(ctx context.Context, userUUID string) (user_service.Deletion, error) {
+	f.started = append(f.started, userUUID)
+	return user_service.Deletion{UserUUID: userUUID, Status: user_service.DeletionRunning}, nil
+}
+
+func TestDeleteAccountRequiresPassword(t *testing.T) {
+	tokens := &fakeJWT{}
+	deletions := &fakeDeletions{}
+	h := &Handler{
+		UserService: &fakeUsers{user: user_service.User{UUID: "u1", Email: "a@b.c"}, password: "secret"},
+		JWTHelper:   tokens,
+		Deletions:   deletions,
+		Logger:      logging.Logger{Entry: logrus.NewEntry(logrus.New())},
+	}
+	deleteAccount := func(password string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodDelete, meURL, strings.NewReader(`{"password":"`+password+`"}`))
+		r = r.WithContext(context.WithValue(r.Context(), "user_uuid", "u1"))
+		w := httptest.NewRecorder()
+		return w, h.DeleteAccount(w, r)
+	}
+
+	_, err := deleteAccount("guess")
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.Status != http.StatusForbidden {
+		t.Fatalf("want 403 for a wrong password, got %v", err)
+	}
+	if len(deletions.started) != 0 || len(tokens.revoked) != 0 {
+		t.Fatalf("wrong password started %v, revoked %v", deletions.started, tokens.revoked)
+	}
+
+	w, err := deleteAccount("secret")
+	if err != nil {
+		t.Fatalf("delete account: %v", err)
+	}
+	if w.Code != http.StatusAccepted || w.Header().Get("Location") != meDeletionURL {
+		t.Fatalf("want 202 with Location %s, got %d %q", meDeletionURL, w.Code, w.Header().Get("Location"))
+	}
+	if len(deletions.started) != 1 || len(tokens.revoked) != 1 || tokens.revoked[0] != "u1" {
+		t.Fatalf("started %v, revoked %v", deletions.started, tokens.revoked)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
 	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/deletion"
 	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
 	"net/http"
@@ -13,18 +14,36 @@ import (
 const (
 	authURL   = "/api/auth"
 	signupURL = "/api/signup"
+	verifyURL = "/api/verify"
 )
 
 type Handler struct {
 	Logger      logging.Logger
 	UserService user_service.UserService
 	JWTHelper   jwt.Helper
+	// TrustForwardedFor takes client addresses from X-Forwarded-For
+	TrustForwardedFor bool
+	// Admins are uuids of users allowed to lift lockouts
+	Admins []string
+	// Deletions deletes accounts together with the data of the users in every service
+	Deletions deletion.Service
 }
 
 func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodPost, authURL, apperror.Middleware(h.Auth))
 	router.HandlerFunc(http.MethodPut, authURL, apperror.Middleware(h.Auth))
 	router.HandlerFunc(http.MethodPost, signupURL, apperror.Middleware(h.Signup))
+	router.HandlerFunc(http.MethodGet, verifyURL, apperror.Middleware(h.Verify))
+	router.HandlerFunc(http.MethodPost, verifyURL, apperror.Middleware(h.ResendVerification))
+	router.HandlerFunc(http.MethodPost, forgotPasswordURL, apperror.Middleware(h.ForgotPassword))
+	router.HandlerFunc(http.MethodPost, resetPasswordURL, apperror.Middleware(h.ResetPassword))
+	router.HandlerFunc(http.MethodDelete, lockoutsURL, jwt.Middleware(apperror.Middleware(h.Unlock)))
+	router.HandlerFunc(http.MethodPost, mfaURL, apperror.Middleware(h.AuthMFA))
+	router.HandlerFunc(http.MethodPost, totpURL, jwt.Middleware(apperror.Middleware(h.EnrollTOTP)))
+	router.HandlerFunc(http.MethodPut, totpURL, jwt.Middleware(apperror.Middleware(h.ConfirmTOTP)))
+	router.HandlerFunc(http.MethodPost, totpDisableURL, jwt.Middleware(apperror.Middleware(h.DisableTOTP)))
+	router.HandlerFunc(http.MethodDelete, meURL, jwt.Middleware(apperror.Middleware(h.DeleteAccount)))
+	router.HandlerFunc(http.MethodGet, meDeletionURL, jwt.Middleware(apperror.Middleware(h.GetDeletion)))
 }
 
 func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) error {
@@ -40,13 +59,46 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	token, err := h.JWTHelper.GenerateAccessToken(u)
+	// no tokens until the email is verified, the user signs in after opening the mailed link
+	userBytes, err := json.Marshal(u)
 	if err != nil {
 		return err
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	w.Write(token)
+	w.Write(userBytes)
+
+	return nil
+}
+
+// Verify is the target of the link mailed on signup
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return apperror.BadRequestError("token is required")
+	}
+	if err := h.UserService.Verify(r.Context(), token); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+	var dto user_service.ResendVerificationDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+	if err := h.UserService.ResendVerification(r.Context(), dto.Email); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
 
 	return nil
 }
@@ -63,10 +115,16 @@ func (h *Handler) Auth(w http.ResponseWriter, r *http.Request) error {
 		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 			return apperror.BadRequestError("failed to decode data")
 		}
-		u, err := h.UserService.GetByEmailAndPassword(r.Context(), dto.Email, dto.Password)
+		u, err := h.UserService.GetByEmailAndPassword(r.Context(), dto.Email, dto.Password, h.clientIP(r))
 		if err != nil {
 			return err
 		}
+		if !u.Verified {
+			return apperror.UnverifiedError("email is not verified")
+		}
+		if u.TOTPEnabled {
+			return h.challengeMFA(w, u)
+		}
 		token, err = h.JWTHelper.GenerateAccessToken(u)
 		if err != nil {
 			return err
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeUsers struct {
+	user_service.UserService
+	user       user_service.User
+	retryAfter int
+	clientIPs  []string
+	password   string
+}
+
+func (f *fakeUsers) GetByUUID(ctx context.Context, uuid string) (user_service.User, error) {
+	return f.user, nil
+}
+
+func (f *fakeUsers) VerifyTOTP(ctx context.Context, uuid, code, clientIP string) error {
+	if code != "123456" {
+		return apperror.APIError(http.StatusBadRequest, "NS-000002", "invalid code", "")
+	}
+	return nil
+}
+
+func (f *fakeUsers) ResetPassword(ctx context.Context, dto user_service.ResetPasswordDTO) (user_service.User, error) {
+	if dto.Token != "good" {
+		return user_service.User{}, apperror.APIError(http.StatusBadRequest, "NS-000002", "invalid or expired reset token", "")
+	}
+	return f.user, nil
+}
+
+func (f *fakeUsers) GetByEmailAndPassword(ctx context.Context, email, password, clientIP string) (user_service.User, error) {
+	f.clientIPs = append(f.clientIPs, clientIP)
+	if f.retryAfter > 0 {
+		appErr := apperror.APIError(http.StatusTooManyRequests, "NS-000004", "too many failed sign in attempts", "")
+		appErr.RetryAfter = f.retryAfter
+		return user_service.User{}, appErr
+	}
+	if f.password != "" && password != f.password {
+		return user_service.User{}, apperror.APIError(http.StatusNotFound, "NS-000003", "not found", "")
+	}
+	return f.user, nil
+}
+
+func (f *fakeUsers) Create(ctx context.Context, dto user_service.CreateUserDTO) (user_service.User, error) {
+	if strings.EqualFold(strings.TrimSpace(dto.Email), f.user.Email) {
+		return user_service.User{}, apperror.APIError(http.StatusConflict, "NS-000005", "email is taken", "")
+	}
+	return user_service.User{UUID: "u2", Email: dto.Email}, nil
+}
+
+type fakeJWT struct {
+	jwt.Helper
+	revoked    []string
+	challenges map[string]user_service.User
+	failures   int
+}
+
+func (f *fakeJWT) CreateMFAChallenge(u user_service.User) (string, error) {
+	f.challenges = map[string]user_service.User{"challenge-1": u}
+	return "challenge-1", nil
+}
+
+func (f *fakeJWT) MFAChallengeUser(token string) (user_service.User, error) {
+	u, ok := f.challenges[token]
+	if !ok {
+		return u, jwt.ErrNoChallenge
+	}
+	return u, nil
+}
+
+func (f *fakeJWT) FailMFAChallenge(token string) {
+	f.failures++
+}
+
+func (f *fakeJWT) CompleteMFAChallenge(token string) ([]byte, error) {
+	delete(f.challenges, token)
+	return []byte(`{"token":"t"}`), nil
+}
+
+func (f *fakeJWT) RevokeUserTokens(userUUID string) int {
+	f.revoked = append(f.revoked, userUUID)
+	return 1
+}
+
+func (f *fakeJWT) GenerateAccessToken(u user_service.User) ([]byte, error) {
+	return []byte(`{"token":"t"}`), nil
+}
+
+func signin(h *Handler) (*httptest.ResponseRecorder, error) {
+	r := httptest.NewRequest(http.MethodPost, authURL, strings.NewReader(`{"email":"a@b.c","password":"p"}`))
+	w := httptest.NewRecorder()
+	return w, h.Auth(w, r)
+}
+
+func TestAuthRequiresVerifiedEmail(t *testing.T) {
+	users := &fakeUsers{user: user_service.User{UUID: "u1", Email: "a@b.c"}}
+	h := &Handler{UserService: users, JWTHelper: &fakeJWT{}}
+
+	_, err := signin(h)
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.Status != http.StatusForbidden {
+		t.Fatalf("want 403 for an unverified user, got %v", err)
+	}
+
+	users.user.Verified = true
+	w, err := signin(h)
+	if err != nil {
+		t.Fatalf("sign in: %v", err)
+	}
+	if w.Code != http.StatusCreated {
+		t.Fatalf("want 201, got %d", w.Code)
+	}
+}
+
+func TestResetPasswordRevokesRefreshTokens(t *testing.T) {
+	tokens := &fakeJWT{}
+	h := &Handler{
+		UserService: &fakeUsers{user: user_service.User{UUID: "u1", Verified: true}},
+		JWTHelper:   tokens,
+		Logger:      logging.Logger{Entry: logrus.NewEntry(logrus.New())},
+	}
+	reset := func(token string) (*httptest.ResponseRecorder, error) {
+		body := `{"token":"` + token + `","password":"n","repeat_password":"n"}`
+		w := httptest.NewRecorder()
+		return w, h.ResetPassword(w, httptest.NewRequest(http.MethodPost, resetPasswordURL, strings.NewReader(body)))
+	}
+
+	if _, err := reset("bad"); err == nil || len(tokens.revoked) != 0 {
+		t.Fatalf("bad token: err %v, revoked %v", err, tokens.revoked)
+	}
+	w, err := reset("good")
+	if err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if w.Code != http.StatusNoContent || len(tokens.revoked) != 1 || tokens.revoked[0] != "u1" {
+		t.Fatalf("want 204 and tokens of u1 revoked, got %d %v", w.Code, tokens.revoked)
+	}
+}
+
+func TestAuthThrottledPassesRetryAfter(t *testing.T) {
+	users := &fakeUsers{retryAfter: 30}
+	h := &Handler{UserService: users, JWTHelper: &fakeJWT{}, TrustForwardedFor: true}
+
+	r := httptest.NewRequest(http.MethodPost, authURL, strings.NewReader(`{"email":"a@b.c","password":"p"}`))
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	w := httptest.NewRecorder()
+	apperror.Middleware(h.Auth)(w, r)
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("want 429 with Retry-After 30, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if len(users.clientIPs) != 1 || users.clientIPs[0] != "203.0.113.7" {
+		t.Fatalf("client ips = %v", users.clientIPs)
+	}
+
+	h.TrustForwardedFor = false
+	signin(h)
+	if users.clientIPs[1] != "192.0.2.1" {
+		t.Fatalf("client ip without trusted proxy = %q", users.clientIPs[1])
+	}
+}
+
+func TestSignupWithTakenEmailConflicts(t *testing.T) {
+	h := &Handler{UserService: &fakeUsers{user: user_service.User{UUID: "u1", Email: "a@b.c"}}}
+
+	r := httptest.NewRequest(http.MethodPost, signupURL, strings.NewReader(`{"email":" A@B.c","password":"p","repeat_password":"p"}`))
+	w := httptest.NewRecorder()
+	apperror.Middleware(h.Signup)(w, r)
+
+	var appErr apperror.AppError
+	if err := json.NewDecoder(w.Body).Decode(&appErr); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if w.Code != http.StatusConflict || appErr.Code != "NS-000005" {
+		t.Fatalf("want 409 NS-000005, got %d %q", w.Code, appErr.Code)
+	}
+}
+
+func TestAuthWithSecondFactor(t *testing.T) {
+	tokens := &fakeJWT{}
+	h := &Handler{UserService: &fakeUsers{user: user_service.User{UUID: "u1", Verified: true, TOTPEnabled: true}}, JWTHelper: tokens}
+
+	w, err := signin(h)
+	if err != nil {
+		t.Fatalf("sign in: %v", err)
+	}
+	var challenge MFAChallenge
+	if err = json.Unmarshal(w.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("decode challenge: %v", err)
+	}
+	if w.Code != http.StatusAccepted || !challenge.MFARequired || challenge.MFAToken != "challenge-1" {
+		t.Fatalf("want 202 with an mfa challenge, got %d %s", w.Code, w.Body.String())
+	}
+
+	mfa := func(token, code string) (*httptest.ResponseRecorder, error) {
+		body := `{"mfa_token":"` + token + `","code":"` + code + `"}`
+		w := httptest.NewRecorder()
+		return w, h.AuthMFA(w, httptest.NewRequest(http.MethodPost, mfaURL, strings.NewReader(body)))
+	}
+	if _, err = mfa("challenge-1", "000000"); err == nil || tokens.failures != 1 {
+		t.Fatalf("wrong code: err %v, failures %d", err, tokens.failures)
+	}
+	if w, err = mfa("challenge-1", "123456"); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("right code: %v, %d", err, w.Code)
+	}
+	if _, err = mfa("challenge-1", "123456"); err == nil {
+		t.Fatal("challenge used twice")
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const lockoutsURL = "/api/admin/lockouts"
+
+// Unlock lifts sign in throttling of the email and of the ip given in the query, admins only
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	if !h.isAdmin(userUUID) {
+		return apperror.ForbiddenError("only admins may lift lockouts")
+	}
+
+	email, ip := r.URL.Query().Get("email"), r.URL.Query().Get("ip")
+	if email == "" && ip == "" {
+		return apperror.BadRequestError("email or ip is required")
+	}
+	if err := h.UserService.Unlock(r.Context(), email, ip); err != nil {
+		return err
+	}
+	h.Logger.Infof("admin %s lifted lockout of email %q and ip %q", userUUID, email, ip)
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) isAdmin(userUUID string) bool {
+	for _, admin := range h.Admins {
+		if admin == userUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address a request came from, the first X-Forwarded-For entry when it is trusted
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.TrustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
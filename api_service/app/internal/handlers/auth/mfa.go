@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"net/http"
+)
+
+const (
+	mfaURL         = "/api/auth/mfa"
+	totpURL        = "/api/me/totp"
+	totpDisableURL = "/api/me/totp/disable"
+)
+
+// MFAChallenge answers a password sign in of a user with the second factor on
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type MFACodeDTO struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// challengeMFA answers 202 Accepted with a token to exchange for the JWT pair at AuthMFA
+func (h *Handler) challengeMFA(w http.ResponseWriter, u user_service.User) error {
+	token, err := h.JWTHelper.CreateMFAChallenge(u)
+	if err != nil {
+		return err
+	}
+
+	challengeBytes, err := json.Marshal(MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(jwt.MFAChallengeTTL.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(challengeBytes)
+
+	return nil
+}
+
+// AuthMFA is the second sign in step, a one-time or a recovery code for the JWT pair
+func (h *Handler) AuthMFA(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+	var dto MFACodeDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+
+	u, err := h.JWTHelper.MFAChallengeUser(dto.MFAToken)
+	if err != nil {
+		return apperror.UnauthorizedError("mfa token is expired or unknown, sign in again")
+	}
+	if err = h.UserService.VerifyTOTP(r.Context(), u.UUID, dto.Code, h.clientIP(r)); err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Status >= http.StatusBadRequest && appErr.Status < http.StatusInternalServerError {
+			h.JWTHelper.FailMFAChallenge(dto.MFAToken)
+		}
+		return err
+	}
+
+	token, err := h.JWTHelper.CompleteMFAChallenge(dto.MFAToken)
+	if err != nil {
+		if errors.Is(err, jwt.ErrNoChallenge) {
+			return apperror.UnauthorizedError("mfa token is expired or unknown, sign in again")
+		}
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(token)
+
+	return nil
+}
+
+// EnrollTOTP answers with the secret and the otpauth URI to show as a QR code
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	enrollment, err := h.UserService.EnrollTOTP(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP turns the second factor on and answers with the recovery codes
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	var dto user_service.TOTPCodeDTO
+	if err = json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+
+	codes, err := h.UserService.ConfirmTOTP(r.Context(), userUUID, dto.Code)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, codes)
+}
+
+// DisableTOTP turns the second factor off, it needs the password and a code again
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	var dto user_service.DisableTOTPDTO
+	if err = json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+
+	if err = h.UserService.DisableTOTP(r.Context(), userUUID, dto); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) userUUID(r *http.Request) (string, error) {
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return "", apperror.UnauthorizedError("")
+	}
+	return userUUID, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response. error: %w", err)
+	}
+	w.WriteHeader(status)
+	w.Write(b)
+	return nil
+}
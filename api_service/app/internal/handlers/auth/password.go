@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"net/http"
+)
+
+const (
+	forgotPasswordURL = "/api/password/forgot"
+	resetPasswordURL  = "/api/password/reset"
+)
+
+// ForgotPassword always answers 202 Accepted, whether the email belongs to a user or not
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+	var dto user_service.ForgotPasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+	if err := h.UserService.ForgotPassword(r.Context(), dto.Email); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+// ResetPassword sets a new password and signs the user out of every session
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+	var dto user_service.ResetPasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("failed to decode data")
+	}
+
+	u, err := h.UserService.ResetPassword(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	revoked := h.JWTHelper.RevokeUserTokens(u.UUID)
+	h.Logger.Infof("password of user %s reset, %d refresh tokens revoked", u.UUID, revoked)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
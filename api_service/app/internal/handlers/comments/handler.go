@@ -0,0 +1,159 @@
+package comments
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"net/http"
+)
+
+const (
+	commentsURL = "/api/notes/:uuid/comments"
+	commentURL  = "/api/notes/:uuid/comments/:comment_uuid"
+)
+
+type Handler struct {
+	Logger      logging.Logger
+	NoteService note_service.NoteService
+	UserService user_service.UserService
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, commentsURL, jwt.Middleware(apperror.Middleware(h.GetComments)))
+	router.HandlerFunc(http.MethodPost, commentsURL, jwt.Middleware(apperror.Middleware(h.CreateComment)))
+	router.HandlerFunc(http.MethodPatch, commentURL, jwt.Middleware(apperror.Middleware(h.PartiallyUpdateComment)))
+	router.HandlerFunc(http.MethodDelete, commentURL, jwt.Middleware(apperror.Middleware(h.DeleteComment)))
+}
+
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	threads, err := h.NoteService.GetComments(r.Context(), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	authors := make(map[string]*note_service.CommentAuthor)
+	author := func(uuid string) *note_service.CommentAuthor {
+		// comments of deleted accounts have no author
+		if uuid == "" {
+			return nil
+		}
+		if a, ok := authors[uuid]; ok {
+			return a
+		}
+		a := &note_service.CommentAuthor{UUID: uuid}
+		if u, err := h.UserService.GetByUUID(r.Context(), uuid); err != nil {
+			h.Logger.Warnf("failed to resolve comment author %s: %v", uuid, err)
+		} else {
+			a.Email = u.Email
+		}
+		authors[uuid] = a
+		return a
+	}
+	for i := range threads {
+		threads[i].Author = author(threads[i].AuthorUUID)
+		for j := range threads[i].Replies {
+			threads[i].Replies[j].Author = author(threads[i].Replies[j].AuthorUUID)
+		}
+	}
+
+	threadsBytes, err := json.Marshal(threads)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(threadsBytes)
+
+	return nil
+}
+
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	noteUUID := params.ByName("uuid")
+
+	author, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
+	var dto note_service.CreateCommentDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("can't decode")
+	}
+
+	commentUUID, err := h.NoteService.CreateComment(r.Context(), noteUUID, author, dto)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/notes/%s/comments/%s", noteUUID, commentUUID))
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) PartiallyUpdateComment(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	editor, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
+	var dto note_service.UpdateCommentDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("can't decode")
+	}
+
+	err = h.NoteService.UpdateComment(r.Context(), params.ByName("uuid"), params.ByName("comment_uuid"), editor, dto)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	editor, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
+	err = h.NoteService.DeleteComment(r.Context(), params.ByName("uuid"), params.ByName("comment_uuid"), editor)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) editor(r *http.Request) (note_service.Editor, error) {
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return note_service.Editor{}, apperror.UnauthorizedError("")
+	}
+	sessionID, _ := r.Context().Value("session_id").(string)
+
+	return note_service.Editor{UserUUID: userUUID, SessionID: sessionID}, nil
+}
@@ -0,0 +1,90 @@
+package exports
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/export"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"net/http"
+)
+
+const (
+	exportsURL = "/api/me/export"
+	exportURL  = "/api/me/export/:id"
+)
+
+type Handler struct {
+	Logger        logging.Logger
+	ExportService export.Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodPost, exportsURL, jwt.Middleware(apperror.Middleware(h.StartExport)))
+	router.HandlerFunc(http.MethodGet, exportURL, jwt.Middleware(apperror.Middleware(h.GetExport)))
+}
+
+// StartExport answers 202 Accepted, the archive is at GET /api/me/export/:id once it is done
+func (h *Handler) StartExport(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+
+	e, err := h.ExportService.Start(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", exportsURL, e.ID))
+	return writeExport(w, http.StatusAccepted, e)
+}
+
+// GetExport sends the archive of a done export. Otherwise it answers with the export,
+// 202 Accepted while it runs and 200 OK when it failed.
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	f, e, err := h.ExportService.Open(r.Context(), userUUID, params.ByName("id"))
+	if err != nil {
+		return err
+	}
+	switch e.Status {
+	case export.StatusRunning:
+		return writeExport(w, http.StatusAccepted, e)
+	case export.StatusFailed:
+		return writeExport(w, http.StatusOK, e)
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("notes-export-%s.zip", e.ID)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	http.ServeContent(w, r, name, *e.FinishedAt, f)
+
+	return nil
+}
+
+func writeExport(w http.ResponseWriter, status int, e export.Export) error {
+	exportBytes, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(status)
+	w.Write(exportBytes)
+
+	return nil
+}
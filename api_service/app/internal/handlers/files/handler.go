@@ -0,0 +1,123 @@
+package files
+
+import (
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/file_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/quota"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	filesURL = "/api/notes/:uuid/files"
+	fileURL  = "/api/notes/:uuid/files/:file_id"
+)
+
+// the same cap file_service puts on a multipart form
+const maxUploadMemory = 32 << 20
+
+type Handler struct {
+	Logger       logging.Logger
+	FileService  file_service.FileService
+	QuotaService quota.Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, filesURL, jwt.Middleware(apperror.Middleware(h.GetFiles)))
+	router.HandlerFunc(http.MethodPost, filesURL, jwt.Middleware(apperror.Middleware(h.UploadFile)))
+	router.HandlerFunc(http.MethodGet, fileURL, jwt.Middleware(apperror.Middleware(h.GetFile)))
+	router.HandlerFunc(http.MethodDelete, fileURL, jwt.Middleware(apperror.Middleware(h.DeleteFile)))
+}
+
+func (h *Handler) GetFiles(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	files, err := h.FileService.GetByNoteUUID(r.Context(), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(files)
+
+	return nil
+}
+
+func (h *Handler) GetFile(w http.ResponseWriter, r *http.Request) error {
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	f, err := h.FileService.GetFile(r.Context(), params.ByName("uuid"), params.ByName("file_id"))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	w.Write(f.Bytes)
+
+	return nil
+}
+
+func (h *Handler) UploadFile(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return apperror.BadRequestError("can't parse multipart form")
+	}
+	files, ok := r.MultipartForm.File["file"]
+	if !ok || len(files) == 0 {
+		return apperror.BadRequestError("file required")
+	}
+	fileInfo := files[0]
+
+	if err := h.QuotaService.CheckUpload(r.Context(), userUUID, fileInfo.Size); err != nil {
+		return err
+	}
+
+	fileReader, err := fileInfo.Open()
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+	fileBytes, err := ioutil.ReadAll(fileReader)
+	if err != nil {
+		return err
+	}
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	dto := file_service.UploadFileDTO{
+		NoteUUID: params.ByName("uuid"),
+		Name:     fileInfo.Filename,
+		Size:     fileInfo.Size,
+		Bytes:    fileBytes,
+	}
+	if err = h.FileService.Upload(r.Context(), userUUID, dto); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) DeleteFile(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err := h.FileService.Delete(r.Context(), params.ByName("uuid"), params.ByName("file_id")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
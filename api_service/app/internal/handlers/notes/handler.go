@@ -6,19 +6,26 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
 	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/quota"
 	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 const (
-	notesURL = "/api/notes"
-	noteURL  = "/api/notes/:uuid"
+	notesURL    = "/api/notes"
+	noteURL     = "/api/notes/:uuid"
+	noteLockURL = "/api/notes/:uuid/lock"
 )
 
 type Handler struct {
-	Logger      logging.Logger
-	NoteService note_service.NoteService
+	Logger       logging.Logger
+	NoteService  note_service.NoteService
+	TagService   tag_service.TagService
+	QuotaService quota.Service
 }
 
 func (h *Handler) Register(router *httprouter.Router) {
@@ -27,13 +34,56 @@ func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodGet, noteURL, jwt.Middleware(apperror.Middleware(h.GetNoteByUuid)))
 	router.HandlerFunc(http.MethodPatch, noteURL, jwt.Middleware(apperror.Middleware(h.PartiallyUpdateNote)))
 	router.HandlerFunc(http.MethodDelete, noteURL, jwt.Middleware(apperror.Middleware(h.DeleteNote)))
+	router.HandlerFunc(http.MethodPost, noteLockURL, jwt.Middleware(apperror.Middleware(h.LockNote)))
+	router.HandlerFunc(http.MethodDelete, noteLockURL, jwt.Middleware(apperror.Middleware(h.UnlockNote)))
 }
 
+// GetNotes returns notes of the category. The tag parameter keeps only notes with any of the
+// listed tags, given by id, path or alias; descendants=true counts the descendant tags in as well.
 func (h *Handler) GetNotes(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
 	categoryUUID := r.URL.Query().Get("category_uuid")
-	notes, err := h.NoteService.GetByCategoryUUID(r.Context(), categoryUUID)
+
+	var tagIDs []int
+	var tagNames []string
+	if tagsParam := r.URL.Query().Get("tag"); tagsParam != "" {
+		for _, idStr := range strings.Split(tagsParam, ",") {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				tagNames = append(tagNames, idStr)
+				continue
+			}
+			tagIDs = append(tagIDs, id)
+		}
+	}
+	if len(tagNames) > 0 {
+		editor, err := h.editor(r)
+		if err != nil {
+			return err
+		}
+		named, err := h.resolveTagNames(r.Context(), editor.UserUUID, tagNames)
+		if err != nil {
+			return err
+		}
+		tagIDs = addTags(tagIDs, named)
+	}
+	if len(tagIDs) > 0 && r.URL.Query().Get("descendants") == "true" {
+		editor, err := h.editor(r)
+		if err != nil {
+			return err
+		}
+		subtrees, err := h.TagService.GetSubtrees(r.Context(), editor.UserUUID, tagIDs)
+		if err != nil {
+			return err
+		}
+		tagIDs = tagIDs[:0]
+		for _, t := range subtrees {
+			tagIDs = append(tagIDs, t.ID)
+		}
+	}
+
+	notes, err := h.NoteService.GetByCategoryUUID(r.Context(), categoryUUID, tagIDs)
 	if err != nil {
 		return err
 	}
@@ -47,13 +97,26 @@ func (h *Handler) GetNotes(w http.ResponseWriter, r *http.Request) error {
 func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
+	owner, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
 	defer r.Body.Close()
 	var crNote note_service.CreateNoteDTO
 	if err := json.NewDecoder(r.Body).Decode(&crNote); err != nil {
 		return apperror.BadRequestError("can't decode")
 	}
+	named, err := h.resolveTagNames(r.Context(), owner.UserUUID, crNote.TagNames)
+	if err != nil {
+		return err
+	}
+	crNote.Tags, crNote.TagNames = addTags(crNote.Tags, named), nil
+	if err = h.QuotaService.CheckNoteCreate(r.Context(), owner.UserUUID, len(crNote.Body)); err != nil {
+		return err
+	}
 
-	noteUUID, err := h.NoteService.Create(r.Context(), crNote)
+	noteUUID, err := h.NoteService.Create(r.Context(), owner, crNote)
 	if err != nil {
 		return err
 	}
@@ -87,12 +150,25 @@ func (h *Handler) PartiallyUpdateNote(w http.ResponseWriter, r *http.Request) er
 	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
 	noteUUID := params.ByName("uuid")
 
+	editor, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
 	var dto note_service.UpdateNoteDTO
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 		return apperror.BadRequestError("can't decode")
 	}
-	if err := h.NoteService.Update(r.Context(), noteUUID, dto); err != nil {
+	named, err := h.resolveTagNames(r.Context(), editor.UserUUID, dto.TagNames)
+	if err != nil {
+		return err
+	}
+	dto.Tags, dto.TagNames = addTags(dto.Tags, named), nil
+	if err := h.QuotaService.CheckNoteBody(editor.UserUUID, len(dto.Body)); err != nil {
+		return err
+	}
+	if err := h.NoteService.Update(r.Context(), noteUUID, editor, dto); err != nil {
 		return err
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -112,3 +188,51 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+func (h *Handler) LockNote(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	editor, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	lease, err := h.NoteService.Lock(r.Context(), params.ByName("uuid"), editor)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(lease)
+
+	return nil
+}
+
+func (h *Handler) UnlockNote(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	editor, err := h.editor(r)
+	if err != nil {
+		return err
+	}
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err = h.NoteService.Unlock(r.Context(), params.ByName("uuid"), editor); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) editor(r *http.Request) (note_service.Editor, error) {
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return note_service.Editor{}, apperror.UnauthorizedError("")
+	}
+	sessionID, _ := r.Context().Value("session_id").(string)
+
+	return note_service.Editor{UserUUID: userUUID, SessionID: sessionID}, nil
+}
@@ -0,0 +1,48 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+)
+
+// resolveTagNames turns tag paths and aliases of the owner into tag ids in the order of names.
+// Unknown names are refused, tags are not created on the fly.
+func (h *Handler) resolveTagNames(ctx context.Context, ownerUUID string, names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	resolved, err := h.TagService.Resolve(ctx, ownerUUID, names)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]int, len(resolved))
+	for _, r := range resolved {
+		byName[r.Name] = r.Tag.ID
+	}
+
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, apperror.BadRequestError(fmt.Sprintf("unknown tag %s", name))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// addTags appends the ids missing from tags
+func addTags(tags []int, ids []int) []int {
+	present := make(map[int]bool, len(tags))
+	for _, id := range tags {
+		present[id] = true
+	}
+	for _, id := range ids {
+		if !present[id] {
+			present[id] = true
+			tags = append(tags, id)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,46 @@
+package quotas
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/quota"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"net/http"
+)
+
+const quotaURL = "/api/quota"
+
+type Handler struct {
+	Logger       logging.Logger
+	QuotaService quota.Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, quotaURL, jwt.Middleware(apperror.Middleware(h.GetQuota)))
+}
+
+func (h *Handler) GetQuota(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+
+	report, err := h.QuotaService.Report(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	reportBytes, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(reportBytes)
+
+	return nil
+}
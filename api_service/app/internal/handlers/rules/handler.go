@@ -0,0 +1,229 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"net/http"
+)
+
+const (
+	rulesURL  = "/api/rules"
+	ruleURL   = "/api/rules/:uuid"
+	dryRunURL = "/api/rules/:uuid/dry-run"
+	applyURL  = "/api/rules/:uuid/apply"
+	jobURL    = "/api/rule-jobs/:uuid"
+)
+
+type Handler struct {
+	Logger      logging.Logger
+	NoteService note_service.NoteService
+	TagService  tag_service.TagService
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, rulesURL, jwt.Middleware(apperror.Middleware(h.GetRules)))
+	router.HandlerFunc(http.MethodPost, rulesURL, jwt.Middleware(apperror.Middleware(h.CreateRule)))
+	router.HandlerFunc(http.MethodGet, ruleURL, jwt.Middleware(apperror.Middleware(h.GetRule)))
+	router.HandlerFunc(http.MethodPatch, ruleURL, jwt.Middleware(apperror.Middleware(h.PartiallyUpdateRule)))
+	router.HandlerFunc(http.MethodDelete, ruleURL, jwt.Middleware(apperror.Middleware(h.DeleteRule)))
+	router.HandlerFunc(http.MethodGet, dryRunURL, jwt.Middleware(apperror.Middleware(h.DryRun)))
+	router.HandlerFunc(http.MethodPost, applyURL, jwt.Middleware(apperror.Middleware(h.Apply)))
+	router.HandlerFunc(http.MethodGet, jobURL, jwt.Middleware(apperror.Middleware(h.GetJob)))
+}
+
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	rules, err := h.NoteService.GetRules(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(rules)
+
+	return nil
+}
+
+func (h *Handler) GetRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	rule, err := h.NoteService.GetRule(r.Context(), userUUID, params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(rule)
+
+	return nil
+}
+
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+
+	var dto note_service.CreateRuleDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("can't decode")
+	}
+	if err = h.checkTag(r, userUUID, dto.TagID); err != nil {
+		return err
+	}
+
+	ruleUUID, err := h.NoteService.CreateRule(r.Context(), userUUID, dto)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", rulesURL, ruleUUID))
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) PartiallyUpdateRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto note_service.UpdateRuleDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("can't decode")
+	}
+	if dto.TagID != 0 {
+		if err = h.checkTag(r, userUUID, dto.TagID); err != nil {
+			return err
+		}
+	}
+
+	if err = h.NoteService.UpdateRule(r.Context(), userUUID, params.ByName("uuid"), dto); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err = h.NoteService.DeleteRule(r.Context(), userUUID, params.ByName("uuid")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DryRun(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	notes, err := h.NoteService.DryRunRule(r.Context(), userUUID, params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(notes)
+
+	return nil
+}
+
+func (h *Handler) Apply(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	jobUUID, err := h.NoteService.ApplyRule(r.Context(), userUUID, params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/rule-jobs/%s", jobUUID))
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, err := h.userUUID(r)
+	if err != nil {
+		return err
+	}
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	job, err := h.NoteService.GetRuleJob(r.Context(), userUUID, params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(job)
+
+	return nil
+}
+
+// checkTag makes sure rules only tag notes with tags of their owner
+func (h *Handler) checkTag(r *http.Request, userUUID string, tagID int) error {
+	if tagID <= 0 {
+		return apperror.BadRequestError("rule tag is required")
+	}
+	if _, err := h.TagService.GetOne(r.Context(), userUUID, tagID); err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Status == http.StatusNotFound {
+			return apperror.BadRequestError(fmt.Sprintf("tag %d not found", tagID))
+		}
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) userUUID(r *http.Request) (string, error) {
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return "", apperror.UnauthorizedError("")
+	}
+	return userUUID, nil
+}
@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+)
+
+// fakeTags knows the tags of the caller only
+type fakeTags struct {
+	tag_service.TagService
+	tags map[int]bool
+}
+
+func (f *fakeTags) GetOne(ctx context.Context, ownerUUID string, id int) ([]byte, error) {
+	if !f.tags[id] {
+		return nil, apperror.APIError(http.StatusNotFound, "TS-000003", "not found", "")
+	}
+	return []byte("{}"), nil
+}
+
+type fakeNotes struct {
+	note_service.NoteService
+	created []note_service.CreateRuleDTO
+}
+
+func (f *fakeNotes) CreateRule(ctx context.Context, ownerUUID string, dto note_service.CreateRuleDTO) (string, error) {
+	f.created = append(f.created, dto)
+	return "rule-1", nil
+}
+
+func createRule(h *Handler, body string) (*httptest.ResponseRecorder, error) {
+	r := httptest.NewRequest(http.MethodPost, rulesURL, strings.NewReader(body))
+	ctx := context.WithValue(r.Context(), "user_uuid", "alice")
+	ctx = context.WithValue(ctx, httprouter.ParamsKey, httprouter.Params{})
+	w := httptest.NewRecorder()
+	return w, h.CreateRule(w, r.WithContext(ctx))
+}
+
+func TestCreateRuleChecksTagOwnership(t *testing.T) {
+	notes := &fakeNotes{}
+	h := &Handler{NoteService: notes, TagService: &fakeTags{tags: map[int]bool{7: true}}}
+
+	w, err := createRule(h, `{"name":"incidents","body_pattern":"INC-\\d+","tag_id":7}`)
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/rules/rule-1" {
+		t.Fatalf("want 201 with location, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	_, err = createRule(h, `{"name":"foreign","category_uuid":"c","tag_id":8}`)
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.Code != apperror.BadRequestError("").Code {
+		t.Fatalf("want bad request for a tag of another owner, got %v", err)
+	}
+	if len(notes.created) != 1 {
+		t.Fatalf("rule with a foreign tag reached note_service: %+v", notes.created)
+	}
+}
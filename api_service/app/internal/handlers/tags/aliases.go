@@ -0,0 +1,64 @@
+package tags
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	tagAliasesURL = "/api/tags/:id/aliases"
+	tagAliasURL   = "/api/tags/:id/aliases/:alias"
+)
+
+// AddTagAlias gives the tag another name, e.g. k8s for kubernetes
+func (h *Handler) AddTagAlias(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Context().Value("user_uuid") == nil {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+	userUUID := r.Context().Value("user_uuid").(string)
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	tagID := params.ByName("id")
+
+	var dto tag_service.AliasDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("can't decode")
+	}
+
+	if err := h.TagService.AddAlias(r.Context(), userUUID, tagID, dto.Alias); err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%s/aliases/%s", tagsURL, tagID, url.PathEscape(strings.TrimSpace(dto.Alias))))
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) RemoveTagAlias(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Context().Value("user_uuid") == nil {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+	userUUID := r.Context().Value("user_uuid").(string)
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err := h.TagService.RemoveAlias(r.Context(), userUUID, params.ByName("id"), params.ByName("alias")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -1,10 +1,12 @@
 package tags
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
 	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
 	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
 	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
@@ -14,13 +16,17 @@ import (
 )
 
 const (
-	tagsURL = "/api/tags"
-	tagURL  = "/api/tags/:id"
+	tagsURL     = "/api/tags"
+	tagURL      = "/api/tags/:id"
+	tagMergeURL = "/api/tags/:id/merge"
+	// suggestID makes GET /api/tags/suggest, httprouter does not allow a static segment next to :id
+	suggestID = "suggest"
 )
 
 type Handler struct {
-	Logger     logging.Logger
-	TagService tag_service.TagService
+	Logger      logging.Logger
+	TagService  tag_service.TagService
+	NoteService note_service.NoteService
 }
 
 func (h *Handler) Register(router *httprouter.Router) {
@@ -29,19 +35,31 @@ func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodPost, tagsURL, jwt.Middleware(apperror.Middleware(h.CreateTag)))
 	router.HandlerFunc(http.MethodPatch, tagURL, jwt.Middleware(apperror.Middleware(h.PartiallyUpdateTag)))
 	router.HandlerFunc(http.MethodDelete, tagURL, jwt.Middleware(apperror.Middleware(h.DeleteTag)))
+	router.HandlerFunc(http.MethodPost, tagMergeURL, jwt.Middleware(apperror.Middleware(h.MergeTags)))
+	router.HandlerFunc(http.MethodPost, tagAliasesURL, jwt.Middleware(apperror.Middleware(h.AddTagAlias)))
+	router.HandlerFunc(http.MethodDelete, tagAliasURL, jwt.Middleware(apperror.Middleware(h.RemoveTagAlias)))
 }
 
 func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
+	if r.Context().Value("user_uuid") == nil {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+	userUUID := r.Context().Value("user_uuid").(string)
+
 	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
 	tagIDStr := params.ByName("id")
+	if tagIDStr == suggestID {
+		return h.SuggestTags(w, r)
+	}
 	id, err := strconv.Atoi(tagIDStr)
 	if err != nil {
 		return apperror.BadRequestError("invalid id")
 	}
 
-	tag, err := h.TagService.GetOne(r.Context(), id)
+	tag, err := h.TagService.GetOne(r.Context(), userUUID, id)
 	if err != nil {
 		return err
 	}
@@ -52,12 +70,34 @@ func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// GetManyTags lists the tag tree of the user, or only the requested tags when the id parameter is set.
+// with_counts=true adds usage of every tag, view=cloud returns the weighted tag cloud instead.
 func (h *Handler) GetManyTags(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
+	if r.Context().Value("user_uuid") == nil {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+	userUUID := r.Context().Value("user_uuid").(string)
+
 	idsParam := r.URL.Query().Get("id")
 	if idsParam == "" {
-		return apperror.BadRequestError("invalid id")
+		tags, err := h.TagService.GetAll(r.Context(), userUUID)
+		if err != nil {
+			return err
+		}
+		withCounts := r.URL.Query().Get("with_counts") == "true"
+		view := r.URL.Query().Get("view")
+		if withCounts || view == "cloud" {
+			if tags, err = h.countedTags(r.Context(), userUUID, tags, view == "cloud"); err != nil {
+				return err
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(tags)
+
+		return nil
 	}
 
 	var tagsIds []int
@@ -70,7 +110,7 @@ func (h *Handler) GetManyTags(w http.ResponseWriter, r *http.Request) error {
 		tagsIds = append(tagsIds, id)
 	}
 
-	tags, err := h.TagService.GetMany(r.Context(), tagsIds)
+	tags, err := h.TagService.GetMany(r.Context(), userUUID, tagsIds)
 	if err != nil {
 		return err
 	}
@@ -99,14 +139,18 @@ func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) error {
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 		return apperror.BadRequestError("can't decode")
 	}
-	dto.UserUUID = userUUID
 
-	tagID, err := h.TagService.Create(r.Context(), dto)
+	tagID, existing, err := h.TagService.Create(r.Context(), userUUID, dto, r.URL.Query().Get("on_conflict"))
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Location", fmt.Sprintf("%s/%s", tagsURL, tagID))
+	if existing != nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write(existing)
+		return nil
+	}
 	w.WriteHeader(http.StatusCreated)
 
 	return nil
@@ -129,8 +173,7 @@ func (h *Handler) PartiallyUpdateTag(w http.ResponseWriter, r *http.Request) err
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 		return apperror.BadRequestError("can't decode")
 	}
-	dto.UserUUID = userUUID
-	if err := h.TagService.Update(r.Context(), tagId, dto); err != nil {
+	if err := h.TagService.Update(r.Context(), userUUID, tagId, dto); err != nil {
 		return err
 	}
 
@@ -146,13 +189,108 @@ func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) error {
 		h.Logger.Error("there is no user_uuid in context")
 		return apperror.UnauthorizedError("")
 	}
+	userUUID := r.Context().Value("user_uuid").(string)
 
 	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
-	tagId := params.ByName("id")
-	if err := h.TagService.Delete(r.Context(), tagId); err != nil {
+	if err := h.deleteTag(r.Context(), userUUID, params.ByName("id")); err != nil {
 		return err
 	}
 	w.WriteHeader(http.StatusNoContent)
 
 	return nil
 }
+
+// MergeTags merges the tags listed in source_ids into the tag from the URL
+func (h *Handler) MergeTags(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Context().Value("user_uuid") == nil {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+	userUUID := r.Context().Value("user_uuid").(string)
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	targetID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		return apperror.BadRequestError("invalid id")
+	}
+
+	var dto MergeTagsDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("can't decode")
+	}
+
+	result, err := h.merge(r.Context(), userUUID, targetID, dto.SourceIDs)
+	if err != nil {
+		return err
+	}
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBytes)
+
+	return nil
+}
+
+// SuggestTags completes q to the user's tags ranked by match, usage and recency
+func (h *Handler) SuggestTags(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID, ok := r.Context().Value("user_uuid").(string)
+	if !ok {
+		h.Logger.Error("there is no user_uuid in context")
+		return apperror.UnauthorizedError("")
+	}
+
+	limit := defaultSuggestLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit <= 0 {
+			return apperror.BadRequestError("invalid limit")
+		}
+		if limit > maxSuggestLimit {
+			limit = maxSuggestLimit
+		}
+	}
+
+	// tags ranked like the last candidate are ranked by usage too, cutting among them loses used tags
+	suggestions, err := h.TagService.Suggest(r.Context(), userUUID, r.URL.Query().Get("q"), maxSuggestLimit, true)
+	if err != nil {
+		return err
+	}
+	usage, err := h.NoteService.GetTagUsage(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	suggestionsBytes, err := json.Marshal(rankSuggestions(suggestions, usage, limit))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(suggestionsBytes)
+
+	return nil
+}
+
+// countedTags adds usage to the tag tree as returned by tag_service
+func (h *Handler) countedTags(ctx context.Context, userUUID string, treeBytes []byte, asCloud bool) ([]byte, error) {
+	var tree []tag_service.TagNode
+	if err := json.Unmarshal(treeBytes, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode tag tree. error: %v", err)
+	}
+	usage, err := h.NoteService.GetTagUsage(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if asCloud {
+		return json.Marshal(cloud(tree, usage))
+	}
+	return json.Marshal(withCounts(tree, usage))
+}
@@ -0,0 +1,97 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+)
+
+type MergeTagsDTO struct {
+	SourceIDs []int `json:"source_ids"`
+}
+
+type MergeTagsResult struct {
+	TargetID     int `json:"target_id"`
+	TagsMerged   int `json:"tags_merged"`
+	NotesChanged int `json:"notes_changed"`
+}
+
+// merge makes the target absorb the source tags: notes referencing a source are rewritten to the
+// target and the sources are deleted afterwards. Sources that are gone already are skipped, so a
+// merge interrupted partway is finished by repeating it.
+func (h *Handler) merge(ctx context.Context, ownerUUID string, targetID int, sourceIDs []int) (result MergeTagsResult, err error) {
+	result.TargetID = targetID
+
+	isSource := make(map[int]bool, len(sourceIDs))
+	var sources []int
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return result, apperror.BadRequestError("tag can not be merged into itself")
+		}
+		if !isSource[id] {
+			isSource[id] = true
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return result, apperror.BadRequestError("source_ids are required")
+	}
+
+	if _, err = h.TagService.GetOne(ctx, ownerUUID, targetID); err != nil {
+		return result, err
+	}
+
+	subtrees, err := h.TagService.GetSubtrees(ctx, ownerUUID, sources)
+	if err != nil {
+		if isNotFound(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	var found []int
+	for _, t := range subtrees {
+		if !isSource[t.ID] {
+			return result, apperror.BadRequestError(fmt.Sprintf("tag %s is a child of a source tag, move or delete it first", t.Path))
+		}
+		found = append(found, t.ID)
+	}
+
+	if result.NotesChanged, err = h.NoteService.ReplaceTags(ctx, found, targetID); err != nil {
+		return result, err
+	}
+
+	for _, id := range found {
+		if err = h.TagService.Delete(ctx, ownerUUID, strconv.Itoa(id)); err != nil && !isNotFound(err) {
+			return result, err
+		}
+		result.TagsMerged++
+	}
+	return result, nil
+}
+
+// deleteTag deletes the tag and then the rules of the owner tagging with it, the tag may refuse to
+// be deleted. Rules are dropped when the tag is gone already too, so repeating a delete
+// interrupted in between finishes it.
+func (h *Handler) deleteTag(ctx context.Context, ownerUUID, id string) error {
+	tagID, err := strconv.Atoi(id)
+	if err != nil {
+		return apperror.BadRequestError("invalid id")
+	}
+	err = h.TagService.Delete(ctx, ownerUUID, id)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if ruleErr := h.NoteService.DeleteTagRules(ctx, ownerUUID, tagID); ruleErr != nil {
+		return ruleErr
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Status == http.StatusNotFound
+}
@@ -0,0 +1,207 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+)
+
+// fakeTags keeps tags of a single owner
+type fakeTags struct {
+	tag_service.TagService
+	tags map[int]tag_service.Tag
+}
+
+func notFound() error {
+	return apperror.APIError(http.StatusNotFound, "TS-000003", "not found", "")
+}
+
+func (f *fakeTags) GetOne(ctx context.Context, ownerUUID string, id int) ([]byte, error) {
+	if _, ok := f.tags[id]; !ok {
+		return nil, notFound()
+	}
+	return []byte("{}"), nil
+}
+
+func (f *fakeTags) GetSubtrees(ctx context.Context, ownerUUID string, ids []int) (tags []tag_service.Tag, err error) {
+	for _, id := range ids {
+		root, ok := f.tags[id]
+		if !ok {
+			continue
+		}
+		for _, t := range f.tags {
+			if t.ID == root.ID || t.ParentID == root.ID {
+				tags = append(tags, t)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, notFound()
+	}
+	return tags, nil
+}
+
+func (f *fakeTags) Delete(ctx context.Context, ownerUUID string, id string) error {
+	tagID, _ := strconv.Atoi(id)
+	if _, ok := f.tags[tagID]; !ok {
+		return notFound()
+	}
+	for _, t := range f.tags {
+		if t.ParentID == tagID {
+			return apperror.BadRequestError("tag has child tags")
+		}
+	}
+	delete(f.tags, tagID)
+	return nil
+}
+
+type fakeNotes struct {
+	note_service.NoteService
+	notes map[string][]int
+	// ruleTags are the tags of the rules of the owner
+	ruleTags []int
+	fail     bool
+}
+
+func (f *fakeNotes) DeleteTagRules(ctx context.Context, ownerUUID string, tagID int) error {
+	if f.fail {
+		return errors.New("note_service is down")
+	}
+	var kept []int
+	for _, id := range f.ruleTags {
+		if id != tagID {
+			kept = append(kept, id)
+		}
+	}
+	f.ruleTags = kept
+	return nil
+}
+
+func (f *fakeNotes) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (changed int, err error) {
+	if f.fail {
+		return 0, errors.New("note_service is down")
+	}
+	sources := make(map[int]bool)
+	for _, id := range sourceIDs {
+		sources[id] = true
+	}
+	for uuid, tags := range f.notes {
+		var rewritten []int
+		seen := make(map[int]bool)
+		touched := false
+		for _, id := range tags {
+			if sources[id] {
+				id, touched = targetID, true
+			}
+			if !seen[id] {
+				seen[id] = true
+				rewritten = append(rewritten, id)
+			}
+		}
+		if touched {
+			f.notes[uuid] = rewritten
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func newMergeHandler() (*Handler, *fakeTags, *fakeNotes) {
+	tags := &fakeTags{tags: map[int]tag_service.Tag{
+		1: {ID: 1, Name: "go", Path: "go"},
+		2: {ID: 2, Name: "golang", Path: "golang"},
+		3: {ID: 3, Name: "go-lang", Path: "go-lang"},
+		4: {ID: 4, Name: "generics", Path: "go-lang/generics", ParentID: 3},
+	}}
+	notes := &fakeNotes{notes: map[string][]int{
+		"a": {2, 1},
+		"b": {2},
+		"c": {5},
+	}}
+	return &Handler{TagService: tags, NoteService: notes}, tags, notes
+}
+
+func TestMergeRewritesNotesAndDeletesSources(t *testing.T) {
+	h, tags, notes := newMergeHandler()
+
+	result, err := h.merge(context.Background(), "alice", 1, []int{2, 2})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if result.NotesChanged != 2 || result.TagsMerged != 1 {
+		t.Fatalf("result = %+v, want 2 notes changed and 1 tag merged", result)
+	}
+	if got := notes.notes["a"]; len(got) != 1 || got[0] != 1 {
+		t.Fatalf("tags of note a = %v, want [1]", got)
+	}
+	if _, ok := tags.tags[2]; ok {
+		t.Fatal("source tag is not deleted")
+	}
+
+	result, err = h.merge(context.Background(), "alice", 1, []int{2})
+	if err != nil || result.NotesChanged != 0 || result.TagsMerged != 0 {
+		t.Fatalf("repeated merge = %+v, %v; want nothing to do", result, err)
+	}
+}
+
+func TestDeleteTagDropsItsRules(t *testing.T) {
+	h, tags, notes := newMergeHandler()
+	notes.ruleTags = []int{1, 2, 1}
+
+	if err := h.deleteTag(context.Background(), "alice", "3"); err == nil {
+		t.Fatal("tag with a child deleted")
+	}
+	notes.fail = true
+	if err := h.deleteTag(context.Background(), "alice", "1"); err == nil {
+		t.Fatal("delete succeeded while note_service is down")
+	}
+	if _, ok := tags.tags[1]; ok {
+		t.Fatal("tag is not deleted")
+	}
+
+	// the tag is gone, repeating the delete drops the rules left behind
+	notes.fail = false
+	if err := h.deleteTag(context.Background(), "alice", "1"); !isNotFound(err) {
+		t.Fatalf("repeated delete = %v, want not found", err)
+	}
+	if len(notes.ruleTags) != 1 || notes.ruleTags[0] != 2 {
+		t.Fatalf("rule tags = %v, want [2]", notes.ruleTags)
+	}
+}
+
+func TestMergeKeepsSourcesWhenNotesFail(t *testing.T) {
+	h, tags, notes := newMergeHandler()
+	notes.fail = true
+
+	if _, err := h.merge(context.Background(), "alice", 1, []int{2}); err == nil {
+		t.Fatal("merge succeeded while note_service is down")
+	}
+	if _, ok := tags.tags[2]; !ok {
+		t.Fatal("source tag deleted before notes were rewritten")
+	}
+}
+
+func TestMergeRejects(t *testing.T) {
+	h, _, _ := newMergeHandler()
+
+	cases := map[string]struct {
+		target  int
+		sources []int
+	}{
+		"into itself":       {1, []int{1, 2}},
+		"without sources":   {1, nil},
+		"unknown target":    {9, []int{2}},
+		"source with child": {1, []int{3}},
+	}
+	for name, c := range cases {
+		if _, err := h.merge(context.Background(), "alice", c.target, c.sources); err == nil {
+			t.Errorf("%s: merge succeeded", name)
+		}
+	}
+}
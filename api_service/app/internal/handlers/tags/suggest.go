@@ -0,0 +1,51 @@
+package tags
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+)
+
+const (
+	defaultSuggestLimit = 10
+	// maxSuggestLimit is also the number of candidates asked from tag_service before ranking, it
+	// adds the candidates ranked like the last one
+	maxSuggestLimit = 50
+)
+
+type SuggestedTag struct {
+	tag_service.Suggestion
+	Notes      int        `json:"notes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// rankSuggestions keeps the order of match quality from tag_service and puts the more used and
+// the more recently used tags first among equally good matches
+func rankSuggestions(suggestions []tag_service.Suggestion, usage []note_service.TagUsage, limit int) []SuggestedTag {
+	byTag := usageByTag(usage)
+
+	ranked := make([]SuggestedTag, 0, len(suggestions))
+	for _, s := range suggestions {
+		u := byTag[s.ID]
+		ranked = append(ranked, SuggestedTag{Suggestion: s, Notes: u.Notes, LastUsedAt: u.LastUsedAt})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		if a.Notes != b.Notes {
+			return a.Notes > b.Notes
+		}
+		if a.LastUsedAt == nil || b.LastUsedAt == nil {
+			return a.LastUsedAt != nil && b.LastUsedAt == nil
+		}
+		return a.LastUsedAt.After(*b.LastUsedAt)
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
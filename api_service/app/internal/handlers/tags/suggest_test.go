@@ -0,0 +1,97 @@
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+)
+
+func TestRankSuggestions(t *testing.T) {
+	suggestion := func(id, rank int) tag_service.Suggestion {
+		return tag_service.Suggestion{Tag: tag_service.Tag{ID: id}, Rank: rank}
+	}
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	usage := []note_service.TagUsage{
+		{TagID: 2, Notes: 5, LastUsedAt: &early},
+		{TagID: 3, Notes: 5, LastUsedAt: &late},
+		{TagID: 4, Notes: 9},
+		{TagID: 5, Notes: 1, LastUsedAt: &late},
+	}
+
+	ranked := rankSuggestions([]tag_service.Suggestion{
+		suggestion(1, 1), suggestion(2, 1), suggestion(3, 1), suggestion(4, 3), suggestion(5, 0),
+	}, usage, 4)
+
+	var ids []int
+	for _, s := range ranked {
+		ids = append(ids, s.ID)
+	}
+	want := []int{5, 3, 2, 1}
+	if len(ids) != len(want) {
+		t.Fatalf("ranked = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ranked = %v, want %v", ids, want)
+		}
+	}
+	if ranked[1].Notes != 5 || !ranked[1].LastUsedAt.Equal(late) {
+		t.Fatalf("usage is not attached: %+v", ranked[1])
+	}
+}
+
+// fakeSuggestions completes to equally ranked tags ordered by path like tag_service does
+type fakeSuggestions struct {
+	tag_service.TagService
+	tags []tag_service.Suggestion
+}
+
+func (f *fakeSuggestions) Suggest(ctx context.Context, ownerUUID string, query string, limit int, ties bool) ([]tag_service.Suggestion, error) {
+	cut := limit
+	for ties && cut < len(f.tags) && f.tags[cut].Rank == f.tags[cut-1].Rank {
+		cut++
+	}
+	if cut > len(f.tags) {
+		cut = len(f.tags)
+	}
+	return f.tags[:cut], nil
+}
+
+type fakeUsage struct {
+	note_service.NoteService
+	usage []note_service.TagUsage
+}
+
+func (f *fakeUsage) GetTagUsage(ctx context.Context, ownerUUID string) ([]note_service.TagUsage, error) {
+	return f.usage, nil
+}
+
+func TestSuggestRanksUsedTagsPastTheCandidateLimit(t *testing.T) {
+	tags := &fakeSuggestions{}
+	for id := 1; id <= maxSuggestLimit+10; id++ {
+		tags.tags = append(tags.tags, tag_service.Suggestion{Tag: tag_service.Tag{ID: id}, Rank: 1})
+	}
+	used := maxSuggestLimit + 5
+	h := &Handler{TagService: tags, NoteService: &fakeUsage{usage: []note_service.TagUsage{{TagID: used, Notes: 100}}}}
+
+	r := httptest.NewRequest("GET", "/api/tags/suggest?q=tag&limit=1", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "user_uuid", "alice"))
+	w := httptest.NewRecorder()
+	if err := h.SuggestTags(w, r); err != nil {
+		t.Fatalf("suggest: %v", err)
+	}
+
+	var suggestions []SuggestedTag
+	if err := json.NewDecoder(w.Body).Decode(&suggestions); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].ID != used {
+		t.Fatalf("suggestions = %+v, want the most used tag %d", suggestions, used)
+	}
+}
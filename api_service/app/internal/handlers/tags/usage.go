@@ -0,0 +1,91 @@
+package tags
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+)
+
+// maxCloudWeight is the weight of the most used tag in the tag cloud, the least used get 1
+const maxCloudWeight = 5
+
+// CountedTag is a node of the tag tree with the usage of the tag. Unused tags are referenced
+// neither by a note nor through a descendant and can be cleaned up.
+type CountedTag struct {
+	tag_service.Tag
+	Notes      int          `json:"notes"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	Unused     bool         `json:"unused"`
+	Children   []CountedTag `json:"children"`
+}
+
+type CloudTag struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Color  string `json:"color"`
+	Notes  int    `json:"notes"`
+	Weight int    `json:"weight"`
+}
+
+func usageByTag(usage []note_service.TagUsage) map[int]note_service.TagUsage {
+	byTag := make(map[int]note_service.TagUsage, len(usage))
+	for _, u := range usage {
+		byTag[u.TagID] = u
+	}
+	return byTag
+}
+
+func withCounts(tree []tag_service.TagNode, usage []note_service.TagUsage) []CountedTag {
+	byTag := usageByTag(usage)
+
+	var count func(nodes []tag_service.TagNode) []CountedTag
+	count = func(nodes []tag_service.TagNode) []CountedTag {
+		counted := make([]CountedTag, 0, len(nodes))
+		for _, n := range nodes {
+			u := byTag[n.ID]
+			c := CountedTag{Tag: n.Tag, Notes: u.Notes, LastUsedAt: u.LastUsedAt, Children: count(n.Children)}
+			c.Unused = c.Notes == 0
+			for _, child := range c.Children {
+				c.Unused = c.Unused && child.Unused
+			}
+			counted = append(counted, c)
+		}
+		return counted
+	}
+	return count(tree)
+}
+
+// cloud lists the used tags by path, weighted on a logarithmic scale from 1 to maxCloudWeight
+func cloud(tree []tag_service.TagNode, usage []note_service.TagUsage) []CloudTag {
+	byTag := usageByTag(usage)
+
+	tags := []CloudTag{}
+	maxNotes := 0
+	var walk func(nodes []tag_service.TagNode)
+	walk = func(nodes []tag_service.TagNode) {
+		for _, n := range nodes {
+			if notes := byTag[n.ID].Notes; notes > 0 {
+				tags = append(tags, CloudTag{ID: n.ID, Name: n.Name, Path: n.Path, Color: n.Color, Notes: notes})
+				if notes > maxNotes {
+					maxNotes = notes
+				}
+			}
+			walk(n.Children)
+		}
+	}
+	walk(tree)
+
+	for i := range tags {
+		weight := 1.0
+		if maxNotes > 1 {
+			weight += (maxCloudWeight - 1) * math.Log(float64(tags[i].Notes)) / math.Log(float64(maxNotes))
+		}
+		tags[i].Weight = int(math.Round(weight))
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Path < tags[j].Path })
+	return tags
+}
@@ -0,0 +1,62 @@
+package tags
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+)
+
+func testTree() []tag_service.TagNode {
+	node := func(id int, path string, children ...tag_service.TagNode) tag_service.TagNode {
+		return tag_service.TagNode{Tag: tag_service.Tag{ID: id, Name: path, Path: path}, Children: children}
+	}
+	return []tag_service.TagNode{
+		node(1, "work", node(2, "work/acme"), node(3, "work/old")),
+		node(4, "home"),
+		node(5, "misc"),
+	}
+}
+
+func TestWithCountsFlagsUnusedSubtrees(t *testing.T) {
+	used := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	usage := []note_service.TagUsage{{TagID: 2, Notes: 3, LastUsedAt: &used}, {TagID: 4, Notes: 1}}
+
+	tree := withCounts(testTree(), usage)
+
+	work := tree[0]
+	if work.Notes != 0 || work.Unused {
+		t.Fatalf("work = %+v, want no own notes but used through a child", work)
+	}
+	if acme := work.Children[0]; acme.Notes != 3 || acme.Unused || !acme.LastUsedAt.Equal(used) {
+		t.Fatalf("acme = %+v", acme)
+	}
+	if old := work.Children[1]; !old.Unused {
+		t.Fatalf("old = %+v, want unused", old)
+	}
+	if misc := tree[2]; !misc.Unused {
+		t.Fatalf("misc = %+v, want unused", misc)
+	}
+}
+
+func TestCloudWeights(t *testing.T) {
+	usage := []note_service.TagUsage{{TagID: 2, Notes: 100}, {TagID: 4, Notes: 10}, {TagID: 5, Notes: 1}}
+
+	tags := cloud(testTree(), usage)
+
+	if len(tags) != 3 {
+		t.Fatalf("cloud = %+v, want only the used tags", tags)
+	}
+	weights := map[string]int{}
+	for _, c := range tags {
+		weights[c.Path] = c.Weight
+	}
+	if weights["work/acme"] != maxCloudWeight || weights["home"] != 3 || weights["misc"] != 1 {
+		t.Fatalf("weights = %v", weights)
+	}
+
+	if tags := cloud(testTree(), nil); tags == nil || len(tags) != 0 {
+		t.Fatalf("cloud without usage = %#v, want empty list", tags)
+	}
+}
@@ -0,0 +1,150 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/file_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/config"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+)
+
+// Plan limits what one user may keep, zero means unlimited
+type Plan struct {
+	Name               string `json:"name"`
+	MaxNotes           int    `json:"max_notes"`
+	MaxBodyBytes       int64  `json:"max_body_bytes"`
+	MaxAttachmentBytes int64  `json:"max_attachment_bytes"`
+}
+
+type Usage struct {
+	Notes           int   `json:"notes"`
+	BodyBytes       int64 `json:"body_bytes"`
+	Attachments     int   `json:"attachments"`
+	AttachmentBytes int64 `json:"attachment_bytes"`
+}
+
+type Report struct {
+	Plan  Plan  `json:"plan"`
+	Usage Usage `json:"usage"`
+}
+
+var _ Service = &service{}
+
+// Service enforces quota plans. Checks read usage right before the write, so two
+// concurrent requests of one user may overshoot a limit by one item.
+type Service interface {
+	Report(ctx context.Context, userUUID string) (Report, error)
+	CheckNoteCreate(ctx context.Context, userUUID string, bodySize int) error
+	CheckNoteBody(userUUID string, bodySize int) error
+	CheckUpload(ctx context.Context, userUUID string, size int64) error
+}
+
+type service struct {
+	plans       map[string]Plan
+	defaultPlan string
+	users       map[string]string
+	notes       note_service.NoteService
+	files       file_service.FileService
+	logger      logging.Logger
+}
+
+func NewService(cfg *config.Config, notes note_service.NoteService, files file_service.FileService, logger logging.Logger) (Service, error) {
+	plans := make(map[string]Plan, len(cfg.Quota.Plans))
+	for name, p := range cfg.Quota.Plans {
+		plans[name] = Plan{
+			Name:               name,
+			MaxNotes:           p.MaxNotes,
+			MaxBodyBytes:       p.MaxBodyBytes,
+			MaxAttachmentBytes: p.MaxAttachmentBytes,
+		}
+	}
+	if _, ok := plans[cfg.Quota.DefaultPlan]; !ok {
+		return nil, fmt.Errorf("default quota plan %q is not configured", cfg.Quota.DefaultPlan)
+	}
+	for user, plan := range cfg.Quota.Users {
+		if _, ok := plans[plan]; !ok {
+			return nil, fmt.Errorf("quota plan %q of user %s is not configured", plan, user)
+		}
+	}
+
+	return &service{
+		plans:       plans,
+		defaultPlan: cfg.Quota.DefaultPlan,
+		users:       cfg.Quota.Users,
+		notes:       notes,
+		files:       files,
+		logger:      logger,
+	}, nil
+}
+
+func (s *service) plan(userUUID string) Plan {
+	if name, ok := s.users[userUUID]; ok {
+		return s.plans[name]
+	}
+	return s.plans[s.defaultPlan]
+}
+
+func (s *service) Report(ctx context.Context, userUUID string) (r Report, err error) {
+	r.Plan = s.plan(userUUID)
+
+	notesUsage, err := s.notes.GetUsage(ctx, userUUID)
+	if err != nil {
+		return r, err
+	}
+	filesUsage, err := s.files.GetUsage(ctx, userUUID)
+	if err != nil {
+		return r, err
+	}
+	r.Usage = Usage{
+		Notes:           notesUsage.Notes,
+		BodyBytes:       notesUsage.BodyBytes,
+		Attachments:     filesUsage.Files,
+		AttachmentBytes: filesUsage.Bytes,
+	}
+	return r, nil
+}
+
+func (s *service) CheckNoteCreate(ctx context.Context, userUUID string, bodySize int) error {
+	if err := s.CheckNoteBody(userUUID, bodySize); err != nil {
+		return err
+	}
+
+	plan := s.plan(userUUID)
+	if plan.MaxNotes == 0 {
+		return nil
+	}
+	usage, err := s.notes.GetUsage(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	if usage.Notes >= plan.MaxNotes {
+		return apperror.QuotaExceededError(fmt.Sprintf("plan %q allows at most %d notes", plan.Name, plan.MaxNotes))
+	}
+	return nil
+}
+
+func (s *service) CheckNoteBody(userUUID string, bodySize int) error {
+	plan := s.plan(userUUID)
+	if plan.MaxBodyBytes != 0 && int64(bodySize) > plan.MaxBodyBytes {
+		return apperror.QuotaExceededError(fmt.Sprintf("plan %q allows note bodies up to %d bytes", plan.Name, plan.MaxBodyBytes))
+	}
+	return nil
+}
+
+func (s *service) CheckUpload(ctx context.Context, userUUID string, size int64) error {
+	plan := s.plan(userUUID)
+	if plan.MaxAttachmentBytes == 0 {
+		return nil
+	}
+	usage, err := s.files.GetUsage(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	if usage.Bytes+size > plan.MaxAttachmentBytes {
+		return apperror.QuotaExceededError(fmt.Sprintf("plan %q allows at most %d bytes of attachments, %d are used",
+			plan.Name, plan.MaxAttachmentBytes, usage.Bytes))
+	}
+	return nil
+}
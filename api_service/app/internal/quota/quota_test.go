@@ -0,0 +1,87 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/file_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/config"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+type fakeNotes struct {
+	note_service.NoteService
+	usage note_service.Usage
+}
+
+func (f fakeNotes) GetUsage(ctx context.Context, ownerUUID string) (note_service.Usage, error) {
+	return f.usage, nil
+}
+
+type fakeFiles struct {
+	file_service.FileService
+	usage file_service.Usage
+}
+
+func (f fakeFiles) GetUsage(ctx context.Context, ownerUUID string) (file_service.Usage, error) {
+	return f.usage, nil
+}
+
+func newTestService(t *testing.T, notes note_service.Usage, files file_service.Usage) Service {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Quota.DefaultPlan = "free"
+	cfg.Quota.Plans = map[string]config.QuotaPlan{
+		"free":      {MaxNotes: 2, MaxBodyBytes: 10, MaxAttachmentBytes: 100},
+		"unlimited": {},
+	}
+	cfg.Quota.Users = map[string]string{"vip": "unlimited"}
+
+	s, err := NewService(cfg, fakeNotes{usage: notes}, fakeFiles{usage: files}, testLogger)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	return s
+}
+
+func TestChecks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, note_service.Usage{Notes: 2}, file_service.Usage{Bytes: 90})
+
+	if err := s.CheckNoteCreate(ctx, "user", 5); !isQuotaError(err) {
+		t.Fatalf("third note returned %v, want quota error", err)
+	}
+	if err := s.CheckNoteBody("user", 11); !isQuotaError(err) {
+		t.Fatalf("oversized body returned %v, want quota error", err)
+	}
+	if err := s.CheckUpload(ctx, "user", 11); !isQuotaError(err) {
+		t.Fatalf("upload over the attachment limit returned %v, want quota error", err)
+	}
+	if err := s.CheckUpload(ctx, "user", 10); err != nil {
+		t.Fatalf("upload up to the limit: %v", err)
+	}
+
+	if err := s.CheckNoteCreate(ctx, "vip", 1<<20); err != nil {
+		t.Fatalf("unlimited plan: %v", err)
+	}
+}
+
+func TestUnknownDefaultPlan(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Quota.DefaultPlan = "missing"
+	if _, err := NewService(cfg, fakeNotes{}, fakeFiles{}, testLogger); err == nil {
+		t.Fatal("service accepted a default plan that is not configured")
+	}
+}
+
+func isQuotaError(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Status == http.StatusForbidden
+}
@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"time"
+)
+
+const (
+	// MFAChallengeTTL is how long a sign in waits for the second factor
+	MFAChallengeTTL = 5 * time.Minute
+	// mfaChallengeAttempts wrong codes drop the challenge, the password is asked again
+	mfaChallengeAttempts = 5
+)
+
+var ErrNoChallenge = errors.New("mfa challenge is expired or unknown")
+
+// challenge is a sign in that passed the password and waits for the second factor
+type challenge struct {
+	User      user_service.User `json:"user"`
+	Failures  int               `json:"failures"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+func (h *helper) CreateMFAChallenge(u user_service.User) (string, error) {
+	token := uuid.New().String()
+	c := challenge{User: u, ExpiresAt: time.Now().Add(MFAChallengeTTL)}
+	if err := h.setChallenge(token, c); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (h *helper) MFAChallengeUser(token string) (user_service.User, error) {
+	c, err := h.getChallenge(token)
+	if err != nil {
+		return user_service.User{}, err
+	}
+	return c.User, nil
+}
+
+func (h *helper) FailMFAChallenge(token string) {
+	c, err := h.getChallenge(token)
+	if err != nil {
+		return
+	}
+	c.Failures++
+	if c.Failures >= mfaChallengeAttempts {
+		h.MFACache.Del([]byte(token))
+		return
+	}
+	if err = h.setChallenge(token, c); err != nil {
+		h.Logger.Error(err)
+	}
+}
+
+func (h *helper) CompleteMFAChallenge(token string) ([]byte, error) {
+	c, err := h.getChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+	// only one of concurrent completions gets the tokens
+	if !h.MFACache.Del([]byte(token)) {
+		return nil, ErrNoChallenge
+	}
+	return h.GenerateAccessToken(c.User)
+}
+
+func (h *helper) getChallenge(token string) (c challenge, err error) {
+	b, err := h.MFACache.Get([]byte(token))
+	if err != nil {
+		return c, ErrNoChallenge
+	}
+	if err = json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// setChallenge keeps the challenge until its own expiry, updates do not prolong it
+func (h *helper) setChallenge(token string, c challenge) error {
+	ttl := int(time.Until(c.ExpiresAt).Seconds())
+	if ttl <= 0 {
+		return ErrNoChallenge
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return h.MFACache.Set([]byte(token), b, ttl)
+}
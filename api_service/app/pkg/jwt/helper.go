@@ -15,7 +15,14 @@ var _ Helper = &helper{}
 
 type UserClaims struct {
 	jwtshka.RegisteredClaims
-	Email string `json:"email"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+}
+
+// session is what a refresh token resolves to. Refreshing keeps the session id of the sign-in.
+type session struct {
+	User      user_service.User `json:"user"`
+	SessionID string            `json:"session_id"`
 }
 
 type RT struct {
@@ -25,33 +32,72 @@ type RT struct {
 type helper struct {
 	Logger  logging.Logger
 	RTCache cache.Repository
+	// MFACache keeps sign ins waiting for the second factor apart from refresh tokens
+	MFACache cache.Repository
 }
 
-func NewHelper(RTCache cache.Repository, logger logging.Logger) Helper {
-	return &helper{RTCache: RTCache, Logger: logger}
+func NewHelper(RTCache, MFACache cache.Repository, logger logging.Logger) Helper {
+	return &helper{RTCache: RTCache, MFACache: MFACache, Logger: logger}
 }
 
 type Helper interface {
 	GenerateAccessToken(u user_service.User) ([]byte, error)
 	UpdateRefreshToken(rt RT) ([]byte, error)
+	// RevokeUserTokens drops every refresh token of the user and returns how many were dropped
+	RevokeUserTokens(userUUID string) int
+	// CreateMFAChallenge returns a short-lived token standing for the user until the second factor
+	CreateMFAChallenge(u user_service.User) (string, error)
+	MFAChallengeUser(token string) (user_service.User, error)
+	// FailMFAChallenge counts a wrong code, too many of them drop the challenge
+	FailMFAChallenge(token string)
+	// CompleteMFAChallenge drops the challenge and returns the token pair of its user
+	CompleteMFAChallenge(token string) ([]byte, error)
 }
 
 func (h *helper) UpdateRefreshToken(rt RT) ([]byte, error) {
 	defer h.RTCache.Del([]byte(rt.RefreshToken))
 
-	userBytes, err := h.RTCache.Get([]byte(rt.RefreshToken))
+	sessionBytes, err := h.RTCache.Get([]byte(rt.RefreshToken))
 	if err != nil {
 		return nil, err
 	}
-	var u user_service.User
-	err = json.Unmarshal(userBytes, &u)
+	var s session
+	err = json.Unmarshal(sessionBytes, &s)
 	if err != nil {
 		return nil, err
 	}
-	return h.GenerateAccessToken(u)
+	return h.generateTokens(s)
+}
+
+func (h *helper) RevokeUserTokens(userUUID string) int {
+	var tokens [][]byte
+	iter := h.RTCache.GetIterator()
+	for entry := iter.Next(); entry != nil; entry = iter.Next() {
+		var s session
+		if err := json.Unmarshal(entry.Value, &s); err != nil {
+			continue
+		}
+		if s.User.UUID == userUUID {
+			tokens = append(tokens, entry.Key)
+		}
+	}
+
+	revoked := 0
+	for _, token := range tokens {
+		if h.RTCache.Del(token) {
+			revoked++
+		}
+	}
+	return revoked
 }
 
 func (h *helper) GenerateAccessToken(u user_service.User) ([]byte, error) {
+	return h.generateTokens(session{User: u, SessionID: uuid.New().String()})
+}
+
+func (h *helper) generateTokens(s session) ([]byte, error) {
+	u := s.User
+
 	key := []byte(config.GetConfig().JWT.Secret)
 	signer, err := jwtshka.NewSignerHS(jwtshka.HS256, key)
 	if err != nil {
@@ -63,9 +109,10 @@ func (h *helper) GenerateAccessToken(u user_service.User) ([]byte, error) {
 		RegisteredClaims: jwtshka.RegisteredClaims{
 			ID:        u.UUID,
 			Audience:  []string{"users"},
-			ExpiresAt: jwtshka.NewNumericDate(time.Now().Add(time.Minute * 60)),
+			ExpiresAt: jwtshka.NewNumericDate(time.Now().Add(accessTokenTTL)),
 		},
-		Email: u.Email,
+		Email:     u.Email,
+		SessionID: s.SessionID,
 	}
 	token, err := builder.Build(claims)
 	if err != nil {
@@ -74,8 +121,8 @@ func (h *helper) GenerateAccessToken(u user_service.User) ([]byte, error) {
 
 	h.Logger.Info("create refresh token")
 	refreshTokenUuid := uuid.New()
-	userBytes, _ := json.Marshal(u)
-	err = h.RTCache.Set([]byte(refreshTokenUuid.String()), userBytes, 0)
+	sessionBytes, _ := json.Marshal(s)
+	err = h.RTCache.Set([]byte(refreshTokenUuid.String()), sessionBytes, 0)
 	if err != nil {
 		h.Logger.Error(err)
 		return nil, err
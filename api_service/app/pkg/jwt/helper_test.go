@@ -0,0 +1,78 @@
+package jwt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/pkg/cache/freecache"
+)
+
+func TestRevokeUserTokens(t *testing.T) {
+	rtCache := freecache.NewCacheRepo(1024 * 1024)
+	h := &helper{RTCache: rtCache}
+
+	store := func(token, userUUID string) {
+		b, _ := json.Marshal(session{User: user_service.User{UUID: userUUID}, SessionID: token})
+		if err := rtCache.Set([]byte(token), b, 0); err != nil {
+			t.Fatalf("set %s: %v", token, err)
+		}
+	}
+	store("rt-1", "alice")
+	store("rt-2", "alice")
+	store("rt-3", "bob")
+
+	if revoked := h.RevokeUserTokens("alice"); revoked != 2 {
+		t.Fatalf("revoked %d tokens, want 2", revoked)
+	}
+	for _, token := range []string{"rt-1", "rt-2"} {
+		if _, err := rtCache.Get([]byte(token)); err == nil {
+			t.Errorf("%s of alice survived", token)
+		}
+	}
+	if _, err := rtCache.Get([]byte("rt-3")); err != nil {
+		t.Errorf("token of bob revoked: %v", err)
+	}
+}
+
+func TestMFAChallengeDroppedAfterFailures(t *testing.T) {
+	h := &helper{MFACache: freecache.NewCacheRepo(1024 * 1024)}
+
+	token, err := h.CreateMFAChallenge(user_service.User{UUID: "alice", TOTPEnabled: true})
+	if err != nil {
+		t.Fatalf("create challenge: %v", err)
+	}
+	if u, err := h.MFAChallengeUser(token); err != nil || u.UUID != "alice" {
+		t.Fatalf("challenge user = %+v, %v", u, err)
+	}
+
+	for i := 0; i < mfaChallengeAttempts-1; i++ {
+		h.FailMFAChallenge(token)
+	}
+	if _, err := h.MFAChallengeUser(token); err != nil {
+		t.Fatalf("challenge dropped before the last attempt: %v", err)
+	}
+	h.FailMFAChallenge(token)
+	if _, err := h.MFAChallengeUser(token); err != ErrNoChallenge {
+		t.Fatalf("challenge after %d failures: %v", mfaChallengeAttempts, err)
+	}
+	if _, err := h.CompleteMFAChallenge(token); err != ErrNoChallenge {
+		t.Fatalf("dropped challenge completed: %v", err)
+	}
+}
+
+func TestRevokeAccessExpires(t *testing.T) {
+	RevokeAccess("alice")
+
+	now := time.Now()
+	if !isRevoked("alice", now) {
+		t.Fatalf("alice is not revoked")
+	}
+	if isRevoked("bob", now) {
+		t.Fatalf("bob is revoked")
+	}
+	if isRevoked("alice", now.Add(accessTokenTTL+time.Second)) {
+		t.Fatalf("alice is revoked after the access tokens expired")
+	}
+}
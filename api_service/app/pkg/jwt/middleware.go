@@ -48,8 +48,15 @@ func Middleware(h http.HandlerFunc) http.HandlerFunc {
 			unauthorized(w, err)
 			return
 		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && isRevoked(uc.ID, time.Now()) {
+			logger.Warnf("write of revoked user %s refused", uc.ID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("account is being deleted"))
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), "user_uuid", uc.ID)
+		ctx = context.WithValue(ctx, "session_id", uc.SessionID)
 		h(w, r.WithContext(ctx))
 	}
 }
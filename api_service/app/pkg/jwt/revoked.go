@@ -0,0 +1,35 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+)
+
+// accessTokenTTL is how long an access token is valid, a revoked user is known for as long
+const accessTokenTTL = 60 * time.Minute
+
+var revoked = struct {
+	sync.Mutex
+	users map[string]time.Time
+}{users: make(map[string]time.Time)}
+
+// RevokeAccess makes Middleware refuse writes with the access tokens of the user until they
+// expire. Reads still pass, so the user follows the deletion of the account. The list is kept
+// by this process only, like refresh tokens are.
+func RevokeAccess(userUUID string) {
+	revoked.Lock()
+	defer revoked.Unlock()
+	revoked.users[userUUID] = time.Now().Add(accessTokenTTL)
+}
+
+func isRevoked(userUUID string, now time.Time) bool {
+	revoked.Lock()
+	defer revoked.Unlock()
+	for u, until := range revoked.users {
+		if now.After(until) {
+			delete(revoked.users, u)
+		}
+	}
+	_, ok := revoked.users[userUUID]
+	return ok
+}
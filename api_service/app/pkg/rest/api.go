@@ -27,14 +27,22 @@ func (ar *APIResponse) StatusCode() int {
 	return ar.response.StatusCode
 }
 
+func (ar *APIResponse) Header() http.Header {
+	return ar.response.Header
+}
+
 func (ar *APIResponse) Location() (*url.URL, error) {
 	return ar.response.Location()
 }
 
 type APIError struct {
-	Message          string `json:"error,omitempty"`
-	ErrorCode        string `json:"error_code,omitempty"`
+	Message          string `json:"message,omitempty"`
+	ErrorCode        string `json:"code,omitempty"`
 	DeveloperMessage string `json:"developer_message,omitempty"`
+	// ConflictingID identifies what a 409 Conflict collides with, when the service tells it
+	ConflictingID int `json:"conflicting_id,omitempty"`
+	// RetryAfter comes with 429 Too Many Requests, in seconds
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 func (aep *APIError) ToString() string {
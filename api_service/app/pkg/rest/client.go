@@ -28,7 +28,10 @@ func (c *BaseClient) SendRequest(req *http.Request) (*APIResponse, error) {
 	}
 
 	req.Header.Set("Accept", "application/json; charset=utf-8")
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	// multipart uploads come with their own content type and boundary
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
 
 	response, err := c.HTTPClient.Do(req)
 	if err != nil {
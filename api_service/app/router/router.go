@@ -0,0 +1,116 @@
+// Package router builds the routed handler of the gateway. The gateway binary serves it on its own
+// listener, the all-in-one binary serves it with the services mounted in the same process.
+package router
+
+import (
+	"context"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/category_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/file_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/note_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/tag_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/client/user_service"
+	"github.com/ohdaddyplease/notes/api_service/internal/config"
+	"github.com/ohdaddyplease/notes/api_service/internal/deletion"
+	"github.com/ohdaddyplease/notes/api_service/internal/export"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/auth"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/categories"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/comments"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/exports"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/files"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/notes"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/quotas"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/rules"
+	"github.com/ohdaddyplease/notes/api_service/internal/handlers/tags"
+	"github.com/ohdaddyplease/notes/api_service/internal/quota"
+	"github.com/ohdaddyplease/notes/api_service/pkg/cache/freecache"
+	"github.com/ohdaddyplease/notes/api_service/pkg/jwt"
+	"github.com/ohdaddyplease/notes/api_service/pkg/logging"
+	"github.com/ohdaddyplease/notes/api_service/pkg/metrics"
+	"net/http"
+)
+
+// Config is the config of the gateway, the service URLs say where each service is reached
+type Config = config.Config
+
+// GetConfig reads config.yml once. Tokens are signed and checked with the JWT secret of this config,
+// so the config given to New is the one returned here.
+func GetConfig() *Config {
+	return config.GetConfig()
+}
+
+// New builds the handler of every route of the gateway, background jobs run until ctx is done
+func New(ctx context.Context, cfg *Config, logger logging.Logger) (http.Handler, error) {
+	router := httprouter.New()
+
+	refreshTokenCache := freecache.NewCacheRepo(104857600) // 100MB
+
+	mfaChallengeCache := freecache.NewCacheRepo(10485760) // 10MB
+
+	jwtHelper := jwt.NewHelper(refreshTokenCache, mfaChallengeCache, logger)
+
+	metricHandler := metric.Handler{Logger: logger}
+	metricHandler.Register(router)
+
+	userService := user_service.NewService(cfg.UserService.URL, "/users", logger)
+	categoryService := category_service.NewService(cfg.CategoryService.URL, "/categories", logger)
+	noteService := note_service.NewService(cfg.NoteService.URL, "/notes", logger)
+	tagService := tag_service.NewService(cfg.TagService.URL, "/tags", logger)
+	fileService := file_service.NewService(cfg.FileService.URL, "/files", logger)
+
+	exportService, err := export.NewService(export.Clients{
+		Users:      userService,
+		Categories: categoryService,
+		Notes:      noteService,
+		Tags:       tagService,
+		Files:      fileService,
+	}, cfg.Export.Dir, cfg.Export.TTL, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// notes go first, they are what the user sees, the user itself is removed by user_service last
+	deletionService := deletion.NewService(userService, []deletion.Step{
+		{Name: "notes", Purge: noteService.DeleteByOwner},
+		{Name: "files", Purge: fileService.DeleteByOwner},
+		{Name: "tags", Purge: tagService.DeleteByOwner},
+		{Name: "categories", Purge: categoryService.DeleteByOwner},
+		{Name: "exports", Purge: exportService.DeleteByOwner},
+	}, logger)
+	go deletionService.ResumeEvery(ctx, cfg.Deletion.RetryInterval)
+
+	authHandler := auth.Handler{JWTHelper: jwtHelper, UserService: userService, Logger: logger,
+		TrustForwardedFor: cfg.Auth.TrustForwardedFor, Admins: cfg.Auth.Admins, Deletions: deletionService}
+	authHandler.Register(router)
+
+	categoriesHandler := categories.Handler{CategoryService: categoryService, Logger: logger}
+	categoriesHandler.Register(router)
+
+	quotaService, err := quota.NewService(cfg, noteService, fileService, logger)
+	if err != nil {
+		return nil, err
+	}
+	quotasHandler := quotas.Handler{QuotaService: quotaService, Logger: logger}
+	quotasHandler.Register(router)
+
+	notesHandler := notes.Handler{NoteService: noteService, TagService: tagService, QuotaService: quotaService, Logger: logger}
+	notesHandler.Register(router)
+
+	filesHandler := files.Handler{FileService: fileService, QuotaService: quotaService, Logger: logger}
+	filesHandler.Register(router)
+
+	commentsHandler := comments.Handler{NoteService: noteService, UserService: userService, Logger: logger}
+	commentsHandler.Register(router)
+
+	tagsHandler := tags.Handler{TagService: tagService, NoteService: noteService, Logger: logger}
+	tagsHandler.Register(router)
+
+	rulesHandler := rules.Handler{NoteService: noteService, TagService: tagService, Logger: logger}
+	rulesHandler.Register(router)
+
+	go exportService.RemoveExpiredEvery(ctx, cfg.Export.CleanInterval)
+	exportsHandler := exports.Handler{ExportService: exportService, Logger: logger}
+	exportsHandler.Register(router)
+
+	return router, nil
+}
@@ -14,7 +14,7 @@ from constants import LOG_DIR, CONFIG_FILE_PATH, ANY_ORIGIN, EXPOSE_HEADERS
 from di import StorageModule, LoggerModule
 from exceptions import AppException, ValidationException, AppError
 from helpers.flask import app_exception_handler, uncaught_exception_handler
-from resources import CategoryResource, CategoriesResource
+from resources import CategoryResource, CategoriesResource, OwnerResource
 
 config = Config(yaml_file=CONFIG_FILE_PATH)
 
@@ -37,6 +37,7 @@ app.config.from_object(config)
 api = Api(app)
 api.add_resource(CategoriesResource, "/api/categories")
 api.add_resource(CategoryResource, "/api/categories/<string:cuuid>")
+api.add_resource(OwnerResource, "/api/owners/<string:uuid>")
 
 CORS(app, resources={"*": ANY_ORIGIN}, expose_headers=EXPOSE_HEADERS)
 
@@ -33,3 +33,7 @@ class CategoryDAO(ABC):
     @abstractmethod
     def delete_category(self, category: DeleteCategoryDTO):
         raise NotImplementedError
+
+    @abstractmethod
+    def delete_user_categories(self, user_uuid: str):
+        raise NotImplementedError
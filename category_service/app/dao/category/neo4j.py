@@ -92,3 +92,13 @@ class Neo4jCategoryDAO(CategoryDAO):
             DETACH DELETE path
             """
         )
+
+    def delete_user_categories(self, user_uuid: str):
+        # the user node goes too, a missing user deletes nothing
+        self.storage.delete(
+            f"""
+            MATCH (u:User {'{'} id: "{user_uuid}" {'}'})
+            OPTIONAL MATCH (u)-[*]->(c:Category)
+            DETACH DELETE c, u
+            """
+        )
@@ -1,3 +1,4 @@
 from resources.categories import CategoryResource, CategoriesResource
+from resources.owners import OwnerResource
 
-__all__ = ["CategoryResource", "CategoriesResource"]
+__all__ = ["CategoryResource", "CategoriesResource", "OwnerResource"]
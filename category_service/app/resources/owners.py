@@ -0,0 +1,26 @@
+import logging
+from http import HTTPStatus
+
+from flask import make_response, jsonify
+from flask_apispec import marshal_with, MethodResource
+from flask_restful import Resource
+from injector import inject
+
+from service import CategoryService
+
+
+class OwnerResource(MethodResource, Resource):
+    """Called by the gateway when the account of the owner is deleted"""
+    __slots__ = ["service", "logger"]
+
+    @inject
+    def __init__(self, service: CategoryService, logger: logging.Logger):
+        self.service = service
+        self.logger = logger
+
+    @marshal_with(None, code=HTTPStatus.NO_CONTENT)
+    def delete(self, uuid: str):
+        self.service.delete_user_categories(user_uuid=uuid)
+        r = make_response(jsonify(), HTTPStatus.NO_CONTENT)
+        r.headers["Content-Type"] = "application/json"
+        return r
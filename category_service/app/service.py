@@ -48,3 +48,7 @@ class CategoryService:
         if not is_exist:
             raise NotFoundException(exc_data=AppError.CATEGORY_NOT_FOUND)
         self.category_dao.delete_category(category=category)
+
+    def delete_user_categories(self, user_uuid: str) -> None:
+        # called again after a failure, so a user without categories is not an error
+        self.category_dao.delete_user_categories(user_uuid=user_uuid)
@@ -1,15 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"github.com/julienschmidt/httprouter"
 	"github.com/ohdaddyplease/notes/file_service/internal/config"
-	"github.com/ohdaddyplease/notes/file_service/internal/file"
-	"github.com/ohdaddyplease/notes/file_service/internal/file/storage/minio"
-	"github.com/ohdaddyplease/notes/file_service/pkg/handlers/metric"
 	"github.com/ohdaddyplease/notes/file_service/pkg/logging"
 	"github.com/ohdaddyplease/notes/file_service/pkg/shutdown"
+	"github.com/ohdaddyplease/notes/file_service/router"
 	"net"
 	"net/http"
 	"os"
@@ -25,26 +23,19 @@ func main() {
 
 	cfg := config.GetConfig()
 
-	router := httprouter.New()
-
-	metricHandler := metric.Handler{Logger: logger}
-	metricHandler.Register(router)
-
-	fileStorage, err := minio.NewStorage(cfg.MinIO.Endpoint, cfg.MinIO.AccessKey, cfg.MinIO.SecretKey, logger)
-	if err != nil {
-		logger.Fatal(err)
+	if len(os.Args) > 1 && os.Args[1] == "index-owners" {
+		if err := indexOwnersCommand(context.Background(), cfg, logger, os.Args[2:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
-	fileService, err := file.NewService(fileStorage, logger)
+
+	handler, err := router.New(context.Background(), cfg, logger)
 	if err != nil {
 		logger.Fatal(err)
 	}
-	filesHandler := file.Handler{
-		Logger:      logger,
-		FileService: fileService,
-	}
-	filesHandler.Register(router)
 
-	start(router, logger, cfg)
+	start(handler, logger, cfg)
 }
 
 func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
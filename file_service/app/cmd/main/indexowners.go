@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/ohdaddyplease/notes/file_service/internal/config"
+	"github.com/ohdaddyplease/notes/file_service/pkg/logging"
+	"github.com/ohdaddyplease/notes/file_service/pkg/minio"
+	"io"
+	"os"
+	"strings"
+)
+
+// indexOwnersCommand handles `app index-owners [file]`: it adds every stored file to the owners index
+// that usage and account deletion read. Files uploaded before files kept their owner get the owner of
+// their note, the pairs come from `app note-owners` of the note service, read from file or stdin.
+func indexOwnersCommand(ctx context.Context, cfg *config.Config, logger logging.Logger, args []string) error {
+	if cfg.Storage.Type != "minio" {
+		return fmt.Errorf("index-owners command works with minio storage only, configured %q", cfg.Storage.Type)
+	}
+
+	in := io.Reader(os.Stdin)
+	if len(args) > 0 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	owners, err := readNoteOwners(in)
+	if err != nil {
+		return err
+	}
+
+	client, err := minio.NewClient(cfg.MinIO.Endpoint, cfg.MinIO.AccessKey, cfg.MinIO.SecretKey, logger)
+	if err != nil {
+		return err
+	}
+	indexed, left, err := client.IndexOwners(ctx, owners)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d files indexed, %d files left without an owner\n", indexed, len(left))
+	for _, f := range left {
+		fmt.Println(f)
+	}
+	return nil
+}
+
+// readNoteOwners reads "note_uuid owner_uuid" lines into the owner of each note
+func readNoteOwners(in io.Reader) (map[string]string, error) {
+	owners := make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want note uuid and owner uuid, got %q", line, scanner.Text())
+		}
+		owners[fields[0]] = fields[1]
+	}
+	return owners, scanner.Err()
+}
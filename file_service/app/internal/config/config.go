@@ -13,10 +13,13 @@ type Config struct {
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
 		Port   string `yaml:"port" env-default:"10002"`
 	}
+	Storage struct {
+		Type string `yaml:"type" env-default:"minio"`
+	} `yaml:"storage"`
 	MinIO struct {
-		Endpoint  string `yaml:"endpoint" env-required:"true"`
-		AccessKey string `yaml:"access_key" env-required:"true"`
-		SecretKey string `yaml:"secret_key" env-required:"true"`
+		Endpoint  string `yaml:"endpoint"`
+		AccessKey string `yaml:"access_key"`
+		SecretKey string `yaml:"secret_key"`
 	} `yaml:"minio"`
 }
 
@@ -12,6 +12,9 @@ import (
 const (
 	filesURL = "/api/files"
 	fileURL  = "/api/files/:id"
+	usageURL = "/api/usage"
+	// ownerURL is called by the gateway when the account of the owner is deleted
+	ownerURL = "/api/owners/:uuid"
 )
 
 type Handler struct {
@@ -24,6 +27,8 @@ func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodGet, filesURL, apperror.Middleware(h.GetFilesByNoteUUID))
 	router.HandlerFunc(http.MethodPost, filesURL, apperror.Middleware(h.CreateFile))
 	router.HandlerFunc(http.MethodDelete, fileURL, apperror.Middleware(h.DeleteFile))
+	router.HandlerFunc(http.MethodGet, usageURL, apperror.Middleware(h.GetUsage))
+	router.HandlerFunc(http.MethodDelete, ownerURL, apperror.Middleware(h.DeleteOwnerFiles))
 }
 
 func (h *Handler) GetFile(w http.ResponseWriter, r *http.Request) error {
@@ -91,6 +96,8 @@ func (h *Handler) CreateFile(w http.ResponseWriter, r *http.Request) error {
 		Name:   fileInfo.Filename,
 		Size:   fileInfo.Size,
 		Reader: fileReader,
+		// set by the gateway for the authenticated user
+		OwnerUUID: r.Header.Get("X-User-UUID"),
 	}
 
 	err = h.FileService.Create(r.Context(), r.Form.Get("note_uuid"), dto)
@@ -121,3 +128,33 @@ func (h *Handler) DeleteFile(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	usage, err := h.FileService.GetUsage(r.Context(), r.URL.Query().Get("owner_uuid"))
+	if err != nil {
+		return err
+	}
+	usageBytes, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(usageBytes)
+
+	return nil
+}
+
+func (h *Handler) DeleteOwnerFiles(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if _, err := h.FileService.DeleteByOwner(r.Context(), params.ByName("uuid")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -12,16 +12,26 @@ import (
 )
 
 type File struct {
-	ID 	  string `json:"id"`
+	ID    string `json:"id"`
 	Name  string `json:"name"`
 	Size  int64  `json:"size"`
 	Bytes []byte `json:"file"`
+	// OwnerUUID is the user who uploaded the file, attachment quotas are counted by it
+	OwnerUUID string `json:"owner_uuid,omitempty"`
+}
+
+// Usage is what one user keeps in the file storage
+type Usage struct {
+	OwnerUUID string `json:"owner_uuid"`
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
 }
 
 type CreateFileDTO struct {
-	Name     string `json:"name"`
-	Size     int64  `json:"size"`
-	Reader   io.Reader
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	OwnerUUID string `json:"owner_uuid"`
+	Reader    io.Reader
 }
 
 func isMn(r rune) bool {
@@ -45,9 +55,10 @@ func NewFile(dto CreateFileDTO) (*File, error) {
 	}
 
 	return &File{
-		ID:    id.String(),
-		Name:  dto.Name,
-		Size:  dto.Size,
-		Bytes: bytes,
+		ID:        id.String(),
+		Name:      dto.Name,
+		Size:      dto.Size,
+		Bytes:     bytes,
+		OwnerUUID: dto.OwnerUUID,
 	}, nil
 }
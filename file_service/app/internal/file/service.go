@@ -2,6 +2,7 @@ package file
 
 import (
 	"context"
+	"github.com/ohdaddyplease/notes/file_service/internal/apperror"
 	"github.com/ohdaddyplease/notes/file_service/pkg/logging"
 )
 
@@ -24,6 +25,8 @@ type Service interface {
 	GetFilesByNoteUUID(ctx context.Context, noteUUID string) ([]*File, error)
 	Create(ctx context.Context, noteUUID string, dto CreateFileDTO) error
 	Delete(ctx context.Context, noteUUID, fileName string) error
+	GetUsage(ctx context.Context, ownerUUID string) (Usage, error)
+	DeleteByOwner(ctx context.Context, ownerUUID string) (int, error)
 }
 
 func (s *service) GetFile(ctx context.Context, noteUUID, fileId string) (f *File, err error) {
@@ -62,3 +65,27 @@ func (s *service) Delete(ctx context.Context, noteUUID, fileName string) error {
 	}
 	return nil
 }
+
+func (s *service) GetUsage(ctx context.Context, ownerUUID string) (Usage, error) {
+	if ownerUUID == "" {
+		return Usage{}, apperror.BadRequestError("owner_uuid query parameter is required")
+	}
+	usage, err := s.storage.UsageByOwner(ctx, ownerUUID)
+	if err != nil {
+		return usage, err
+	}
+	usage.OwnerUUID = ownerUUID
+	return usage, nil
+}
+
+func (s *service) DeleteByOwner(ctx context.Context, ownerUUID string) (int, error) {
+	if ownerUUID == "" {
+		return 0, apperror.BadRequestError("owner uuid is required")
+	}
+	deleted, err := s.storage.DeleteByOwner(ctx, ownerUUID)
+	if err != nil {
+		return deleted, err
+	}
+	s.logger.Infof("deleted %d files of owner %s", deleted, ownerUUID)
+	return deleted, nil
+}
@@ -9,4 +9,7 @@ type Storage interface {
 	GetFilesByNoteUUID(ctx context.Context, uuid string) ([]*File, error)
 	CreateFile(ctx context.Context, noteUUID string, file *File) error
 	DeleteFile(ctx context.Context, noteUUID, fileName string) error
+	UsageByOwner(ctx context.Context, ownerUUID string) (Usage, error)
+	// DeleteByOwner removes every file uploaded by the owner and returns how many were removed
+	DeleteByOwner(ctx context.Context, ownerUUID string) (int, error)
 }
\ No newline at end of file
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"github.com/ohdaddyplease/notes/file_service/internal/apperror"
+	"github.com/ohdaddyplease/notes/file_service/internal/file"
+	"github.com/ohdaddyplease/notes/file_service/pkg/logging"
+	"sync"
+)
+
+// memoryStorage keeps files in process memory, one "bucket" per note like minio does
+type memoryStorage struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]file.File
+	logger  logging.Logger
+}
+
+func NewStorage(logger logging.Logger) file.Storage {
+	return &memoryStorage{
+		buckets: make(map[string]map[string]file.File),
+		logger:  logger,
+	}
+}
+
+func (m *memoryStorage) GetFile(ctx context.Context, bucketName, fileID string) (*file.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.buckets[bucketName][fileID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	f.Bytes = append([]byte{}, f.Bytes...)
+
+	return &f, nil
+}
+
+func (m *memoryStorage) GetFilesByNoteUUID(ctx context.Context, noteUUID string) ([]*file.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bucket := m.buckets[noteUUID]
+	if len(bucket) == 0 {
+		return nil, apperror.ErrNotFound
+	}
+
+	files := make([]*file.File, 0, len(bucket))
+	for _, f := range bucket {
+		f := f
+		f.Bytes = append([]byte{}, f.Bytes...)
+		files = append(files, &f)
+	}
+
+	return files, nil
+}
+
+func (m *memoryStorage) CreateFile(ctx context.Context, noteUUID string, f *file.File) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[noteUUID]
+	if !ok {
+		m.logger.Warnf("no bucket %s. creating new one...", noteUUID)
+		bucket = make(map[string]file.File)
+		m.buckets[noteUUID] = bucket
+	}
+	stored := *f
+	stored.Bytes = append([]byte{}, f.Bytes...)
+	bucket[f.ID] = stored
+
+	return nil
+}
+
+func (m *memoryStorage) DeleteFile(ctx context.Context, noteUUID, fileID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// removing a missing object is not an error in minio either
+	delete(m.buckets[noteUUID], fileID)
+
+	return nil
+}
+
+func (m *memoryStorage) UsageByOwner(ctx context.Context, ownerUUID string) (usage file.Usage, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, bucket := range m.buckets {
+		for _, f := range bucket {
+			if f.OwnerUUID == ownerUUID {
+				usage.Files++
+				usage.Bytes += f.Size
+			}
+		}
+	}
+	return usage, nil
+}
+
+func (m *memoryStorage) DeleteByOwner(ctx context.Context, ownerUUID string) (deleted int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for noteUUID, bucket := range m.buckets {
+		for id, f := range bucket {
+			if f.OwnerUUID == ownerUUID {
+				delete(bucket, id)
+				deleted++
+			}
+		}
+		if len(bucket) == 0 {
+			delete(m.buckets, noteUUID)
+		}
+	}
+	return deleted, nil
+}
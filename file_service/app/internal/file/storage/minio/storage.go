@@ -42,10 +42,11 @@ func (m *minioStorage) GetFile(ctx context.Context, bucketName, fileID string) (
 		return nil, fmt.Errorf("failed to get objects. err: %w", err)
 	}
 	f := file.File{
-		ID:    objectInfo.Key,
-		Name:  objectInfo.UserMetadata["Name"],
-		Size:  objectInfo.Size,
-		Bytes: buffer,
+		ID:        objectInfo.Key,
+		Name:      objectInfo.UserMetadata["Name"],
+		Size:      objectInfo.Size,
+		Bytes:     buffer,
+		OwnerUUID: objectInfo.UserMetadata["Owner"],
 	}
 	return &f, nil
 }
@@ -73,10 +74,11 @@ func (m *minioStorage) GetFilesByNoteUUID(ctx context.Context, noteUUID string)
 			continue
 		}
 		f := file.File{
-			ID:    stat.Key,
-			Name:  stat.UserMetadata["Name"],
-			Size:  stat.Size,
-			Bytes: buffer,
+			ID:        stat.Key,
+			Name:      stat.UserMetadata["Name"],
+			Size:      stat.Size,
+			Bytes:     buffer,
+			OwnerUUID: stat.UserMetadata["Owner"],
 		}
 		files = append(files, &f)
 		obj.Close()
@@ -86,7 +88,7 @@ func (m *minioStorage) GetFilesByNoteUUID(ctx context.Context, noteUUID string)
 }
 
 func (m *minioStorage) CreateFile(ctx context.Context, noteUUID string, file *file.File) error {
-	err := m.client.UploadFile(ctx, file.ID, file.Name, noteUUID, file.Size, bytes.NewBuffer(file.Bytes))
+	err := m.client.UploadFile(ctx, file.ID, file.Name, file.OwnerUUID, noteUUID, file.Size, bytes.NewBuffer(file.Bytes))
 	if err != nil {
 		return err
 	}
@@ -100,3 +102,19 @@ func (m *minioStorage) DeleteFile(ctx context.Context, noteUUID, fileId string)
 	}
 	return nil
 }
+
+func (m *minioStorage) UsageByOwner(ctx context.Context, ownerUUID string) (usage file.Usage, err error) {
+	usage.Files, usage.Bytes, err = m.client.OwnerUsage(ctx, ownerUUID)
+	if err != nil {
+		return usage, fmt.Errorf("failed to count usage. err: %w", err)
+	}
+	return usage, nil
+}
+
+func (m *minioStorage) DeleteByOwner(ctx context.Context, ownerUUID string) (int, error) {
+	deleted, err := m.client.DeleteOwnerFiles(ctx, ownerUUID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete files of owner. err: %w", err)
+	}
+	return deleted, nil
+}
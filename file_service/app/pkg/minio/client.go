@@ -3,12 +3,20 @@ package minio
 import (
 	"context"
 	"fmt"
+	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/ohdaddyplease/notes/file_service/pkg/logging"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// OwnersBucket indexes files by owner, an empty object "<owner>/<bucket>/<file id>" with the size of
+// the file in its metadata stands for every file of the owner. Note buckets are named by uuid, so
+// the name is never taken by a note.
+const OwnersBucket = "owners"
+
 type Object struct {
 	ID   string
 	Size int64
@@ -66,37 +74,209 @@ func (c *Client) GetBucketFiles(ctx context.Context, bucketName string) ([]*mini
 	return files, nil
 }
 
-func (c *Client) UploadFile(ctx context.Context, fileId, fileName, bucketName string, fileSize int64, reader io.Reader) error {
+func (c *Client) UploadFile(ctx context.Context, fileId, fileName, ownerUUID, bucketName string, fileSize int64, reader io.Reader) error {
 	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	exists, errBucketExists := c.minioClient.BucketExists(ctx, bucketName)
-	if errBucketExists != nil || !exists {
-		c.logger.Warnf("no bucket %s. creating new one...", bucketName)
-		err := c.minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to create new bucket. err: %w", err)
-		}
+	if err := c.ensureBucket(ctx, bucketName); err != nil {
+		return err
 	}
 
 	c.logger.Debugf("put new object %s to bucket %s", fileName, bucketName)
 	_, err := c.minioClient.PutObject(reqCtx, bucketName, fileId, reader, fileSize,
 		minio.PutObjectOptions{
 			UserMetadata: map[string]string{
-				"Name": fileName,
+				"Name":  fileName,
+				"Owner": ownerUUID,
 			},
 			ContentType: "application/octet-stream",
 		})
 	if err != nil {
 		return fmt.Errorf("failed to upload file. err: %w", err)
 	}
-	return nil
+	if ownerUUID == "" {
+		return nil
+	}
+	return c.indexFile(ctx, ownerUUID, bucketName, fileId, fileSize)
 }
 
+// DeleteFile removes the file and its entry in the owners index
 func (c *Client) DeleteFile(ctx context.Context, noteUUID, fileName string) error {
-	err := c.minioClient.RemoveObject(ctx, noteUUID, fileName, minio.RemoveObjectOptions{})
+	stat, err := c.minioClient.StatObject(ctx, noteUUID, fileName, minio.StatObjectOptions{})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to stat file. err: %w", err)
+	}
+	err = c.minioClient.RemoveObject(ctx, noteUUID, fileName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete file. err: %w", err)
 	}
+	if owner := stat.UserMetadata["Owner"]; owner != "" {
+		err = c.minioClient.RemoveObject(ctx, OwnersBucket, indexKey(owner, noteUUID, fileName), minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to delete file from owners index. err: %w", err)
+		}
+	}
+	return nil
+}
+
+// OwnerUsage sums the files of the owner from the owners index
+func (c *Client) OwnerUsage(ctx context.Context, ownerUUID string) (files int, size int64, err error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	err = c.walkOwnerIndex(reqCtx, ownerUUID, func(key, bucketName, fileID string) error {
+		stat, err := c.minioClient.StatObject(reqCtx, OwnersBucket, key, minio.StatObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to stat index entry %s. err: %w", key, err)
+		}
+		fileSize, err := strconv.ParseInt(stat.UserMetadata["Size"], 10, 64)
+		if err != nil {
+			return fmt.Errorf("index entry %s has no size. err: %w", key, err)
+		}
+		files++
+		size += fileSize
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return files, size, nil
+}
+
+// DeleteOwnerFiles removes the files of the owner found in the owners index
+func (c *Client) DeleteOwnerFiles(ctx context.Context, ownerUUID string) (deleted int, err error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	err = c.walkOwnerIndex(reqCtx, ownerUUID, func(key, bucketName, fileID string) error {
+		if err := c.minioClient.RemoveObject(reqCtx, bucketName, fileID, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("failed to delete object %s of bucket %s. err: %w", fileID, bucketName, err)
+		}
+		if err := c.minioClient.RemoveObject(reqCtx, OwnersBucket, key, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("failed to delete index entry %s. err: %w", key, err)
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// IndexOwners adds every file of the note buckets to the owners index. The owner is the one the
+// file was uploaded with, or owners[bucket] for files uploaded before files kept their owner.
+// Indexed files are indexed again, files whose owner is unknown are returned as "bucket/file id".
+func (c *Client) IndexOwners(ctx context.Context, owners map[string]string) (indexed int, left []string, err error) {
+	buckets, err := c.minioClient.ListBuckets(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list buckets. err: %w", err)
+	}
+	for _, bucket := range buckets {
+		if bucket.Name == OwnersBucket {
+			continue
+		}
+		for lobj := range c.minioClient.ListObjects(ctx, bucket.Name, minio.ListObjectsOptions{}) {
+			if lobj.Err != nil {
+				return indexed, left, fmt.Errorf("failed to list objects of bucket %s. err: %w", bucket.Name, lobj.Err)
+			}
+			stat, err := c.minioClient.StatObject(ctx, bucket.Name, lobj.Key, minio.StatObjectOptions{})
+			if err != nil {
+				return indexed, left, fmt.Errorf("failed to stat object %s of bucket %s. err: %w", lobj.Key, bucket.Name, err)
+			}
+			owner := stat.UserMetadata["Owner"]
+			if owner == "" {
+				owner = owners[bucket.Name]
+				if owner == "" {
+					left = append(left, bucket.Name+"/"+lobj.Key)
+					continue
+				}
+				// the file keeps its owner from now on, so deleting it leaves the index behind
+				if err = c.setOwner(ctx, bucket.Name, lobj.Key, owner, stat.UserMetadata); err != nil {
+					return indexed, left, err
+				}
+			}
+			if err = c.indexFile(ctx, owner, bucket.Name, lobj.Key, stat.Size); err != nil {
+				return indexed, left, err
+			}
+			indexed++
+		}
+	}
+	return indexed, left, nil
+}
+
+func (c *Client) setOwner(ctx context.Context, bucketName, fileID, ownerUUID string, metadata map[string]string) error {
+	userMetadata := map[string]string{"Owner": ownerUUID}
+	for k, v := range metadata {
+		if k != "Owner" {
+			userMetadata[k] = v
+		}
+	}
+	_, err := c.minioClient.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: bucketName, Object: fileID, UserMetadata: userMetadata, ReplaceMetadata: true},
+		minio.CopySrcOptions{Bucket: bucketName, Object: fileID})
+	if err != nil {
+		return fmt.Errorf("failed to set owner of object %s of bucket %s. err: %w", fileID, bucketName, err)
+	}
+	return nil
+}
+
+func (c *Client) indexFile(ctx context.Context, ownerUUID, bucketName, fileID string, size int64) error {
+	if err := c.ensureBucket(ctx, OwnersBucket); err != nil {
+		return err
+	}
+	_, err := c.minioClient.PutObject(ctx, OwnersBucket, indexKey(ownerUUID, bucketName, fileID), strings.NewReader(""), 0,
+		minio.PutObjectOptions{
+			UserMetadata: map[string]string{
+				"Size": strconv.FormatInt(size, 10),
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to add file to owners index. err: %w", err)
+	}
+	return nil
+}
+
+// walkOwnerIndex calls fn with every index entry of the owner and the file it stands for
+func (c *Client) walkOwnerIndex(ctx context.Context, ownerUUID string, fn func(key, bucketName, fileID string) error) error {
+	exists, err := c.minioClient.BucketExists(ctx, OwnersBucket)
+	if err != nil {
+		return fmt.Errorf("failed to check owners index. err: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	opts := minio.ListObjectsOptions{Prefix: ownerUUID + "/", Recursive: true}
+	for lobj := range c.minioClient.ListObjects(ctx, OwnersBucket, opts) {
+		if lobj.Err != nil {
+			return fmt.Errorf("failed to list owners index. err: %w", lobj.Err)
+		}
+		parts := strings.SplitN(strings.TrimPrefix(lobj.Key, ownerUUID+"/"), "/", 2)
+		if len(parts) != 2 {
+			c.logger.Warnf("skip malformed owners index entry %s", lobj.Key)
+			continue
+		}
+		if err = fn(lobj.Key, parts[0], parts[1]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (c *Client) ensureBucket(ctx context.Context, bucketName string) error {
+	exists, errBucketExists := c.minioClient.BucketExists(ctx, bucketName)
+	if errBucketExists != nil || !exists {
+		c.logger.Warnf("no bucket %s. creating new one...", bucketName)
+		err := c.minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create new bucket. err: %w", err)
+		}
+	}
+	return nil
+}
+
+func indexKey(ownerUUID, bucketName, fileID string) string {
+	return ownerUUID + "/" + bucketName + "/" + fileID
+}
+
+func isNotFound(err error) bool {
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NoSuchBucket"
+}
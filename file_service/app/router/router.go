@@ -0,0 +1,55 @@
+// Package router builds the routed handler of the file service. The service binary serves it on its
+// own listener, the all-in-one binary mounts it next to the other services in one process.
+package router
+
+import (
+	"context"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ohdaddyplease/notes/file_service/internal/config"
+	"github.com/ohdaddyplease/notes/file_service/internal/file"
+	"github.com/ohdaddyplease/notes/file_service/internal/file/storage/memory"
+	"github.com/ohdaddyplease/notes/file_service/internal/file/storage/minio"
+	"github.com/ohdaddyplease/notes/file_service/pkg/handlers/metric"
+	"github.com/ohdaddyplease/notes/file_service/pkg/logging"
+	"net/http"
+)
+
+// Config is the config of the service, Storage.Type chooses where files are kept
+type Config = config.Config
+
+// New builds the handler of every route of the service on the storage of cfg
+func New(ctx context.Context, cfg *Config, logger logging.Logger) (http.Handler, error) {
+	router := httprouter.New()
+
+	metricHandler := metric.Handler{Logger: logger}
+	metricHandler.Register(router)
+
+	fileStorage, err := newStorage(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	fileService, err := file.NewService(fileStorage, logger)
+	if err != nil {
+		return nil, err
+	}
+	filesHandler := file.Handler{
+		Logger:      logger,
+		FileService: fileService,
+	}
+	filesHandler.Register(router)
+
+	return router, nil
+}
+
+func newStorage(cfg *Config, logger logging.Logger) (file.Storage, error) {
+	switch cfg.Storage.Type {
+	case "minio":
+		return minio.NewStorage(cfg.MinIO.Endpoint, cfg.MinIO.AccessKey, cfg.MinIO.SecretKey, logger)
+	case "memory":
+		logger.Warn("files are kept in memory and will be lost on restart")
+		return memory.NewStorage(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Storage.Type)
+	}
+}
@@ -4,14 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/julienschmidt/httprouter"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/config"
-	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
-	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/db"
-	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/handlers/metric"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
-	mongo "gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/mongodb"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/shutdown"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/router"
 	"net"
 	"net/http"
 	"os"
@@ -27,31 +23,37 @@ func main() {
 
 	cfg := config.GetConfig()
 
-	router := httprouter.New()
-
-	metricHandler := metric.Handler{Logger: logger}
-	metricHandler.Register(router)
-
-	mongoClient, err := mongo.NewClient(context.Background(), cfg.MongoDB.Host, cfg.MongoDB.Port,
-		cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
-	if err != nil {
-		logger.Fatal(err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrateCommand(context.Background(), cfg, logger, os.Args[2:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
-	noteStorage := db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger)
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		if err := backfillCommand(context.Background(), cfg, logger); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
-	noteService, err := note.NewService(noteStorage, logger)
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 && os.Args[1] == "tag-owners" {
+		if err := tagOwnersCommand(context.Background(), cfg, logger); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
-	notesHandler := note.Handler{
-		Logger:      logger,
-		NoteService: noteService,
+	if len(os.Args) > 1 && os.Args[1] == "note-owners" {
+		if err := noteOwnersCommand(context.Background(), cfg, logger); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
+	handler, err := router.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Fatal(err)
 	}
-	notesHandler.Register(router)
 
-	start(router, logger, cfg)
+	start(handler, logger, cfg)
 }
 
 func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/router"
+)
+
+// backfillCommand handles `app backfill`: it recomputes derived fields of notes written before they existed
+func backfillCommand(ctx context.Context, cfg *config.Config, logger logging.Logger) error {
+	storages, err := router.NewStorages(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	noteService, err := note.NewService(storages.Notes, cfg.Lease.TTL, nil, logger)
+	if err != nil {
+		return err
+	}
+
+	updated, err := noteService.Backfill(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("derived fields recomputed for %d notes\n", updated)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/router"
+)
+
+// noteOwnersCommand handles `app note-owners`: it prints "note_uuid owner_uuid" for every note with
+// an owner. The file service reads them with `app index-owners` to count files uploaded before files
+// kept their owner.
+func noteOwnersCommand(ctx context.Context, cfg *config.Config, logger logging.Logger) error {
+	const pageSize = 100
+
+	storages, err := router.NewStorages(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	afterUUID := ""
+	for {
+		notes, err := storages.Notes.FindPage(ctx, afterUUID, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to get notes page. error: %w", err)
+		}
+		for _, n := range notes {
+			afterUUID = n.UUID
+			if n.OwnerUUID == "" {
+				continue
+			}
+			fmt.Printf("%s %s\n", n.UUID, n.OwnerUUID)
+		}
+		if len(notes) < pageSize {
+			break
+		}
+	}
+	return nil
+}
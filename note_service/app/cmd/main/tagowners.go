@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/router"
+	"sort"
+)
+
+// tagOwnersCommand handles `app tag-owners`: it prints "tag_id owner_uuid" for every tag referenced
+// by the notes of an owner. The tag service reads them with `app migrate owners` to give owners to
+// tags written before tags had one.
+func tagOwnersCommand(ctx context.Context, cfg *config.Config, logger logging.Logger) error {
+	const pageSize = 100
+
+	storages, err := router.NewStorages(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	type pair struct {
+		tagID     int
+		ownerUUID string
+	}
+	seen := make(map[pair]bool)
+	afterUUID := ""
+	for {
+		notes, err := storages.Notes.FindPage(ctx, afterUUID, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to get notes page. error: %w", err)
+		}
+		for _, n := range notes {
+			afterUUID = n.UUID
+			// notes older than owners tell nothing about them
+			if n.OwnerUUID == "" {
+				continue
+			}
+			for _, id := range n.Tags {
+				seen[pair{tagID: id, ownerUUID: n.OwnerUUID}] = true
+			}
+		}
+		if len(notes) < pageSize {
+			break
+		}
+	}
+
+	pairs := make([]pair, 0, len(seen))
+	for p := range seen {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].tagID != pairs[j].tagID {
+			return pairs[i].tagID < pairs[j].tagID
+		}
+		return pairs[i].ownerUUID < pairs[j].ownerUUID
+	})
+	for _, p := range pairs {
+		fmt.Printf("%d %s\n", p.tagID, p.ownerUUID)
+	}
+	return nil
+}
@@ -5,6 +5,11 @@ import (
 	"fmt"
 )
 
+const (
+	LeaseHeldCode = "NS-000004"
+	ForbiddenCode = "NS-000005"
+)
+
 var (
 	ErrNotFound = NewAppError("not found", "NS-000003", "")
 )
@@ -39,6 +44,14 @@ func (e *AppError) Marshal() []byte {
 	return bytes
 }
 
+func LeaseHeldError(message string) *AppError {
+	return NewAppError(message, LeaseHeldCode, "note is leased for editing by another session")
+}
+
+func ForbiddenError(message string) *AppError {
+	return NewAppError(message, ForbiddenCode, "the user is not allowed to do that")
+}
+
 func BadRequestError(message string) *AppError {
 	return NewAppError(message, "NS-000002", "some thing wrong with user data")
 }
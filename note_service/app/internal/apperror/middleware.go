@@ -19,6 +19,16 @@ func Middleware(h appHandler) http.HandlerFunc {
 					return
 				}
 				err := err.(*AppError)
+				if err.Code == LeaseHeldCode {
+					w.WriteHeader(http.StatusLocked)
+					w.Write(err.Marshal())
+					return
+				}
+				if err.Code == ForbiddenCode {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(err.Marshal())
+					return
+				}
 				w.WriteHeader(http.StatusBadRequest)
 				w.Write(err.Marshal())
 				return
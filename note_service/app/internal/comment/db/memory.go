@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+)
+
+var _ comment.Storage = &memoryDB{}
+
+// memoryDB keeps comments in process memory, uuids are object id hexes like in mongo
+type memoryDB struct {
+	mu       sync.RWMutex
+	comments map[string]comment.Comment
+	logger   logging.Logger
+}
+
+func NewMemoryStorage(logger logging.Logger) comment.Storage {
+	return &memoryDB{
+		comments: make(map[string]comment.Comment),
+		logger:   logger,
+	}
+}
+
+func (s *memoryDB) Create(ctx context.Context, c comment.Comment) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.UUID = primitive.NewObjectID().Hex()
+	c.Anchor = copyAnchor(c.Anchor)
+	s.comments[c.UUID] = c
+
+	return c.UUID, nil
+}
+
+func (s *memoryDB) FindOne(ctx context.Context, uuid string) (c comment.Comment, err error) {
+	if _, err = primitive.ObjectIDFromHex(uuid); err != nil {
+		return c, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.comments[uuid]
+	if !ok {
+		return c, apperror.ErrNotFound
+	}
+	c.Anchor = copyAnchor(c.Anchor)
+	return c, nil
+}
+
+func (s *memoryDB) FindByNoteUUID(ctx context.Context, noteUUID string) (comments []comment.Comment, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.comments {
+		if c.NoteUUID == noteUUID {
+			c.Anchor = copyAnchor(c.Anchor)
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].UUID < comments[j].UUID
+		}
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+	return comments, nil
+}
+
+func (s *memoryDB) Update(ctx context.Context, c comment.Comment) error {
+	if _, err := primitive.ObjectIDFromHex(c.UUID); err != nil {
+		return fmt.Errorf("failed to parse comment uuid due to error %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.comments[c.UUID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Body = c.Body
+	stored.Resolved = c.Resolved
+	stored.UpdatedAt = c.UpdatedAt
+	s.comments[c.UUID] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Delete(ctx context.Context, uuid string) error {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return fmt.Errorf("failed to parse comment uuid")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.comments[uuid]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(s.comments, uuid)
+	for id, c := range s.comments {
+		if c.ParentUUID == uuid {
+			delete(s.comments, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryDB) Anonymize(ctx context.Context, authorUUID, body string) (changed int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, c := range s.comments {
+		if c.AuthorUUID == authorUUID {
+			c.AuthorUUID = ""
+			c.Body = body
+			s.comments[id] = c
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func copyAnchor(anchor *comment.Anchor) *comment.Anchor {
+	if anchor == nil {
+		return nil
+	}
+	a := *anchor
+	return &a
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS comments (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_uuid    TEXT        NOT NULL,
+    parent_id    UUID REFERENCES comments (id) ON DELETE CASCADE,
+    author_uuid  TEXT        NOT NULL,
+    body         TEXT        NOT NULL,
+    anchor_start INTEGER,
+    anchor_end   INTEGER,
+    resolved     BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS comments_note_uuid_idx ON comments (note_uuid, created_at);
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var _ comment.Storage = &db{}
+
+type db struct {
+	collection *mongo.Collection
+	logger     logging.Logger
+}
+
+func NewStorage(storage *mongo.Database, collection string, logger logging.Logger) comment.Storage {
+	return &db{
+		collection: storage.Collection(collection),
+		logger:     logger,
+	}
+}
+
+func (s *db) Create(ctx context.Context, c comment.Comment) (uuid string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.InsertOne(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if ok {
+		return oid.Hex(), nil
+	}
+	return "", fmt.Errorf("failed to convet objectid to hex")
+}
+
+func (s *db) FindOne(ctx context.Context, uuid string) (c comment.Comment, err error) {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return c, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err = s.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&c); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c, apperror.ErrNotFound
+		}
+		return c, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *db) FindByNoteUUID(ctx context.Context, noteUUID string) (comments []comment.Comment, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := s.collection.Find(ctx, bson.M{"note_uuid": noteUUID}, opts)
+	if err != nil {
+		return comments, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &comments); err != nil {
+		return comments, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return comments, nil
+}
+
+func (s *db) Update(ctx context.Context, c comment.Comment) error {
+	objectID, err := primitive.ObjectIDFromHex(c.UUID)
+	if err != nil {
+		return fmt.Errorf("failed to parse comment uuid due to error %w", err)
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"body":       c.Body,
+			"resolved":   c.Resolved,
+			"updated_at": c.UpdatedAt,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *db) Delete(ctx context.Context, uuid string) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to parse comment uuid")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	replies, err := s.collection.DeleteMany(ctx, bson.M{"parent_uuid": uuid})
+	if err != nil {
+		return fmt.Errorf("failed to delete replies. error: %w", err)
+	}
+	s.logger.Tracef("Deleted comment %s with %v replies.\n", uuid, replies.DeletedCount)
+
+	return nil
+}
+
+func (s *db) Anonymize(ctx context.Context, authorUUID, body string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateMany(ctx, bson.M{"author_uuid": authorUUID},
+		bson.M{"$set": bson.M{"author_uuid": "", "body": body}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.ModifiedCount), nil
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/mongodb/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations returns the schema history of the comments collection. It shares
+// schema_migrations with the notes collection, so versions continue the notes ones.
+func MongoMigrations(collection string) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     2,
+			Description: "index comments by note_uuid",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "note_uuid", Value: 1}, {Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("note_uuid_created_at"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "note_uuid_created_at")
+				return err
+			},
+		},
+	}
+}
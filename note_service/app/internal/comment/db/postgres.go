@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/postgresql"
+	"io/fs"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+// invalid_text_representation, returned when uuid is not a valid UUID
+const pgInvalidTextRepresentation = "22P02"
+
+const selectComment = `SELECT id::text, note_uuid, COALESCE(parent_id::text, ''), author_uuid, body,
+	anchor_start, anchor_end, resolved, created_at, updated_at FROM comments`
+
+var _ comment.Storage = &pgDB{}
+
+type pgDB struct {
+	pool   *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewPostgresStorage(pool *pgxpool.Pool, logger logging.Logger) comment.Storage {
+	return &pgDB{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// MigratePostgres brings the comments schema up to date. Its file names are prefixed
+// with comments_ because schema_migrations is shared with the notes schema.
+func MigratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := fs.Sub(postgresMigrations, "migrations")
+	if err != nil {
+		return err
+	}
+	return postgresql.Migrate(ctx, pool, migrations)
+}
+
+func (s *pgDB) Create(ctx context.Context, c comment.Comment) (uuid string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var parentID, anchorStart, anchorEnd interface{}
+	if c.ParentUUID != "" {
+		parentID = c.ParentUUID
+	}
+	if c.Anchor != nil {
+		anchorStart, anchorEnd = c.Anchor.Start, c.Anchor.End
+	}
+
+	q := `INSERT INTO comments (note_uuid, parent_id, author_uuid, body, anchor_start, anchor_end, resolved, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id::text`
+	err = s.pool.QueryRow(ctx, q, c.NoteUUID, parentID, c.AuthorUUID, c.Body, anchorStart, anchorEnd,
+		c.Resolved, c.CreatedAt, c.UpdatedAt).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (s *pgDB) FindOne(ctx context.Context, uuid string) (c comment.Comment, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	c, err = scanComment(s.pool.QueryRow(ctx, selectComment+` WHERE id = $1`, uuid))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c, apperror.ErrNotFound
+		}
+		if isInvalidUUID(err) {
+			return c, fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return c, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return c, nil
+}
+
+func (s *pgDB) FindByNoteUUID(ctx context.Context, noteUUID string) (comments []comment.Comment, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, selectComment+` WHERE note_uuid = $1 ORDER BY created_at, id`, noteUUID)
+	if err != nil {
+		return comments, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return comments, fmt.Errorf("failed to decode row. error: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err = rows.Err(); err != nil {
+		return comments, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return comments, nil
+}
+
+func (s *pgDB) Update(ctx context.Context, c comment.Comment) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	q := `UPDATE comments SET body = $2, resolved = $3, updated_at = $4 WHERE id = $1`
+	result, err := s.pool.Exec(ctx, q, c.UUID, c.Body, c.Resolved, c.UpdatedAt)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return fmt.Errorf("failed to parse comment uuid due to error %w", err)
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *pgDB) Delete(ctx context.Context, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// replies go away with ON DELETE CASCADE
+	result, err := s.pool.Exec(ctx, `DELETE FROM comments WHERE id = $1`, uuid)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return fmt.Errorf("failed to parse comment uuid")
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *pgDB) Anonymize(ctx context.Context, authorUUID, body string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result, err := s.pool.Exec(ctx, `UPDATE comments SET author_uuid = '', body = $2 WHERE author_uuid = $1`, authorUUID, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func scanComment(row pgx.Row) (c comment.Comment, err error) {
+	var anchorStart, anchorEnd *int
+	err = row.Scan(&c.UUID, &c.NoteUUID, &c.ParentUUID, &c.AuthorUUID, &c.Body,
+		&anchorStart, &anchorEnd, &c.Resolved, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return c, err
+	}
+	if anchorStart != nil && anchorEnd != nil {
+		c.Anchor = &comment.Anchor{Start: *anchorStart, End: *anchorEnd}
+	}
+	return c, nil
+}
+
+func isInvalidUUID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgInvalidTextRepresentation
+}
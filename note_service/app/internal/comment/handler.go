@@ -0,0 +1,113 @@
+package comment
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"net/http"
+)
+
+const (
+	commentsURL = "/api/notes/:uuid/comments"
+	commentURL  = "/api/notes/:uuid/comments/:comment_uuid"
+)
+
+// header the gateway uses to pass the authenticated user
+const userUUIDHeader = "X-User-UUID"
+
+type Handler struct {
+	Logger         logging.Logger
+	CommentService Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, commentsURL, apperror.Middleware(h.GetComments))
+	router.HandlerFunc(http.MethodPost, commentsURL, apperror.Middleware(h.CreateComment))
+	router.HandlerFunc(http.MethodPatch, commentURL, apperror.Middleware(h.PartiallyUpdateComment))
+	router.HandlerFunc(http.MethodDelete, commentURL, apperror.Middleware(h.DeleteComment))
+}
+
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	threads, err := h.CommentService.GetThreads(r.Context(), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	threadsBytes, err := json.Marshal(threads)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(threadsBytes)
+
+	return nil
+}
+
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	noteUUID := params.ByName("uuid")
+
+	var dto CreateCommentDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid data")
+	}
+	dto.NoteUUID = noteUUID
+	dto.AuthorUUID = r.Header.Get(userUUIDHeader)
+
+	commentUUID, err := h.CommentService.Create(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/notes/%s/comments/%s", noteUUID, commentUUID))
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) PartiallyUpdateComment(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto UpdateCommentDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid data")
+	}
+	dto.UUID = params.ByName("comment_uuid")
+	dto.NoteUUID = params.ByName("uuid")
+	dto.EditorUUID = r.Header.Get(userUUIDHeader)
+
+	if err := h.CommentService.Update(r.Context(), dto); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	dto := DeleteCommentDTO{
+		UUID:       params.ByName("comment_uuid"),
+		NoteUUID:   params.ByName("uuid"),
+		EditorUUID: r.Header.Get(userUUIDHeader),
+	}
+
+	if err := h.CommentService.Delete(r.Context(), dto); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
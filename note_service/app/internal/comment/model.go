@@ -0,0 +1,64 @@
+package comment
+
+import "time"
+
+// DeletedBody replaces the text of comments left by a deleted account
+const DeletedBody = "[deleted]"
+
+// Anchor is a character range [Start, End) of the note body, counted in runes
+type Anchor struct {
+	Start int `json:"start" bson:"start"`
+	End   int `json:"end" bson:"end"`
+}
+
+type Comment struct {
+	UUID       string    `json:"uuid" bson:"_id,omitempty"`
+	NoteUUID   string    `json:"note_uuid" bson:"note_uuid"`
+	ParentUUID string    `json:"parent_uuid,omitempty" bson:"parent_uuid,omitempty"`
+	AuthorUUID string    `json:"author_uuid" bson:"author_uuid"`
+	Body       string    `json:"body" bson:"body"`
+	Anchor     *Anchor   `json:"anchor,omitempty" bson:"anchor,omitempty"`
+	Resolved   bool      `json:"resolved" bson:"resolved"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Thread is a top level comment together with its replies, oldest first
+type Thread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+type CreateCommentDTO struct {
+	NoteUUID   string  `json:"-"`
+	AuthorUUID string  `json:"-"`
+	ParentUUID string  `json:"parent_uuid,omitempty"`
+	Body       string  `json:"body"`
+	Anchor     *Anchor `json:"anchor,omitempty"`
+}
+
+type UpdateCommentDTO struct {
+	UUID       string `json:"-"`
+	NoteUUID   string `json:"-"`
+	EditorUUID string `json:"-"`
+	Body       string `json:"body,omitempty"`
+	Resolved   *bool  `json:"resolved,omitempty"`
+}
+
+type DeleteCommentDTO struct {
+	UUID       string
+	NoteUUID   string
+	EditorUUID string
+}
+
+func NewComment(dto CreateCommentDTO, now time.Time) Comment {
+	return Comment{
+		NoteUUID:   dto.NoteUUID,
+		ParentUUID: dto.ParentUUID,
+		AuthorUUID: dto.AuthorUUID,
+		Body:       dto.Body,
+		Anchor:     dto.Anchor,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
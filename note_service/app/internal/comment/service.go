@@ -0,0 +1,177 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"time"
+)
+
+var _ Service = &service{}
+
+// NoteFinder is the part of note.Service comments need: anchors are checked against the note body
+type NoteFinder interface {
+	GetOne(ctx context.Context, uuid string) (note.Note, error)
+}
+
+type service struct {
+	storage Storage
+	notes   NoteFinder
+	logger  logging.Logger
+}
+
+func NewService(commentStorage Storage, notes NoteFinder, logger logging.Logger) (Service, error) {
+	return &service{
+		storage: commentStorage,
+		notes:   notes,
+		logger:  logger,
+	}, nil
+}
+
+type Service interface {
+	Create(ctx context.Context, dto CreateCommentDTO) (string, error)
+	GetThreads(ctx context.Context, noteUUID string) ([]Thread, error)
+	Update(ctx context.Context, dto UpdateCommentDTO) error
+	Delete(ctx context.Context, dto DeleteCommentDTO) error
+}
+
+func (s service) Create(ctx context.Context, dto CreateCommentDTO) (commentUUID string, err error) {
+	if dto.AuthorUUID == "" {
+		return "", apperror.BadRequestError("comment author is unknown")
+	}
+	if dto.Body == "" {
+		return "", apperror.BadRequestError("comment body is required")
+	}
+
+	n, err := s.notes.GetOne(ctx, dto.NoteUUID)
+	if err != nil {
+		return "", err
+	}
+
+	if dto.ParentUUID != "" {
+		if dto.Anchor != nil {
+			return "", apperror.BadRequestError("replies share the anchor of their thread")
+		}
+		parent, err := s.getOne(ctx, dto.NoteUUID, dto.ParentUUID)
+		if err != nil {
+			return "", err
+		}
+		// threads are one level deep, a reply to a reply joins the same thread
+		if parent.ParentUUID != "" {
+			dto.ParentUUID = parent.ParentUUID
+		}
+	} else if dto.Anchor != nil {
+		bodyLength := len([]rune(n.Body))
+		if dto.Anchor.Start < 0 || dto.Anchor.Start >= dto.Anchor.End || dto.Anchor.End > bodyLength {
+			return "", apperror.BadRequestError(fmt.Sprintf("anchor must be a non empty range within [0, %d]", bodyLength))
+		}
+	}
+
+	commentUUID, err = s.storage.Create(ctx, NewComment(dto, time.Now().UTC()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create comment. error: %w", err)
+	}
+
+	return commentUUID, nil
+}
+
+func (s service) GetThreads(ctx context.Context, noteUUID string) ([]Thread, error) {
+	if _, err := s.notes.GetOne(ctx, noteUUID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.storage.FindByNoteUUID(ctx, noteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments of note. error: %w", err)
+	}
+
+	threads := make([]Thread, 0)
+	index := make(map[string]int)
+	for _, c := range comments {
+		if c.ParentUUID == "" {
+			index[c.UUID] = len(threads)
+			threads = append(threads, Thread{Comment: c, Replies: []Comment{}})
+		}
+	}
+	for _, c := range comments {
+		if c.ParentUUID == "" {
+			continue
+		}
+		if i, ok := index[c.ParentUUID]; ok {
+			threads[i].Replies = append(threads[i].Replies, c)
+		}
+	}
+
+	return threads, nil
+}
+
+func (s service) Update(ctx context.Context, dto UpdateCommentDTO) error {
+	if dto.Body == "" && dto.Resolved == nil {
+		return apperror.BadRequestError("nothing to update")
+	}
+	if dto.EditorUUID == "" {
+		return apperror.BadRequestError("comment editor is unknown")
+	}
+
+	c, err := s.getOne(ctx, dto.NoteUUID, dto.UUID)
+	if err != nil {
+		return err
+	}
+	if dto.Body != "" {
+		if c.AuthorUUID != dto.EditorUUID {
+			return apperror.ForbiddenError("only the author can edit a comment")
+		}
+		c.Body = dto.Body
+	}
+	if dto.Resolved != nil {
+		if c.ParentUUID != "" {
+			return apperror.BadRequestError("only a thread can be resolved, not a reply")
+		}
+		c.Resolved = *dto.Resolved
+	}
+	c.UpdatedAt = time.Now().UTC()
+
+	if err = s.storage.Update(ctx, c); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to update comment. error: %w", err)
+	}
+	return nil
+}
+
+func (s service) Delete(ctx context.Context, dto DeleteCommentDTO) error {
+	c, err := s.getOne(ctx, dto.NoteUUID, dto.UUID)
+	if err != nil {
+		return err
+	}
+	if c.AuthorUUID != dto.EditorUUID {
+		return apperror.ForbiddenError("only the author can delete a comment")
+	}
+
+	if err = s.storage.Delete(ctx, dto.UUID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete comment. error: %w", err)
+	}
+	return nil
+}
+
+// getOne finds a comment and makes sure it belongs to the note from the url
+func (s service) getOne(ctx context.Context, noteUUID, uuid string) (c Comment, err error) {
+	c, err = s.storage.FindOne(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return c, err
+		}
+		return c, fmt.Errorf("failed to find comment by uuid. error: %w", err)
+	}
+	if c.NoteUUID != noteUUID {
+		return c, apperror.ErrNotFound
+	}
+	return c, nil
+}
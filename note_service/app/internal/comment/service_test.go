@@ -0,0 +1,123 @@
+package comment_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	commentdb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	notedb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+)
+
+func newService(t *testing.T) (comment.Service, string) {
+	t.Helper()
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+	notes, err := note.NewService(notedb.NewMemoryStorage(logger), time.Minute, nil, logger)
+	if err != nil {
+		t.Fatalf("note service: %v", err)
+	}
+	noteUUID, err := notes.Create(context.Background(), note.CreateNoteDTO{Header: "h", Body: "привет мир", CategoryUUID: "c"})
+	if err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+
+	comments, err := comment.NewService(commentdb.NewMemoryStorage(logger), notes, logger)
+	if err != nil {
+		t.Fatalf("comment service: %v", err)
+	}
+	return comments, noteUUID
+}
+
+func TestThreads(t *testing.T) {
+	ctx := context.Background()
+	service, noteUUID := newService(t)
+
+	root, err := service.Create(ctx, comment.CreateCommentDTO{NoteUUID: noteUUID, AuthorUUID: "alice", Body: "why?",
+		Anchor: &comment.Anchor{Start: 0, End: 10}})
+	if err != nil {
+		t.Fatalf("create root: %v", err)
+	}
+	reply, err := service.Create(ctx, comment.CreateCommentDTO{NoteUUID: noteUUID, AuthorUUID: "bob", Body: "because", ParentUUID: root})
+	if err != nil {
+		t.Fatalf("create reply: %v", err)
+	}
+	_, err = service.Create(ctx, comment.CreateCommentDTO{NoteUUID: noteUUID, AuthorUUID: "alice", Body: "ok", ParentUUID: reply})
+	if err != nil {
+		t.Fatalf("create reply to reply: %v", err)
+	}
+
+	threads, err := service.GetThreads(ctx, noteUUID)
+	if err != nil {
+		t.Fatalf("get threads: %v", err)
+	}
+	if len(threads) != 1 || threads[0].UUID != root || len(threads[0].Replies) != 2 {
+		t.Fatalf("want one thread with two replies, got %+v", threads)
+	}
+
+	if err = service.Delete(ctx, comment.DeleteCommentDTO{UUID: root, NoteUUID: noteUUID, EditorUUID: "alice"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	threads, err = service.GetThreads(ctx, noteUUID)
+	if err != nil {
+		t.Fatalf("get threads: %v", err)
+	}
+	if len(threads) != 0 {
+		t.Fatalf("replies survived deletion of their thread: %+v", threads)
+	}
+}
+
+func TestAnchorIsCountedInRunes(t *testing.T) {
+	ctx := context.Background()
+	service, noteUUID := newService(t)
+
+	_, err := service.Create(ctx, comment.CreateCommentDTO{NoteUUID: noteUUID, AuthorUUID: "alice", Body: "b",
+		Anchor: &comment.Anchor{Start: 0, End: 11}})
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("anchor past the end of the body returned %v, want a bad request", err)
+	}
+}
+
+func TestOnlyAuthorEditsAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	service, noteUUID := newService(t)
+
+	root, err := service.Create(ctx, comment.CreateCommentDTO{NoteUUID: noteUUID, AuthorUUID: "alice", Body: "mine"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	err = service.Update(ctx, comment.UpdateCommentDTO{UUID: root, NoteUUID: noteUUID, EditorUUID: "bob", Body: "theirs"})
+	if !isForbidden(err) {
+		t.Fatalf("edit by another user returned %v, want forbidden", err)
+	}
+	err = service.Delete(ctx, comment.DeleteCommentDTO{UUID: root, NoteUUID: noteUUID, EditorUUID: "bob"})
+	if !isForbidden(err) {
+		t.Fatalf("delete by another user returned %v, want forbidden", err)
+	}
+
+	resolved := true
+	err = service.Update(ctx, comment.UpdateCommentDTO{UUID: root, NoteUUID: noteUUID, EditorUUID: "bob", Resolved: &resolved})
+	if err != nil {
+		t.Fatalf("resolve by a participant: %v", err)
+	}
+	threads, err := service.GetThreads(ctx, noteUUID)
+	if err != nil {
+		t.Fatalf("get threads: %v", err)
+	}
+	if !threads[0].Resolved || threads[0].Body != "mine" {
+		t.Fatalf("unexpected thread after resolve: %+v", threads[0])
+	}
+}
+
+func isForbidden(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperror.ForbiddenCode
+}
@@ -0,0 +1,17 @@
+package comment
+
+import "context"
+
+type Storage interface {
+	Create(ctx context.Context, comment Comment) (string, error)
+	FindOne(ctx context.Context, uuid string) (Comment, error)
+	// FindByNoteUUID returns every comment of the note ordered by creation time
+	FindByNoteUUID(ctx context.Context, noteUUID string) ([]Comment, error)
+	// Update rewrites body, resolved state and update time of the comment
+	Update(ctx context.Context, comment Comment) error
+	// Delete removes the comment together with its replies
+	Delete(ctx context.Context, uuid string) error
+	// Anonymize clears the author of every comment the author left and puts body in place of their
+	// text, it returns how many comments were changed
+	Anonymize(ctx context.Context, authorUUID, body string) (int, error)
+}
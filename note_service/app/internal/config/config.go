@@ -4,6 +4,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
 	"sync"
+	"time"
 )
 
 type Config struct {
@@ -13,15 +14,31 @@ type Config struct {
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
 		Port   string `yaml:"port" env-default:"8080"`
 	}
+	Lease struct {
+		TTL time.Duration `yaml:"ttl" env-default:"2m"`
+	} `yaml:"lease"`
+	Storage struct {
+		Type string `yaml:"type" env-default:"mongodb"`
+	} `yaml:"storage"`
 	MongoDB struct {
-		Host       string `yaml:"host" env-required:"true"`
-		Port       string `yaml:"port" env-required:"true"`
+		Host       string `yaml:"host"`
+		Port       string `yaml:"port"`
 		Username   string `yaml:"username"`
 		Password   string `yaml:"password"`
-		AuthDB     string `yaml:"auth_db" env-required:"true"`
-		Database   string `yaml:"database" env-required:"true"`
-		Collection string `yaml:"collection" env-required:"true"`
-	} `yaml:"mongodb" env-required:"true"`
+		AuthDB     string `yaml:"auth_db"`
+		Database   string `yaml:"database"`
+		Collection string `yaml:"collection"`
+
+		CommentsCollection string `yaml:"comments_collection" env-default:"comments"`
+		RulesCollection    string `yaml:"rules_collection" env-default:"rules"`
+	} `yaml:"mongodb"`
+	PostgreSQL struct {
+		Host     string `yaml:"host"`
+		Port     string `yaml:"port" env-default:"5432"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		Database string `yaml:"database"`
+	} `yaml:"postgresql"`
 }
 
 var instance *Config
@@ -0,0 +1,38 @@
+package note
+
+import "context"
+
+// AutoTagger picks the tags a note gets from the tagging rules of its owner
+type AutoTagger interface {
+	AutoTags(ctx context.Context, n Note) ([]int, error)
+	// ReplaceTags makes the rules tagging with a merged tag tag with its target
+	ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error)
+}
+
+// AddTags appends the tags missing from tags, keeping the order of both
+func AddTags(tags []int, extra []int) []int {
+	present := make(map[int]bool, len(tags)+len(extra))
+	merged := make([]int, 0, len(tags)+len(extra))
+	for _, id := range append(append([]int{}, tags...), extra...) {
+		if !present[id] {
+			present[id] = true
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+// autoTag adds the tags of the matching rules to the tags of n
+func (s service) autoTag(ctx context.Context, n Note) ([]int, error) {
+	if s.autoTagger == nil {
+		return n.Tags, nil
+	}
+	tags, err := s.autoTagger.AutoTags(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return n.Tags, nil
+	}
+	return AddTags(n.Tags, tags), nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// postgresDSN returns NOTES_TEST_POSTGRES_DSN when it is set, otherwise it starts a throwaway
+// server in a container that is removed when the test ends. Without docker the test is skipped.
+func postgresDSN(t *testing.T) string {
+	t.Helper()
+	if dsn := os.Getenv("NOTES_TEST_POSTGRES_DSN"); dsn != "" {
+		return dsn
+	}
+
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	defer failOnPanic(t, "postgres", "NOTES_TEST_POSTGRES_DSN")
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, "postgres:14-alpine",
+		postgres.WithDatabase("notes"),
+		postgres.WithUsername("notes"),
+		postgres.WithPassword("notes"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("start postgres container: %v", err)
+	}
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("postgres connection string: %v", err)
+	}
+	return dsn
+}
+
+// mongoURI returns NOTES_TEST_MONGODB_URI when it is set, otherwise it starts a throwaway
+// server in a container that is removed when the test ends. Without docker the test is skipped.
+func mongoURI(t *testing.T) string {
+	t.Helper()
+	if uri := os.Getenv("NOTES_TEST_MONGODB_URI"); uri != "" {
+		return uri
+	}
+
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	defer failOnPanic(t, "mongodb", "NOTES_TEST_MONGODB_URI")
+	ctx := context.Background()
+	container, err := mongodb.Run(ctx, "mongo:5.0")
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("mongodb connection string: %v", err)
+	}
+	return uri
+}
+
+// failOnPanic turns a panic of testcontainers into a test failure, docker is known to be reachable
+// by then
+func failOnPanic(t *testing.T, server, env string) {
+	if r := recover(); r != nil {
+		t.Fatalf("start %s container, set %s to use a running server instead: %v", server, env, r)
+	}
+}
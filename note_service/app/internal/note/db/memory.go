@@ -0,0 +1,373 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ note.Storage = &memoryDB{}
+
+// memoryDB keeps notes in process memory. It mimics the mongo storage: uuids are
+// object id hexes and the same fields are left out of FindOne and FindByCategoryUUID results.
+type memoryDB struct {
+	mu     sync.RWMutex
+	notes  map[string]note.Note
+	logger logging.Logger
+}
+
+func NewMemoryStorage(logger logging.Logger) note.Storage {
+	return &memoryDB{
+		notes:  make(map[string]note.Note),
+		logger: logger,
+	}
+}
+
+func (s *memoryDB) Create(ctx context.Context, n note.Note) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n.UUID = primitive.NewObjectID().Hex()
+	n.Tags = copyTags(n.Tags)
+	n.Links = copyLinks(n.Links)
+	n.Lease = copyLease(n.Lease)
+	s.notes[n.UUID] = n
+
+	return n.UUID, nil
+}
+
+func (s *memoryDB) FindOne(ctx context.Context, uuid string) (n note.Note, err error) {
+	if _, err = primitive.ObjectIDFromHex(uuid); err != nil {
+		return n, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, ok := s.notes[uuid]
+	if !ok {
+		return n, apperror.ErrNotFound
+	}
+	n.ShortBody = ""
+	n.Tags = copyTags(n.Tags)
+	n.Links = copyLinks(n.Links)
+	n.Lease = copyLease(n.Lease)
+
+	return n, nil
+}
+
+func (s *memoryDB) FindByCategoryUUID(ctx context.Context, categoryUUID string) (notes []note.Note, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, n := range s.notes {
+		if n.CategoryUUID != categoryUUID {
+			continue
+		}
+		n.Body = ""
+		n.Tags = copyTags(n.Tags)
+		n.Links = copyLinks(n.Links)
+		n.Lease = copyLease(n.Lease)
+		notes = append(notes, n)
+	}
+
+	return notes, nil
+}
+
+func (s *memoryDB) Update(ctx context.Context, n note.Note, holderUUID, sessionID string, now time.Time) (note.Lease, bool, error) {
+	if _, err := primitive.ObjectIDFromHex(n.UUID); err != nil {
+		return note.Lease{}, false, fmt.Errorf("failed to parse note uuid due to error %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[n.UUID]
+	if !ok {
+		return note.Lease{}, false, apperror.ErrNotFound
+	}
+	if stored.Lease.IsActive(now) && !stored.Lease.IsHeldBy(holderUUID, sessionID) {
+		return *stored.Lease, false, nil
+	}
+	if n.Header != "" {
+		stored.Header = n.Header
+	}
+	if n.Body != "" {
+		stored.Body = n.Body
+	}
+	if n.ShortBody != "" {
+		stored.ShortBody = n.ShortBody
+	}
+	if n.CategoryUUID != "" {
+		stored.CategoryUUID = n.CategoryUUID
+	}
+	if n.Tags != nil {
+		stored.Tags = copyTags(n.Tags)
+	}
+	if !n.UpdatedAt.IsZero() {
+		stored.UpdatedAt = n.UpdatedAt
+	}
+	if n.Body != "" {
+		stored.WordCount = n.WordCount
+		stored.ReadingTime = n.ReadingTime
+		stored.Links = copyLinks(n.Links)
+		stored.Language = n.Language
+	}
+	s.notes[n.UUID] = stored
+
+	return note.Lease{}, true, nil
+}
+
+func (s *memoryDB) UpdateDerived(ctx context.Context, n note.Note) (bool, error) {
+	if _, err := primitive.ObjectIDFromHex(n.UUID); err != nil {
+		return false, fmt.Errorf("failed to parse note uuid due to error %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[n.UUID]
+	if !ok || stored.Body != n.Body {
+		return false, nil
+	}
+	stored.ShortBody = n.ShortBody
+	stored.WordCount = n.WordCount
+	stored.ReadingTime = n.ReadingTime
+	stored.Links = copyLinks(n.Links)
+	stored.Language = n.Language
+	s.notes[n.UUID] = stored
+
+	return true, nil
+}
+
+func (s *memoryDB) Delete(ctx context.Context, uuid string) error {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return fmt.Errorf("failed to parse note uuid")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notes[uuid]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(s.notes, uuid)
+
+	return nil
+}
+
+func copyLease(lease *note.Lease) *note.Lease {
+	if lease == nil {
+		return nil
+	}
+	l := *lease
+	return &l
+}
+
+func copyLinks(links []string) []string {
+	if links == nil {
+		return nil
+	}
+	return append([]string{}, links...)
+}
+
+func copyTags(tags []int) []int {
+	if tags == nil {
+		return nil
+	}
+	return append([]int{}, tags...)
+}
+
+func (s *memoryDB) FindByOwner(ctx context.Context, ownerUUID string) (notes []note.Note, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, n := range s.notes {
+		if n.OwnerUUID == ownerUUID {
+			n.Tags = copyTags(n.Tags)
+			n.Links = copyLinks(n.Links)
+			n.Lease = copyLease(n.Lease)
+			notes = append(notes, n)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].UUID < notes[j].UUID })
+	return notes, nil
+}
+
+func (s *memoryDB) UsageByOwner(ctx context.Context, ownerUUID string) (usage note.Usage, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, n := range s.notes {
+		if n.OwnerUUID == ownerUUID {
+			usage.Notes++
+			usage.BodyBytes += int64(len(n.Body))
+		}
+	}
+	return usage, nil
+}
+
+func (s *memoryDB) TagUsage(ctx context.Context, ownerUUID string) (usage []note.TagUsage, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byTag := make(map[int]*note.TagUsage)
+	for _, n := range s.notes {
+		if n.OwnerUUID != ownerUUID {
+			continue
+		}
+		counted := make(map[int]bool, len(n.Tags))
+		for _, id := range n.Tags {
+			if counted[id] {
+				continue
+			}
+			counted[id] = true
+			u, ok := byTag[id]
+			if !ok {
+				u = &note.TagUsage{TagID: id}
+				byTag[id] = u
+			}
+			u.Notes++
+			if !n.UpdatedAt.IsZero() && (u.LastUsedAt == nil || n.UpdatedAt.After(*u.LastUsedAt)) {
+				updatedAt := n.UpdatedAt
+				u.LastUsedAt = &updatedAt
+			}
+		}
+	}
+
+	for _, u := range byTag {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].TagID < usage[j].TagID })
+	return usage, nil
+}
+
+func (s *memoryDB) AddTag(ctx context.Context, uuid string, tagID int, now time.Time) (bool, error) {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return false, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[uuid]
+	if !ok || stored.Lease.IsActive(now) {
+		return false, nil
+	}
+	for _, id := range stored.Tags {
+		if id == tagID {
+			return false, nil
+		}
+	}
+	stored.Tags = append(copyTags(stored.Tags), tagID)
+	s.notes[uuid] = stored
+
+	return true, nil
+}
+
+func (s *memoryDB) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (changed int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sources := make(map[int]bool, len(sourceIDs))
+	for _, id := range sourceIDs {
+		sources[id] = true
+	}
+	for uuid, n := range s.notes {
+		referenced := false
+		for _, id := range n.Tags {
+			referenced = referenced || sources[id]
+		}
+		if !referenced {
+			continue
+		}
+
+		seen := make(map[int]bool, len(n.Tags))
+		tags := make([]int, 0, len(n.Tags))
+		for _, id := range n.Tags {
+			if sources[id] {
+				id = targetID
+			}
+			if !seen[id] {
+				seen[id] = true
+				tags = append(tags, id)
+			}
+		}
+		n.Tags = tags
+		s.notes[uuid] = n
+		changed++
+	}
+	return changed, nil
+}
+
+func (s *memoryDB) FindPage(ctx context.Context, afterUUID string, limit int) (notes []note.Note, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uuids := make([]string, 0, len(s.notes))
+	for uuid := range s.notes {
+		if uuid > afterUUID {
+			uuids = append(uuids, uuid)
+		}
+	}
+	sort.Strings(uuids)
+	if len(uuids) > limit {
+		uuids = uuids[:limit]
+	}
+
+	for _, uuid := range uuids {
+		n := s.notes[uuid]
+		n.Tags = copyTags(n.Tags)
+		n.Links = copyLinks(n.Links)
+		n.Lease = copyLease(n.Lease)
+		notes = append(notes, n)
+	}
+	return notes, nil
+}
+
+func (s *memoryDB) AcquireLease(ctx context.Context, uuid string, lease note.Lease, now time.Time) (note.Lease, bool, error) {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return note.Lease{}, false, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[uuid]
+	if !ok {
+		return note.Lease{}, false, apperror.ErrNotFound
+	}
+	if stored.Lease.IsActive(now) && !stored.Lease.IsHeldBy(lease.HolderUUID, lease.SessionID) {
+		return *stored.Lease, false, nil
+	}
+	stored.Lease = &lease
+	s.notes[uuid] = stored
+
+	return lease, true, nil
+}
+
+func (s *memoryDB) ReleaseLease(ctx context.Context, uuid, holderUUID, sessionID string) error {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	if stored.Lease.IsHeldBy(holderUUID, sessionID) {
+		stored.Lease = nil
+		s.notes[uuid] = stored
+	}
+
+	return nil
+}
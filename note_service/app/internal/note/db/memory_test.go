@@ -0,0 +1,16 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/storagetest"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) note.Storage {
+		return NewMemoryStorage(logging.Logger{Entry: logrus.NewEntry(logrus.New())})
+	})
+}
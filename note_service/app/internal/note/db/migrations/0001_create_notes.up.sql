@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS notes (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    header        TEXT      NOT NULL DEFAULT '',
+    body          TEXT      NOT NULL DEFAULT '',
+    short_body    TEXT      NOT NULL DEFAULT '',
+    category_uuid TEXT      NOT NULL DEFAULT '',
+    tags          INTEGER[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS notes_category_uuid_idx ON notes (category_uuid);
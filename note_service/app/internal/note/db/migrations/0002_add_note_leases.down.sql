@@ -0,0 +1,4 @@
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS lease_holder_uuid,
+    DROP COLUMN IF EXISTS lease_session_id,
+    DROP COLUMN IF EXISTS lease_expires_at;
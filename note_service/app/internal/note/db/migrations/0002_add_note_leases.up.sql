@@ -0,0 +1,4 @@
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS lease_holder_uuid TEXT,
+    ADD COLUMN IF NOT EXISTS lease_session_id  TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at  TIMESTAMPTZ;
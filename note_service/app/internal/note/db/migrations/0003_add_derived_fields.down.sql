@@ -0,0 +1,5 @@
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS word_count,
+    DROP COLUMN IF EXISTS reading_time,
+    DROP COLUMN IF EXISTS links,
+    DROP COLUMN IF EXISTS language;
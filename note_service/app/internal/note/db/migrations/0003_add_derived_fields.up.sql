@@ -0,0 +1,5 @@
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS word_count   INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS reading_time INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS links        TEXT[]  NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS language     TEXT    NOT NULL DEFAULT '';
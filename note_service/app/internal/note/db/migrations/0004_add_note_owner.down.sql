@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notes_owner_uuid_idx;
+
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS owner_uuid;
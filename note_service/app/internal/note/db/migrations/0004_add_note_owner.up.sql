@@ -0,0 +1,4 @@
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS owner_uuid TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS notes_owner_uuid_idx ON notes (owner_uuid);
@@ -0,0 +1,2 @@
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
@@ -94,23 +94,24 @@ func (s *db) FindByCategoryUUID(ctx context.Context, categoryUUID string) (notes
 	return notes, fmt.Errorf("failed to decode document. error: %w", err)
 }
 
-func (s *db) Update(ctx context.Context, note note.Note) error {
+func (s *db) Update(ctx context.Context, note note.Note, holderUUID, sessionID string, now time.Time) (held note.Lease, updated bool, err error) {
 	objectID, err := primitive.ObjectIDFromHex(note.UUID)
 	if err != nil {
-		return fmt.Errorf("failed to parse note uuid due to error %w", err)
+		return held, false, fmt.Errorf("failed to parse note uuid due to error %w", err)
 	}
 
-	filter := bson.M{"_id": objectID}
+	// the lease is checked by the write itself, so a lease taken meanwhile is never overwritten
+	filter := unleasedFilter(objectID, holderUUID, sessionID, now)
 
 	noteByte, err := bson.Marshal(note)
 	if err != nil {
-		return fmt.Errorf("failed to marshal document. error: %w", err)
+		return held, false, fmt.Errorf("failed to marshal document. error: %w", err)
 	}
 
 	var updateObj bson.M
 	err = bson.Unmarshal(noteByte, &updateObj)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal document. error: %w", err)
+		return held, false, fmt.Errorf("failed to unmarshal document. error: %w", err)
 	}
 
 	delete(updateObj, "_id")
@@ -123,19 +124,62 @@ func (s *db) Update(ctx context.Context, note note.Note) error {
 		update["$set"].(bson.M)["tags"] = note.Tags
 	}
 
+	if note.Body != "" {
+		// derived fields follow the body even when they are empty
+		links := note.Links
+		if links == nil {
+			links = []string{}
+		}
+		update["$set"].(bson.M)["word_count"] = note.WordCount
+		update["$set"].(bson.M)["reading_time"] = note.ReadingTime
+		update["$set"].(bson.M)["links"] = links
+		update["$set"].(bson.M)["language"] = note.Language
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	result, err := s.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to execute query. error: %w", err)
+		return held, false, fmt.Errorf("failed to execute query. error: %w", err)
 	}
 	if result.MatchedCount == 0 {
-		return apperror.ErrNotFound
+		held, err = s.heldLease(ctx, objectID)
+		return held, false, err
 	}
 
 	s.logger.Tracef("Matched %v documents and updated %v documents.\n", result.MatchedCount, result.ModifiedCount)
 
-	return nil
+	return held, true, nil
+}
+
+func (s *db) UpdateDerived(ctx context.Context, n note.Note) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(n.UUID)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse note uuid due to error %w", err)
+	}
+
+	links := n.Links
+	if links == nil {
+		links = []string{}
+	}
+	// the body is the condition only, an edit since it was read wins
+	filter := bson.M{"_id": objectID, "body": n.Body}
+	update := bson.M{"$set": bson.M{
+		"short_body":   n.ShortBody,
+		"word_count":   n.WordCount,
+		"reading_time": n.ReadingTime,
+		"links":        links,
+		"language":     n.Language,
+	}}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return result.MatchedCount == 1, nil
 }
 
 func (s *db) Delete(ctx context.Context, uuid string) error {
@@ -156,4 +200,214 @@ func (s *db) Delete(ctx context.Context, uuid string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+func (s *db) FindByOwner(ctx context.Context, ownerUUID string) (notes []note.Note, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cur, err := s.collection.Find(ctx, bson.M{"owner_uuid": ownerUUID}, opts)
+	if err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &notes); err != nil {
+		return notes, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return notes, nil
+}
+
+func (s *db) UsageByOwner(ctx context.Context, ownerUUID string) (usage note.Usage, err error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"owner_uuid": ownerUUID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"notes":      bson.M{"$sum": 1},
+			"body_bytes": bson.M{"$sum": bson.M{"$strLenBytes": bson.M{"$ifNull": bson.A{"$body", ""}}}},
+		}}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return usage, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	var result []struct {
+		Notes     int   `bson:"notes"`
+		BodyBytes int64 `bson:"body_bytes"`
+	}
+	if err = cur.All(ctx, &result); err != nil {
+		return usage, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	if len(result) > 0 {
+		usage.Notes, usage.BodyBytes = result[0].Notes, result[0].BodyBytes
+	}
+	return usage, nil
+}
+
+func (s *db) TagUsage(ctx context.Context, ownerUUID string) (usage []note.TagUsage, err error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"owner_uuid": ownerUUID, "tags.0": bson.M{"$exists": true}}}},
+		// a tag listed twice in one note counts once
+		{{Key: "$project", Value: bson.M{"updated_at": 1, "tags": bson.M{"$setUnion": bson.A{"$tags", bson.A{}}}}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$tags",
+			"notes":        bson.M{"$sum": 1},
+			"last_used_at": bson.M{"$max": "$updated_at"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return usage, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &usage); err != nil {
+		return usage, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return usage, nil
+}
+
+func (s *db) AddTag(ctx context.Context, uuid string, tagID int, now time.Time) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return false, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	// nobody holds a lease with an empty session, so only notes without an active lease match
+	filter := unleasedFilter(objectID, "", "", now)
+	filter["tags"] = bson.M{"$ne": tagID}
+	update := bson.M{"$addToSet": bson.M{"tags": tagID}}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (s *db) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error) {
+	// replace in place, then fold the array keeping the first occurrence of every tag
+	replaced := bson.M{"$map": bson.M{
+		"input": "$tags",
+		"in":    bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$$this", sourceIDs}}, targetID, "$$this"}},
+	}}
+	deduplicated := bson.M{"$reduce": bson.M{
+		"input":        replaced,
+		"initialValue": bson.A{},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{"$$this", "$$value"}},
+			"$$value",
+			bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+		}},
+	}}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateMany(ctx, bson.M{"tags": bson.M{"$in": sourceIDs}},
+		bson.A{bson.M{"$set": bson.M{"tags": deduplicated}}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.ModifiedCount), nil
+}
+
+func (s *db) FindPage(ctx context.Context, afterUUID string, limit int) (notes []note.Note, err error) {
+	filter := bson.M{}
+	if afterUUID != "" {
+		objectID, err := primitive.ObjectIDFromHex(afterUUID)
+		if err != nil {
+			return notes, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+		}
+		filter["_id"] = bson.M{"$gt": objectID}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &notes); err != nil {
+		return notes, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return notes, nil
+}
+
+func (s *db) AcquireLease(ctx context.Context, uuid string, lease note.Lease, now time.Time) (current note.Lease, acquired bool, err error) {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return current, false, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	filter := unleasedFilter(objectID, lease.HolderUUID, lease.SessionID, now)
+	update := bson.M{"$set": bson.M{"lease": lease}}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return current, false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 1 {
+		return lease, true, nil
+	}
+
+	current, err = s.heldLease(ctx, objectID)
+	return current, false, err
+}
+
+// unleasedFilter matches the note when it has no lease active at now or the session of the holder has it
+func unleasedFilter(objectID primitive.ObjectID, holderUUID, sessionID string, now time.Time) bson.M {
+	return bson.M{
+		"_id": objectID,
+		"$or": bson.A{
+			bson.M{"lease": bson.M{"$exists": false}},
+			bson.M{"lease.expires_at": bson.M{"$lte": now}},
+			bson.M{"lease.holder_uuid": holderUUID, "lease.session_id": sessionID},
+		},
+	}
+}
+
+// heldLease returns the lease that kept unleasedFilter from matching the note
+func (s *db) heldLease(ctx context.Context, objectID primitive.ObjectID) (note.Lease, error) {
+	var n note.Note
+	err := s.collection.FindOne(ctx, bson.M{"_id": objectID}, options.FindOne().SetProjection(bson.M{"lease": 1})).Decode(&n)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return note.Lease{}, apperror.ErrNotFound
+		}
+		return note.Lease{}, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if n.Lease == nil {
+		return note.Lease{}, fmt.Errorf("lease of note %s changed concurrently", objectID.Hex())
+	}
+
+	return *n.Lease, nil
+}
+
+func (s *db) ReleaseLease(ctx context.Context, uuid, holderUUID, sessionID string) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	filter := bson.M{"_id": objectID, "lease.holder_uuid": holderUUID, "lease.session_id": sessionID}
+	update := bson.M{"$unset": bson.M{"lease": ""}}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err = s.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return nil
+}
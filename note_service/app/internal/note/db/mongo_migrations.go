@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/mongodb/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations returns the schema history of the notes collection
+func MongoMigrations(collection string) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     1,
+			Description: "index notes by category_uuid",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "category_uuid", Value: 1}},
+					Options: options.Index().SetName("category_uuid"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "category_uuid")
+				return err
+			},
+		},
+		{
+			Version:     3,
+			Description: "index notes by owner_uuid",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "owner_uuid", Value: 1}},
+					Options: options.Index().SetName("owner_uuid"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "owner_uuid")
+				return err
+			},
+		},
+	}
+}
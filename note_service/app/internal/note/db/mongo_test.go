@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/storagetest"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestMongoStorage runs against a server in a container, NOTES_TEST_MONGODB_URI points it to
+// another throwaway server instead, e.g. NOTES_TEST_MONGODB_URI=mongodb://localhost:27017
+func TestMongoStorage(t *testing.T) {
+	uri := mongoURI(t)
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	database := client.Database("notes_test")
+
+	storagetest.Run(t, func(t *testing.T) note.Storage {
+		collection := fmt.Sprintf("notes_%d", time.Now().UnixNano())
+		t.Cleanup(func() { database.Collection(collection).Drop(ctx) })
+		return NewStorage(database, collection, logging.Logger{Entry: logrus.NewEntry(logrus.New())})
+	})
+}
@@ -0,0 +1,430 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/postgresql"
+	"io/fs"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+// invalid_text_representation, returned when uuid is not a valid UUID
+const pgInvalidTextRepresentation = "22P02"
+
+var _ note.Storage = &pgDB{}
+
+type pgDB struct {
+	pool   *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewPostgresStorage(pool *pgxpool.Pool, logger logging.Logger) note.Storage {
+	return &pgDB{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// MigratePostgres brings the notes schema up to date
+func MigratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := fs.Sub(postgresMigrations, "migrations")
+	if err != nil {
+		return err
+	}
+	return postgresql.Migrate(ctx, pool, migrations)
+}
+
+func (s *pgDB) Create(ctx context.Context, note note.Note) (uuid string, err error) {
+	nCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tags := note.Tags
+	if tags == nil {
+		tags = []int{}
+	}
+
+	links := note.Links
+	if links == nil {
+		links = []string{}
+	}
+
+	q := `INSERT INTO notes (header, body, short_body, category_uuid, owner_uuid, tags, word_count, reading_time, links, language,
+		updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id::text`
+	err = s.pool.QueryRow(nCtx, q, note.Header, note.Body, note.ShortBody, note.CategoryUUID, note.OwnerUUID, tags,
+		note.WordCount, note.ReadingTime, links, note.Language, nullTime(note.UpdatedAt)).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (s *pgDB) FindOne(ctx context.Context, uuid string) (n note.Note, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var lease pgLease
+	var updatedAt *time.Time
+	q := `SELECT id::text, header, body, category_uuid, owner_uuid, tags, word_count, reading_time, links, language,
+		updated_at, lease_holder_uuid, lease_session_id, lease_expires_at
+		FROM notes WHERE id = $1`
+	err = s.pool.QueryRow(ctx, q, uuid).Scan(&n.UUID, &n.Header, &n.Body, &n.CategoryUUID, &n.OwnerUUID, &n.Tags,
+		&n.WordCount, &n.ReadingTime, &n.Links, &n.Language, &updatedAt,
+		&lease.HolderUUID, &lease.SessionID, &lease.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return n, apperror.ErrNotFound
+		}
+		if isInvalidUUID(err) {
+			return n, fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return n, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	n.Lease = lease.toLease()
+	if updatedAt != nil {
+		n.UpdatedAt = *updatedAt
+	}
+
+	return n, nil
+}
+
+func (s *pgDB) FindByCategoryUUID(ctx context.Context, categoryUUID string) (notes []note.Note, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q := `SELECT id::text, header, short_body, category_uuid, tags, word_count, reading_time, links, language
+		FROM notes WHERE category_uuid = $1`
+	rows, err := s.pool.Query(ctx, q, categoryUUID)
+	if err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n note.Note
+		if err = rows.Scan(&n.UUID, &n.Header, &n.ShortBody, &n.CategoryUUID, &n.Tags,
+			&n.WordCount, &n.ReadingTime, &n.Links, &n.Language); err != nil {
+			return notes, fmt.Errorf("failed to decode row. error: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	if err = rows.Err(); err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return notes, nil
+}
+
+func (s *pgDB) FindByOwner(ctx context.Context, ownerUUID string) (notes []note.Note, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	q := `SELECT id::text, header, body, category_uuid, owner_uuid, tags, word_count, reading_time, links, language, updated_at
+		FROM notes WHERE owner_uuid = $1 ORDER BY id`
+	rows, err := s.pool.Query(ctx, q, ownerUUID)
+	if err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n note.Note
+		var updatedAt *time.Time
+		if err = rows.Scan(&n.UUID, &n.Header, &n.Body, &n.CategoryUUID, &n.OwnerUUID, &n.Tags,
+			&n.WordCount, &n.ReadingTime, &n.Links, &n.Language, &updatedAt); err != nil {
+			return notes, fmt.Errorf("failed to decode row. error: %w", err)
+		}
+		if updatedAt != nil {
+			n.UpdatedAt = *updatedAt
+		}
+		notes = append(notes, n)
+	}
+	if err = rows.Err(); err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return notes, nil
+}
+
+func (s *pgDB) UsageByOwner(ctx context.Context, ownerUUID string) (usage note.Usage, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q := `SELECT count(*), COALESCE(sum(octet_length(body)), 0) FROM notes WHERE owner_uuid = $1`
+	if err = s.pool.QueryRow(ctx, q, ownerUUID).Scan(&usage.Notes, &usage.BodyBytes); err != nil {
+		return usage, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return usage, nil
+}
+
+func (s *pgDB) TagUsage(ctx context.Context, ownerUUID string) (usage []note.TagUsage, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q := `SELECT t, count(DISTINCT id), max(updated_at)
+		FROM notes, unnest(tags) AS t
+		WHERE owner_uuid = $1
+		GROUP BY t ORDER BY t`
+	rows, err := s.pool.Query(ctx, q, ownerUUID)
+	if err != nil {
+		return usage, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u note.TagUsage
+		if err = rows.Scan(&u.TagID, &u.Notes, &u.LastUsedAt); err != nil {
+			return usage, fmt.Errorf("failed to decode row. error: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err = rows.Err(); err != nil {
+		return usage, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return usage, nil
+}
+
+func (s *pgDB) AddTag(ctx context.Context, uuid string, tagID int, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// nobody holds a lease with an empty session, so only notes without an active lease match
+	q := `UPDATE notes SET tags = array_append(tags, $2::int)
+		WHERE id = $1 AND NOT ($2::int = ANY(tags)) AND ` + unleased(3, 3, 4)
+	result, err := s.pool.Exec(ctx, q, uuid, tagID, "", now)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return false, fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *pgDB) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	q := `UPDATE notes SET tags = ARRAY(
+		SELECT t FROM (
+			SELECT CASE WHEN x = ANY($1::int[]) THEN $2::int ELSE x END AS t, min(ord) AS first
+			FROM unnest(tags) WITH ORDINALITY AS u(x, ord)
+			GROUP BY 1
+		) s ORDER BY first)
+		WHERE tags && $1::int[]`
+	result, err := s.pool.Exec(ctx, q, sourceIDs, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (s *pgDB) FindPage(ctx context.Context, afterUUID string, limit int) (notes []note.Note, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q := `SELECT id::text, header, body, category_uuid, tags, word_count, reading_time, links, language
+		FROM notes WHERE $1 = '' OR id > $1::uuid ORDER BY id LIMIT $2`
+	rows, err := s.pool.Query(ctx, q, afterUUID, limit)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return notes, fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n note.Note
+		if err = rows.Scan(&n.UUID, &n.Header, &n.Body, &n.CategoryUUID, &n.Tags,
+			&n.WordCount, &n.ReadingTime, &n.Links, &n.Language); err != nil {
+			return notes, fmt.Errorf("failed to decode row. error: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	if err = rows.Err(); err != nil {
+		return notes, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return notes, nil
+}
+
+func (s *pgDB) Update(ctx context.Context, note note.Note, holderUUID, sessionID string, now time.Time) (held note.Lease, updated bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// empty fields are left untouched, the same way mongo $set skips omitempty fields
+	q := `UPDATE notes SET
+		header = COALESCE(NULLIF($2, ''), header),
+		body = COALESCE(NULLIF($3, ''), body),
+		short_body = COALESCE(NULLIF($4, ''), short_body),
+		category_uuid = COALESCE(NULLIF($5, ''), category_uuid),
+		tags = COALESCE($6, tags),
+		word_count = CASE WHEN $3 <> '' THEN $7 ELSE word_count END,
+		reading_time = CASE WHEN $3 <> '' THEN $8 ELSE reading_time END,
+		links = CASE WHEN $3 <> '' THEN $9 ELSE links END,
+		language = CASE WHEN $3 <> '' THEN $10 ELSE language END,
+		updated_at = COALESCE($11, updated_at)
+		WHERE id = $1 AND ` + unleased(12, 13, 14)
+	links := note.Links
+	if links == nil {
+		links = []string{}
+	}
+	result, err := s.pool.Exec(ctx, q, note.UUID, note.Header, note.Body, note.ShortBody, note.CategoryUUID, note.Tags,
+		note.WordCount, note.ReadingTime, links, note.Language, nullTime(note.UpdatedAt), holderUUID, sessionID, now)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return held, false, fmt.Errorf("failed to parse note uuid due to error %w", err)
+		}
+		return held, false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		held, err = s.heldLease(ctx, note.UUID)
+		return held, false, err
+	}
+
+	s.logger.Tracef("Updated %v rows.\n", result.RowsAffected())
+
+	return held, true, nil
+}
+
+func (s *pgDB) UpdateDerived(ctx context.Context, n note.Note) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// the body is the condition only, an edit since it was read wins
+	q := `UPDATE notes SET short_body = $3, word_count = $4, reading_time = $5, links = $6, language = $7
+		WHERE id = $1 AND body = $2`
+	links := n.Links
+	if links == nil {
+		links = []string{}
+	}
+	result, err := s.pool.Exec(ctx, q, n.UUID, n.Body, n.ShortBody, n.WordCount, n.ReadingTime, links, n.Language)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return false, fmt.Errorf("failed to parse note uuid due to error %w", err)
+		}
+		return false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *pgDB) Delete(ctx context.Context, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.pool.Exec(ctx, `DELETE FROM notes WHERE id = $1`, uuid)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return fmt.Errorf("failed to parse note uuid")
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func isInvalidUUID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgInvalidTextRepresentation
+}
+
+func (s *pgDB) AcquireLease(ctx context.Context, uuid string, lease note.Lease, now time.Time) (current note.Lease, acquired bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	q := `UPDATE notes SET lease_holder_uuid = $2, lease_session_id = $3, lease_expires_at = $4
+		WHERE id = $1 AND ` + unleased(2, 3, 5)
+	result, err := s.pool.Exec(ctx, q, uuid, lease.HolderUUID, lease.SessionID, lease.ExpiresAt, now)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return current, false, fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return current, false, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 1 {
+		return lease, true, nil
+	}
+
+	current, err = s.heldLease(ctx, uuid)
+	return current, false, err
+}
+
+// unleased is the condition that the note has no lease active at the parameter now or the session
+// of the holder has it, the arguments are the numbers of the parameters
+func unleased(holderUUID, sessionID, now int) string {
+	return fmt.Sprintf(`(lease_expires_at IS NULL OR lease_expires_at <= $%[3]d
+			OR (lease_holder_uuid = $%[1]d AND lease_session_id = $%[2]d))`, holderUUID, sessionID, now)
+}
+
+// heldLease returns the lease that kept the unleased condition from matching the note
+func (s *pgDB) heldLease(ctx context.Context, uuid string) (note.Lease, error) {
+	var held pgLease
+	q := `SELECT lease_holder_uuid, lease_session_id, lease_expires_at FROM notes WHERE id = $1`
+	err := s.pool.QueryRow(ctx, q, uuid).Scan(&held.HolderUUID, &held.SessionID, &held.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return note.Lease{}, apperror.ErrNotFound
+		}
+		return note.Lease{}, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if held.toLease() == nil {
+		return note.Lease{}, fmt.Errorf("lease of note %s changed concurrently", uuid)
+	}
+
+	return *held.toLease(), nil
+}
+
+func (s *pgDB) ReleaseLease(ctx context.Context, uuid, holderUUID, sessionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	q := `UPDATE notes SET lease_holder_uuid = NULL, lease_session_id = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND lease_holder_uuid = $2 AND lease_session_id = $3`
+	if _, err := s.pool.Exec(ctx, q, uuid, holderUUID, sessionID); err != nil {
+		if isInvalidUUID(err) {
+			return fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return nil
+}
+
+// pgLease scans the nullable lease columns
+type pgLease struct {
+	HolderUUID *string
+	SessionID  *string
+	ExpiresAt  *time.Time
+}
+
+func (l pgLease) toLease() *note.Lease {
+	if l.HolderUUID == nil || l.SessionID == nil || l.ExpiresAt == nil {
+		return nil
+	}
+	return &note.Lease{HolderUUID: *l.HolderUUID, SessionID: *l.SessionID, ExpiresAt: *l.ExpiresAt}
+}
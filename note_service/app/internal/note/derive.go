@@ -0,0 +1,120 @@
+package note
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	shortBodyThreshold = 1000 // notes longer than that, in runes, get a cut short body
+	shortBodyLength    = 300
+	wordsPerMinute     = 200
+	undeterminedLang   = "und"
+)
+
+var linkRegexp = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// deriver fills one group of fields computed from the note body
+type deriver func(n *Note)
+
+var derivers = []deriver{
+	deriveShortBody,
+	deriveWordCount,
+	deriveLinks,
+	deriveLanguage,
+}
+
+// Derive recomputes every field that depends on the body. It has to run on each write of Body.
+func (cn *Note) Derive() {
+	for _, d := range derivers {
+		d(cn)
+	}
+}
+
+func deriveShortBody(n *Note) {
+	runes := []rune(n.Body)
+	if len(runes) > shortBodyThreshold {
+		n.ShortBody = string(runes[:shortBodyLength])
+	} else {
+		n.ShortBody = n.Body
+	}
+}
+
+func deriveWordCount(n *Note) {
+	// a word is any whitespace separated token with a letter or a digit in it, so links count once
+	n.WordCount = 0
+	for _, field := range strings.Fields(n.Body) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) >= 0 {
+			n.WordCount++
+		}
+	}
+	n.ReadingTime = (n.WordCount + wordsPerMinute - 1) / wordsPerMinute
+}
+
+func deriveLinks(n *Note) {
+	n.Links = []string{}
+	seen := make(map[string]bool)
+	for _, link := range linkRegexp.FindAllString(n.Body, -1) {
+		link = strings.TrimRight(link, ".,;:!?")
+		if !seen[link] {
+			seen[link] = true
+			n.Links = append(n.Links, link)
+		}
+	}
+}
+
+// stop words of the latin script languages we tell apart
+var stopWords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "that", "it", "for", "with"},
+	"de": {"der", "die", "und", "ist", "das", "nicht", "ein", "zu", "mit", "sich"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "pas", "pour", "dans"},
+	"es": {"el", "los", "las", "y", "es", "que", "una", "por", "para", "con"},
+}
+
+// deriveLanguage makes a cheap guess: the script first, then stop words for latin texts
+func deriveLanguage(n *Note) {
+	var cyrillic, latin int
+	ukrainian := false
+	for _, r := range n.Body {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian = true
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	switch {
+	case cyrillic == 0 && latin == 0:
+		n.Language = undeterminedLang
+	case cyrillic >= latin && ukrainian:
+		n.Language = "uk"
+	case cyrillic >= latin:
+		n.Language = "ru"
+	default:
+		n.Language = guessLatinLanguage(n.Body)
+	}
+}
+
+func guessLatinLanguage(text string) string {
+	words := make(map[string]int)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		words[w]++
+	}
+
+	best, bestScore := undeterminedLang, 0
+	for _, lang := range []string{"en", "de", "fr", "es"} {
+		score := 0
+		for _, w := range stopWords[lang] {
+			score += words[w]
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,42 @@
+package note
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeriveShortBodyIsRuneSafe(t *testing.T) {
+	n := Note{Body: strings.Repeat("ж", shortBodyThreshold+1)}
+	n.Derive()
+
+	if got := []rune(n.ShortBody); len(got) != shortBodyLength {
+		t.Fatalf("short body has %d runes, want %d", len(got), shortBodyLength)
+	}
+	if !strings.HasPrefix(n.Body, n.ShortBody) {
+		t.Fatalf("short body is not a prefix of the body")
+	}
+}
+
+func TestDerive(t *testing.T) {
+	n := Note{Body: "The docs are at https://example.com/docs. See https://example.com/docs, and it is fine."}
+	n.Derive()
+
+	if n.WordCount != 11 {
+		t.Fatalf("word count is %d, want 11", n.WordCount)
+	}
+	if n.ReadingTime != 1 {
+		t.Fatalf("reading time is %d, want 1", n.ReadingTime)
+	}
+	if len(n.Links) != 1 || n.Links[0] != "https://example.com/docs" {
+		t.Fatalf("links are %v, want [https://example.com/docs]", n.Links)
+	}
+	if n.Language != "en" {
+		t.Fatalf("language is %q, want en", n.Language)
+	}
+
+	n.Body = "Привет, это заметка"
+	n.Derive()
+	if n.Language != "ru" || len(n.Links) != 0 {
+		t.Fatalf("derived fields were not recomputed: %+v", n)
+	}
+}
@@ -0,0 +1,20 @@
+package note
+
+import "testing"
+
+func TestWithAnyTag(t *testing.T) {
+	notes := []Note{
+		{UUID: "a", Tags: []int{1, 2}},
+		{UUID: "b", Tags: []int{3}},
+		{UUID: "c"},
+		{UUID: "d", Tags: []int{2, 4}},
+	}
+
+	got := withAnyTag(notes, []int{2, 5})
+	if len(got) != 2 || got[0].UUID != "a" || got[1].UUID != "d" {
+		t.Fatalf("withAnyTag = %+v, want notes a and d", got)
+	}
+	if got := withAnyTag(notes, []int{7}); len(got) != 0 {
+		t.Fatalf("withAnyTag = %+v, want none", got)
+	}
+}
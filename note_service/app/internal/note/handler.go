@@ -7,11 +7,23 @@ import (
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 const (
-	notesURL = "/api/notes"
-	noteURL  = "/api/notes/:uuid"
+	notesURL     = "/api/notes"
+	noteURL      = "/api/notes/:uuid"
+	noteLeaseURL = "/api/notes/:uuid/lock"
+	usageURL     = "/api/usage"
+	tagMergesURL = "/api/tag-merges"
+	tagUsageURL  = "/api/tag-usage"
+)
+
+// headers the gateway uses to pass the authenticated user and its session
+const (
+	userUUIDHeader  = "X-User-UUID"
+	sessionIDHeader = "X-Session-ID"
 )
 
 type Handler struct {
@@ -25,6 +37,11 @@ func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodPost, notesURL, apperror.Middleware(h.CreateNote))
 	router.HandlerFunc(http.MethodPatch, noteURL, apperror.Middleware(h.PartiallyUpdateNote))
 	router.HandlerFunc(http.MethodDelete, noteURL, apperror.Middleware(h.DeleteNote))
+	router.HandlerFunc(http.MethodPost, noteLeaseURL, apperror.Middleware(h.AcquireLease))
+	router.HandlerFunc(http.MethodDelete, noteLeaseURL, apperror.Middleware(h.ReleaseLease))
+	router.HandlerFunc(http.MethodGet, usageURL, apperror.Middleware(h.GetUsage))
+	router.HandlerFunc(http.MethodGet, tagUsageURL, apperror.Middleware(h.GetTagUsage))
+	router.HandlerFunc(http.MethodPost, tagMergesURL, apperror.Middleware(h.ReplaceTags))
 }
 
 func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) error {
@@ -59,7 +76,18 @@ func (h *Handler) GetNotesByCategory(w http.ResponseWriter, r *http.Request) err
 		return apperror.BadRequestError("category_uuid query parameter is required and must be a comma separated integers")
 	}
 
-	notes, err := h.NoteService.GetByCategoryUUID(r.Context(), categoryUUID)
+	var tagIDs []int
+	if tagsParam := r.URL.Query().Get("tag"); tagsParam != "" {
+		for _, idStr := range strings.Split(tagsParam, ",") {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				return apperror.BadRequestError("tag query parameter must be a comma separated integers")
+			}
+			tagIDs = append(tagIDs, id)
+		}
+	}
+
+	notes, err := h.NoteService.GetByCategoryUUID(r.Context(), categoryUUID, tagIDs)
 	if err != nil {
 		return err
 	}
@@ -83,6 +111,7 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) error {
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 		return apperror.BadRequestError("invalid data")
 	}
+	dto.OwnerUUID = r.Header.Get(userUUIDHeader)
 
 	noteUUID, err := h.NoteService.Create(r.Context(), dto)
 	if err != nil {
@@ -110,6 +139,8 @@ func (h *Handler) PartiallyUpdateNote(w http.ResponseWriter, r *http.Request) er
 	}
 
 	dto.UUID = noteUUID
+	dto.EditorUUID = r.Header.Get(userUUIDHeader)
+	dto.SessionID = r.Header.Get(sessionIDHeader)
 
 	err := h.NoteService.Update(r.Context(), dto)
 	if err != nil {
@@ -137,3 +168,108 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+func (h *Handler) AcquireLease(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	dto := LeaseDTO{
+		NoteUUID:   params.ByName("uuid"),
+		HolderUUID: r.Header.Get(userUUIDHeader),
+		SessionID:  r.Header.Get(sessionIDHeader),
+	}
+
+	lease, err := h.NoteService.AcquireLease(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	leaseBytes, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(leaseBytes)
+
+	return nil
+}
+
+func (h *Handler) ReleaseLease(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	dto := LeaseDTO{
+		NoteUUID:   params.ByName("uuid"),
+		HolderUUID: r.Header.Get(userUUIDHeader),
+		SessionID:  r.Header.Get(sessionIDHeader),
+	}
+
+	err := h.NoteService.ReleaseLease(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	usage, err := h.NoteService.GetUsage(r.Context(), r.URL.Query().Get("owner_uuid"))
+	if err != nil {
+		return err
+	}
+	usageBytes, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(usageBytes)
+
+	return nil
+}
+
+func (h *Handler) GetTagUsage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	usage, err := h.NoteService.GetTagUsage(r.Context(), r.URL.Query().Get("owner_uuid"))
+	if err != nil {
+		return err
+	}
+	usageBytes, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(usageBytes)
+
+	return nil
+}
+
+// ReplaceTags rewrites tags of all notes when tags are merged
+func (h *Handler) ReplaceTags(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var dto ReplaceTagsDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme")
+	}
+
+	result, err := h.NoteService.ReplaceTags(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBytes)
+
+	return nil
+}
@@ -1,22 +1,38 @@
 package note
 
+import "time"
+
 type Note struct {
 	UUID         string `json:"uuid" bson:"_id,omitempty"`
 	Header       string `json:"header" bson:"header,omitempty"`
 	Body         string `json:"body,omitempty" bson:"body,omitempty"`
 	ShortBody    string `json:"short_body,omitempty" bson:"short_body,omitempty"`
 	CategoryUUID string `json:"category_uuid" bson:"category_uuid,omitempty"`
+	OwnerUUID    string `json:"owner_uuid,omitempty" bson:"owner_uuid,omitempty"`
 	Tags         []int  `json:"tags" bson:"tags,omitempty"`
+	Lease        *Lease `json:"lease,omitempty" bson:"lease,omitempty"`
+	// UpdatedAt is the time of the last create or update, zero for notes written before it was kept
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at,omitempty"`
+	// derived from Body, see Derive
+	WordCount   int      `json:"word_count" bson:"word_count,omitempty"`
+	ReadingTime int      `json:"reading_time" bson:"reading_time,omitempty"` // minutes
+	Links       []string `json:"links" bson:"links,omitempty"`
+	Language    string   `json:"language,omitempty" bson:"language,omitempty"`
 }
 
-func (cn *Note) GenerateShortBody() {
-	var shortLen int
-	if len(cn.Body) > 1000 {
-		shortLen = 300
-	} else {
-		shortLen = len(cn.Body)
-	}
-	cn.ShortBody = cn.Body[0:shortLen]
+// Lease is a short-lived right to edit a note, held by one session of one user
+type Lease struct {
+	HolderUUID string    `json:"holder_uuid" bson:"holder_uuid"`
+	SessionID  string    `json:"session_id" bson:"session_id"`
+	ExpiresAt  time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+func (l *Lease) IsActive(now time.Time) bool {
+	return l != nil && l.ExpiresAt.After(now)
+}
+
+func (l *Lease) IsHeldBy(holderUUID, sessionID string) bool {
+	return l != nil && l.HolderUUID == holderUUID && l.SessionID == sessionID
 }
 
 func NewNote(dto CreateNoteDTO) Note {
@@ -24,6 +40,7 @@ func NewNote(dto CreateNoteDTO) Note {
 		Header:       dto.Header,
 		Body:         dto.Body,
 		CategoryUUID: dto.CategoryUUID,
+		OwnerUUID:    dto.OwnerUUID,
 		Tags:         dto.Tags,
 	}
 }
@@ -39,6 +56,7 @@ func UpdatedNote(dto UpdateNoteDTO) Note {
 }
 
 type CreateNoteDTO struct {
+	OwnerUUID    string `json:"-" bson:"-"`
 	Header       string `json:"header" bson:"header"`
 	Body         string `json:"body" bson:"body"`
 	CategoryUUID string `json:"category_uuid" bson:"category_uuid"`
@@ -47,8 +65,42 @@ type CreateNoteDTO struct {
 
 type UpdateNoteDTO struct {
 	UUID         string `json:"uuid" bson:"_id,omitempty"`
+	EditorUUID   string `json:"-" bson:"-"`
+	SessionID    string `json:"-" bson:"-"`
 	Header       string `json:"header,omitempty" bson:"header,omitempty"`
 	Body         string `json:"body,omitempty" bson:"body,omitempty"`
 	CategoryUUID string `json:"category_uuid,omitempty" bson:"category_uuid,omitempty"`
 	Tags         []int  `json:"tags,omitempty" bson:"tags,omitempty"`
 }
+
+type LeaseDTO struct {
+	NoteUUID   string `json:"-"`
+	HolderUUID string `json:"-"`
+	SessionID  string `json:"-"`
+}
+
+// Usage is what one user keeps in the notes storage
+type Usage struct {
+	OwnerUUID string `json:"owner_uuid"`
+	Notes     int    `json:"notes"`
+	BodyBytes int64  `json:"body_bytes"`
+}
+
+// ReplaceTagsDTO asks to reference TargetID instead of SourceIDs in every note
+type ReplaceTagsDTO struct {
+	SourceIDs []int `json:"source_ids"`
+	TargetID  int   `json:"target_id"`
+}
+
+// TagUsage tells how many notes of the owner reference the tag and when one of them was
+// last written. LastUsedAt is unknown when all the notes predate UpdatedAt.
+type TagUsage struct {
+	TagID      int        `json:"tag_id" bson:"_id"`
+	Notes      int        `json:"notes" bson:"notes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at"`
+}
+
+type ReplaceTagsResult struct {
+	NotesChanged int `json:"notes_changed"`
+	RulesChanged int `json:"rules_changed"`
+}
@@ -6,33 +6,51 @@ import (
 	"fmt"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
 	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"time"
 )
 
 var _ Service = &service{}
 
 type service struct {
-	storage Storage
-	logger  logging.Logger
+	storage    Storage
+	leaseTTL   time.Duration
+	autoTagger AutoTagger
+	logger     logging.Logger
 }
 
-func NewService(noteStorage Storage, logger logging.Logger) (Service, error) {
+// NewService creates the note service, autoTagger may be nil when notes are not tagged automatically
+func NewService(noteStorage Storage, leaseTTL time.Duration, autoTagger AutoTagger, logger logging.Logger) (Service, error) {
 	return &service{
-		storage: noteStorage,
-		logger:  logger,
+		storage:    noteStorage,
+		leaseTTL:   leaseTTL,
+		autoTagger: autoTagger,
+		logger:     logger,
 	}, nil
 }
 
 type Service interface {
 	Create(ctx context.Context, dto CreateNoteDTO) (string, error)
 	GetOne(ctx context.Context, uuid string) (Note, error)
-	GetByCategoryUUID(ctx context.Context, uuid string) ([]Note, error)
+	// GetByCategoryUUID returns notes of the category, only those that reference any of tagIDs
+	// when tagIDs are given
+	GetByCategoryUUID(ctx context.Context, uuid string, tagIDs []int) ([]Note, error)
+	GetUsage(ctx context.Context, ownerUUID string) (Usage, error)
+	GetTagUsage(ctx context.Context, ownerUUID string) ([]TagUsage, error)
+	ReplaceTags(ctx context.Context, dto ReplaceTagsDTO) (ReplaceTagsResult, error)
 	Update(ctx context.Context, dto UpdateNoteDTO) error
 	Delete(ctx context.Context, uuid string) error
+	AcquireLease(ctx context.Context, dto LeaseDTO) (Lease, error)
+	ReleaseLease(ctx context.Context, dto LeaseDTO) error
+	Backfill(ctx context.Context) (int, error)
 }
 
 func (s service) Create(ctx context.Context, dto CreateNoteDTO) (noteUUID string, err error) {
 	note := NewNote(dto)
-	note.GenerateShortBody()
+	note.Derive()
+	note.UpdatedAt = time.Now().UTC()
+	if note.Tags, err = s.autoTag(ctx, note); err != nil {
+		return "", fmt.Errorf("failed to apply tagging rules. error: %w", err)
+	}
 	noteUUID, err = s.storage.Create(ctx, note)
 
 	if err != nil {
@@ -54,10 +72,13 @@ func (s service) GetOne(ctx context.Context, uuid string) (n Note, err error) {
 		}
 		return n, fmt.Errorf("failed to find note by uuid. error: %w", err)
 	}
+	if !n.Lease.IsActive(time.Now()) {
+		n.Lease = nil
+	}
 	return n, nil
 }
 
-func (s service) GetByCategoryUUID(ctx context.Context, uuid string) (notes []Note, err error) {
+func (s service) GetByCategoryUUID(ctx context.Context, uuid string, tagIDs []int) (notes []Note, err error) {
 	notes, err = s.storage.FindByCategoryUUID(ctx, uuid)
 
 	if err != nil {
@@ -66,18 +87,123 @@ func (s service) GetByCategoryUUID(ctx context.Context, uuid string) (notes []No
 		}
 		return notes, fmt.Errorf("failed to get notes by ids. error: %w", err)
 	}
+	if len(tagIDs) > 0 {
+		notes = withAnyTag(notes, tagIDs)
+	}
 	if len(notes) == 0 {
 		return notes, apperror.ErrNotFound
 	}
 	return notes, nil
 }
 
+func withAnyTag(notes []Note, tagIDs []int) []Note {
+	wanted := make(map[int]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		wanted[id] = true
+	}
+
+	var tagged []Note
+	for _, n := range notes {
+		for _, id := range n.Tags {
+			if wanted[id] {
+				tagged = append(tagged, n)
+				break
+			}
+		}
+	}
+	return tagged
+}
+
+func (s service) GetUsage(ctx context.Context, ownerUUID string) (usage Usage, err error) {
+	if ownerUUID == "" {
+		return usage, apperror.BadRequestError("owner_uuid query parameter is required")
+	}
+	usage, err = s.storage.UsageByOwner(ctx, ownerUUID)
+	if err != nil {
+		return usage, fmt.Errorf("failed to count usage of owner. error: %w", err)
+	}
+	usage.OwnerUUID = ownerUUID
+	return usage, nil
+}
+
+func (s service) GetTagUsage(ctx context.Context, ownerUUID string) (usage []TagUsage, err error) {
+	if ownerUUID == "" {
+		return usage, apperror.BadRequestError("owner_uuid query parameter is required")
+	}
+	usage, err = s.storage.TagUsage(ctx, ownerUUID)
+	if err != nil {
+		return usage, fmt.Errorf("failed to count tag usage of owner. error: %w", err)
+	}
+	if usage == nil {
+		usage = []TagUsage{}
+	}
+	return usage, nil
+}
+
+// ReplaceTags is used when tags are merged, it rewrites the tagging rules and the notes. Repeating
+// it is harmless, rules and notes already rewritten are not changed again.
+func (s service) ReplaceTags(ctx context.Context, dto ReplaceTagsDTO) (result ReplaceTagsResult, err error) {
+	if len(dto.SourceIDs) == 0 || dto.TargetID == 0 {
+		return result, apperror.BadRequestError("source_ids and target_id are required")
+	}
+	for _, id := range dto.SourceIDs {
+		if id == dto.TargetID {
+			return result, apperror.BadRequestError("target_id must not be one of source_ids")
+		}
+	}
+
+	// rules first, notes written in between are not tagged with a source again
+	if s.autoTagger != nil {
+		result.RulesChanged, err = s.autoTagger.ReplaceTags(ctx, dto.SourceIDs, dto.TargetID)
+		if err != nil {
+			return result, fmt.Errorf("failed to replace tags of rules. error: %w", err)
+		}
+	}
+	result.NotesChanged, err = s.storage.ReplaceTags(ctx, dto.SourceIDs, dto.TargetID)
+	if err != nil {
+		return result, fmt.Errorf("failed to replace tags of notes. error: %w", err)
+	}
+	return result, nil
+}
+
 func (s service) Update(ctx context.Context, dto UpdateNoteDTO) error {
 	if dto.Body == "" && dto.Header == "" && dto.CategoryUUID == "" && dto.Tags == nil {
 		return apperror.BadRequestError("nothing to update")
 	}
+	current, err := s.GetOne(ctx, dto.UUID)
+	if err != nil {
+		return err
+	}
+
 	note := UpdatedNote(dto)
-	err := s.storage.Update(ctx, note)
+	if note.Body != "" {
+		note.Derive()
+	}
+	note.UpdatedAt = time.Now().UTC()
+
+	// rules see the note as it is going to be stored
+	updated := current
+	if dto.Header != "" {
+		updated.Header = dto.Header
+	}
+	if dto.Body != "" {
+		updated.Body = dto.Body
+	}
+	if dto.CategoryUUID != "" {
+		updated.CategoryUUID = dto.CategoryUUID
+	}
+	if dto.Tags != nil {
+		updated.Tags = dto.Tags
+	}
+	tags, err := s.autoTag(ctx, updated)
+	if err != nil {
+		return fmt.Errorf("failed to apply tagging rules. error: %w", err)
+	}
+	if len(tags) != len(updated.Tags) {
+		note.Tags = tags
+	}
+	// the storage refuses the write while another session holds a lease
+	held, written, err := s.storage.Update(ctx, note, dto.EditorUUID, dto.SessionID, note.UpdatedAt)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -85,6 +211,9 @@ func (s service) Update(ctx context.Context, dto UpdateNoteDTO) error {
 		}
 		return fmt.Errorf("failed to update note. error: %w", err)
 	}
+	if !written {
+		return leaseHeldError(held)
+	}
 	return nil
 }
 
@@ -99,3 +228,85 @@ func (s service) Delete(ctx context.Context, uuid string) error {
 	}
 	return err
 }
+
+func (s service) AcquireLease(ctx context.Context, dto LeaseDTO) (Lease, error) {
+	if dto.HolderUUID == "" || dto.SessionID == "" {
+		return Lease{}, apperror.BadRequestError("lease holder and session are required")
+	}
+
+	now := time.Now()
+	lease := Lease{
+		HolderUUID: dto.HolderUUID,
+		SessionID:  dto.SessionID,
+		ExpiresAt:  now.Add(s.leaseTTL),
+	}
+	current, acquired, err := s.storage.AcquireLease(ctx, dto.NoteUUID, lease, now)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return lease, err
+		}
+		return lease, fmt.Errorf("failed to acquire note lease. error: %w", err)
+	}
+	if !acquired {
+		return current, leaseHeldError(current)
+	}
+	return current, nil
+}
+
+func (s service) ReleaseLease(ctx context.Context, dto LeaseDTO) error {
+	n, err := s.GetOne(ctx, dto.NoteUUID)
+	if err != nil {
+		return err
+	}
+	if n.Lease == nil {
+		return nil
+	}
+	if !n.Lease.IsHeldBy(dto.HolderUUID, dto.SessionID) {
+		return leaseHeldError(*n.Lease)
+	}
+
+	err = s.storage.ReleaseLease(ctx, dto.NoteUUID, dto.HolderUUID, dto.SessionID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to release note lease. error: %w", err)
+	}
+	return nil
+}
+
+// Backfill recomputes derived fields of every stored note and returns how many notes were updated
+func (s service) Backfill(ctx context.Context) (updated int, err error) {
+	const pageSize = 100
+
+	afterUUID := ""
+	for {
+		notes, err := s.storage.FindPage(ctx, afterUUID, pageSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to get notes page. error: %w", err)
+		}
+		for _, n := range notes {
+			afterUUID = n.UUID
+			if n.Body == "" {
+				continue
+			}
+			n.Derive()
+			// a note edited since the page was read already has derived fields of its new body
+			written, err := s.storage.UpdateDerived(ctx, n)
+			if err != nil {
+				return updated, fmt.Errorf("failed to update note %s. error: %w", n.UUID, err)
+			}
+			if written {
+				updated++
+			}
+		}
+		if len(notes) < pageSize {
+			return updated, nil
+		}
+	}
+}
+
+func leaseHeldError(lease Lease) error {
+	return apperror.LeaseHeldError(fmt.Sprintf("note is being edited by user %s until %s",
+		lease.HolderUUID, lease.ExpiresAt.UTC().Format(time.RFC3339)))
+}
@@ -2,12 +2,36 @@ package note
 
 import (
 	"context"
+	"time"
 )
 
 type Storage interface {
 	Create(ctx context.Context, note Note) (string, error)
 	FindOne(ctx context.Context, uuid string) (Note, error)
 	FindByCategoryUUID(ctx context.Context, uuid string) ([]Note, error)
-	Update(ctx context.Context, note Note) error
+	// Update writes the non-empty fields of note unless a lease active at now is held by another
+	// session than sessionID of holderUUID. It returns that lease and false when nothing is written.
+	Update(ctx context.Context, note Note, holderUUID, sessionID string, now time.Time) (Lease, bool, error)
 	Delete(ctx context.Context, uuid string) error
+	// FindByOwner returns every full note of the owner ordered by uuid
+	FindByOwner(ctx context.Context, ownerUUID string) ([]Note, error)
+	// UsageByOwner counts notes of the owner and the total size of their bodies in bytes
+	UsageByOwner(ctx context.Context, ownerUUID string) (Usage, error)
+	// TagUsage counts notes of the owner per referenced tag
+	TagUsage(ctx context.Context, ownerUUID string) ([]TagUsage, error)
+	// ReplaceTags replaces sourceIDs with targetID in the tags of every note, keeping the first
+	// occurrence of each tag. It returns the number of notes changed.
+	ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error)
+	// AddTag appends tagID to the tags of the note unless it has it already or a lease is active
+	// at now. It returns false when the note is not changed, including when it is gone.
+	AddTag(ctx context.Context, uuid string, tagID int, now time.Time) (bool, error)
+	// FindPage returns up to limit full notes with uuid greater than afterUUID, ordered by uuid
+	FindPage(ctx context.Context, afterUUID string, limit int) ([]Note, error)
+	// UpdateDerived writes short body, word count, reading time, links and language of n unless the
+	// stored body is no longer n.Body. It returns false when nothing is written.
+	UpdateDerived(ctx context.Context, n Note) (bool, error)
+	// AcquireLease stores lease unless another holder has a lease that is still active at now.
+	// It returns the lease in effect afterwards and whether it is the requested one.
+	AcquireLease(ctx context.Context, uuid string, lease Lease, now time.Time) (Lease, bool, error)
+	ReleaseLease(ctx context.Context, uuid, holderUUID, sessionID string) error
 }
@@ -0,0 +1,543 @@
+// Package storagetest is the contract every note.Storage implementation has to fulfil.
+// Backend tests call Run with a fresh, empty storage.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+)
+
+func Run(t *testing.T, newStorage func(t *testing.T) note.Storage) {
+	t.Run("CreateAndFindOne", func(t *testing.T) { testCreateAndFindOne(t, newStorage(t)) })
+	t.Run("FindOneNotFound", func(t *testing.T) { testFindOneNotFound(t, newStorage(t)) })
+	t.Run("FindByCategoryUUID", func(t *testing.T) { testFindByCategoryUUID(t, newStorage(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newStorage(t)) })
+	t.Run("UpdateDerivedFields", func(t *testing.T) { testUpdateDerivedFields(t, newStorage(t)) })
+	t.Run("FindByOwner", func(t *testing.T) { testFindByOwner(t, newStorage(t)) })
+	t.Run("UsageByOwner", func(t *testing.T) { testUsageByOwner(t, newStorage(t)) })
+	t.Run("TagUsage", func(t *testing.T) { testTagUsage(t, newStorage(t)) })
+	t.Run("ReplaceTags", func(t *testing.T) { testReplaceTags(t, newStorage(t)) })
+	t.Run("AddTag", func(t *testing.T) { testAddTag(t, newStorage(t)) })
+	t.Run("FindPage", func(t *testing.T) { testFindPage(t, newStorage(t)) })
+	t.Run("UpdateDerived", func(t *testing.T) { testUpdateDerived(t, newStorage(t)) })
+	t.Run("UpdateNotFound", func(t *testing.T) { testUpdateNotFound(t, newStorage(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newStorage(t)) })
+	t.Run("Lease", func(t *testing.T) { testLease(t, newStorage(t)) })
+	t.Run("UpdateLeased", func(t *testing.T) { testUpdateLeased(t, newStorage(t)) })
+	t.Run("LeaseNotFound", func(t *testing.T) { testLeaseNotFound(t, newStorage(t)) })
+}
+
+func testCreateAndFindOne(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	in := note.Note{
+		Header:       "header",
+		Body:         "body",
+		ShortBody:    "bo",
+		CategoryUUID: "category",
+		Tags:         []int{1, 2},
+	}
+
+	uuid, err := storage.Create(ctx, in)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if uuid == "" {
+		t.Fatal("create returned empty uuid")
+	}
+
+	got, err := storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if got.UUID != uuid || got.Header != in.Header || got.Body != in.Body || got.CategoryUUID != in.CategoryUUID {
+		t.Fatalf("find one returned %+v, want %+v with uuid %s", got, in, uuid)
+	}
+	if !equalTags(got.Tags, in.Tags) {
+		t.Fatalf("find one returned tags %v, want %v", got.Tags, in.Tags)
+	}
+	if got.ShortBody != "" {
+		t.Fatalf("find one must not return short body, got %q", got.ShortBody)
+	}
+}
+
+func testFindOneNotFound(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "gone", CategoryUUID: "category"})
+	if err := storage.Delete(ctx, uuid); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if _, err := storage.FindOne(ctx, uuid); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("find one of deleted note returned %v, want %v", err, apperror.ErrNotFound)
+	}
+}
+
+func testFindByCategoryUUID(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	createNote(t, storage, note.Note{Header: "first", Body: "body", ShortBody: "short", CategoryUUID: "a"})
+	createNote(t, storage, note.Note{Header: "second", Body: "body", ShortBody: "short", CategoryUUID: "a"})
+	createNote(t, storage, note.Note{Header: "other", Body: "body", ShortBody: "short", CategoryUUID: "b"})
+
+	notes, err := storage.FindByCategoryUUID(ctx, "a")
+	if err != nil {
+		t.Fatalf("find by category: %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("find by category returned %d notes, want 2", len(notes))
+	}
+	for _, n := range notes {
+		if n.CategoryUUID != "a" {
+			t.Fatalf("find by category returned note of category %q", n.CategoryUUID)
+		}
+		if n.Body != "" {
+			t.Fatalf("find by category must not return body, got %q", n.Body)
+		}
+		if n.ShortBody != "short" {
+			t.Fatalf("find by category returned short body %q, want %q", n.ShortBody, "short")
+		}
+	}
+
+	notes, err = storage.FindByCategoryUUID(ctx, "missing")
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("find by unknown category: %v", err)
+	}
+	if len(notes) != 0 {
+		t.Fatalf("find by unknown category returned %d notes", len(notes))
+	}
+}
+
+func testUpdate(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", Body: "body", CategoryUUID: "a", Tags: []int{1}})
+
+	err := update(storage, note.Note{UUID: uuid, Body: "new body"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if got.Body != "new body" || got.Header != "header" || got.CategoryUUID != "a" || !equalTags(got.Tags, []int{1}) {
+		t.Fatalf("partial update changed untouched fields: %+v", got)
+	}
+
+	err = update(storage, note.Note{UUID: uuid, Tags: []int{2, 3}})
+	if err != nil {
+		t.Fatalf("update tags: %v", err)
+	}
+	got, err = storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if !equalTags(got.Tags, []int{2, 3}) {
+		t.Fatalf("update returned tags %v, want %v", got.Tags, []int{2, 3})
+	}
+}
+
+func testUpdateDerivedFields(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", Body: "see https://a.example", CategoryUUID: "a",
+		WordCount: 3, ReadingTime: 1, Links: []string{"https://a.example"}, Language: "en"})
+
+	err := update(storage, note.Note{UUID: uuid, Body: "...", Links: []string{}, Language: "und"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if got.WordCount != 0 || got.ReadingTime != 0 || len(got.Links) != 0 || got.Language != "und" {
+		t.Fatalf("derived fields were not rewritten together with the body: %+v", got)
+	}
+
+	err = update(storage, note.Note{UUID: uuid, Header: "new header"})
+	if err != nil {
+		t.Fatalf("update header: %v", err)
+	}
+	got, err = storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if got.Language != "und" {
+		t.Fatalf("update without body changed derived fields: %+v", got)
+	}
+}
+
+func testFindByOwner(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	first := createNote(t, storage, note.Note{Header: "a", Body: "first", CategoryUUID: "a", OwnerUUID: "alice"})
+	createNote(t, storage, note.Note{Header: "b", Body: "other", CategoryUUID: "a", OwnerUUID: "bob"})
+	second := createNote(t, storage, note.Note{Header: "c", Body: "second", CategoryUUID: "b", OwnerUUID: "alice"})
+
+	notes, err := storage.FindByOwner(ctx, "alice")
+	if err != nil {
+		t.Fatalf("find by owner: %v", err)
+	}
+	if len(notes) != 2 || notes[0].UUID != first || notes[1].UUID != second {
+		t.Fatalf("find by owner returned %+v, want notes %s and %s", notes, first, second)
+	}
+	if notes[0].Body != "first" || notes[0].OwnerUUID != "alice" {
+		t.Fatalf("find by owner returned a partial note %+v", notes[0])
+	}
+
+	notes, err = storage.FindByOwner(ctx, "nobody")
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("find by owner of unknown owner returned %+v, %v", notes, err)
+	}
+}
+
+func testUsageByOwner(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	createNote(t, storage, note.Note{Header: "a", Body: "12345", CategoryUUID: "a", OwnerUUID: "alice"})
+	createNote(t, storage, note.Note{Header: "b", Body: "привет", CategoryUUID: "a", OwnerUUID: "alice"})
+	createNote(t, storage, note.Note{Header: "c", Body: "other", CategoryUUID: "a", OwnerUUID: "bob"})
+
+	usage, err := storage.UsageByOwner(ctx, "alice")
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if usage.Notes != 2 || usage.BodyBytes != 5+12 {
+		t.Fatalf("usage is %+v, want 2 notes and 17 body bytes", usage)
+	}
+
+	usage, err = storage.UsageByOwner(ctx, "nobody")
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if usage.Notes != 0 || usage.BodyBytes != 0 {
+		t.Fatalf("usage of unknown owner is %+v, want zero", usage)
+	}
+}
+
+func testTagUsage(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	early := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	late := early.Add(48 * time.Hour)
+	createNote(t, storage, note.Note{Header: "a", Body: "a", CategoryUUID: "a", OwnerUUID: "alice", Tags: []int{1, 2, 1}, UpdatedAt: early})
+	createNote(t, storage, note.Note{Header: "b", Body: "b", CategoryUUID: "a", OwnerUUID: "alice", Tags: []int{2}, UpdatedAt: late})
+	createNote(t, storage, note.Note{Header: "c", Body: "c", CategoryUUID: "a", OwnerUUID: "alice", Tags: []int{3}})
+	createNote(t, storage, note.Note{Header: "d", Body: "d", CategoryUUID: "a", OwnerUUID: "bob", Tags: []int{1}, UpdatedAt: late})
+
+	usage, err := storage.TagUsage(ctx, "alice")
+	if err != nil {
+		t.Fatalf("tag usage: %v", err)
+	}
+	if len(usage) != 3 {
+		t.Fatalf("tag usage is %+v, want 3 tags", usage)
+	}
+	want := []struct {
+		notes    int
+		lastUsed time.Time
+	}{{1, early}, {2, late}, {1, time.Time{}}}
+	for i, u := range usage {
+		if u.TagID != i+1 || u.Notes != want[i].notes {
+			t.Fatalf("usage of tag %d is %+v, want %d notes", i+1, u, want[i].notes)
+		}
+		if want[i].lastUsed.IsZero() != (u.LastUsedAt == nil) ||
+			(u.LastUsedAt != nil && !u.LastUsedAt.Equal(want[i].lastUsed)) {
+			t.Fatalf("tag %d last used at %v, want %v", u.TagID, u.LastUsedAt, want[i].lastUsed)
+		}
+	}
+
+	usage, err = storage.TagUsage(ctx, "nobody")
+	if err != nil || len(usage) != 0 {
+		t.Fatalf("tag usage of unknown owner is %+v, %v; want none", usage, err)
+	}
+}
+
+func testReplaceTags(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	both := createNote(t, storage, note.Note{Header: "a", Body: "a", CategoryUUID: "a", Tags: []int{5, 1, 7, 2}})
+	target := createNote(t, storage, note.Note{Header: "b", Body: "b", CategoryUUID: "a", Tags: []int{7, 2}})
+	other := createNote(t, storage, note.Note{Header: "c", Body: "c", CategoryUUID: "a", Tags: []int{3}})
+
+	changed, err := storage.ReplaceTags(ctx, []int{1, 2}, 7)
+	if err != nil {
+		t.Fatalf("replace tags: %v", err)
+	}
+	if changed != 2 {
+		t.Fatalf("replace tags changed %d notes, want 2", changed)
+	}
+
+	for uuid, want := range map[string][]int{both: {5, 7}, target: {7}, other: {3}} {
+		got, err := storage.FindOne(ctx, uuid)
+		if err != nil {
+			t.Fatalf("find one: %v", err)
+		}
+		if !equalTags(got.Tags, want) {
+			t.Fatalf("tags after replace are %v, want %v", got.Tags, want)
+		}
+	}
+
+	changed, err = storage.ReplaceTags(ctx, []int{1, 2}, 7)
+	if err != nil {
+		t.Fatalf("repeat replace tags: %v", err)
+	}
+	if changed != 0 {
+		t.Fatalf("repeated replace changed %d notes, want 0", changed)
+	}
+}
+
+func testAddTag(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", CategoryUUID: "a", Tags: []int{1}})
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	if added, err := storage.AddTag(ctx, uuid, 2, now); err != nil || !added {
+		t.Fatalf("add tag: added %v, error %v", added, err)
+	}
+	if added, err := storage.AddTag(ctx, uuid, 2, now); err != nil || added {
+		t.Fatalf("add present tag: added %v, error %v", added, err)
+	}
+	got, err := storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if !equalTags(got.Tags, []int{1, 2}) {
+		t.Fatalf("tags = %v, want %v", got.Tags, []int{1, 2})
+	}
+
+	lease := note.Lease{HolderUUID: "user1", SessionID: "s1", ExpiresAt: now.Add(time.Minute)}
+	if _, acquired, err := storage.AcquireLease(ctx, uuid, lease, now); err != nil || !acquired {
+		t.Fatalf("acquire lease: acquired %v, error %v", acquired, err)
+	}
+	if added, err := storage.AddTag(ctx, uuid, 3, now); err != nil || added {
+		t.Fatalf("add tag to leased note: added %v, error %v", added, err)
+	}
+	if added, err := storage.AddTag(ctx, uuid, 3, now.Add(2*time.Minute)); err != nil || !added {
+		t.Fatalf("add tag after lease expired: added %v, error %v", added, err)
+	}
+
+	if err = storage.Delete(ctx, uuid); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if added, err := storage.AddTag(ctx, uuid, 4, now); err != nil || added {
+		t.Fatalf("add tag to deleted note: added %v, error %v", added, err)
+	}
+}
+
+func testFindPage(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	want := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		want[createNote(t, storage, note.Note{Header: "header", Body: "body", CategoryUUID: "a"})] = true
+	}
+
+	after := ""
+	seen := make(map[string]bool)
+	for {
+		notes, err := storage.FindPage(ctx, after, 2)
+		if err != nil {
+			t.Fatalf("find page: %v", err)
+		}
+		if len(notes) > 2 {
+			t.Fatalf("find page returned %d notes, limit is 2", len(notes))
+		}
+		for _, n := range notes {
+			if seen[n.UUID] {
+				t.Fatalf("note %s returned twice", n.UUID)
+			}
+			if n.Body != "body" {
+				t.Fatalf("find page returned body %q, want %q", n.Body, "body")
+			}
+			seen[n.UUID] = true
+			after = n.UUID
+		}
+		if len(notes) < 2 {
+			break
+		}
+	}
+	for uuid := range want {
+		if !seen[uuid] {
+			t.Fatalf("note %s was not paged", uuid)
+		}
+	}
+}
+
+func testUpdateDerived(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", Body: "see https://a.example", CategoryUUID: "a"})
+
+	derived := note.Note{UUID: uuid, Body: "see https://a.example", ShortBody: "see",
+		WordCount: 2, ReadingTime: 1, Links: []string{"https://a.example"}, Language: "en"}
+	if written, err := storage.UpdateDerived(ctx, derived); err != nil || !written {
+		t.Fatalf("update derived: written %v, error %v", written, err)
+	}
+	got, err := storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if got.Body != derived.Body || got.Header != "header" || got.WordCount != 2 || got.ReadingTime != 1 ||
+		len(got.Links) != 1 || got.Language != "en" {
+		t.Fatalf("update derived stored %+v", got)
+	}
+
+	if err = update(storage, note.Note{UUID: uuid, Body: "edited"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	derived.Language = "de"
+	if written, err := storage.UpdateDerived(ctx, derived); err != nil || written {
+		t.Fatalf("update derived of an edited body: written %v, error %v", written, err)
+	}
+	if got, _ = storage.FindOne(ctx, uuid); got.Body != "edited" || got.Language == "de" {
+		t.Fatalf("derived fields of a stale body were written: %+v", got)
+	}
+}
+
+func testUpdateNotFound(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "gone", CategoryUUID: "a"})
+	if err := storage.Delete(ctx, uuid); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if err := update(storage, note.Note{UUID: uuid, Header: "new"}); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("update of deleted note returned %v, want %v", err, apperror.ErrNotFound)
+	}
+}
+
+func testDelete(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", CategoryUUID: "a"})
+
+	if err := storage.Delete(ctx, uuid); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := storage.Delete(ctx, uuid); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("second delete returned %v, want %v", err, apperror.ErrNotFound)
+	}
+}
+
+func testLease(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", CategoryUUID: "a"})
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	first := note.Lease{HolderUUID: "user1", SessionID: "s1", ExpiresAt: now.Add(time.Minute)}
+	second := note.Lease{HolderUUID: "user2", SessionID: "s2", ExpiresAt: now.Add(time.Minute)}
+
+	if _, acquired, err := storage.AcquireLease(ctx, uuid, first, now); err != nil || !acquired {
+		t.Fatalf("acquire free lease: acquired %v, error %v", acquired, err)
+	}
+	got, err := storage.FindOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if !got.Lease.IsHeldBy("user1", "s1") || !got.Lease.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Fatalf("find one returned lease %+v, want %+v", got.Lease, first)
+	}
+
+	current, acquired, err := storage.AcquireLease(ctx, uuid, second, now)
+	if err != nil {
+		t.Fatalf("acquire held lease: %v", err)
+	}
+	if acquired || current.HolderUUID != "user1" {
+		t.Fatalf("acquire held lease: acquired %v, current %+v", acquired, current)
+	}
+
+	renewed := first
+	renewed.ExpiresAt = now.Add(2 * time.Minute)
+	if _, acquired, err = storage.AcquireLease(ctx, uuid, renewed, now); err != nil || !acquired {
+		t.Fatalf("renew lease: acquired %v, error %v", acquired, err)
+	}
+
+	if _, acquired, err = storage.AcquireLease(ctx, uuid, second, now.Add(3*time.Minute)); err != nil || !acquired {
+		t.Fatalf("acquire expired lease: acquired %v, error %v", acquired, err)
+	}
+
+	if err = storage.ReleaseLease(ctx, uuid, "user1", "s1"); err != nil {
+		t.Fatalf("release foreign lease: %v", err)
+	}
+	if got, _ = storage.FindOne(ctx, uuid); !got.Lease.IsHeldBy("user2", "s2") {
+		t.Fatalf("release by another holder dropped lease %+v", got.Lease)
+	}
+	if err = storage.ReleaseLease(ctx, uuid, "user2", "s2"); err != nil {
+		t.Fatalf("release lease: %v", err)
+	}
+	if got, _ = storage.FindOne(ctx, uuid); got.Lease != nil {
+		t.Fatalf("released lease is still stored: %+v", got.Lease)
+	}
+}
+
+func testUpdateLeased(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "header", CategoryUUID: "a"})
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	lease := note.Lease{HolderUUID: "user1", SessionID: "s1", ExpiresAt: now.Add(time.Minute)}
+	if _, acquired, err := storage.AcquireLease(ctx, uuid, lease, now); err != nil || !acquired {
+		t.Fatalf("acquire lease: acquired %v, error %v", acquired, err)
+	}
+
+	held, updated, err := storage.Update(ctx, note.Note{UUID: uuid, Header: "by user2"}, "user2", "s2", now)
+	if err != nil {
+		t.Fatalf("update leased note: %v", err)
+	}
+	if updated || !held.IsHeldBy("user1", "s1") {
+		t.Fatalf("update leased note: updated %v, held %+v", updated, held)
+	}
+	if _, updated, _ = storage.Update(ctx, note.Note{UUID: uuid, Header: "by s2"}, "user1", "s2", now); updated {
+		t.Fatal("another session of the holder updated the leased note")
+	}
+	if got, _ := storage.FindOne(ctx, uuid); got.Header != "header" {
+		t.Fatalf("refused update wrote header %q", got.Header)
+	}
+
+	if _, updated, err = storage.Update(ctx, note.Note{UUID: uuid, Header: "by user1"}, "user1", "s1", now); err != nil || !updated {
+		t.Fatalf("update by holder: updated %v, error %v", updated, err)
+	}
+	if _, updated, err = storage.Update(ctx, note.Note{UUID: uuid, Header: "after"}, "user2", "s2", now.Add(2*time.Minute)); err != nil || !updated {
+		t.Fatalf("update after lease expired: updated %v, error %v", updated, err)
+	}
+	if got, _ := storage.FindOne(ctx, uuid); got.Header != "after" {
+		t.Fatalf("header = %q, want %q", got.Header, "after")
+	}
+}
+
+func testLeaseNotFound(t *testing.T, storage note.Storage) {
+	ctx := context.Background()
+	uuid := createNote(t, storage, note.Note{Header: "gone", CategoryUUID: "a"})
+	if err := storage.Delete(ctx, uuid); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	lease := note.Lease{HolderUUID: "user1", SessionID: "s1", ExpiresAt: time.Now().Add(time.Minute)}
+	if _, _, err := storage.AcquireLease(ctx, uuid, lease, time.Now()); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("acquire lease of deleted note returned %v, want %v", err, apperror.ErrNotFound)
+	}
+}
+
+// update writes n as a client holding no lease
+func update(storage note.Storage, n note.Note) error {
+	_, updated, err := storage.Update(context.Background(), n, "", "", time.Now())
+	if err == nil && !updated {
+		return errors.New("note is leased")
+	}
+	return err
+}
+
+func createNote(t *testing.T, storage note.Storage, n note.Note) string {
+	t.Helper()
+	uuid, err := storage.Create(context.Background(), n)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return uuid
+}
+
+func equalTags(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package owner
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"net/http"
+)
+
+// ownerURL is called by the gateway when the account of the owner is deleted
+const ownerURL = "/api/owners/:uuid"
+
+type Handler struct {
+	Logger       logging.Logger
+	OwnerService Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodDelete, ownerURL, apperror.Middleware(h.PurgeOwner))
+}
+
+func (h *Handler) PurgeOwner(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if _, err := h.OwnerService.Purge(r.Context(), params.ByName("uuid")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package owner
+
+// Purged counts what a purge removed
+type Purged struct {
+	Notes    int `json:"notes"`
+	Comments int `json:"comments"`
+	Rules    int `json:"rules"`
+	// AnonymizedComments were left by the owner on notes of others
+	AnonymizedComments int `json:"anonymized_comments"`
+}
@@ -0,0 +1,95 @@
+package owner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+)
+
+var _ Service = &service{}
+
+type service struct {
+	notes    note.Storage
+	comments comment.Storage
+	rules    rule.Storage
+	logger   logging.Logger
+}
+
+func NewService(noteStorage note.Storage, commentStorage comment.Storage, ruleStorage rule.Storage, logger logging.Logger) (Service, error) {
+	return &service{
+		notes:    noteStorage,
+		comments: commentStorage,
+		rules:    ruleStorage,
+		logger:   logger,
+	}, nil
+}
+
+type Service interface {
+	// Purge removes everything the owner keeps in the service. Only what is still there is deleted,
+	// so a purge that failed half way is simply run again. Comments the owner left on notes of
+	// others stay in their threads without author and text.
+	Purge(ctx context.Context, ownerUUID string) (Purged, error)
+}
+
+func (s *service) Purge(ctx context.Context, ownerUUID string) (purged Purged, err error) {
+	if ownerUUID == "" {
+		return purged, apperror.BadRequestError("owner uuid is required")
+	}
+
+	// rules go first, they would tag notes created while the purge runs
+	rules, err := s.rules.FindByOwner(ctx, ownerUUID)
+	if err != nil {
+		return purged, fmt.Errorf("failed to find rules of owner. error: %w", err)
+	}
+	for _, r := range rules {
+		if err = ignoreNotFound(s.rules.Delete(ctx, r.UUID)); err != nil {
+			return purged, fmt.Errorf("failed to delete rule %s. error: %w", r.UUID, err)
+		}
+		purged.Rules++
+	}
+
+	notes, err := s.notes.FindByOwner(ctx, ownerUUID)
+	if err != nil {
+		return purged, fmt.Errorf("failed to find notes of owner. error: %w", err)
+	}
+	for _, n := range notes {
+		comments, err := s.comments.FindByNoteUUID(ctx, n.UUID)
+		if err != nil {
+			return purged, fmt.Errorf("failed to find comments of note %s. error: %w", n.UUID, err)
+		}
+		for _, c := range comments {
+			// deleting a thread removes its replies, they are not found afterwards
+			if err = ignoreNotFound(s.comments.Delete(ctx, c.UUID)); err != nil {
+				return purged, fmt.Errorf("failed to delete comment %s. error: %w", c.UUID, err)
+			}
+			purged.Comments++
+		}
+		// the note goes after its comments, a retry finds comments through the note
+		if err = ignoreNotFound(s.notes.Delete(ctx, n.UUID)); err != nil {
+			return purged, fmt.Errorf("failed to delete note %s. error: %w", n.UUID, err)
+		}
+		purged.Notes++
+	}
+
+	// with the notes gone only comments on notes of others are left
+	purged.AnonymizedComments, err = s.comments.Anonymize(ctx, ownerUUID, comment.DeletedBody)
+	if err != nil {
+		return purged, fmt.Errorf("failed to anonymize comments of owner. error: %w", err)
+	}
+
+	s.logger.Infof("purged owner %s: %d notes, %d comments, %d rules, %d comments anonymized", ownerUUID,
+		purged.Notes, purged.Comments, purged.Rules, purged.AnonymizedComments)
+	return purged, nil
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil
+	}
+	return err
+}
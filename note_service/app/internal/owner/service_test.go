@@ -0,0 +1,72 @@
+package owner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	commentdb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	notedb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/owner"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	ruledb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+)
+
+func TestPurgeRemovesOnlyTheOwnerData(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	notes := notedb.NewMemoryStorage(logger)
+	comments := commentdb.NewMemoryStorage(logger)
+	rules := ruledb.NewMemoryStorage(logger)
+
+	mustCreate := func(_ string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	aliceNote, err := notes.Create(ctx, note.Note{OwnerUUID: "alice", Header: "a"})
+	mustCreate(aliceNote, err)
+	bobNote, err := notes.Create(ctx, note.Note{OwnerUUID: "bob", Header: "b"})
+	mustCreate(bobNote, err)
+	thread, err := comments.Create(ctx, comment.Comment{NoteUUID: aliceNote, AuthorUUID: "bob", Body: "?"})
+	mustCreate(thread, err)
+	mustCreate(comments.Create(ctx, comment.Comment{NoteUUID: aliceNote, AuthorUUID: "alice", Body: "!", ParentUUID: thread}))
+	mustCreate(comments.Create(ctx, comment.Comment{NoteUUID: bobNote, AuthorUUID: "alice", Body: "hi"}))
+	mustCreate(rules.Create(ctx, rule.Rule{OwnerUUID: "alice", Name: "r", TagID: 1}))
+	mustCreate(rules.Create(ctx, rule.Rule{OwnerUUID: "bob", Name: "r", TagID: 2}))
+
+	service, err := owner.NewService(notes, comments, rules, logger)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	purged, err := service.Purge(ctx, "alice")
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if purged.Notes != 1 || purged.Rules != 1 || purged.Comments != 2 || purged.AnonymizedComments != 1 {
+		t.Fatalf("purged %+v", purged)
+	}
+
+	if left, _ := notes.FindByOwner(ctx, "alice"); len(left) != 0 {
+		t.Fatalf("notes of alice left: %+v", left)
+	}
+	if left, _ := comments.FindByNoteUUID(ctx, aliceNote); len(left) != 0 {
+		t.Fatalf("comments of the deleted note left: %+v", left)
+	}
+	if left, _ := rules.FindByOwner(ctx, "bob"); len(left) != 1 {
+		t.Fatalf("rules of bob: %+v", left)
+	}
+	left, _ := comments.FindByNoteUUID(ctx, bobNote)
+	if len(left) != 1 || left[0].AuthorUUID != "" || left[0].Body != comment.DeletedBody {
+		t.Fatalf("comments on the note of bob: %+v", left)
+	}
+
+	// a second run finds nothing to do
+	if purged, err = service.Purge(ctx, "alice"); err != nil || purged != (owner.Purged{}) {
+		t.Fatalf("second purge: %+v %v", purged, err)
+	}
+}
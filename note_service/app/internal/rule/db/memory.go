@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ rule.Storage = &memoryDB{}
+
+// memoryDB keeps rules in process memory, uuids are object id hexes like in mongo
+type memoryDB struct {
+	mu     sync.RWMutex
+	rules  map[string]rule.Rule
+	logger logging.Logger
+}
+
+func NewMemoryStorage(logger logging.Logger) rule.Storage {
+	return &memoryDB{
+		rules:  make(map[string]rule.Rule),
+		logger: logger,
+	}
+}
+
+func (s *memoryDB) Create(ctx context.Context, r rule.Rule) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.UUID = primitive.NewObjectID().Hex()
+	s.rules[r.UUID] = r
+
+	return r.UUID, nil
+}
+
+func (s *memoryDB) FindOne(ctx context.Context, uuid string) (r rule.Rule, err error) {
+	if _, err = primitive.ObjectIDFromHex(uuid); err != nil {
+		return r, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rules[uuid]
+	if !ok {
+		return r, apperror.ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *memoryDB) FindByOwner(ctx context.Context, ownerUUID string) (rules []rule.Rule, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.rules {
+		if r.OwnerUUID == ownerUUID {
+			rules = append(rules, r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].UUID < rules[j].UUID
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules, nil
+}
+
+func (s *memoryDB) Update(ctx context.Context, r rule.Rule) error {
+	if _, err := primitive.ObjectIDFromHex(r.UUID); err != nil {
+		return fmt.Errorf("failed to parse rule uuid due to error %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.rules[r.UUID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Name = r.Name
+	stored.BodyPattern = r.BodyPattern
+	stored.CategoryUUID = r.CategoryUUID
+	stored.TagID = r.TagID
+	stored.UpdatedAt = r.UpdatedAt
+	s.rules[r.UUID] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Delete(ctx context.Context, uuid string) error {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return fmt.Errorf("failed to parse rule uuid")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rules[uuid]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(s.rules, uuid)
+
+	return nil
+}
+
+func (s *memoryDB) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int, now time.Time) (changed int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for uuid, r := range s.rules {
+		for _, id := range sourceIDs {
+			if r.TagID == id {
+				r.TagID = targetID
+				r.UpdatedAt = now
+				s.rules[uuid] = r
+				changed++
+				break
+			}
+		}
+	}
+	return changed, nil
+}
+
+func (s *memoryDB) DeleteByTag(ctx context.Context, ownerUUID string, tagID int) (deleted int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for uuid, r := range s.rules {
+		if r.OwnerUUID == ownerUUID && r.TagID == tagID {
+			delete(s.rules, uuid)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS rules (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_uuid    TEXT        NOT NULL,
+    name          TEXT        NOT NULL,
+    body_pattern  TEXT        NOT NULL DEFAULT '',
+    category_uuid TEXT        NOT NULL DEFAULT '',
+    tag_id        INTEGER     NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rules_owner_uuid_idx ON rules (owner_uuid, created_at);
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var _ rule.Storage = &db{}
+
+type db struct {
+	collection *mongo.Collection
+	logger     logging.Logger
+}
+
+func NewStorage(storage *mongo.Database, collection string, logger logging.Logger) rule.Storage {
+	return &db{
+		collection: storage.Collection(collection),
+		logger:     logger,
+	}
+}
+
+func (s *db) Create(ctx context.Context, r rule.Rule) (uuid string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.InsertOne(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if ok {
+		return oid.Hex(), nil
+	}
+	return "", fmt.Errorf("failed to convet objectid to hex")
+}
+
+func (s *db) FindOne(ctx context.Context, uuid string) (r rule.Rule, err error) {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return r, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err = s.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&r); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return r, apperror.ErrNotFound
+		}
+		return r, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *db) FindByOwner(ctx context.Context, ownerUUID string) (rules []rule.Rule, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := s.collection.Find(ctx, bson.M{"owner_uuid": ownerUUID}, opts)
+	if err != nil {
+		return rules, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &rules); err != nil {
+		return rules, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *db) Update(ctx context.Context, r rule.Rule) error {
+	objectID, err := primitive.ObjectIDFromHex(r.UUID)
+	if err != nil {
+		return fmt.Errorf("failed to parse rule uuid due to error %w", err)
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":          r.Name,
+			"body_pattern":  r.BodyPattern,
+			"category_uuid": r.CategoryUUID,
+			"tag_id":        r.TagID,
+			"updated_at":    r.UpdatedAt,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *db) Delete(ctx context.Context, uuid string) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to parse rule uuid")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *db) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateMany(ctx, bson.M{"tag_id": bson.M{"$in": sourceIDs}},
+		bson.M{"$set": bson.M{"tag_id": targetID, "updated_at": now}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.ModifiedCount), nil
+}
+
+func (s *db) DeleteByTag(ctx context.Context, ownerUUID string, tagID int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	result, err := s.collection.DeleteMany(ctx, bson.M{"owner_uuid": ownerUUID, "tag_id": tagID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.DeletedCount), nil
+}
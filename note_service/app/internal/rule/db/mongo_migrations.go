@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/mongodb/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations returns the schema history of the rules collection. It shares
+// schema_migrations with the notes and comments collections, so versions continue theirs.
+func MongoMigrations(collection string) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     4,
+			Description: "index rules by owner_uuid",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "owner_uuid", Value: 1}, {Key: "created_at", Value: 1}},
+					Options: options.Index().SetName("owner_uuid_created_at"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "owner_uuid_created_at")
+				return err
+			},
+		},
+	}
+}
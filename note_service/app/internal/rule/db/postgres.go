@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/postgresql"
+	"io/fs"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+// invalid_text_representation, returned when uuid is not a valid UUID
+const pgInvalidTextRepresentation = "22P02"
+
+const selectRule = `SELECT id::text, owner_uuid, name, body_pattern, category_uuid, tag_id,
+	created_at, updated_at FROM rules`
+
+var _ rule.Storage = &pgDB{}
+
+type pgDB struct {
+	pool   *pgxpool.Pool
+	logger logging.Logger
+}
+
+func NewPostgresStorage(pool *pgxpool.Pool, logger logging.Logger) rule.Storage {
+	return &pgDB{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// MigratePostgres brings the rules schema up to date. Its file names are prefixed
+// with rules_ because schema_migrations is shared with the notes schema.
+func MigratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := fs.Sub(postgresMigrations, "migrations")
+	if err != nil {
+		return err
+	}
+	return postgresql.Migrate(ctx, pool, migrations)
+}
+
+func (s *pgDB) Create(ctx context.Context, r rule.Rule) (uuid string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	q := `INSERT INTO rules (owner_uuid, name, body_pattern, category_uuid, tag_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id::text`
+	err = s.pool.QueryRow(ctx, q, r.OwnerUUID, r.Name, r.BodyPattern, r.CategoryUUID, r.TagID,
+		r.CreatedAt, r.UpdatedAt).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (s *pgDB) FindOne(ctx context.Context, uuid string) (r rule.Rule, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	r, err = scanRule(s.pool.QueryRow(ctx, selectRule+` WHERE id = $1`, uuid))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r, apperror.ErrNotFound
+		}
+		if isInvalidUUID(err) {
+			return r, fmt.Errorf("failed to convert uuid. error: %w", err)
+		}
+		return r, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *pgDB) FindByOwner(ctx context.Context, ownerUUID string) (rules []rule.Rule, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, selectRule+` WHERE owner_uuid = $1 ORDER BY created_at, id`, ownerUUID)
+	if err != nil {
+		return rules, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return rules, fmt.Errorf("failed to decode row. error: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err = rows.Err(); err != nil {
+		return rules, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (s *pgDB) Update(ctx context.Context, r rule.Rule) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	q := `UPDATE rules SET name = $2, body_pattern = $3, category_uuid = $4, tag_id = $5, updated_at = $6
+		WHERE id = $1`
+	result, err := s.pool.Exec(ctx, q, r.UUID, r.Name, r.BodyPattern, r.CategoryUUID, r.TagID, r.UpdatedAt)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return fmt.Errorf("failed to parse rule uuid due to error %w", err)
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *pgDB) Delete(ctx context.Context, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.pool.Exec(ctx, `DELETE FROM rules WHERE id = $1`, uuid)
+	if err != nil {
+		if isInvalidUUID(err) {
+			return fmt.Errorf("failed to parse rule uuid")
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *pgDB) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	q := `UPDATE rules SET tag_id = $2, updated_at = $3 WHERE tag_id = ANY($1::int[])`
+	result, err := s.pool.Exec(ctx, q, sourceIDs, targetID, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (s *pgDB) DeleteByTag(ctx context.Context, ownerUUID string, tagID int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := s.pool.Exec(ctx, `DELETE FROM rules WHERE owner_uuid = $1 AND tag_id = $2`, ownerUUID, tagID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func scanRule(row pgx.Row) (r rule.Rule, err error) {
+	err = row.Scan(&r.UUID, &r.OwnerUUID, &r.Name, &r.BodyPattern, &r.CategoryUUID, &r.TagID,
+		&r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+func isInvalidUUID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgInvalidTextRepresentation
+}
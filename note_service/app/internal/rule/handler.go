@@ -0,0 +1,177 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"net/http"
+	"strconv"
+)
+
+const (
+	rulesURL  = "/api/rules"
+	ruleURL   = "/api/rules/:uuid"
+	dryRunURL = "/api/rules/:uuid/dry-run"
+	applyURL  = "/api/rules/:uuid/apply"
+	jobURL    = "/api/rule-jobs/:uuid"
+	tagURL    = "/api/tag-rules/:tag_id"
+)
+
+// header the gateway uses to pass the authenticated user
+const userUUIDHeader = "X-User-UUID"
+
+type Handler struct {
+	Logger      logging.Logger
+	RuleService Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, rulesURL, apperror.Middleware(h.GetRules))
+	router.HandlerFunc(http.MethodPost, rulesURL, apperror.Middleware(h.CreateRule))
+	router.HandlerFunc(http.MethodGet, ruleURL, apperror.Middleware(h.GetRule))
+	router.HandlerFunc(http.MethodPatch, ruleURL, apperror.Middleware(h.PartiallyUpdateRule))
+	router.HandlerFunc(http.MethodDelete, ruleURL, apperror.Middleware(h.DeleteRule))
+	router.HandlerFunc(http.MethodGet, dryRunURL, apperror.Middleware(h.DryRun))
+	router.HandlerFunc(http.MethodPost, applyURL, apperror.Middleware(h.Apply))
+	router.HandlerFunc(http.MethodGet, jobURL, apperror.Middleware(h.GetJob))
+	router.HandlerFunc(http.MethodDelete, tagURL, apperror.Middleware(h.DeleteTagRules))
+}
+
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	rules, err := h.RuleService.GetByOwner(r.Context(), r.Header.Get(userUUIDHeader))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, rules)
+}
+
+func (h *Handler) GetRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	rule, err := h.RuleService.GetOne(r.Context(), r.Header.Get(userUUIDHeader), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, rule)
+}
+
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var dto CreateRuleDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid data")
+	}
+	dto.OwnerUUID = r.Header.Get(userUUIDHeader)
+
+	ruleUUID, err := h.RuleService.Create(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", rulesURL, ruleUUID))
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) PartiallyUpdateRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto UpdateRuleDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid data")
+	}
+	dto.UUID = params.ByName("uuid")
+	dto.OwnerUUID = r.Header.Get(userUUIDHeader)
+
+	if err := h.RuleService.Update(r.Context(), dto); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	if err := h.RuleService.Delete(r.Context(), r.Header.Get(userUUIDHeader), params.ByName("uuid")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// DeleteTagRules drops the rules of the caller tagging with a tag that was deleted
+func (h *Handler) DeleteTagRules(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	tagID, err := strconv.Atoi(params.ByName("tag_id"))
+	if err != nil {
+		return apperror.BadRequestError("invalid tag id")
+	}
+	if _, err = h.RuleService.DeleteByTag(r.Context(), r.Header.Get(userUUIDHeader), tagID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DryRun(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	notes, err := h.RuleService.DryRun(r.Context(), r.Header.Get(userUUIDHeader), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, notes)
+}
+
+func (h *Handler) Apply(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	jobUUID, err := h.RuleService.Apply(r.Context(), r.Header.Get(userUUIDHeader), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/rule-jobs/%s", jobUUID))
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	job, err := h.RuleService.GetJob(r.Context(), r.Header.Get(userUUIDHeader), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, job)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	return nil
+}
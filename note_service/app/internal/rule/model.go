@@ -0,0 +1,108 @@
+package rule
+
+import (
+	"regexp"
+	"time"
+
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+)
+
+// Rule tags the notes of its owner with TagID when every condition it sets holds: the body
+// matches BodyPattern and the note is in CategoryUUID.
+type Rule struct {
+	UUID         string    `json:"uuid" bson:"_id,omitempty"`
+	OwnerUUID    string    `json:"owner_uuid" bson:"owner_uuid"`
+	Name         string    `json:"name" bson:"name"`
+	BodyPattern  string    `json:"body_pattern,omitempty" bson:"body_pattern,omitempty"`
+	CategoryUUID string    `json:"category_uuid,omitempty" bson:"category_uuid,omitempty"`
+	TagID        int       `json:"tag_id" bson:"tag_id"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Matches tells whether the rule tags n. BodyPattern has to be valid, rules are validated
+// before they are stored.
+func (r Rule) Matches(n note.Note) bool {
+	if r.CategoryUUID != "" && r.CategoryUUID != n.CategoryUUID {
+		return false
+	}
+	if r.BodyPattern != "" {
+		re, err := regexp.Compile(r.BodyPattern)
+		if err != nil || !re.MatchString(n.Body) {
+			return false
+		}
+	}
+	return true
+}
+
+// Affects tells whether applying the rule changes n
+func (r Rule) Affects(n note.Note) bool {
+	if !r.Matches(n) {
+		return false
+	}
+	for _, id := range n.Tags {
+		if id == r.TagID {
+			return false
+		}
+	}
+	return true
+}
+
+type CreateRuleDTO struct {
+	OwnerUUID    string `json:"-"`
+	Name         string `json:"name"`
+	BodyPattern  string `json:"body_pattern,omitempty"`
+	CategoryUUID string `json:"category_uuid,omitempty"`
+	TagID        int    `json:"tag_id"`
+}
+
+// UpdateRuleDTO changes the fields that are set, an empty condition removes it
+type UpdateRuleDTO struct {
+	UUID         string  `json:"-"`
+	OwnerUUID    string  `json:"-"`
+	Name         string  `json:"name,omitempty"`
+	BodyPattern  *string `json:"body_pattern,omitempty"`
+	CategoryUUID *string `json:"category_uuid,omitempty"`
+	TagID        int     `json:"tag_id,omitempty"`
+}
+
+func NewRule(dto CreateRuleDTO, now time.Time) Rule {
+	return Rule{
+		OwnerUUID:    dto.OwnerUUID,
+		Name:         dto.Name,
+		BodyPattern:  dto.BodyPattern,
+		CategoryUUID: dto.CategoryUUID,
+		TagID:        dto.TagID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// AffectedNote is a note a dry run of the rule would tag
+type AffectedNote struct {
+	UUID   string `json:"uuid"`
+	Header string `json:"header"`
+}
+
+type JobStatus string
+
+const (
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Job applies a rule to the notes that existed before it
+type Job struct {
+	UUID         string    `json:"uuid"`
+	RuleUUID     string    `json:"rule_uuid"`
+	OwnerUUID    string    `json:"owner_uuid"`
+	Status       JobStatus `json:"status"`
+	NotesChanged int       `json:"notes_changed"`
+	// NotesSkipped were being edited, tagged or deleted since they were found, applying the rule
+	// again tags the edited ones
+	NotesSkipped int        `json:"notes_skipped"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,318 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var _ Service = &service{}
+var _ note.AutoTagger = &service{}
+
+type service struct {
+	storage Storage
+	notes   note.Storage
+	logger  logging.Logger
+
+	// apply jobs are kept in memory, they only matter while somebody waits for them
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// jobTTL is how long a finished apply job can be looked up
+const jobTTL = time.Hour
+
+func NewService(ruleStorage Storage, noteStorage note.Storage, logger logging.Logger) (Service, error) {
+	return &service{
+		storage: ruleStorage,
+		notes:   noteStorage,
+		logger:  logger,
+		jobs:    make(map[string]Job),
+	}, nil
+}
+
+type Service interface {
+	Create(ctx context.Context, dto CreateRuleDTO) (string, error)
+	GetOne(ctx context.Context, ownerUUID, uuid string) (Rule, error)
+	GetByOwner(ctx context.Context, ownerUUID string) ([]Rule, error)
+	Update(ctx context.Context, dto UpdateRuleDTO) error
+	Delete(ctx context.Context, ownerUUID, uuid string) error
+	// AutoTags returns the tags of the rules of the note owner that match the note
+	AutoTags(ctx context.Context, n note.Note) ([]int, error)
+	// DryRun lists the existing notes of the owner the rule would tag
+	DryRun(ctx context.Context, ownerUUID, uuid string) ([]AffectedNote, error)
+	// Apply starts tagging the existing notes of the owner the rule matches and returns the job uuid
+	Apply(ctx context.Context, ownerUUID, uuid string) (string, error)
+	GetJob(ctx context.Context, ownerUUID, uuid string) (Job, error)
+	// ReplaceTags makes the rules tagging with any of sourceIDs tag with targetID, tags are merged
+	ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error)
+	// DeleteByTag deletes the rules of the owner tagging with tagID, the tag is deleted
+	DeleteByTag(ctx context.Context, ownerUUID string, tagID int) (int, error)
+}
+
+func (s *service) Create(ctx context.Context, dto CreateRuleDTO) (string, error) {
+	if dto.OwnerUUID == "" {
+		return "", apperror.BadRequestError("rule owner is unknown")
+	}
+	r := NewRule(dto, time.Now().UTC())
+	if err := validate(r); err != nil {
+		return "", err
+	}
+
+	ruleUUID, err := s.storage.Create(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to create rule. error: %w", err)
+	}
+	return ruleUUID, nil
+}
+
+func (s *service) GetOne(ctx context.Context, ownerUUID, uuid string) (r Rule, err error) {
+	r, err = s.storage.FindOne(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return r, err
+		}
+		return r, fmt.Errorf("failed to find rule by uuid. error: %w", err)
+	}
+	// rules of other users are not there for the caller
+	if r.OwnerUUID != ownerUUID {
+		return Rule{}, apperror.ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *service) GetByOwner(ctx context.Context, ownerUUID string) ([]Rule, error) {
+	if ownerUUID == "" {
+		return nil, apperror.BadRequestError("rule owner is unknown")
+	}
+	rules, err := s.storage.FindByOwner(ctx, ownerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rules of owner. error: %w", err)
+	}
+	if rules == nil {
+		rules = []Rule{}
+	}
+	return rules, nil
+}
+
+func (s *service) Update(ctx context.Context, dto UpdateRuleDTO) error {
+	if dto.Name == "" && dto.BodyPattern == nil && dto.CategoryUUID == nil && dto.TagID == 0 {
+		return apperror.BadRequestError("nothing to update")
+	}
+	r, err := s.GetOne(ctx, dto.OwnerUUID, dto.UUID)
+	if err != nil {
+		return err
+	}
+	if dto.Name != "" {
+		r.Name = dto.Name
+	}
+	if dto.BodyPattern != nil {
+		r.BodyPattern = *dto.BodyPattern
+	}
+	if dto.CategoryUUID != nil {
+		r.CategoryUUID = *dto.CategoryUUID
+	}
+	if dto.TagID != 0 {
+		r.TagID = dto.TagID
+	}
+	if err = validate(r); err != nil {
+		return err
+	}
+	r.UpdatedAt = time.Now().UTC()
+
+	if err = s.storage.Update(ctx, r); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to update rule. error: %w", err)
+	}
+	return nil
+}
+
+func (s *service) Delete(ctx context.Context, ownerUUID, uuid string) error {
+	if _, err := s.GetOne(ctx, ownerUUID, uuid); err != nil {
+		return err
+	}
+	if err := s.storage.Delete(ctx, uuid); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete rule. error: %w", err)
+	}
+	return nil
+}
+
+func (s *service) AutoTags(ctx context.Context, n note.Note) ([]int, error) {
+	if n.OwnerUUID == "" {
+		return nil, nil
+	}
+	rules, err := s.storage.FindByOwner(ctx, n.OwnerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rules of owner. error: %w", err)
+	}
+
+	var tags []int
+	for _, r := range rules {
+		if r.Matches(n) {
+			tags = append(tags, r.TagID)
+		}
+	}
+	return tags, nil
+}
+
+func (s *service) ReplaceTags(ctx context.Context, sourceIDs []int, targetID int) (int, error) {
+	changed, err := s.storage.ReplaceTags(ctx, sourceIDs, targetID, time.Now().UTC())
+	if err != nil {
+		return changed, fmt.Errorf("failed to replace tags of rules. error: %w", err)
+	}
+	return changed, nil
+}
+
+func (s *service) DeleteByTag(ctx context.Context, ownerUUID string, tagID int) (int, error) {
+	if ownerUUID == "" {
+		return 0, apperror.BadRequestError("rule owner is unknown")
+	}
+	if tagID <= 0 {
+		return 0, apperror.BadRequestError("tag id is required")
+	}
+	deleted, err := s.storage.DeleteByTag(ctx, ownerUUID, tagID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete rules of tag. error: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *service) DryRun(ctx context.Context, ownerUUID, uuid string) ([]AffectedNote, error) {
+	r, err := s.GetOne(ctx, ownerUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.affected(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := make([]AffectedNote, 0, len(notes))
+	for _, n := range notes {
+		affected = append(affected, AffectedNote{UUID: n.UUID, Header: n.Header})
+	}
+	return affected, nil
+}
+
+func (s *service) Apply(ctx context.Context, ownerUUID, uuid string) (string, error) {
+	r, err := s.GetOne(ctx, ownerUUID, uuid)
+	if err != nil {
+		return "", err
+	}
+
+	job := Job{
+		UUID:      primitive.NewObjectID().Hex(),
+		RuleUUID:  r.UUID,
+		OwnerUUID: r.OwnerUUID,
+		Status:    JobRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	s.saveJob(job)
+
+	// the job outlives the request that started it
+	go s.apply(context.Background(), r, job)
+
+	return job.UUID, nil
+}
+
+func (s *service) GetJob(ctx context.Context, ownerUUID, uuid string) (Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[uuid]
+	if !ok || job.OwnerUUID != ownerUUID {
+		return Job{}, apperror.ErrNotFound
+	}
+	return job, nil
+}
+
+// apply adds the tag of r to the notes it matches, the notes are only ever added a tag to
+// so the job can be started again after a failure. Notes being edited are skipped.
+func (s *service) apply(ctx context.Context, r Rule, job Job) {
+	notes, err := s.affected(ctx, r)
+	if err == nil {
+		for _, n := range notes {
+			// the tag is added to the stored tags, edits since the notes were found are kept
+			var added bool
+			if added, err = s.notes.AddTag(ctx, n.UUID, r.TagID, time.Now()); err != nil {
+				err = fmt.Errorf("failed to tag note %s. error: %w", n.UUID, err)
+				break
+			}
+			if added {
+				job.NotesChanged++
+			} else {
+				job.NotesSkipped++
+			}
+		}
+	}
+
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	job.Status = JobDone
+	if err != nil {
+		s.logger.Errorf("rule %s apply job %s failed: %v", r.UUID, job.UUID, err)
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	s.saveJob(job)
+}
+
+// affected returns the notes of the rule owner that applying r changes
+func (s *service) affected(ctx context.Context, r Rule) ([]note.Note, error) {
+	notes, err := s.notes.FindByOwner(ctx, r.OwnerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notes of owner. error: %w", err)
+	}
+
+	var affected []note.Note
+	for _, n := range notes {
+		if r.Affects(n) {
+			affected = append(affected, n)
+		}
+	}
+	return affected, nil
+}
+
+// saveJob also forgets the jobs finished more than jobTTL ago
+func (s *service) saveJob(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.UUID] = job
+
+	expired := time.Now().Add(-jobTTL)
+	for uuid, j := range s.jobs {
+		if j.FinishedAt != nil && j.FinishedAt.Before(expired) {
+			delete(s.jobs, uuid)
+		}
+	}
+}
+
+func validate(r Rule) error {
+	if r.Name == "" {
+		return apperror.BadRequestError("rule name is required")
+	}
+	if r.TagID <= 0 {
+		return apperror.BadRequestError("rule tag is required")
+	}
+	if r.BodyPattern == "" && r.CategoryUUID == "" {
+		return apperror.BadRequestError("rule needs a body pattern or a category")
+	}
+	if r.BodyPattern != "" {
+		if _, err := regexp.Compile(r.BodyPattern); err != nil {
+			return apperror.BadRequestError(fmt.Sprintf("body pattern is not a valid regular expression: %v", err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package rule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	notedb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	ruledb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+)
+
+const incidentTag = 7
+
+func newServices(t *testing.T) (rule.Service, note.Service) {
+	t.Helper()
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+	noteStorage := notedb.NewMemoryStorage(logger)
+	rules, err := rule.NewService(ruledb.NewMemoryStorage(logger), noteStorage, logger)
+	if err != nil {
+		t.Fatalf("rule service: %v", err)
+	}
+	notes, err := note.NewService(noteStorage, time.Minute, rules, logger)
+	if err != nil {
+		t.Fatalf("note service: %v", err)
+	}
+	return rules, notes
+}
+
+func mustCreateNote(t *testing.T, notes note.Service, dto note.CreateNoteDTO) string {
+	t.Helper()
+	uuid, err := notes.Create(context.Background(), dto)
+	if err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+	return uuid
+}
+
+func tagsOf(t *testing.T, notes note.Service, uuid string) []int {
+	t.Helper()
+	n, err := notes.GetOne(context.Background(), uuid)
+	if err != nil {
+		t.Fatalf("get note: %v", err)
+	}
+	return n.Tags
+}
+
+func TestRulesTagNotesOnCreateAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	rules, notes := newServices(t)
+
+	_, err := rules.Create(ctx, rule.CreateRuleDTO{OwnerUUID: "alice", Name: "incidents", BodyPattern: `INC-\d+`, TagID: incidentTag})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	_, err = rules.Create(ctx, rule.CreateRuleDTO{OwnerUUID: "alice", Name: "work", CategoryUUID: "work", TagID: 8})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	matching := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "h", Body: "see INC-42", CategoryUUID: "work", Tags: []int{1}})
+	if got := tagsOf(t, notes, matching); !equal(got, []int{1, incidentTag, 8}) {
+		t.Fatalf("want tags [1 %d 8], got %v", incidentTag, got)
+	}
+
+	other := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "bob", Header: "h", Body: "see INC-42", CategoryUUID: "home"})
+	if got := tagsOf(t, notes, other); len(got) != 0 {
+		t.Fatalf("rules of another owner tagged the note: %v", got)
+	}
+
+	plain := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "h", Body: "nothing", CategoryUUID: "home"})
+	if got := tagsOf(t, notes, plain); len(got) != 0 {
+		t.Fatalf("want no tags, got %v", got)
+	}
+	if err = notes.Update(ctx, note.UpdateNoteDTO{UUID: plain, Body: "now INC-7"}); err != nil {
+		t.Fatalf("update note: %v", err)
+	}
+	if got := tagsOf(t, notes, plain); !equal(got, []int{incidentTag}) {
+		t.Fatalf("want tags [%d] after update, got %v", incidentTag, got)
+	}
+}
+
+func TestDryRunAndApply(t *testing.T) {
+	ctx := context.Background()
+	rules, notes := newServices(t)
+
+	matching := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "outage", Body: "INC-1 happened", CategoryUUID: "c"})
+	mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "tagged", Body: "INC-2", CategoryUUID: "c", Tags: []int{incidentTag}})
+	mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "other", Body: "nothing", CategoryUUID: "c"})
+	mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "bob", Header: "foreign", Body: "INC-3", CategoryUUID: "c"})
+
+	ruleUUID, err := rules.Create(ctx, rule.CreateRuleDTO{OwnerUUID: "alice", Name: "incidents", BodyPattern: `INC-\d+`, TagID: incidentTag})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	affected, err := rules.DryRun(ctx, "alice", ruleUUID)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(affected) != 1 || affected[0].UUID != matching || affected[0].Header != "outage" {
+		t.Fatalf("want only the untagged matching note, got %+v", affected)
+	}
+	if got := tagsOf(t, notes, matching); len(got) != 0 {
+		t.Fatalf("dry run changed the note: %v", got)
+	}
+
+	jobUUID, err := rules.Apply(ctx, "alice", ruleUUID)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	job := waitForJob(t, rules, jobUUID)
+	if job.Status != rule.JobDone || job.NotesChanged != 1 {
+		t.Fatalf("want done job that changed one note, got %+v", job)
+	}
+	if got := tagsOf(t, notes, matching); !equal(got, []int{incidentTag}) {
+		t.Fatalf("want tags [%d] after apply, got %v", incidentTag, got)
+	}
+
+	if _, err = rules.GetJob(ctx, "bob", jobUUID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("want not found for a job of another owner, got %v", err)
+	}
+}
+
+func TestRulesFollowMergedAndDeletedTags(t *testing.T) {
+	ctx := context.Background()
+	rules, notes := newServices(t)
+
+	ruleUUID, err := rules.Create(ctx, rule.CreateRuleDTO{OwnerUUID: "alice", Name: "incidents", BodyPattern: `INC-\d+`, TagID: 3})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	result, err := notes.ReplaceTags(ctx, note.ReplaceTagsDTO{SourceIDs: []int{3}, TargetID: incidentTag})
+	if err != nil {
+		t.Fatalf("replace tags: %v", err)
+	}
+	if result.RulesChanged != 1 {
+		t.Fatalf("want one rule changed, got %+v", result)
+	}
+	merged := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "h", Body: "INC-1", CategoryUUID: "c"})
+	if got := tagsOf(t, notes, merged); !equal(got, []int{incidentTag}) {
+		t.Fatalf("want tags [%d] after the merge, got %v", incidentTag, got)
+	}
+
+	// tags of other owners are not theirs to drop rules of
+	if _, err = rules.DeleteByTag(ctx, "bob", incidentTag); err != nil {
+		t.Fatalf("delete rules of bob: %v", err)
+	}
+	if _, err = rules.GetOne(ctx, "alice", ruleUUID); err != nil {
+		t.Fatalf("rule of alice gone after bob deleted a tag: %v", err)
+	}
+
+	deleted, err := rules.DeleteByTag(ctx, "alice", incidentTag)
+	if err != nil {
+		t.Fatalf("delete rules of tag: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("want one rule deleted, got %d", deleted)
+	}
+	if _, err = rules.GetOne(ctx, "alice", ruleUUID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("want the rule of the deleted tag gone, got %v", err)
+	}
+	plain := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "h", Body: "INC-2", CategoryUUID: "c"})
+	if got := tagsOf(t, notes, plain); len(got) != 0 {
+		t.Fatalf("note tagged with a deleted tag: %v", got)
+	}
+}
+
+func TestApplySkipsNotesBeingEdited(t *testing.T) {
+	ctx := context.Background()
+	rules, notes := newServices(t)
+	free := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "free", Body: "INC-1", CategoryUUID: "c"})
+	leased := mustCreateNote(t, notes, note.CreateNoteDTO{OwnerUUID: "alice", Header: "leased", Body: "INC-2", CategoryUUID: "c"})
+	if _, err := notes.AcquireLease(ctx, note.LeaseDTO{NoteUUID: leased, HolderUUID: "alice", SessionID: "s1"}); err != nil {
+		t.Fatalf("acquire lease: %v", err)
+	}
+
+	ruleUUID, err := rules.Create(ctx, rule.CreateRuleDTO{OwnerUUID: "alice", Name: "incidents", BodyPattern: `INC-\d+`, TagID: incidentTag})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	jobUUID, err := rules.Apply(ctx, "alice", ruleUUID)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	job := waitForJob(t, rules, jobUUID)
+	if job.Status != rule.JobDone || job.NotesChanged != 1 || job.NotesSkipped != 1 {
+		t.Fatalf("want done job that changed one note and skipped one, got %+v", job)
+	}
+	if got := tagsOf(t, notes, free); !equal(got, []int{incidentTag}) {
+		t.Fatalf("want tags [%d] of the free note, got %v", incidentTag, got)
+	}
+	if got := tagsOf(t, notes, leased); len(got) != 0 {
+		t.Fatalf("leased note was tagged: %v", got)
+	}
+}
+
+func TestRulesAreValidatedAndScopedToTheirOwner(t *testing.T) {
+	ctx := context.Background()
+	rules, _ := newServices(t)
+
+	invalid := []rule.CreateRuleDTO{
+		{OwnerUUID: "alice", Name: "no condition", TagID: 1},
+		{OwnerUUID: "alice", Name: "bad pattern", BodyPattern: "INC-(", TagID: 1},
+		{OwnerUUID: "alice", Name: "no tag", CategoryUUID: "c"},
+		{Name: "no owner", CategoryUUID: "c", TagID: 1},
+	}
+	for _, dto := range invalid {
+		var appErr *apperror.AppError
+		if _, err := rules.Create(ctx, dto); !errors.As(err, &appErr) || appErr.Code != apperror.BadRequestError("").Code {
+			t.Errorf("%s: want bad request, got %v", dto.Name, err)
+		}
+	}
+
+	ruleUUID, err := rules.Create(ctx, rule.CreateRuleDTO{OwnerUUID: "alice", Name: "work", CategoryUUID: "c", TagID: 1})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	if _, err = rules.GetOne(ctx, "bob", ruleUUID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("want not found for a rule of another owner, got %v", err)
+	}
+	if err = rules.Delete(ctx, "bob", ruleUUID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("want not found deleting a rule of another owner, got %v", err)
+	}
+
+	empty := ""
+	pattern := "urgent"
+	if err = rules.Update(ctx, rule.UpdateRuleDTO{UUID: ruleUUID, OwnerUUID: "alice", CategoryUUID: &empty, BodyPattern: &pattern}); err != nil {
+		t.Fatalf("update rule: %v", err)
+	}
+	r, err := rules.GetOne(ctx, "alice", ruleUUID)
+	if err != nil {
+		t.Fatalf("get rule: %v", err)
+	}
+	if r.CategoryUUID != "" || r.BodyPattern != "urgent" {
+		t.Fatalf("update not applied: %+v", r)
+	}
+	if err = rules.Update(ctx, rule.UpdateRuleDTO{UUID: ruleUUID, OwnerUUID: "alice", BodyPattern: &empty}); err == nil {
+		t.Fatalf("rule without conditions was accepted")
+	}
+}
+
+func waitForJob(t *testing.T, rules rule.Service, uuid string) rule.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := rules.GetJob(context.Background(), "alice", uuid)
+		if err != nil {
+			t.Fatalf("get job: %v", err)
+		}
+		if job.Status != rule.JobRunning || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package rule
+
+import (
+	"context"
+	"time"
+)
+
+type Storage interface {
+	Create(ctx context.Context, r Rule) (string, error)
+	FindOne(ctx context.Context, uuid string) (Rule, error)
+	// FindByOwner returns every rule of the owner ordered by creation time
+	FindByOwner(ctx context.Context, ownerUUID string) ([]Rule, error)
+	// Update rewrites name, conditions, tag and update time of the rule
+	Update(ctx context.Context, r Rule) error
+	Delete(ctx context.Context, uuid string) error
+	// ReplaceTags makes the rules tagging with any of sourceIDs tag with targetID and returns
+	// the number of rules changed
+	ReplaceTags(ctx context.Context, sourceIDs []int, targetID int, now time.Time) (int, error)
+	// DeleteByTag deletes the rules of the owner tagging with tagID and returns their number
+	DeleteByTag(ctx context.Context, ownerUUID string, tagID int) (int, error)
+}
@@ -0,0 +1,231 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps the records and the lock like the collections do
+type memoryStore struct {
+	mu        sync.Mutex
+	records   map[int]Status
+	owner     string
+	expiresAt time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[int]Status)}
+}
+
+func (s *memoryStore) applied(ctx context.Context) (map[int]Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	applied := make(map[int]Status, len(s.records))
+	for version, record := range s.records {
+		applied[version] = record
+	}
+	return applied, nil
+}
+
+func (s *memoryStore) record(ctx context.Context, status Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[status.Version]; ok {
+		return errors.New("duplicate key")
+	}
+	s.records[status.Version] = status
+	return nil
+}
+
+func (s *memoryStore) unrecord(ctx context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, version)
+	return nil
+}
+
+func (s *memoryStore) lock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != "" && time.Now().Before(s.expiresAt) {
+		return false, nil
+	}
+	s.owner, s.expiresAt = owner, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *memoryStore) renew(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != owner {
+		return false, nil
+	}
+	s.expiresAt = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *memoryStore) unlock(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
+
+func (s *memoryStore) steal(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owner = owner
+}
+
+func newTestMigrator(s store, migrations []Migration) *Migrator {
+	m := newMigrator(nil, s, migrations, logging.Logger{Entry: logrus.NewEntry(logrus.New())})
+	m.lockTTL = 60 * time.Millisecond
+	m.retryInterval = 5 * time.Millisecond
+	return m
+}
+
+func recording(version int, applied *[]int, mu *sync.Mutex) Migration {
+	return Migration{Version: version, Up: func(ctx context.Context, db *mongo.Database) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*applied = append(*applied, version)
+		return nil
+	}}
+}
+
+func TestUpAppliesInVersionOrder(t *testing.T) {
+	var mu sync.Mutex
+	var applied []int
+	s := newMemoryStore()
+	m := newTestMigrator(s, []Migration{recording(3, &applied, &mu), recording(1, &applied, &mu), recording(2, &applied, &mu)})
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) != 3 || applied[0] != 1 || applied[1] != 2 || applied[2] != 3 {
+		t.Fatalf("applied = %v, want [1 2 3]", applied)
+	}
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("migration %d is not recorded", status.Version)
+		}
+	}
+}
+
+func TestUpSkipsAppliedVersions(t *testing.T) {
+	var mu sync.Mutex
+	var applied []int
+	s := newMemoryStore()
+	s.records[1] = Status{Version: 1, AppliedAt: now()}
+	m := newTestMigrator(s, []Migration{recording(1, &applied, &mu), recording(2, &applied, &mu)})
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	if len(applied) != 1 || applied[0] != 2 {
+		t.Fatalf("applied = %v, want [2]", applied)
+	}
+}
+
+func TestConcurrentMigratorsApplyOnce(t *testing.T) {
+	s := newMemoryStore()
+	var running, overlapped, applied int32
+	migration := Migration{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&applied, 1)
+		return nil
+	}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- newTestMigrator(s, []Migration{migration}).Up(context.Background())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("up: %v", err)
+		}
+	}
+	if overlapped != 0 {
+		t.Fatal("migrations ran concurrently")
+	}
+	if applied != 1 {
+		t.Fatalf("migration applied %d times, want once", applied)
+	}
+}
+
+func TestLockIsRenewedWhileMigrating(t *testing.T) {
+	s := newMemoryStore()
+	m := newTestMigrator(s, []Migration{{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+		// longer than the time to live of the lock
+		time.Sleep(150 * time.Millisecond)
+		return nil
+	}}})
+	other := newTestMigrator(s, nil)
+
+	done := make(chan error, 1)
+	go func() { done <- m.Up(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+	if taken, _ := s.lock(context.Background(), other.owner, other.lockTTL); taken {
+		t.Fatal("lock taken while a migration runs")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("up: %v", err)
+	}
+}
+
+func TestLostLockStopsMigrations(t *testing.T) {
+	s := newMemoryStore()
+	var secondApplied bool
+	m := newTestMigrator(s, []Migration{
+		{Version: 1, Up: func(ctx context.Context, db *mongo.Database) error {
+			s.steal("other")
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			return nil
+		}},
+		{Version: 2, Up: func(ctx context.Context, db *mongo.Database) error {
+			secondApplied = true
+			return nil
+		}},
+	})
+
+	if err := m.Up(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("up = %v, want %v", err, ErrLockLost)
+	}
+	if applied, _ := s.applied(context.Background()); len(applied) != 0 {
+		t.Fatalf("recorded %v after the lock was lost", applied)
+	}
+	if secondApplied {
+		t.Fatal("migration applied after the lock was lost")
+	}
+}
@@ -0,0 +1,37 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"net"
+	"net/url"
+	"time"
+)
+
+func NewClient(ctx context.Context, host, port, username, password, database string) (*pgxpool.Pool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	pool, err := pgxpool.Connect(reqCtx, connString(host, port, username, password, database))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client to postgresql due to error %w", err)
+	}
+
+	err = pool.Ping(reqCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client to postgresql due to error %w", err)
+	}
+
+	return pool, nil
+}
+
+// connString escapes the credentials, a password may hold @, : or /
+func connString(host, port, username, password, database string) string {
+	dsn := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(username, password),
+		Host:   net.JoinHostPort(host, port),
+		Path:   database,
+	}
+	return dsn.String()
+}
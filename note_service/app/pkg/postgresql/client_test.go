@@ -0,0 +1,20 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func TestConnStringEscapesCredentials(t *testing.T) {
+	dsn := connString("db.local", "5432", "notes", "p@ss:w/rd", "notes")
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse %q: %v", dsn, err)
+	}
+	c := cfg.ConnConfig
+	if c.Host != "db.local" || c.Port != 5432 || c.User != "notes" || c.Password != "p@ss:w/rd" || c.Database != "notes" {
+		t.Fatalf("parsed %q as host %s port %d user %s password %s database %s", dsn, c.Host, c.Port, c.User, c.Password, c.Database)
+	}
+}
@@ -0,0 +1,79 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    TEXT PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+// advisory lock key shared by every replica, so only one of them migrates at a time
+const migrationLockKey = 7301
+
+// Migrate applies every *.up.sql file from migrations that is not recorded in
+// schema_migrations yet. Files are applied in lexical order, each one in its own transaction.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, migrations fs.FS) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection. error: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock. error: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	if _, err = conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table. error: %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "*.up.sql")
+	if err != nil {
+		return fmt.Errorf("failed to list migrations. error: %w", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(file, ".up.sql")
+
+		var applied bool
+		err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %s. error: %w", version, err)
+		}
+		if applied {
+			continue
+		}
+
+		query, err := fs.ReadFile(migrations, file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s. error: %w", version, err)
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction. error: %w", err)
+		}
+		if _, err = tx.Exec(ctx, string(query)); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to apply migration %s. error: %w", version, err)
+		}
+		if _, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to record migration %s. error: %w", version, err)
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit migration %s. error: %w", version, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package router builds the routed handler of the note service. The service binary serves it on its
+// own listener, the all-in-one binary mounts it next to the other services in one process.
+package router
+
+import (
+	"context"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment"
+	commentdb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/comment/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/note/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/owner"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule"
+	ruledb "gitlab.konstweb.ru/ow/arch/notes/note_service/internal/rule/db"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/handlers/metric"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/logging"
+	mongo "gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/mongodb"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/mongodb/migrate"
+	"gitlab.konstweb.ru/ow/arch/notes/note_service/pkg/postgresql"
+	"net/http"
+)
+
+// Config is the config of the service, Storage.Type chooses where notes are kept
+type Config = config.Config
+
+// New builds the handler of every route of the service on the storage of cfg
+func New(ctx context.Context, cfg *Config, logger logging.Logger) (http.Handler, error) {
+	router := httprouter.New()
+
+	metricHandler := metric.Handler{Logger: logger}
+	metricHandler.Register(router)
+
+	storages, err := NewStorages(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	ruleService, err := rule.NewService(storages.Rules, storages.Notes, logger)
+	if err != nil {
+		return nil, err
+	}
+	rulesHandler := rule.Handler{
+		Logger:      logger,
+		RuleService: ruleService,
+	}
+	rulesHandler.Register(router)
+
+	noteService, err := note.NewService(storages.Notes, cfg.Lease.TTL, ruleService, logger)
+	if err != nil {
+		return nil, err
+	}
+	notesHandler := note.Handler{
+		Logger:      logger,
+		NoteService: noteService,
+	}
+	notesHandler.Register(router)
+
+	commentService, err := comment.NewService(storages.Comments, noteService, logger)
+	if err != nil {
+		return nil, err
+	}
+	commentsHandler := comment.Handler{
+		Logger:         logger,
+		CommentService: commentService,
+	}
+	commentsHandler.Register(router)
+
+	ownerService, err := owner.NewService(storages.Notes, storages.Comments, storages.Rules, logger)
+	if err != nil {
+		return nil, err
+	}
+	ownersHandler := owner.Handler{
+		Logger:       logger,
+		OwnerService: ownerService,
+	}
+	ownersHandler.Register(router)
+
+	return router, nil
+}
+
+// Storages are the storages of the service, all of them kept in the same database
+type Storages struct {
+	Notes    note.Storage
+	Comments comment.Storage
+	Rules    rule.Storage
+}
+
+// NewStorages connects to the storage of cfg and brings its schema up to date
+func NewStorages(ctx context.Context, cfg *Config, logger logging.Logger) (s Storages, err error) {
+	switch cfg.Storage.Type {
+	case "mongodb":
+		mongoClient, err := mongo.NewClient(ctx, cfg.MongoDB.Host, cfg.MongoDB.Port,
+			cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
+		if err != nil {
+			return s, err
+		}
+		logger.Info("apply mongodb migrations")
+		err = migrate.NewMigrator(mongoClient, MongoMigrations(cfg), logger).Up(ctx)
+		if err != nil {
+			return s, err
+		}
+		return Storages{
+			Notes:    db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger),
+			Comments: commentdb.NewStorage(mongoClient, cfg.MongoDB.CommentsCollection, logger),
+			Rules:    ruledb.NewStorage(mongoClient, cfg.MongoDB.RulesCollection, logger),
+		}, nil
+	case "postgresql":
+		pgClient, err := postgresql.NewClient(ctx, cfg.PostgreSQL.Host, cfg.PostgreSQL.Port,
+			cfg.PostgreSQL.Username, cfg.PostgreSQL.Password, cfg.PostgreSQL.Database)
+		if err != nil {
+			return s, err
+		}
+		logger.Info("apply postgresql migrations")
+		if err = db.MigratePostgres(ctx, pgClient); err != nil {
+			return s, err
+		}
+		if err = commentdb.MigratePostgres(ctx, pgClient); err != nil {
+			return s, err
+		}
+		if err = ruledb.MigratePostgres(ctx, pgClient); err != nil {
+			return s, err
+		}
+		return Storages{
+			Notes:    db.NewPostgresStorage(pgClient, logger),
+			Comments: commentdb.NewPostgresStorage(pgClient, logger),
+			Rules:    ruledb.NewPostgresStorage(pgClient, logger),
+		}, nil
+	case "memory":
+		logger.Warn("notes are kept in memory and will be lost on restart")
+		return Storages{
+			Notes:    db.NewMemoryStorage(logger),
+			Comments: commentdb.NewMemoryStorage(logger),
+			Rules:    ruledb.NewMemoryStorage(logger),
+		}, nil
+	default:
+		return s, fmt.Errorf("unknown storage type %q", cfg.Storage.Type)
+	}
+}
+
+// MongoMigrations is the schema history of the whole service database
+func MongoMigrations(cfg *Config) []migrate.Migration {
+	migrations := append(db.MongoMigrations(cfg.MongoDB.Collection), commentdb.MongoMigrations(cfg.MongoDB.CommentsCollection)...)
+	return append(migrations, ruledb.MongoMigrations(cfg.MongoDB.RulesCollection)...)
+}
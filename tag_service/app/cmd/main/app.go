@@ -4,14 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/julienschmidt/httprouter"
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/config"
-	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
-	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag/db"
-	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/handlers/metric"
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
-	mongo "gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/mongodb"
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/shutdown"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/router"
 	"net"
 	"net/http"
 	"os"
@@ -27,34 +23,20 @@ func main() {
 
 	cfg := config.GetConfig()
 
-	router := httprouter.New()
-
-	metricHandler := metric.Handler{Logger: logger}
-	metricHandler.Register(router)
-
-	mongoClient, err := mongo.NewClient(context.Background(), cfg.MongoDB.Host, cfg.MongoDB.Port,
-		cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
-	if err != nil {
-		logger.Fatal(err)
-	}
-	tagStorage := db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger)
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrateCommand(context.Background(), cfg, logger, os.Args[2:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
 
-	tagService, err := tag.NewService(tagStorage, logger)
+	handler, err := router.New(context.Background(), cfg, logger)
 	if err != nil {
-		panic(err)
-	}
-
-	tagsHandler := tag.Handler{
-		Logger:     logger,
-		TagService: tagService,
+		logger.Fatal(err)
 	}
-	tagsHandler.Register(router)
 
 	logger.Println("start application")
-	start(router, logger, cfg)
+	start(handler, logger, cfg)
 }
 
 func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag/db"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	mongo "gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/mongodb"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/mongodb/migrate"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const migrateUsage = "usage: app migrate [up | down [steps] | status | owners [file]]"
+
+// migrateCommand handles `app migrate ...` and exits without starting the server
+func migrateCommand(ctx context.Context, cfg *config.Config, logger logging.Logger, args []string) error {
+	if cfg.Storage.Type != "mongodb" {
+		return fmt.Errorf("migrate command works with mongodb storage only, configured %q", cfg.Storage.Type)
+	}
+
+	mongoClient, err := mongo.NewClient(ctx, cfg.MongoDB.Host, cfg.MongoDB.Port,
+		cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
+	if err != nil {
+		return err
+	}
+	migrator := migrate.NewMigrator(mongoClient, db.MongoMigrations(cfg.MongoDB.Collection, logger), logger)
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		if err = migrator.Up(ctx); err != nil {
+			return err
+		}
+		return db.EnsureUniquePaths(ctx, mongoClient.Collection(cfg.MongoDB.Collection), logger)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive integer. %s", migrateUsage)
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-19s  %s\n", status.Version, applied, status.Description)
+		}
+		return nil
+	case "owners":
+		// the pairs come from `app tag-owners` of the note service, read from file or stdin
+		in := io.Reader(os.Stdin)
+		if len(args) > 1 {
+			f, err := os.Open(args[1])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		owners, err := readTagOwners(in)
+		if err != nil {
+			return err
+		}
+		assigned, left, err := db.AssignOwners(ctx, mongoClient, cfg.MongoDB.Collection, owners)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("owners assigned to %d tags, %d tags left without an owner\n", assigned, len(left))
+		for _, orphan := range left {
+			fmt.Printf("%6d  %-30s  %s %v\n", orphan.ID, orphan.Path, orphan.Reason, orphan.Owners)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q. %s", command, migrateUsage)
+	}
+}
+
+// readTagOwners reads "tag_id owner_uuid" lines into the owners of each tag
+func readTagOwners(in io.Reader) (map[int][]string, error) {
+	owners := make(map[int][]string)
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want tag id and owner uuid, got %q", line, scanner.Text())
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: tag id %q is not a number", line, fields[0])
+		}
+		owners[id] = appendOwner(owners[id], fields[1])
+	}
+	return owners, scanner.Err()
+}
+
+func appendOwner(owners []string, owner string) []string {
+	for _, o := range owners {
+		if o == owner {
+			return owners
+		}
+	}
+	return append(owners, owner)
+}
@@ -9,11 +9,15 @@ var (
 	ErrNotFound = NewAppError("not found", "TS-000003", "")
 )
 
+const conflictCode = "TS-000004"
+
 type AppError struct {
 	Err              error  `json:"-"`
 	Message          string `json:"message,omitempty"`
 	DeveloperMessage string `json:"developer_message,omitempty"`
 	Code             string `json:"code,omitempty"`
+	// ConflictingID is the tag a ConflictError collides with
+	ConflictingID int `json:"conflicting_id,omitempty"`
 }
 
 func NewAppError(message, code, developerMessage string) *AppError {
@@ -43,6 +47,13 @@ func BadRequestError(message string) *AppError {
 	return NewAppError(message, "TS-000002", "some thing wrong with user data")
 }
 
+// ConflictError is answered with 409 Conflict
+func ConflictError(message string, conflictingID int) *AppError {
+	appErr := NewAppError(message, conflictCode, "tag names are unique per owner ignoring case")
+	appErr.ConflictingID = conflictingID
+	return appErr
+}
+
 func systemError(developerMessage string) *AppError {
 	return NewAppError("system error", "TS-000001", developerMessage)
 }
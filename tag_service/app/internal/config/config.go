@@ -13,15 +13,18 @@ type Config struct {
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
 		Port   string `yaml:"port" env-default:"8080"`
 	}
+	Storage struct {
+		Type string `yaml:"type" env-default:"mongodb"`
+	} `yaml:"storage"`
 	MongoDB struct {
-		Host       string `yaml:"host" env-required:"true"`
-		Port       string `yaml:"port" env-required:"true"`
-		Username   string `yaml:"username" env-required:"true"`
-		Password   string `yaml:"password" env-required:"true"`
-		AuthDB     string `yaml:"auth_db" env-required:"true"`
-		Database   string `yaml:"database" env-required:"true"`
-		Collection string `yaml:"collection" env-required:"true"`
-	} `yaml:"mongodb" env-required:"true"`
+		Host       string `yaml:"host"`
+		Port       string `yaml:"port"`
+		Username   string `yaml:"username"`
+		Password   string `yaml:"password"`
+		AuthDB     string `yaml:"auth_db"`
+		Database   string `yaml:"database"`
+		Collection string `yaml:"collection"`
+	} `yaml:"mongodb"`
 }
 
 var instance *Config
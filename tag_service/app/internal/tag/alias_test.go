@@ -0,0 +1,88 @@
+package tag_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+)
+
+func TestAliasesShareTheNamespaceOfPaths(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	kubernetes := mustCreate(t, s, "alice", "kubernetes")
+	work := mustCreate(t, s, "alice", "work")
+
+	if err := s.AddAlias(ctx, "alice", kubernetes, " k8s "); err != nil {
+		t.Fatalf("add alias: %v", err)
+	}
+	if got, _ := s.GetOne(ctx, "alice", kubernetes); len(got.Aliases) != 1 || got.Aliases[0] != "k8s" {
+		t.Fatalf("aliases = %v", got.Aliases)
+	}
+
+	if got := conflictingID(t, s.AddAlias(ctx, "alice", work, "K8S")); got != kubernetes {
+		t.Fatalf("alias of another tag: conflicting id = %d, want %d", got, kubernetes)
+	}
+	if got := conflictingID(t, s.AddAlias(ctx, "alice", kubernetes, "Work")); got != work {
+		t.Fatalf("alias named like a tag: conflicting id = %d, want %d", got, work)
+	}
+	_, _, err := s.Create(ctx, tag.CreateTagDTO{Name: "k8s", OwnerID: "alice"})
+	if got := conflictingID(t, err); got != kubernetes {
+		t.Fatalf("tag named like an alias: conflicting id = %d, want %d", got, kubernetes)
+	}
+	if got := conflictingID(t, s.Update(ctx, tag.UpdateTagDTO{ID: work, Name: "K8s", OwnerID: "alice"})); got != kubernetes {
+		t.Fatalf("rename onto an alias: conflicting id = %d, want %d", got, kubernetes)
+	}
+	if err = s.AddAlias(ctx, "alice", kubernetes, "a/b"); err == nil {
+		t.Fatal("alias with / accepted")
+	}
+	if err = s.AddAlias(ctx, "bob", kubernetes, "kube"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("alias added to a foreign tag: %v", err)
+	}
+	mustCreate(t, s, "bob", "k8s")
+
+	if err = s.RemoveAlias(ctx, "alice", kubernetes, "K8S"); err != nil {
+		t.Fatalf("remove alias: %v", err)
+	}
+	if err = s.RemoveAlias(ctx, "alice", kubernetes, "k8s"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("want not found removing a missing alias, got %v", err)
+	}
+	mustCreate(t, s, "alice", "k8s")
+}
+
+func TestAliasesResolveToTheirTag(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	kubernetes := mustCreate(t, s, "alice", "kubernetes")
+	acme := mustCreate(t, s, "alice", "work/clients/acme")
+	if err := s.AddAlias(ctx, "alice", kubernetes, "k8s"); err != nil {
+		t.Fatalf("add alias: %v", err)
+	}
+
+	resolved, err := s.Resolve(ctx, "alice", []string{"K8s", "work/clients/ACME", "unknown", "kubernetes"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	want := map[string]int{"K8s": kubernetes, "work/clients/ACME": acme, "kubernetes": kubernetes}
+	if len(resolved) != len(want) {
+		t.Fatalf("resolved = %+v, want %v", resolved, want)
+	}
+	for _, r := range resolved {
+		if want[r.Name] != r.Tag.ID {
+			t.Fatalf("%s resolved to %d, want %d", r.Name, r.Tag.ID, want[r.Name])
+		}
+	}
+	if resolved, _ = s.Resolve(ctx, "bob", []string{"k8s"}); len(resolved) != 0 {
+		t.Fatalf("aliases of another owner resolved: %+v", resolved)
+	}
+
+	suggestions, err := s.Suggest(ctx, "alice", "k8", 10, false)
+	if err != nil {
+		t.Fatalf("suggest: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].ID != kubernetes || suggestions[0].Alias != "k8s" || suggestions[0].Match != tag.MatchPrefix {
+		t.Fatalf("suggestions = %+v, want kubernetes by its alias", suggestions)
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const parallelCreates = 1000
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+func TestMemoryConcurrentCreate(t *testing.T) {
+	testConcurrentCreate(t, NewMemoryStorage(testLogger))
+}
+
+// TestMongoConcurrentCreate allocates ids from the counters collection of a real server, see mongoURI
+func TestMongoConcurrentCreate(t *testing.T) {
+	uri := mongoURI(t)
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMaxPoolSize(parallelCreates))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	database := client.Database("tags_test")
+
+	collection := fmt.Sprintf("tags_%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		database.Collection(collection).Drop(ctx)
+		database.Collection(countersCollection).DeleteOne(ctx, bson.M{"_id": collection})
+	})
+
+	testConcurrentCreate(t, NewStorage(database, collection, testLogger))
+}
+
+func testConcurrentCreate(t *testing.T, storage tag.Storage) {
+	ids := make(chan int, parallelCreates)
+	errs := make(chan error, parallelCreates)
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallelCreates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("tag %d", i)
+			id, err := storage.Create(context.Background(), tag.Tag{Name: name, OwnerID: "owner", Path: name})
+			if err != nil {
+				errs <- err
+				return
+			}
+			ids <- id
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("create: %v", err)
+	}
+	seen := make(map[int]bool, parallelCreates)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("id %d was allocated twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != parallelCreates {
+		t.Fatalf("got %d ids, want %d", len(seen), parallelCreates)
+	}
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var _ tag.Storage = &memoryDB{}
+
+// memoryDB keeps tags in process memory. Ids come from a sequence and are never
+// reused, exactly like the mongo storage does.
+type memoryDB struct {
+	mu     sync.RWMutex
+	tags   map[int]tag.Tag
+	lastID int
+	logger logging.Logger
+}
+
+func NewMemoryStorage(logger logging.Logger) tag.Storage {
+	return &memoryDB{
+		tags:   make(map[int]tag.Tag),
+		logger: logger,
+	}
+}
+
+func (s *memoryDB) Create(ctx context.Context, t tag.Tag) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.tags {
+		if stored.OwnerID == t.OwnerID && samePath(stored.Path, t.Path) {
+			return 0, tag.ErrPathTaken
+		}
+	}
+
+	s.lastID++
+	t.ID = s.lastID
+	s.tags[t.ID] = t
+
+	return t.ID, nil
+}
+
+func (s *memoryDB) FindOne(ctx context.Context, id int) (t tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tags[id]
+	if !ok {
+		return t, apperror.ErrNotFound
+	}
+
+	return t, nil
+}
+
+func (s *memoryDB) FindMany(ctx context.Context, ids []int) (tags []tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range ids {
+		if t, ok := s.tags[id]; ok {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags, nil
+}
+
+func (s *memoryDB) FindByOwner(ctx context.Context, ownerID string) (tags []tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tags {
+		if t.OwnerID == ownerID {
+			tags = append(tags, t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+
+	return tags, nil
+}
+
+func (s *memoryDB) FindByNamePrefix(ctx context.Context, ownerID, prefix string, limit int) (tags []tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix = tag.Fold(prefix)
+	for _, t := range s.tags {
+		if t.OwnerID != ownerID {
+			continue
+		}
+		for _, name := range append([]string{t.Name}, t.Aliases...) {
+			if strings.HasPrefix(tag.Fold(name), prefix) {
+				tags = append(tags, t)
+				break
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tag.Fold(tags[i].Name) < tag.Fold(tags[j].Name) })
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+func (s *memoryDB) FindByPath(ctx context.Context, ownerID, path string) (t tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tags {
+		if t.OwnerID == ownerID && samePath(t.Path, path) {
+			return t, nil
+		}
+	}
+
+	return t, apperror.ErrNotFound
+}
+
+func (s *memoryDB) FindByAlias(ctx context.Context, ownerID, alias string) (t tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tags {
+		if t.OwnerID == ownerID && hasAlias(t, alias) {
+			return t, nil
+		}
+	}
+
+	return t, apperror.ErrNotFound
+}
+
+func (s *memoryDB) FindSubtree(ctx context.Context, ownerID, path string) (tags []tag.Tag, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tags {
+		if t.OwnerID == ownerID && tag.InSubtree(t.Path, path) {
+			tags = append(tags, t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Path < tags[j].Path })
+
+	return tags, nil
+}
+
+func (s *memoryDB) Update(ctx context.Context, t tag.Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tags[t.ID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	if t.Name != "" {
+		stored.Name = t.Name
+	}
+	if t.Color != "" {
+		stored.Color = t.Color
+	}
+	if t.OwnerID != "" {
+		stored.OwnerID = t.OwnerID
+	}
+	s.tags[t.ID] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Move(ctx context.Context, t tag.Tag, oldPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tags[t.ID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Name = t.Name
+	stored.ParentID = t.ParentID
+	s.tags[t.ID] = stored
+
+	for id, d := range s.tags {
+		if d.OwnerID == stored.OwnerID && tag.InSubtree(d.Path, oldPath) {
+			d.Path = t.Path + strings.TrimPrefix(d.Path, oldPath)
+			s.tags[id] = d
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryDB) AddAlias(ctx context.Context, id int, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tags[id]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	for _, t := range s.tags {
+		if t.OwnerID == stored.OwnerID && hasAlias(t, alias) {
+			if t.ID == id {
+				return nil
+			}
+			return tag.ErrAliasTaken
+		}
+	}
+	// aliases are replaced, never changed in place, returned tags may share them
+	stored.Aliases = append(append([]string{}, stored.Aliases...), alias)
+	s.tags[id] = stored
+
+	return nil
+}
+
+func (s *memoryDB) RemoveAlias(ctx context.Context, id int, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tags[id]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	var aliases []string
+	for _, a := range stored.Aliases {
+		if a != alias {
+			aliases = append(aliases, a)
+		}
+	}
+	if len(aliases) == len(stored.Aliases) {
+		return apperror.ErrNotFound
+	}
+	stored.Aliases = aliases
+	s.tags[id] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[id]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(s.tags, id)
+
+	return nil
+}
+
+// samePath compares paths and aliases ignoring case like pathCollation does
+func samePath(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+func hasAlias(t tag.Tag, alias string) bool {
+	for _, a := range t.Aliases {
+		if samePath(a, alias) {
+			return true
+		}
+	}
+	return false
+}
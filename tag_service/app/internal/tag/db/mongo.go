@@ -10,65 +10,72 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
 	"time"
+	"unicode/utf8"
 )
 
 var _ tag.Storage = &db{}
 
+// nameCollation compares names ignoring case and diacritics, queries have to use it to be served
+// by the owner_id_name index
+var nameCollation = &options.Collation{Locale: "en", Strength: 1}
+
+// pathCollation compares paths ignoring case, the owner_id_path_unique index keeps paths of an
+// owner unique under it
+var pathCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// countersCollection keeps one sequence document per collection: {_id: <collection>, seq: <last id>}
+const countersCollection = "counters"
+
 type db struct {
 	collection *mongo.Collection
+	counters   *mongo.Collection
 	logger     logging.Logger
 }
 
 func NewStorage(storage *mongo.Database, collection string, logger logging.Logger) tag.Storage {
 	return &db{
 		collection: storage.Collection(collection),
+		counters:   storage.Collection(countersCollection),
 		logger:     logger,
 	}
 }
 func (s *db) Create(ctx context.Context, t tag.Tag) (id int, err error) {
+	id, err = s.nextID(ctx)
+	if err != nil {
+		return id, err
+	}
+
+	t.ID = id
 	nCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-
-	findOptions := options.FindOptions{}
-	findOptions.SetSort(bson.D{{"_id", -1}})
-	findOptions.SetLimit(1)
-	var nTag tag.Tag
-	cursor, err := s.collection.Find(nCtx, bson.M{}, &findOptions)
-	if err != nil {
-		return id, fmt.Errorf("failed to execute query. error: %w", err)
-	}
-
-	nTagID := 1
-	tryCount := 3
-	for tryCount >= 0 {
-		if cursor.Next(ctx) {
-			err = cursor.Decode(&nTag)
-			if err != nil {
-				return 0, err
-			}
-			nTagID = nTag.ID + 1
-		} else if tryCount < 3 {
-			return id, fmt.Errorf("duplicate key error")
-		}
-
-		tryCount--
-
-		t.ID = nTagID
-		nCtx, cancel = context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-		_, err = s.collection.InsertOne(nCtx, t)
-		if err != nil {
-			if mongo.IsDuplicateKeyError(err) {
-				continue
-			} else {
-				return id, fmt.Errorf("failed to execute query. error: %w", err)
-			}
+	if _, err = s.collection.InsertOne(nCtx, t); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, tag.ErrPathTaken
 		}
-		break
+		return 0, fmt.Errorf("failed to execute query. error: %w", err)
 	}
 
-	return nTagID, nil
+	return id, nil
+}
+
+// nextID atomically takes the next value of the collection sequence. Ids are never reused,
+// a failed insert leaves a gap.
+func (s *db) nextID(ctx context.Context) (int, error) {
+	nCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var counter struct {
+		Seq int `bson:"seq"`
+	}
+	err := s.counters.FindOneAndUpdate(nCtx, bson.M{"_id": s.collection.Name()}, bson.M{"$inc": bson.M{"seq": 1}}, opts).
+		Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate tag id. error: %w", err)
+	}
+	return counter.Seq, nil
 }
 
 func (s *db) FindOne(ctx context.Context, id int) (t tag.Tag, err error) {
@@ -111,6 +118,99 @@ func (s *db) FindMany(ctx context.Context, ids []int) (tags []tag.Tag, err error
 	return tags, fmt.Errorf("failed to decode document. error: %w", err)
 }
 
+func (s *db) FindByOwner(ctx context.Context, ownerID string) (tags []tag.Tag, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cur, err := s.collection.Find(ctx, bson.M{"owner_id": ownerID}, opts)
+	if err != nil {
+		return tags, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &tags); err != nil {
+		return tags, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return tags, nil
+}
+
+func (s *db) FindByNamePrefix(ctx context.Context, ownerID, prefix string, limit int) (tags []tag.Tag, err error) {
+	// U+FFFF sorts after every character under the collation, the range covers all the names with prefix
+	inRange := bson.M{"$gte": prefix, "$lt": prefix + "\uffff"}
+	filter := bson.M{"owner_id": ownerID, "$or": bson.A{bson.M{"name": inRange}, bson.M{"aliases": inRange}}}
+	opts := options.Find().SetCollation(nameCollation).SetSort(bson.D{{Key: "name", Value: 1}}).SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cur, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return tags, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &tags); err != nil {
+		return tags, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return tags, nil
+}
+
+func (s *db) FindByPath(ctx context.Context, ownerID, path string) (t tag.Tag, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetCollation(pathCollation)
+	err = s.collection.FindOne(ctx, bson.M{"owner_id": ownerID, "path": path}, opts).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return t, apperror.ErrNotFound
+		}
+		return t, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *db) FindByAlias(ctx context.Context, ownerID, alias string) (t tag.Tag, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetCollation(pathCollation)
+	err = s.collection.FindOne(ctx, bson.M{"owner_id": ownerID, "aliases": alias}, opts).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return t, apperror.ErrNotFound
+		}
+		return t, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *db) FindSubtree(ctx context.Context, ownerID, path string) (tags []tag.Tag, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "path", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cur, err := s.collection.Find(ctx, subtreeFilter(ownerID, path), opts)
+	if err != nil {
+		return tags, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cur.All(ctx, &tags); err != nil {
+		return tags, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return tags, nil
+}
+
+func subtreeFilter(ownerID, path string) bson.M {
+	return bson.M{
+		"owner_id": ownerID,
+		"$or": bson.A{
+			bson.M{"path": path},
+			bson.M{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(path+tag.PathSeparator)}},
+		},
+	}
+}
+
 func (s *db) Update(ctx context.Context, t tag.Tag) error {
 	filter := bson.M{"_id": t.ID}
 
@@ -146,6 +246,78 @@ func (s *db) Update(ctx context.Context, t tag.Tag) error {
 	return nil
 }
 
+// Move rewrites the paths first, so repeating an interrupted move finishes it
+func (s *db) Move(ctx context.Context, t tag.Tag, oldPath string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stored, err := s.FindOne(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+
+	rewrite := bson.A{bson.M{"$set": bson.M{"path": bson.M{"$concat": bson.A{
+		t.Path,
+		bson.M{"$substrCP": bson.A{"$path", utf8.RuneCountInString(oldPath), bson.M{"$strLenCP": "$path"}}},
+	}}}}}
+	result, err := s.collection.UpdateMany(ctx, subtreeFilter(stored.OwnerID, oldPath), rewrite)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	s.logger.Tracef("Moved %v documents.\n", result.ModifiedCount)
+
+	update := bson.M{"$set": bson.M{"name": t.Name}}
+	if t.ParentID == 0 {
+		update["$unset"] = bson.M{"parent_id": ""}
+	} else {
+		update["$set"].(bson.M)["parent_id"] = t.ParentID
+	}
+	if _, err = s.collection.UpdateOne(ctx, bson.M{"_id": t.ID}, update); err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *db) AddAlias(ctx context.Context, id int, alias string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"aliases": alias}})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return tag.ErrAliasTaken
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *db) RemoveAlias(ctx context.Context, id int, alias string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id, "aliases": alias}, bson.M{"$pull": bson.M{"aliases": alias}})
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	// the unique index skips tags without aliases only when the field is gone, not when it is empty
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": id, "aliases": bson.M{"$size": 0}}, bson.M{"$unset": bson.M{"aliases": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	return nil
+}
+
 func (s *db) Delete(ctx context.Context, id int) error {
 	filter := bson.M{"_id": id}
 
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/mongodb/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations returns the schema history of the tags collection
+func MongoMigrations(collection string, logger logging.Logger) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     1,
+			Description: "index tags by owner_id",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "owner_id", Value: 1}},
+					Options: options.Index().SetName("owner_id"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "owner_id")
+				return err
+			},
+		},
+		{
+			Version:     2,
+			Description: "seed tag id sequence with the highest existing id",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				var last struct {
+					ID int `bson:"_id"`
+				}
+				opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{"_id": 1})
+				err := db.Collection(collection).FindOne(ctx, bson.M{}, opts).Decode(&last)
+				if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+					return err
+				}
+				// $max keeps the sequence if tags were created after an earlier partial run
+				_, err = db.Collection(countersCollection).UpdateOne(ctx, bson.M{"_id": collection},
+					bson.M{"$max": bson.M{"seq": last.ID}}, options.Update().SetUpsert(true))
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(countersCollection).DeleteOne(ctx, bson.M{"_id": collection})
+				return err
+			},
+		},
+		{
+			Version:     3,
+			Description: "make flat tags roots of the tag tree and index tags by path",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).UpdateMany(ctx, bson.M{"path": bson.M{"$exists": false}},
+					bson.A{bson.M{"$set": bson.M{"path": "$name"}}})
+				if err != nil {
+					return err
+				}
+				_, err = db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "path", Value: 1}},
+					Options: options.Index().SetName("owner_id_path"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "owner_id_path")
+				return err
+			},
+		},
+		{
+			Version:     4,
+			Description: "index tag names of an owner ignoring case and diacritics",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "name", Value: 1}},
+					Options: options.Index().SetName("owner_id_name").SetCollation(nameCollation),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "owner_id_name")
+				return err
+			},
+		},
+		{
+			Version:     5,
+			Description: "make tag paths of an owner unique ignoring case",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return EnsureUniquePaths(ctx, db.Collection(collection), logger)
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				unique, err := hasIndex(ctx, db.Collection(collection), "owner_id_path_unique")
+				if err != nil || !unique {
+					return err
+				}
+				_, err = db.Collection(collection).Indexes().DropOne(ctx, "owner_id_path_unique")
+				return err
+			},
+		},
+		{
+			Version:     6,
+			Description: "make tag aliases of an owner unique ignoring case",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "aliases", Value: 1}},
+					Options: options.Index().SetName("owner_id_aliases_unique").SetUnique(true).SetCollation(pathCollation).
+						SetPartialFilterExpression(bson.M{"aliases": bson.M{"$exists": true}}),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "owner_id_aliases_unique")
+				return err
+			},
+		},
+	}
+}
+
+// ownedFilter matches tags with an owner, tags without one are not unique per owner
+var ownedFilter = bson.M{"owner_id": bson.M{"$gt": ""}}
+
+// EnsureUniquePaths makes tag paths of an owner unique ignoring case. While an owner has paths
+// that differ only by case the index is left out and the tags are logged, they have to be merged
+// or renamed through the API. The next start or `app migrate up` creates the index once they are gone.
+func EnsureUniquePaths(ctx context.Context, collection *mongo.Collection, logger logging.Logger) error {
+	unique, err := hasIndex(ctx, collection, "owner_id_path_unique")
+	if err != nil || unique {
+		return err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: ownedFilter}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"owner_id": "$owner_id", "path": "$path"},
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	}
+	cur, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetCollation(pathCollation))
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	var duplicates []struct {
+		Group struct {
+			OwnerID string `bson:"owner_id"`
+			Path    string `bson:"path"`
+		} `bson:"_id"`
+		IDs []int `bson:"ids"`
+	}
+	if err = cur.All(ctx, &duplicates); err != nil {
+		return fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	if len(duplicates) > 0 {
+		for _, d := range duplicates {
+			logger.Warnf("owner %s has tags %v with path %q ignoring case", d.Group.OwnerID, d.IDs, d.Group.Path)
+		}
+		logger.Warnf("%d tag paths are not unique ignoring case, merge or rename the tags to make them unique", len(duplicates))
+		return nil
+	}
+
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "path", Value: 1}},
+		Options: options.Index().SetName("owner_id_path_unique").SetUnique(true).SetCollation(pathCollation).
+			SetPartialFilterExpression(ownedFilter),
+	})
+	return err
+}
+
+// hasIndex tells whether the collection has an index with the name
+func hasIndex(ctx context.Context, collection *mongo.Collection, name string) (bool, error) {
+	cur, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list indexes. error: %w", err)
+	}
+	var indexes []bson.M
+	if err = cur.All(ctx, &indexes); err != nil {
+		return false, fmt.Errorf("failed to decode index. error: %w", err)
+	}
+	for _, index := range indexes {
+		if index["name"] == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoEnsureUniquePaths(t *testing.T) {
+	uri := mongoURI(t)
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	collection := client.Database("tags_test").Collection(fmt.Sprintf("tags_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { collection.Drop(ctx) })
+
+	_, err = collection.InsertMany(ctx, []interface{}{
+		bson.M{"_id": 1, "name": "Go", "path": "Go", "owner_id": "alice"},
+		bson.M{"_id": 2, "name": "go", "path": "go", "owner_id": "alice"},
+		bson.M{"_id": 3, "name": "go", "path": "go", "owner_id": "bob"},
+		// tags without an owner do not share one
+		bson.M{"_id": 4, "name": "go", "path": "go"},
+		bson.M{"_id": 5, "name": "go", "path": "go"},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if err = EnsureUniquePaths(ctx, collection, testLogger); err != nil {
+		t.Fatalf("ensure unique paths with duplicates: %v", err)
+	}
+	if unique, _ := hasIndex(ctx, collection, "owner_id_path_unique"); unique {
+		t.Fatal("unique index created while alice has Go and go")
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": 2}, bson.M{"$set": bson.M{"name": "golang", "path": "golang"}})
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err = EnsureUniquePaths(ctx, collection, testLogger); err != nil {
+		t.Fatalf("ensure unique paths: %v", err)
+	}
+	if unique, _ := hasIndex(ctx, collection, "owner_id_path_unique"); !unique {
+		t.Fatal("unique index missing after the duplicates were resolved")
+	}
+	_, err = collection.InsertOne(ctx, bson.M{"_id": 6, "name": "GO", "path": "GO", "owner_id": "alice"})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("insert GO for alice = %v, want a duplicate key error", err)
+	}
+}
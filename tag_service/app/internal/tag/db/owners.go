@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Orphan is a tag without an owner, written before tags were scoped to their owner. Owners are the
+// owners of the notes referencing it, Reason tells why none of them became its owner.
+type Orphan struct {
+	tag.Tag
+	Owners []string
+	Reason string
+}
+
+// orphanFilter matches tags whose owner_id is missing or empty
+var orphanFilter = bson.M{"owner_id": bson.M{"$in": bson.A{nil, ""}}}
+
+// FindOrphans returns the tags of the collection that have no owner
+func FindOrphans(ctx context.Context, database *mongo.Database, collection string) ([]tag.Tag, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := database.Collection(collection).Find(ctx, orphanFilter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	var orphans []tag.Tag
+	if err = cur.All(ctx, &orphans); err != nil {
+		return nil, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+	return orphans, nil
+}
+
+// AssignOwners gives every tag without an owner the owner of the notes referencing it, owners maps
+// tag ids to the owners of those notes. Tags referenced by the notes of several owners or of none,
+// and tags whose path the owner already has, are returned untouched.
+func AssignOwners(ctx context.Context, database *mongo.Database, collection string, owners map[int][]string) (assigned int, left []Orphan, err error) {
+	orphans, err := FindOrphans(ctx, database, collection)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, t := range orphans {
+		orphan := Orphan{Tag: t, Owners: owners[t.ID]}
+		switch len(orphan.Owners) {
+		case 0:
+			orphan.Reason = "no note references it"
+		case 1:
+			filter := bson.M{"_id": t.ID, "owner_id": orphanFilter["owner_id"]}
+			update := bson.M{"$set": bson.M{"owner_id": orphan.Owners[0]}}
+			_, err = database.Collection(collection).UpdateOne(ctx, filter, update)
+			if err == nil {
+				assigned++
+				continue
+			}
+			if !mongo.IsDuplicateKeyError(err) {
+				return assigned, left, fmt.Errorf("failed to assign owner of tag %d. error: %w", t.ID, err)
+			}
+			orphan.Reason = "the owner already has a tag with its path or alias"
+		default:
+			orphan.Reason = "notes of several owners reference it"
+		}
+		left = append(left, orphan)
+	}
+	return assigned, left, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoAssignOwners(t *testing.T) {
+	uri := mongoURI(t)
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	database := client.Database("tags_test")
+
+	collection := fmt.Sprintf("tags_%d", time.Now().UnixNano())
+	t.Cleanup(func() { database.Collection(collection).Drop(ctx) })
+	// the unique indexes are what keeps an owner from getting a second tag with a path
+	for _, m := range MongoMigrations(collection, testLogger) {
+		if m.Version == 5 || m.Version == 6 {
+			if err = m.Up(ctx, database); err != nil {
+				t.Fatalf("migration %d: %v", m.Version, err)
+			}
+		}
+	}
+
+	_, err = database.Collection(collection).InsertMany(ctx, []interface{}{
+		bson.M{"_id": 1, "name": "work", "path": "work"},
+		bson.M{"_id": 2, "name": "shared", "path": "shared"},
+		bson.M{"_id": 3, "name": "unused", "path": "unused", "owner_id": ""},
+		bson.M{"_id": 4, "name": "home", "path": "home"},
+		bson.M{"_id": 5, "name": "home", "path": "home", "owner_id": "alice"},
+		bson.M{"_id": 6, "name": "owned", "path": "owned", "owner_id": "bob"},
+	})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	owners := map[int][]string{1: {"alice"}, 2: {"alice", "bob"}, 4: {"alice"}}
+	assigned, left, err := AssignOwners(ctx, database, collection, owners)
+	if err != nil {
+		t.Fatalf("assign owners: %v", err)
+	}
+	if assigned != 1 {
+		t.Fatalf("assigned %d owners, want 1", assigned)
+	}
+	if len(left) != 3 || left[0].ID != 2 || left[1].ID != 3 || left[2].ID != 4 {
+		t.Fatalf("left %+v, want tags 2, 3 and 4", left)
+	}
+
+	orphans, err := FindOrphans(ctx, database, collection)
+	if err != nil {
+		t.Fatalf("find orphans: %v", err)
+	}
+	if len(orphans) != 3 {
+		t.Fatalf("%d orphans after assigning, want 3", len(orphans))
+	}
+}
@@ -7,15 +7,27 @@ import (
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/apperror"
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
 const (
-	tagsURL = "/api/tags"
-	tagURL  = "/api/tags/:id"
+	tagsURL    = "/api/tags"
+	tagURL     = "/api/tags/:id"
+	aliasesURL = "/api/tags/:id/aliases"
+	aliasURL   = "/api/tags/:id/aliases/:alias"
+	// ownerURL is called by the gateway when the account of the owner is deleted
+	ownerURL = "/api/owners/:uuid"
+	// suggestID makes GET /api/tags/suggest, httprouter does not allow a static segment next to :id
+	suggestID = "suggest"
+	// resolveID makes GET /api/tags/resolve the same way
+	resolveID = "resolve"
 )
 
+// header the gateway uses to pass the authenticated user, it owns the tags
+const userUUIDHeader = "X-User-UUID"
+
 type Handler struct {
 	Logger     logging.Logger
 	TagService Service
@@ -27,6 +39,9 @@ func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodPost, tagsURL, apperror.Middleware(h.CreateTag))
 	router.HandlerFunc(http.MethodPatch, tagURL, apperror.Middleware(h.PartiallyUpdateTag))
 	router.HandlerFunc(http.MethodDelete, tagURL, apperror.Middleware(h.DeleteTag))
+	router.HandlerFunc(http.MethodPost, aliasesURL, apperror.Middleware(h.AddAlias))
+	router.HandlerFunc(http.MethodDelete, aliasURL, apperror.Middleware(h.RemoveAlias))
+	router.HandlerFunc(http.MethodDelete, ownerURL, apperror.Middleware(h.DeleteOwnerTags))
 }
 
 func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) error {
@@ -34,12 +49,18 @@ func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) error {
 
 	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
 	tagIDStr := params.ByName("id")
+	switch tagIDStr {
+	case suggestID:
+		return h.SuggestTags(w, r)
+	case resolveID:
+		return h.ResolveTags(w, r)
+	}
 	id, err := strconv.Atoi(tagIDStr)
 	if err != nil {
 		return apperror.BadRequestError("id resource identifier is required and must be an integer")
 	}
 
-	tag, err := h.TagService.GetOne(r.Context(), id)
+	tag, err := h.TagService.GetOne(r.Context(), r.Header.Get(userUUIDHeader), id)
 	if err != nil {
 		return err
 	}
@@ -55,12 +76,28 @@ func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// GetTags returns the tag tree of the user. With the id parameter it returns just the requested
+// tags, or the requested subtrees when descendants=true, as a flat list.
 func (h *Handler) GetTags(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
+	ownerID := r.Header.Get(userUUIDHeader)
+
 	idsParam := r.URL.Query().Get("id")
 	if idsParam == "" {
-		return apperror.BadRequestError("id query parameter is required and must be a comma separated integers")
+		tree, err := h.TagService.GetTree(r.Context(), ownerID)
+		if err != nil {
+			return err
+		}
+		treeBytes, err := json.Marshal(tree)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(treeBytes)
+
+		return nil
 	}
 
 	var tagsIds []int
@@ -73,11 +110,120 @@ func (h *Handler) GetTags(w http.ResponseWriter, r *http.Request) error {
 		tagsIds = append(tagsIds, id)
 	}
 
-	tags, err := h.TagService.GetMany(r.Context(), tagsIds)
+	var tags []Tag
+	var err error
+	if r.URL.Query().Get("descendants") == "true" {
+		tags, err = h.TagService.GetSubtrees(r.Context(), ownerID, tagsIds)
+	} else {
+		tags, err = h.TagService.GetMany(r.Context(), ownerID, tagsIds)
+	}
 	if err != nil {
 		return err
 	}
 
+	return writeTags(w, tags)
+}
+
+// SuggestTags completes q to the user's tags, limit caps the number of suggestions. With ties=true
+// the suggestions ranked like the last one are added beyond limit.
+func (h *Handler) SuggestTags(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 0
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitParam); err != nil {
+			return apperror.BadRequestError("limit query parameter must be an integer")
+		}
+	}
+	ties := false
+	if tiesParam := r.URL.Query().Get("ties"); tiesParam != "" {
+		var err error
+		if ties, err = strconv.ParseBool(tiesParam); err != nil {
+			return apperror.BadRequestError("ties query parameter must be a boolean")
+		}
+	}
+
+	suggestions, err := h.TagService.Suggest(r.Context(), r.Header.Get(userUUIDHeader), r.URL.Query().Get("q"), limit, ties)
+	if err != nil {
+		return err
+	}
+	suggestionsBytes, err := json.Marshal(suggestions)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(suggestionsBytes)
+
+	return nil
+}
+
+// ResolveTags finds the user's tags by the paths or aliases in the repeated name parameter
+func (h *Handler) ResolveTags(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	names := r.URL.Query()["name"]
+	if len(names) == 0 {
+		return apperror.BadRequestError("name query parameter is required")
+	}
+	resolved, err := h.TagService.Resolve(r.Context(), r.Header.Get(userUUIDHeader), names)
+	if err != nil {
+		return err
+	}
+	resolvedBytes, err := json.Marshal(resolved)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(resolvedBytes)
+
+	return nil
+}
+
+func (h *Handler) AddAlias(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	tagID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		return apperror.BadRequestError("id resource identifier is required and must be an integer")
+	}
+
+	var dto AliasDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme")
+	}
+
+	if err = h.TagService.AddAlias(r.Context(), r.Header.Get(userUUIDHeader), tagID, dto.Alias); err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/%d/aliases/%s", tagsURL, tagID, url.PathEscape(strings.TrimSpace(dto.Alias))))
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) RemoveAlias(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	tagID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		return apperror.BadRequestError("id resource identifier is required and must be an integer")
+	}
+
+	if err = h.TagService.RemoveAlias(r.Context(), r.Header.Get(userUUIDHeader), tagID, params.ByName("alias")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func writeTags(w http.ResponseWriter, tags []Tag) error {
 	tagsBytes, err := json.Marshal(tags)
 	if err != nil {
 		return err
@@ -97,14 +243,31 @@ func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) error {
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 		return apperror.BadRequestError("invalid JSON scheme")
 	}
+	dto.OwnerID = r.Header.Get(userUUIDHeader)
+	dto.OnConflict = r.URL.Query().Get("on_conflict")
 
-	tagID, err := h.TagService.Create(r.Context(), dto)
+	tagID, created, err := h.TagService.Create(r.Context(), dto)
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Location", fmt.Sprintf("%s/%d", tagsURL, tagID))
-	w.WriteHeader(http.StatusCreated)
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+
+	// an exact duplicate, the caller gets the tag it already has
+	t, err := h.TagService.GetOne(r.Context(), dto.OwnerID, tagID)
+	if err != nil {
+		return err
+	}
+	tagBytes, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(tagBytes)
 
 	return nil
 }
@@ -127,6 +290,7 @@ func (h *Handler) PartiallyUpdateTag(w http.ResponseWriter, r *http.Request) err
 	}
 
 	dto.ID = tagID
+	dto.OwnerID = r.Header.Get(userUUIDHeader)
 
 	err = h.TagService.Update(r.Context(), dto)
 	if err != nil {
@@ -149,10 +313,24 @@ func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) error {
 		return apperror.BadRequestError("id query parameter is required and must be a comma separated integers")
 	}
 
-	err = h.TagService.Delete(r.Context(), tagID)
+	err = h.TagService.Delete(r.Context(), r.Header.Get(userUUIDHeader), tagID)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteOwnerTags(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	deleted, err := h.TagService.DeleteByOwner(r.Context(), params.ByName("uuid"))
 	if err != nil {
 		return err
 	}
+	h.Logger.Infof("deleted %d tags of owner %s", deleted, params.ByName("uuid"))
 	w.WriteHeader(http.StatusNoContent)
 
 	return nil
@@ -1,36 +1,68 @@
 package tag
 
+// Tag is a node of the owner's tag tree. Path joins the names from the root down to
+// the tag with PathSeparator, e.g. work/clients/acme; root tags have no ParentID.
 type Tag struct {
-	ID      int    `json:"id" bson:"_id,omitempty"`
-	Name    string `json:"name" bson:"name,omitempty"`
-	Color   string `json:"color" bson:"color,omitempty"`
-	OwnerID string `json:"owner_id" bson:"owner_id,omitempty"`
+	ID       int    `json:"id" bson:"_id,omitempty"`
+	Name     string `json:"name" bson:"name,omitempty"`
+	Color    string `json:"color" bson:"color,omitempty"`
+	OwnerID  string `json:"owner_id" bson:"owner_id,omitempty"`
+	ParentID int    `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Path     string `json:"path" bson:"path,omitempty"`
+	// Aliases are other names of the tag, e.g. k8s for kubernetes. Tag paths and aliases of an
+	// owner share one namespace, unique ignoring case.
+	Aliases []string `json:"aliases,omitempty" bson:"aliases,omitempty"`
 }
 
-func NewTag(dto CreateTagDTO) Tag {
-	return Tag{
-		Name:    dto.Name,
-		Color:   dto.Color,
-		OwnerID: dto.OwnerID,
-	}
+// Node is a tag together with its children, the shape of tag listings
+type Node struct {
+	Tag
+	Children []Node `json:"children"`
 }
 
 func UpdatedTag(dto UpdateTagDTO) Tag {
 	return Tag{
-		ID:      dto.ID,
-		Name:    dto.Name,
-		Color:   dto.Color,
+		ID:    dto.ID,
+		Name:  dto.Name,
+		Color: dto.Color,
 	}
 }
 
+// CreateTagDTO names the tag either by path, e.g. work/clients/acme, or relative to ParentID.
+// Missing ancestors are created.
 type CreateTagDTO struct {
-	Name    string `json:"name" bson:"name"`
-	Color   string `json:"color" bson:"color"`
-	OwnerID string `json:"owner_id" bson:"owner_id"`
+	Name     string `json:"name" bson:"name"`
+	ParentID int    `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Color    string `json:"color" bson:"color"`
+	OwnerID  string `json:"-" bson:"owner_id"`
+	// OnConflict tells what to do when the owner already has the tag, OnConflictError by default
+	OnConflict string `json:"-" bson:"-"`
 }
 
+const (
+	// OnConflictError refuses to create a tag whose path is taken ignoring case
+	OnConflictError = "error"
+	// OnConflictExisting returns the existing tag when it is an exact duplicate: the same path in
+	// the same case and the same color, if one is given. Other collisions are still refused.
+	OnConflictExisting = "existing"
+)
+
+// UpdateTagDTO renames the tag and moves it under ParentID, 0 moves it to the root.
+// The subtree of the tag follows it.
 type UpdateTagDTO struct {
-	ID    int    `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name  string `json:"name,omitempty" bson:"name,omitempty"`
-	Color string `json:"color,omitempty" bson:"color,omitempty"`
+	ID       int    `json:"_id,omitempty" bson:"_id,omitempty"`
+	OwnerID  string `json:"-" bson:"-"`
+	Name     string `json:"name,omitempty" bson:"name,omitempty"`
+	Color    string `json:"color,omitempty" bson:"color,omitempty"`
+	ParentID *int   `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+}
+
+type AliasDTO struct {
+	Alias string `json:"alias"`
+}
+
+// ResolvedName is a tag found by its path or one of its aliases
+type ResolvedName struct {
+	Name string `json:"name"`
+	Tag  Tag    `json:"tag"`
 }
@@ -0,0 +1,54 @@
+package tag
+
+import (
+	"sort"
+	"strings"
+)
+
+const PathSeparator = "/"
+
+// SplitPath splits a path like work/clients/acme into trimmed tag names
+func SplitPath(path string) ([]string, bool) {
+	names := strings.Split(path, PathSeparator)
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if names[i] == "" {
+			return nil, false
+		}
+	}
+	return names, true
+}
+
+// InSubtree reports whether path is root itself or one of its descendants
+func InSubtree(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+PathSeparator)
+}
+
+// BuildTree nests tags under their parents. Tags whose parent is not among tags become
+// roots, siblings are ordered by name.
+func BuildTree(tags []Tag) []Node {
+	known := make(map[int]bool, len(tags))
+	for _, t := range tags {
+		known[t.ID] = true
+	}
+	children := make(map[int][]Tag)
+	for _, t := range tags {
+		parent := t.ParentID
+		if !known[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], t)
+	}
+
+	var build func(parent int) []Node
+	build = func(parent int) []Node {
+		level := children[parent]
+		sort.Slice(level, func(i, j int) bool { return level[i].Name < level[j].Name })
+		nodes := make([]Node, 0, len(level))
+		for _, t := range level {
+			nodes = append(nodes, Node{Tag: t, Children: build(t.ID)})
+		}
+		return nodes
+	}
+	return build(0)
+}
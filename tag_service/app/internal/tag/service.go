@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/apperror"
 	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	"strings"
 )
 
 var _ Service = &service{}
@@ -22,30 +23,113 @@ func NewService(tagStorage Storage, logger logging.Logger) (Service, error) {
 	}, nil
 }
 
+// Service works with the tags of one owner at a time. Tags of other owners are
+// reported as not found, so their ids can't be probed.
 type Service interface {
-	Create(ctx context.Context, dto CreateTagDTO) (int, error)
-	GetOne(ctx context.Context, id int) (Tag, error)
-	GetMany(ctx context.Context, ids []int) ([]Tag, error)
+	// Create returns the id of the tag and whether it was created, see OnConflictExisting
+	Create(ctx context.Context, dto CreateTagDTO) (int, bool, error)
+	GetOne(ctx context.Context, ownerID string, id int) (Tag, error)
+	GetMany(ctx context.Context, ownerID string, ids []int) ([]Tag, error)
+	GetByOwner(ctx context.Context, ownerID string) ([]Tag, error)
+	GetTree(ctx context.Context, ownerID string) ([]Node, error)
+	// Suggest completes the query to the owner's tags, see suggest
+	Suggest(ctx context.Context, ownerID, query string, limit int, ties bool) ([]Suggestion, error)
+	// GetSubtrees returns the tags with the ids and all their descendants
+	GetSubtrees(ctx context.Context, ownerID string, ids []int) ([]Tag, error)
 	Update(ctx context.Context, dto UpdateTagDTO) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, ownerID string, id int) error
+	// DeleteByOwner removes every tag of the owner together with the aliases and returns how many
+	// were removed, it is run again after a failure
+	DeleteByOwner(ctx context.Context, ownerID string) (int, error)
+	AddAlias(ctx context.Context, ownerID string, id int, alias string) error
+	RemoveAlias(ctx context.Context, ownerID string, id int, alias string) error
+	// Resolve finds the tags with the names as paths or aliases, unknown names are left out
+	Resolve(ctx context.Context, ownerID string, names []string) ([]ResolvedName, error)
 }
 
-func (s service) Create(ctx context.Context, dto CreateTagDTO) (tagID int, err error) {
-	tag := NewTag(dto)
+// Create creates the tag and the missing ancestors of it, the color is given only to the tag itself.
+// Ancestors are looked up ignoring case, so Work/acme goes under an existing work tag.
+func (s service) Create(ctx context.Context, dto CreateTagDTO) (tagID int, created bool, err error) {
+	if dto.OwnerID == "" {
+		return 0, false, apperror.BadRequestError("tag owner is unknown")
+	}
+	switch dto.OnConflict {
+	case "":
+		dto.OnConflict = OnConflictError
+	case OnConflictError, OnConflictExisting:
+	default:
+		return 0, false, apperror.BadRequestError(fmt.Sprintf("on_conflict must be %s or %s", OnConflictError, OnConflictExisting))
+	}
+	names, ok := SplitPath(dto.Name)
+	if !ok {
+		return 0, false, apperror.BadRequestError("tag name must be a path of non-empty names separated by /")
+	}
 
-	tagID, err = s.storage.Create(ctx, tag)
+	var parent Tag
+	if dto.ParentID != 0 {
+		if parent, err = s.GetOne(ctx, dto.OwnerID, dto.ParentID); err != nil {
+			return 0, false, err
+		}
+	}
 
-	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
-			return tagID, err
+	for i, name := range names {
+		path := name
+		if parent.Path != "" {
+			path = parent.Path + PathSeparator + name
+		}
+		leaf := i == len(names)-1
+
+		existing, err := s.storage.FindByPath(ctx, dto.OwnerID, path)
+		if err == nil {
+			if leaf {
+				return s.duplicate(dto, existing, path)
+			}
+			parent = existing
+			continue
+		}
+		if !errors.Is(err, apperror.ErrNotFound) {
+			return 0, false, fmt.Errorf("failed to find tag by path. error: %w", err)
+		}
+		if err = s.checkNotAlias(ctx, dto.OwnerID, path, 0); err != nil {
+			return 0, false, err
+		}
+
+		t := Tag{Name: name, OwnerID: dto.OwnerID, ParentID: parent.ID, Path: path}
+		if leaf {
+			t.Color = dto.Color
+		}
+		if t.ID, err = s.storage.Create(ctx, t); err != nil {
+			if !errors.Is(err, ErrPathTaken) {
+				return 0, false, fmt.Errorf("failed to create tag. error: %w", err)
+			}
+			// a concurrent request created it since it was looked up
+			if existing, err = s.storage.FindByPath(ctx, dto.OwnerID, path); err != nil {
+				return 0, false, fmt.Errorf("failed to find tag by path. error: %w", err)
+			}
+			if leaf {
+				return s.duplicate(dto, existing, path)
+			}
+			t = existing
 		}
-		return tagID, fmt.Errorf("failed to create tag. error: %w", err)
+		parent = t
 	}
 
-	return tagID, nil
+	return parent.ID, true, nil
+}
+
+// duplicate answers a create of the tag with the path the owner already has as existing
+func (s service) duplicate(dto CreateTagDTO, existing Tag, path string) (int, bool, error) {
+	exact := existing.Path == path && (dto.Color == "" || dto.Color == existing.Color)
+	if exact && dto.OnConflict == OnConflictExisting {
+		return existing.ID, false, nil
+	}
+	return 0, false, apperror.ConflictError(fmt.Sprintf("tag %s already exists", existing.Path), existing.ID)
 }
 
-func (s service) GetOne(ctx context.Context, id int) (t Tag, err error) {
+func (s service) GetOne(ctx context.Context, ownerID string, id int) (t Tag, err error) {
+	if ownerID == "" {
+		return t, apperror.BadRequestError("tag owner is unknown")
+	}
 	t, err = s.storage.FindOne(ctx, id)
 
 	if err != nil {
@@ -54,11 +138,17 @@ func (s service) GetOne(ctx context.Context, id int) (t Tag, err error) {
 		}
 		return t, fmt.Errorf("failed to get one tag by id. error: %w", err)
 	}
+	if t.OwnerID != ownerID {
+		return Tag{}, apperror.ErrNotFound
+	}
 	return t, nil
 }
 
-func (s service) GetMany(ctx context.Context, ids []int) (tags []Tag, err error) {
-	tags, err = s.storage.FindMany(ctx, ids)
+func (s service) GetMany(ctx context.Context, ownerID string, ids []int) (tags []Tag, err error) {
+	if ownerID == "" {
+		return tags, apperror.BadRequestError("tag owner is unknown")
+	}
+	found, err := s.storage.FindMany(ctx, ids)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -66,6 +156,11 @@ func (s service) GetMany(ctx context.Context, ids []int) (tags []Tag, err error)
 		}
 		return tags, fmt.Errorf("failed to get many tags by ids. error: %w", err)
 	}
+	for _, t := range found {
+		if t.OwnerID == ownerID {
+			tags = append(tags, t)
+		}
+	}
 	if len(tags) == 0 {
 		return tags, apperror.ErrNotFound
 	}
@@ -73,14 +168,107 @@ func (s service) GetMany(ctx context.Context, ids []int) (tags []Tag, err error)
 	return tags, nil
 }
 
+func (s service) GetByOwner(ctx context.Context, ownerID string) (tags []Tag, err error) {
+	if ownerID == "" {
+		return tags, apperror.BadRequestError("tag owner is unknown")
+	}
+	tags, err = s.storage.FindByOwner(ctx, ownerID)
+	if err != nil {
+		return tags, fmt.Errorf("failed to get tags of owner. error: %w", err)
+	}
+	if tags == nil {
+		tags = []Tag{}
+	}
+	return tags, nil
+}
+
+func (s service) GetTree(ctx context.Context, ownerID string) ([]Node, error) {
+	tags, err := s.GetByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(tags), nil
+}
+
+func (s service) GetSubtrees(ctx context.Context, ownerID string, ids []int) (tags []Tag, err error) {
+	roots, err := s.GetMany(ctx, ownerID, ids)
+	if err != nil {
+		return tags, err
+	}
+
+	seen := make(map[int]bool)
+	for _, root := range roots {
+		subtree, err := s.storage.FindSubtree(ctx, ownerID, root.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tag subtree. error: %w", err)
+		}
+		for _, t := range subtree {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// Suggest looks up prefix matches in the index first and scans all tags of the owner for word and
+// fuzzy matches only when there are not enough of them. Prefix matches are ranked all together, so
+// the owner's tags are scanned as well when there are more than prefixCandidates of them.
+func (s service) Suggest(ctx context.Context, ownerID, query string, limit int, ties bool) ([]Suggestion, error) {
+	if ownerID == "" {
+		return nil, apperror.BadRequestError("tag owner is unknown")
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, apperror.BadRequestError("q query parameter is required")
+	}
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+	if limit > MaxSuggestLimit {
+		limit = MaxSuggestLimit
+	}
+
+	if !strings.Contains(query, PathSeparator) {
+		tags, err := s.storage.FindByNamePrefix(ctx, ownerID, strings.TrimSpace(query), prefixCandidates+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find tags by name prefix. error: %w", err)
+		}
+		if len(tags) >= limit && len(tags) <= prefixCandidates {
+			return suggest(tags, query, limit, ties), nil
+		}
+	}
+
+	tags, err := s.storage.FindByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags of owner. error: %w", err)
+	}
+	return suggest(tags, query, limit, ties), nil
+}
+
+// Update changes the color of the tag, renames it or moves it to another parent. The
+// descendants of the tag keep their place under it.
 func (s service) Update(ctx context.Context, dto UpdateTagDTO) error {
-	if dto.Name == "" && dto.Color == "" {
+	if dto.Name == "" && dto.Color == "" && dto.ParentID == nil {
 		return apperror.BadRequestError("no data to update")
 	}
+	stored, err := s.GetOne(ctx, dto.OwnerID, dto.ID)
+	if err != nil {
+		return err
+	}
+
+	if (dto.Name != "" && dto.Name != stored.Name) || (dto.ParentID != nil && *dto.ParentID != stored.ParentID) {
+		if err = s.move(ctx, stored, dto); err != nil {
+			return err
+		}
+	}
+	if dto.Color == "" {
+		return nil
+	}
 
-	tag := UpdatedTag(dto)
+	tag := UpdatedTag(UpdateTagDTO{ID: dto.ID, Color: dto.Color})
 
-	err := s.storage.Update(ctx, tag)
+	err = s.storage.Update(ctx, tag)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -91,8 +279,61 @@ func (s service) Update(ctx context.Context, dto UpdateTagDTO) error {
 	return nil
 }
 
-func (s service) Delete(ctx context.Context, id int) error {
-	err := s.storage.Delete(ctx, id)
+func (s service) move(ctx context.Context, stored Tag, dto UpdateTagDTO) error {
+	moved := stored
+	if dto.Name != "" {
+		moved.Name = strings.TrimSpace(dto.Name)
+		if moved.Name == "" || strings.Contains(moved.Name, PathSeparator) {
+			return apperror.BadRequestError("tag name must not be empty or contain /, use parent_id to move the tag")
+		}
+	}
+
+	parentPath := ""
+	if dto.ParentID != nil {
+		moved.ParentID = *dto.ParentID
+	}
+	if moved.ParentID != 0 {
+		parent, err := s.GetOne(ctx, stored.OwnerID, moved.ParentID)
+		if err != nil {
+			return err
+		}
+		if InSubtree(parent.Path, stored.Path) {
+			return apperror.BadRequestError("tag can not be moved under itself or its descendant")
+		}
+		parentPath = parent.Path + PathSeparator
+	}
+	moved.Path = parentPath + moved.Name
+
+	// the tag itself is found there when it changes the case of its name or an interrupted move is repeated
+	if existing, err := s.storage.FindByPath(ctx, stored.OwnerID, moved.Path); err == nil && existing.ID != stored.ID {
+		return apperror.ConflictError(fmt.Sprintf("tag %s already exists", existing.Path), existing.ID)
+	} else if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return fmt.Errorf("failed to find tag by path. error: %w", err)
+	}
+	if err := s.checkNotAlias(ctx, stored.OwnerID, moved.Path, stored.ID); err != nil {
+		return err
+	}
+
+	if err := s.storage.Move(ctx, moved, stored.Path); err != nil {
+		return fmt.Errorf("failed to move tag. error: %w", err)
+	}
+	return nil
+}
+
+func (s service) Delete(ctx context.Context, ownerID string, id int) error {
+	stored, err := s.GetOne(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+	subtree, err := s.storage.FindSubtree(ctx, ownerID, stored.Path)
+	if err != nil {
+		return fmt.Errorf("failed to get tag subtree. error: %w", err)
+	}
+	if len(subtree) > 1 {
+		return apperror.BadRequestError("tag has child tags, move or delete them first")
+	}
+
+	err = s.storage.Delete(ctx, id)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -102,3 +343,117 @@ func (s service) Delete(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+func (s service) DeleteByOwner(ctx context.Context, ownerID string) (deleted int, err error) {
+	if ownerID == "" {
+		return 0, apperror.BadRequestError("tag owner is unknown")
+	}
+	tags, err := s.storage.FindByOwner(ctx, ownerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tags of owner. error: %w", err)
+	}
+	// the whole tree goes, so children need not be removed before their parents
+	for _, t := range tags {
+		err = s.storage.Delete(ctx, t.ID)
+		if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			return deleted, fmt.Errorf("failed to delete tag %d. error: %w", t.ID, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// AddAlias gives the tag another name. Aliases are single names like the names of root tags and
+// must not collide with a tag path or another alias of the owner.
+func (s service) AddAlias(ctx context.Context, ownerID string, id int, alias string) error {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || strings.Contains(alias, PathSeparator) {
+		return apperror.BadRequestError("alias must not be empty or contain /")
+	}
+	t, err := s.GetOne(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := s.storage.FindByPath(ctx, ownerID, alias); err == nil {
+		return apperror.ConflictError(fmt.Sprintf("tag %s already exists", existing.Path), existing.ID)
+	} else if !errors.Is(err, apperror.ErrNotFound) {
+		return fmt.Errorf("failed to find tag by path. error: %w", err)
+	}
+	if err = s.checkNotAlias(ctx, ownerID, alias, 0); err != nil {
+		return err
+	}
+
+	if err = s.storage.AddAlias(ctx, t.ID, alias); err != nil {
+		if errors.Is(err, ErrAliasTaken) {
+			// added by a concurrent request since it was checked
+			return s.checkNotAlias(ctx, ownerID, alias, 0)
+		}
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to add tag alias. error: %w", err)
+	}
+	return nil
+}
+
+// RemoveAlias removes the alias of the tag, the alias is matched ignoring case
+func (s service) RemoveAlias(ctx context.Context, ownerID string, id int, alias string) error {
+	t, err := s.GetOne(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+	for _, a := range t.Aliases {
+		if strings.EqualFold(a, alias) {
+			if err = s.storage.RemoveAlias(ctx, t.ID, a); err != nil {
+				if errors.Is(err, apperror.ErrNotFound) {
+					return err
+				}
+				return fmt.Errorf("failed to remove tag alias. error: %w", err)
+			}
+			return nil
+		}
+	}
+	return apperror.ErrNotFound
+}
+
+func (s service) Resolve(ctx context.Context, ownerID string, names []string) ([]ResolvedName, error) {
+	if ownerID == "" {
+		return nil, apperror.BadRequestError("tag owner is unknown")
+	}
+
+	resolved := []ResolvedName{}
+	for _, name := range names {
+		path, ok := SplitPath(name)
+		if !ok {
+			continue
+		}
+		t, err := s.storage.FindByPath(ctx, ownerID, strings.Join(path, PathSeparator))
+		if errors.Is(err, apperror.ErrNotFound) && len(path) == 1 {
+			t, err = s.storage.FindByAlias(ctx, ownerID, path[0])
+		}
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to resolve tag name. error: %w", err)
+		}
+		resolved = append(resolved, ResolvedName{Name: name, Tag: t})
+	}
+	return resolved, nil
+}
+
+// checkNotAlias refuses name when it is an alias of a tag other than exceptID
+func (s service) checkNotAlias(ctx context.Context, ownerID, name string, exceptID int) error {
+	t, err := s.storage.FindByAlias(ctx, ownerID, name)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find tag by alias. error: %w", err)
+	}
+	if t.ID == exceptID {
+		return nil
+	}
+	return apperror.ConflictError(fmt.Sprintf("%s is an alias of tag %s", name, t.Path), t.ID)
+}
@@ -0,0 +1,120 @@
+package tag_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag/db"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+func newService(t *testing.T) tag.Service {
+	t.Helper()
+	s, err := tag.NewService(db.NewMemoryStorage(testLogger), testLogger)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	return s
+}
+
+func mustCreate(t *testing.T, s tag.Service, owner, name string) int {
+	t.Helper()
+	id, _, err := s.Create(context.Background(), tag.CreateTagDTO{Name: name, Color: "#fff", OwnerID: owner})
+	if err != nil {
+		t.Fatalf("create %q: %v", name, err)
+	}
+	return id
+}
+
+func TestOwnerSeesOnlyOwnTags(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	work := mustCreate(t, s, "alice", "work")
+	home := mustCreate(t, s, "alice", "home")
+	foreign := mustCreate(t, s, "bob", "work")
+
+	tags, err := s.GetByOwner(ctx, "alice")
+	if err != nil {
+		t.Fatalf("get by owner: %v", err)
+	}
+	if len(tags) != 2 || tags[0].ID != work || tags[1].ID != home {
+		t.Fatalf("tags of alice = %+v", tags)
+	}
+
+	tags, err = s.GetMany(ctx, "alice", []int{work, foreign})
+	if err != nil {
+		t.Fatalf("get many: %v", err)
+	}
+	if len(tags) != 1 || tags[0].ID != work {
+		t.Fatalf("get many = %+v, want only tag %d", tags, work)
+	}
+
+	if _, err := s.GetOne(ctx, "alice", foreign); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("get foreign tag error = %v, want not found", err)
+	}
+
+	tags, err = s.GetByOwner(ctx, "carol")
+	if err != nil || tags == nil || len(tags) != 0 {
+		t.Fatalf("tags of carol = %#v, %v; want empty list", tags, err)
+	}
+}
+
+func TestForeignTagCanNotBeChanged(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	id := mustCreate(t, s, "bob", "work")
+
+	err := s.Update(ctx, tag.UpdateTagDTO{ID: id, Name: "mine", OwnerID: "alice"})
+	if !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("update error = %v, want not found", err)
+	}
+	if err := s.Delete(ctx, "alice", id); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("delete error = %v, want not found", err)
+	}
+
+	got, err := s.GetOne(ctx, "bob", id)
+	if err != nil || got.Name != "work" {
+		t.Fatalf("tag of bob = %+v, %v", got, err)
+	}
+	if err := s.Delete(ctx, "bob", id); err != nil {
+		t.Fatalf("owner delete: %v", err)
+	}
+}
+
+func TestOwnerIsRequired(t *testing.T) {
+	s := newService(t)
+	if _, _, err := s.Create(context.Background(), tag.CreateTagDTO{Name: "x"}); err == nil {
+		t.Fatal("create without owner succeeded")
+	}
+	if _, err := s.GetByOwner(context.Background(), ""); err == nil {
+		t.Fatal("list without owner succeeded")
+	}
+}
+
+func TestDeleteByOwner(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	mustCreate(t, s, "alice", "work/acme")
+	mustCreate(t, s, "alice", "home")
+	kept := mustCreate(t, s, "bob", "work")
+
+	deleted, err := s.DeleteByOwner(ctx, "alice")
+	if err != nil || deleted != 3 {
+		t.Fatalf("delete by owner = %d, %v; want work, work/acme and home", deleted, err)
+	}
+	if tags, _ := s.GetByOwner(ctx, "alice"); len(tags) != 0 {
+		t.Fatalf("tags of alice left: %+v", tags)
+	}
+	if _, err = s.GetOne(ctx, "bob", kept); err != nil {
+		t.Fatalf("tag of bob: %v", err)
+	}
+	if deleted, err = s.DeleteByOwner(ctx, "alice"); err != nil || deleted != 0 {
+		t.Fatalf("second delete = %d, %v", deleted, err)
+	}
+}
@@ -2,12 +2,37 @@ package tag
 
 import (
 	"context"
+	"errors"
+)
+
+var (
+	// ErrPathTaken is returned by Create when the owner already has a tag with the path ignoring case
+	ErrPathTaken = errors.New("tag path is taken")
+	// ErrAliasTaken is returned by AddAlias when the owner already has the alias ignoring case
+	ErrAliasTaken = errors.New("tag alias is taken")
 )
 
 type Storage interface {
 	Create(ctx context.Context, t Tag) (int, error)
 	FindOne(ctx context.Context, id int) (Tag, error)
 	FindMany(ctx context.Context, ids []int) ([]Tag, error)
+	// FindByOwner returns every tag of the owner ordered by id
+	FindByOwner(ctx context.Context, ownerID string) ([]Tag, error)
+	// FindByNamePrefix returns up to limit tags of the owner whose name or one of the aliases starts
+	// with prefix ignoring case and diacritics
+	FindByNamePrefix(ctx context.Context, ownerID, prefix string, limit int) ([]Tag, error)
+	// FindByPath returns the owner's tag with this path ignoring case
+	FindByPath(ctx context.Context, ownerID, path string) (Tag, error)
+	// FindByAlias returns the owner's tag with this alias ignoring case
+	FindByAlias(ctx context.Context, ownerID, alias string) (Tag, error)
+	// FindSubtree returns the owner's tag with this path and all its descendants ordered by path
+	FindSubtree(ctx context.Context, ownerID, path string) ([]Tag, error)
 	Update(ctx context.Context, t Tag) error
+	// Move sets name and parent of the tag and replaces the oldPath prefix of the tag and its
+	// descendants with t.Path
+	Move(ctx context.Context, t Tag, oldPath string) error
+	AddAlias(ctx context.Context, id int, alias string) error
+	// RemoveAlias removes the alias spelled exactly like this
+	RemoveAlias(ctx context.Context, id int, alias string) error
 	Delete(ctx context.Context, id int) error
 }
@@ -0,0 +1,186 @@
+package tag
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 50
+
+	// prefixCandidates is how many prefix matches are ranked without scanning all tags of the owner
+	prefixCandidates = 200
+)
+
+// Match tells how a suggested tag matches the query, the better matches come first
+type Match string
+
+const (
+	MatchExact  Match = "exact"
+	MatchPrefix Match = "prefix"
+	MatchWord   Match = "word"
+	MatchFuzzy  Match = "fuzzy"
+)
+
+var matchRanks = map[Match]int{MatchExact: 0, MatchPrefix: 1, MatchWord: 2, MatchFuzzy: 3}
+
+type Suggestion struct {
+	Tag
+	Match Match `json:"match"`
+	// Alias is the alias that matched the query better than the name of the tag
+	Alias string `json:"alias,omitempty"`
+	// Rank orders suggestions by the quality of the match, lower is better
+	Rank int `json:"rank"`
+}
+
+// Fold lowercases s and strips diacritics, so that "Café" and "cafe" compare equal
+func Fold(s string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// matchTag matches the folded query against the name and the aliases of the tag, or against its
+// path when the query contains PathSeparator. It returns the alias when one matches best.
+func matchTag(t Tag, query string) (Match, string, bool) {
+	if strings.Contains(query, PathSeparator) {
+		m, ok := matchName(Fold(t.Path), query)
+		return m, "", ok
+	}
+
+	best, ok := matchName(Fold(t.Name), query)
+	alias := ""
+	for _, a := range t.Aliases {
+		if m, found := matchName(Fold(a), query); found && (!ok || matchRanks[m] < matchRanks[best]) {
+			best, alias, ok = m, a, true
+		}
+	}
+	return best, alias, ok
+}
+
+func matchName(target, query string) (Match, bool) {
+	switch {
+	case target == query:
+		return MatchExact, true
+	case strings.HasPrefix(target, query):
+		return MatchPrefix, true
+	}
+	for _, word := range strings.FieldsFunc(target, isWordSeparator) {
+		if strings.HasPrefix(word, query) {
+			return MatchWord, true
+		}
+	}
+	if len([]rune(query)) >= minFuzzyQuery && (isSubsequence(query, target) || prefixDistance(query, target) <= maxTypos(query)) {
+		return MatchFuzzy, true
+	}
+	return "", false
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// shorter queries match almost any tag fuzzily
+const minFuzzyQuery = 3
+
+// maxTypos is how many edits a fuzzy match of the query may need
+func maxTypos(query string) int {
+	switch n := len([]rune(query)); {
+	case n < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func isSubsequence(query, target string) bool {
+	t := []rune(target)
+	i := 0
+	for _, r := range query {
+		for i < len(t) && t[i] != r {
+			i++
+		}
+		if i == len(t) {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// prefixDistance is the smallest edit distance between query and a prefix of target
+func prefixDistance(query, target string) int {
+	q, t := []rune(query), []rune(target)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	// an empty query matches the empty prefix of target
+	for i := 1; i <= len(q); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if q[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	best := prev[0]
+	for _, d := range prev {
+		if d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// suggest matches tags against the query and orders them by the quality of the match, then by
+// the depth of the tag and its path. With ties the suggestions ranked like the last one within
+// limit are kept too, the caller orders them further.
+func suggest(tags []Tag, query string, limit int, ties bool) []Suggestion {
+	query = Fold(strings.TrimSpace(query))
+
+	suggestions := []Suggestion{}
+	for _, t := range tags {
+		if m, alias, ok := matchTag(t, query); ok {
+			suggestions = append(suggestions, Suggestion{Tag: t, Match: m, Alias: alias, Rank: matchRanks[m]})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		if da, db := strings.Count(a.Path, PathSeparator), strings.Count(b.Path, PathSeparator); da != db {
+			return da < db
+		}
+		return a.Path < b.Path
+	})
+	cut := limit
+	for ties && cut > 0 && cut < len(suggestions) && suggestions[cut].Rank == suggestions[cut-1].Rank {
+		cut++
+	}
+	if len(suggestions) > cut {
+		suggestions = suggestions[:cut]
+	}
+	return suggestions
+}
@@ -0,0 +1,94 @@
+package tag_test
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+)
+
+func TestFold(t *testing.T) {
+	for in, want := range map[string]string{"Café": "cafe", "ÜBER": "uber", "Ёлка": "елка", "go": "go"} {
+		if got := tag.Fold(in); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func suggestedPaths(t *testing.T, s tag.Service, query string, limit int) []string {
+	t.Helper()
+	suggestions, err := s.Suggest(context.Background(), "alice", query, limit, false)
+	if err != nil {
+		t.Fatalf("suggest %q: %v", query, err)
+	}
+	var out []string
+	for _, sg := range suggestions {
+		out = append(out, sg.Path)
+	}
+	return out
+}
+
+func TestSuggest(t *testing.T) {
+	s := newService(t)
+	for _, path := range []string{"Café", "cafeteria", "work/clients/acme", "go-lang", "golang", "go", "programming"} {
+		mustCreate(t, s, "alice", path)
+	}
+	mustCreate(t, s, "bob", "cafe-bob")
+
+	cases := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		{"CAFE", 10, []string{"Café", "cafeteria"}},
+		{"go", 10, []string{"go", "go-lang", "golang"}},
+		{"go", 2, []string{"go", "go-lang"}},
+		{"lang", 10, []string{"go-lang", "golang"}}, // a word prefix ranks above a fuzzy match
+		{"acmr", 10, []string{"work/clients/acme"}},
+		{"prgrm", 10, []string{"programming"}},
+		{"work/cl", 10, []string{"work/clients", "work/clients/acme"}},
+		{"zzz", 10, nil},
+	}
+	for _, c := range cases {
+		got := suggestedPaths(t, s, c.query, c.limit)
+		if !equalPaths(got, c.want) {
+			t.Errorf("suggest %q = %v, want %v", c.query, got, c.want)
+		}
+	}
+
+	if _, err := s.Suggest(context.Background(), "alice", " ", 10, false); err == nil {
+		t.Error("empty query accepted")
+	}
+}
+
+func TestSuggestRanksAllPrefixMatches(t *testing.T) {
+	s := newService(t)
+	// by name the nested tags come first, by rank the root one does
+	for _, path := range []string{"x/goa", "x/gob", "gozz"} {
+		mustCreate(t, s, "alice", path)
+	}
+
+	if got, want := suggestedPaths(t, s, "go", 2), []string{"gozz", "x/goa"}; !equalPaths(got, want) {
+		t.Errorf("suggest %q = %v, want %v", "go", got, want)
+	}
+}
+
+func TestSuggestWithTiesKeepsTheBoundaryRank(t *testing.T) {
+	s := newService(t)
+	for _, path := range []string{"go", "goa", "gob", "goc", "learn-go"} {
+		mustCreate(t, s, "alice", path)
+	}
+
+	suggestions, err := s.Suggest(context.Background(), "alice", "go", 2, true)
+	if err != nil {
+		t.Fatalf("suggest: %v", err)
+	}
+	var got []string
+	for _, sg := range suggestions {
+		got = append(got, sg.Path)
+	}
+	// the word match ranks below the prefix matches at the boundary
+	if want := []string{"go", "goa", "gob", "goc"}; !equalPaths(got, want) {
+		t.Errorf("suggest with ties = %v, want %v", got, want)
+	}
+}
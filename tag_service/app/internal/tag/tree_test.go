@@ -0,0 +1,179 @@
+package tag_test
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag/db"
+)
+
+func paths(tags []tag.Tag) []string {
+	var out []string
+	for _, t := range tags {
+		out = append(out, t.Path)
+	}
+	return out
+}
+
+func equalPaths(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCreatePathCreatesAncestors(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+
+	acme := mustCreate(t, s, "alice", "work/clients/acme")
+	globex := mustCreate(t, s, "alice", " work / clients / globex ")
+
+	tags, err := s.GetByOwner(ctx, "alice")
+	if err != nil {
+		t.Fatalf("get by owner: %v", err)
+	}
+	if want := []string{"work", "work/clients", "work/clients/acme", "work/clients/globex"}; !equalPaths(paths(tags), want) {
+		t.Fatalf("paths = %v, want %v", paths(tags), want)
+	}
+
+	leaf, err := s.GetOne(ctx, "alice", acme)
+	if err != nil {
+		t.Fatalf("get acme: %v", err)
+	}
+	if leaf.Name != "acme" || leaf.Color != "#fff" || leaf.ParentID != tags[1].ID {
+		t.Fatalf("acme = %+v", leaf)
+	}
+	if tags[0].Color != "" || tags[0].ParentID != 0 {
+		t.Fatalf("work = %+v, want a root without color", tags[0])
+	}
+
+	child, _, err := s.Create(ctx, tag.CreateTagDTO{Name: "initech", ParentID: tags[1].ID, OwnerID: "alice"})
+	if err != nil {
+		t.Fatalf("create under parent: %v", err)
+	}
+	if got, _ := s.GetOne(ctx, "alice", child); got.Path != "work/clients/initech" {
+		t.Fatalf("child path = %q", got.Path)
+	}
+
+	if _, _, err := s.Create(ctx, tag.CreateTagDTO{Name: "work/clients/acme", OwnerID: "alice"}); err == nil {
+		t.Fatal("duplicate path created")
+	}
+	if _, _, err := s.Create(ctx, tag.CreateTagDTO{Name: "work//acme", OwnerID: "alice"}); err == nil {
+		t.Fatal("path with an empty name created")
+	}
+	if _, _, err := s.Create(ctx, tag.CreateTagDTO{Name: "x", ParentID: globex, OwnerID: "bob"}); err == nil {
+		t.Fatal("tag created under a foreign parent")
+	}
+}
+
+func TestTreeAndSubtrees(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	mustCreate(t, s, "alice", "work/clients/acme")
+	mustCreate(t, s, "alice", "home")
+	mustCreate(t, s, "alice", "work/admin")
+
+	tree, err := s.GetTree(ctx, "alice")
+	if err != nil {
+		t.Fatalf("get tree: %v", err)
+	}
+	if len(tree) != 2 || tree[0].Name != "home" || tree[1].Name != "work" {
+		t.Fatalf("roots = %+v", tree)
+	}
+	work := tree[1]
+	if len(work.Children) != 2 || work.Children[0].Name != "admin" || work.Children[1].Name != "clients" {
+		t.Fatalf("children of work = %+v", work.Children)
+	}
+	if len(work.Children[1].Children) != 1 || work.Children[1].Children[0].Name != "acme" {
+		t.Fatalf("children of clients = %+v", work.Children[1].Children)
+	}
+
+	subtree, err := s.GetSubtrees(ctx, "alice", []int{work.ID, work.Children[1].ID})
+	if err != nil {
+		t.Fatalf("get subtrees: %v", err)
+	}
+	if want := []string{"work", "work/admin", "work/clients", "work/clients/acme"}; !equalPaths(paths(subtree), want) {
+		t.Fatalf("subtree = %v, want %v", paths(subtree), want)
+	}
+}
+
+func TestMoveAndRenameSubtree(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	acme := mustCreate(t, s, "alice", "work/clients/acme")
+	archive := mustCreate(t, s, "alice", "archive")
+	clients, err := s.GetTree(ctx, "alice")
+	if err != nil {
+		t.Fatalf("get tree: %v", err)
+	}
+	clientsID := clients[1].Children[0].ID
+
+	err = s.Update(ctx, tag.UpdateTagDTO{ID: clientsID, Name: "customers", ParentID: &archive, OwnerID: "alice"})
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	got, _ := s.GetOne(ctx, "alice", acme)
+	if got.Path != "archive/customers/acme" {
+		t.Fatalf("acme path after move = %q", got.Path)
+	}
+	moved, _ := s.GetOne(ctx, "alice", clientsID)
+	if moved.Name != "customers" || moved.ParentID != archive {
+		t.Fatalf("moved tag = %+v", moved)
+	}
+
+	root := 0
+	if err := s.Update(ctx, tag.UpdateTagDTO{ID: clientsID, ParentID: &root, OwnerID: "alice"}); err != nil {
+		t.Fatalf("move to root: %v", err)
+	}
+	if got, _ := s.GetOne(ctx, "alice", acme); got.Path != "customers/acme" {
+		t.Fatalf("acme path after moving to root = %q", got.Path)
+	}
+
+	if err := s.Update(ctx, tag.UpdateTagDTO{ID: clientsID, ParentID: &acme, OwnerID: "alice"}); err == nil {
+		t.Fatal("tag moved under its own descendant")
+	}
+	if err := s.Update(ctx, tag.UpdateTagDTO{ID: clientsID, Name: "archive", OwnerID: "alice"}); err == nil {
+		t.Fatal("tag renamed onto an existing path")
+	}
+	if err := s.Delete(ctx, "alice", clientsID); err == nil {
+		t.Fatal("tag with children deleted")
+	}
+}
+
+func TestInterruptedMoveIsFinishedByRepeating(t *testing.T) {
+	ctx := context.Background()
+	storage := db.NewMemoryStorage(testLogger)
+	s, err := tag.NewService(storage, testLogger)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	acme := mustCreate(t, s, "alice", "clients/acme")
+	tree, _ := s.GetTree(ctx, "alice")
+	clientsID := tree[0].ID
+
+	// the paths are rewritten, the name is not
+	if err = storage.Move(ctx, tag.Tag{ID: clientsID, Name: "customers", Path: "customers"}, "clients"); err != nil {
+		t.Fatalf("move paths: %v", err)
+	}
+	if err = storage.Update(ctx, tag.Tag{ID: clientsID, Name: "clients"}); err != nil {
+		t.Fatalf("restore name: %v", err)
+	}
+
+	if err = s.Update(ctx, tag.UpdateTagDTO{ID: clientsID, Name: "customers", OwnerID: "alice"}); err != nil {
+		t.Fatalf("repeated move: %v", err)
+	}
+	got, _ := s.GetOne(ctx, "alice", clientsID)
+	if got.Name != "customers" || got.Path != "customers" {
+		t.Fatalf("moved tag = %+v", got)
+	}
+	if got, _ = s.GetOne(ctx, "alice", acme); got.Path != "customers/acme" {
+		t.Fatalf("acme path = %q", got.Path)
+	}
+}
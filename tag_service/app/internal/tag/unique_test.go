@@ -0,0 +1,89 @@
+package tag_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+)
+
+func conflictingID(t *testing.T, err error) int {
+	t.Helper()
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.ConflictingID == 0 {
+		t.Fatalf("want a conflict, got %v", err)
+	}
+	return appErr.ConflictingID
+}
+
+func TestNamesAreUniqueIgnoringCase(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	work := mustCreate(t, s, "alice", "Work")
+
+	_, _, err := s.Create(ctx, tag.CreateTagDTO{Name: "work", OwnerID: "alice"})
+	if got := conflictingID(t, err); got != work {
+		t.Fatalf("conflicting id = %d, want %d", got, work)
+	}
+
+	// ancestors are reused ignoring case
+	acme, created, err := s.Create(ctx, tag.CreateTagDTO{Name: "WORK/acme", OwnerID: "alice"})
+	if err != nil || !created {
+		t.Fatalf("create under existing ancestor: %d %v %v", acme, created, err)
+	}
+	if got, _ := s.GetOne(ctx, "alice", acme); got.ParentID != work || got.Path != "Work/acme" {
+		t.Fatalf("acme = %+v, want it under %d", got, work)
+	}
+
+	if _, created, err = s.Create(ctx, tag.CreateTagDTO{Name: "work", OwnerID: "bob"}); err != nil || !created {
+		t.Fatalf("other owners have their own names: %v %v", created, err)
+	}
+}
+
+func TestCreateReturnsExistingTagOnExactDuplicate(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	work := mustCreate(t, s, "alice", "work")
+
+	id, created, err := s.Create(ctx, tag.CreateTagDTO{Name: "work", OwnerID: "alice", OnConflict: tag.OnConflictExisting})
+	if err != nil || created || id != work {
+		t.Fatalf("want existing %d, got %d %v %v", work, id, created, err)
+	}
+	id, created, err = s.Create(ctx, tag.CreateTagDTO{Name: "work", Color: "#fff", OwnerID: "alice", OnConflict: tag.OnConflictExisting})
+	if err != nil || created || id != work {
+		t.Fatalf("same color is an exact duplicate: %d %v %v", id, created, err)
+	}
+
+	_, _, err = s.Create(ctx, tag.CreateTagDTO{Name: "Work", OwnerID: "alice", OnConflict: tag.OnConflictExisting})
+	if got := conflictingID(t, err); got != work {
+		t.Fatalf("other case: conflicting id = %d, want %d", got, work)
+	}
+	_, _, err = s.Create(ctx, tag.CreateTagDTO{Name: "work", Color: "#000", OwnerID: "alice", OnConflict: tag.OnConflictExisting})
+	if got := conflictingID(t, err); got != work {
+		t.Fatalf("other color: conflicting id = %d, want %d", got, work)
+	}
+	if _, _, err = s.Create(ctx, tag.CreateTagDTO{Name: "x", OwnerID: "alice", OnConflict: "merge"}); err == nil {
+		t.Fatal("unknown on_conflict accepted")
+	}
+}
+
+func TestRenameCollidingIgnoringCaseIsRefused(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	work := mustCreate(t, s, "alice", "work")
+	home := mustCreate(t, s, "alice", "home")
+
+	err := s.Update(ctx, tag.UpdateTagDTO{ID: home, Name: "WORK", OwnerID: "alice"})
+	if got := conflictingID(t, err); got != work {
+		t.Fatalf("conflicting id = %d, want %d", got, work)
+	}
+
+	if err = s.Update(ctx, tag.UpdateTagDTO{ID: work, Name: "Work", OwnerID: "alice"}); err != nil {
+		t.Fatalf("change case of own name: %v", err)
+	}
+	if got, _ := s.GetOne(ctx, "alice", work); got.Path != "Work" {
+		t.Fatalf("path = %q, want Work", got.Path)
+	}
+}
@@ -0,0 +1,239 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockID               = "lock"
+	lockTTL              = time.Minute
+	lockRetryInterval    = time.Second
+)
+
+// ErrLockLost is returned when another instance took the migration lock while migrations ran,
+// the migration in progress is cancelled and nothing more is recorded
+var ErrLockLost = errors.New("migration lock was lost")
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+type Status struct {
+	Version     int        `bson:"_id"`
+	Description string     `bson:"description"`
+	AppliedAt   *time.Time `bson:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	store      store
+	migrations []Migration
+	owner      string
+	logger     logging.Logger
+
+	lockTTL       time.Duration
+	retryInterval time.Duration
+}
+
+func NewMigrator(db *mongo.Database, migrations []Migration, logger logging.Logger) *Migrator {
+	return newMigrator(db, &mongoStore{db: db}, migrations, logger)
+}
+
+func newMigrator(db *mongo.Database, s store, migrations []Migration, logger logging.Logger) *Migrator {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:            db,
+		store:         s,
+		migrations:    sorted,
+		owner:         primitive.NewObjectID().Hex(),
+		logger:        logger,
+		lockTTL:       lockTTL,
+		retryInterval: lockRetryInterval,
+	}
+}
+
+// Up applies every migration that is not recorded in schema_migrations yet
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.store.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err = m.holdLock(ctx); err != nil {
+				return err
+			}
+			m.logger.Infof("apply migration %d: %s", migration.Version, migration.Description)
+			if err = migration.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("failed to apply migration %d. error: %w", migration.Version, err)
+			}
+			if err = m.holdLock(ctx); err != nil {
+				return err
+			}
+			record := Status{Version: migration.Version, Description: migration.Description, AppliedAt: now()}
+			if err = m.store.record(ctx, record); err != nil {
+				return fmt.Errorf("failed to record migration %d. error: %w", migration.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.store.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			m.logger.Infof("roll back migration %d: %s", migration.Version, migration.Description)
+			if migration.Down == nil {
+				return fmt.Errorf("migration %d can not be rolled back", migration.Version)
+			}
+			if err = m.holdLock(ctx); err != nil {
+				return err
+			}
+			if err = migration.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("failed to roll back migration %d. error: %w", migration.Version, err)
+			}
+			if err = m.holdLock(ctx); err != nil {
+				return err
+			}
+			if err = m.store.unrecord(ctx, migration.Version); err != nil {
+				return fmt.Errorf("failed to unrecord migration %d. error: %w", migration.Version, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration, AppliedAt is nil for pending ones
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.store.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withLock runs f while holding the migration lock, so replicas starting together do not apply the
+// same migration twice. The lock is renewed while f runs, a lock left by a crashed replica expires
+// after lockTTL. When the lock is lost anyway the context of f is cancelled.
+func (m *Migrator) withLock(ctx context.Context, f func(ctx context.Context) error) error {
+	for {
+		taken, err := m.store.lock(ctx, m.owner, m.lockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to take migration lock. error: %w", err)
+		}
+		if taken {
+			break
+		}
+
+		m.logger.Info("migration lock is held by another instance, waiting")
+		select {
+		case <-ctx.Done():
+			return errors.New("timed out waiting for migration lock")
+		case <-time.After(m.retryInterval):
+		}
+	}
+	defer func() {
+		if err := m.store.unlock(context.Background(), m.owner); err != nil {
+			m.logger.Errorf("failed to release migration lock: %v", err)
+		}
+	}()
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	lost := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		m.renewLock(lockCtx, cancel, lost)
+	}()
+
+	err := f(lockCtx)
+	cancel()
+	wg.Wait()
+
+	select {
+	case <-lost:
+		return fmt.Errorf("%w: %v", ErrLockLost, err)
+	default:
+		return err
+	}
+}
+
+// renewLock extends the lock three times per lockTTL until ctx is done. It closes lost and cancels
+// the migrations when the lock can not be extended.
+func (m *Migrator) renewLock(ctx context.Context, cancel context.CancelFunc, lost chan struct{}) {
+	ticker := time.NewTicker(m.lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.holdLock(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.logger.Errorf("stop migrations: %v", err)
+			close(lost)
+			cancel()
+			return
+		}
+	}
+}
+
+// holdLock extends the lock, it fences every change of the schema on still owning it
+func (m *Migrator) holdLock(ctx context.Context) error {
+	held, err := m.store.renew(ctx, m.owner, m.lockTTL)
+	if err != nil {
+		return fmt.Errorf("failed to renew migration lock. error: %w", err)
+	}
+	if !held {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func now() *time.Time {
+	t := time.Now()
+	return &t
+}
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// store keeps the applied migrations and the migration lock
+type store interface {
+	applied(ctx context.Context) (map[int]Status, error)
+	record(ctx context.Context, status Status) error
+	unrecord(ctx context.Context, version int) error
+	// lock takes the lock for owner unless another owner holds it and it has not expired
+	lock(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	// renew extends the lock of owner, false means another owner took it
+	renew(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	unlock(ctx context.Context, owner string) error
+}
+
+type mongoStore struct {
+	db *mongo.Database
+}
+
+func (s *mongoStore) applied(ctx context.Context) (map[int]Status, error) {
+	cur, err := s.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	var records []Status
+	if err = cur.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+
+	applied := make(map[int]Status, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func (s *mongoStore) record(ctx context.Context, status Status) error {
+	_, err := s.db.Collection(migrationsCollection).InsertOne(ctx, status)
+	return err
+}
+
+func (s *mongoStore) unrecord(ctx context.Context, version int) error {
+	_, err := s.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
+
+func (s *mongoStore) lock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	filter := bson.M{"_id": lockID, "expires_at": bson.M{"$lt": time.Now()}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": time.Now().Add(ttl)}}
+	_, err := s.db.Collection(lockCollection).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// the lock document exists and has not expired
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *mongoStore) renew(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	filter := bson.M{"_id": lockID, "owner": owner}
+	update := bson.M{"$set": bson.M{"expires_at": time.Now().Add(ttl)}}
+	result, err := s.db.Collection(lockCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (s *mongoStore) unlock(ctx context.Context, owner string) error {
+	_, err := s.db.Collection(lockCollection).DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	return err
+}
@@ -0,0 +1,81 @@
+// Package router builds the routed handler of the tag service. The service binary serves it on its
+// own listener, the all-in-one binary mounts it next to the other services in one process.
+package router
+
+import (
+	"context"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/internal/tag/db"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/handlers/metric"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/logging"
+	mongo "gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/mongodb"
+	"gitlab.konstweb.ru/ow/arch/notes/tag_service/pkg/mongodb/migrate"
+	"net/http"
+)
+
+// Config is the config of the service, Storage.Type chooses where tags are kept
+type Config = config.Config
+
+// New builds the handler of every route of the service on the storage of cfg
+func New(ctx context.Context, cfg *Config, logger logging.Logger) (http.Handler, error) {
+	router := httprouter.New()
+
+	metricHandler := metric.Handler{Logger: logger}
+	metricHandler.Register(router)
+
+	tagStorage, err := newStorage(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tagService, err := tag.NewService(tagStorage, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsHandler := tag.Handler{
+		Logger:     logger,
+		TagService: tagService,
+	}
+	tagsHandler.Register(router)
+
+	return router, nil
+}
+
+func newStorage(ctx context.Context, cfg *Config, logger logging.Logger) (tag.Storage, error) {
+	switch cfg.Storage.Type {
+	case "mongodb":
+		mongoClient, err := mongo.NewClient(ctx, cfg.MongoDB.Host, cfg.MongoDB.Port,
+			cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("apply mongodb migrations")
+		err = migrate.NewMigrator(mongoClient, db.MongoMigrations(cfg.MongoDB.Collection, logger), logger).Up(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// migration 5 leaves the unique path index out while paths differ only by case
+		err = db.EnsureUniquePaths(ctx, mongoClient.Collection(cfg.MongoDB.Collection), logger)
+		if err != nil {
+			return nil, err
+		}
+		orphans, err := db.FindOrphans(ctx, mongoClient, cfg.MongoDB.Collection)
+		if err != nil {
+			return nil, err
+		}
+		if len(orphans) > 0 {
+			// they predate owners and are not listed to anybody until `app migrate owners` is run
+			logger.Warnf("%d tags have no owner, assign them with `app migrate owners`", len(orphans))
+		}
+		return db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger), nil
+	case "memory":
+		logger.Warn("tags are kept in memory and will be lost on restart")
+		return db.NewMemoryStorage(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Storage.Type)
+	}
+}
@@ -4,14 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/julienschmidt/httprouter"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/config"
-	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
-	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user/db"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
-	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/metric"
-	mongo "gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/shutdown"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/router"
 	"net"
 	"net/http"
 	"os"
@@ -27,30 +23,26 @@ func main() {
 
 	cfg := config.GetConfig()
 
-	router := httprouter.New()
-
-	metricHandler := metric.Handler{Logger: logger}
-	metricHandler.Register(router)
-
-	mongoClient, err := mongo.NewClient(context.Background(), cfg.MongoDB.Host, cfg.MongoDB.Port,
-		cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
-	if err != nil {
-		logger.Fatal(err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrateCommand(context.Background(), cfg, logger, os.Args[2:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
-	userStorage := db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger)
-	userService, err := user.NewService(userStorage, logger)
-	if err != nil {
-		logger.Fatal(err)
+	if len(os.Args) > 1 && os.Args[1] == "duplicates" {
+		if err := duplicatesCommand(context.Background(), cfg, logger); err != nil {
+			logger.Fatal(err)
+		}
+		return
 	}
 
-	usersHandler := user.Handler{
-		Logger:      logger,
-		UserService: userService,
+	handler, err := router.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Fatal(err)
 	}
-	usersHandler.Register(router)
 
 	logger.Println("start application")
-	start(router, logger, cfg)
+	start(handler, logger, cfg)
 }
 
 func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	mongo "gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb"
+)
+
+// duplicatesCommand handles `app duplicates`. It lists users sharing an email after normalisation
+// and fails while there are any, emails get their unique index on the first start after they are resolved.
+func duplicatesCommand(ctx context.Context, cfg *config.Config, logger logging.Logger) error {
+	if cfg.Storage.Type != "mongodb" {
+		return fmt.Errorf("duplicates command works with mongodb storage only, configured %q", cfg.Storage.Type)
+	}
+
+	// no migrations here, the command only reads
+	mongoClient, err := mongo.NewClient(ctx, cfg.MongoDB.Host, cfg.MongoDB.Port,
+		cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
+	if err != nil {
+		return err
+	}
+	users, err := db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger).FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	duplicates := user.DuplicateEmails(users)
+	for _, d := range duplicates {
+		fmt.Println(d.Email)
+		for _, u := range d.Users {
+			fmt.Printf("  %s  %-40q  verified=%t\n", u.UUID, u.Email, u.Verified)
+		}
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%d emails belong to several users", len(duplicates))
+	}
+	fmt.Println("no duplicate emails")
+
+	return nil
+}
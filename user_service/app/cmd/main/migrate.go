@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	mongo "gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb/migrate"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/router"
+	"strconv"
+)
+
+const migrateUsage = "usage: app migrate [up | down [steps] | status]"
+
+// migrateCommand handles `app migrate ...` and exits without starting the server
+func migrateCommand(ctx context.Context, cfg *config.Config, logger logging.Logger, args []string) error {
+	if cfg.Storage.Type != "mongodb" {
+		return fmt.Errorf("migrate command works with mongodb storage only, configured %q", cfg.Storage.Type)
+	}
+
+	mongoClient, err := mongo.NewClient(ctx, cfg.MongoDB.Host, cfg.MongoDB.Port,
+		cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
+	if err != nil {
+		return err
+	}
+	migrator := migrate.NewMigrator(mongoClient, router.MongoMigrations(cfg, logger), logger)
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive integer. %s", migrateUsage)
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-19s  %s\n", status.Version, applied, status.Description)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q. %s", command, migrateUsage)
+	}
+}
@@ -3,10 +3,14 @@ package apperror
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"time"
 )
 
 var (
 	ErrNotFound = NewAppError("not found", "NS-000003", "")
+	// ErrEmailTaken is answered with 409 Conflict
+	ErrEmailTaken = NewAppError("email is taken", "NS-000005", "another user signed up with the email")
 )
 
 type AppError struct {
@@ -14,6 +18,8 @@ type AppError struct {
 	Message          string `json:"message,omitempty"`
 	DeveloperMessage string `json:"developer_message,omitempty"`
 	Code             string `json:"code,omitempty"`
+	// RetryAfter is in seconds, errors with it are answered with 429 Too Many Requests
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 func NewAppError(message, code, developerMessage string) *AppError {
@@ -43,6 +49,12 @@ func BadRequestError(message string) *AppError {
 	return NewAppError(message, "NS-000002", "some thing wrong with user data")
 }
 
+func TooManyRequestsError(message string, retryAfter time.Duration) *AppError {
+	appErr := NewAppError(message, "NS-000004", "too many failed attempts, retry later")
+	appErr.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+	return appErr
+}
+
 func systemError(developerMessage string) *AppError {
 	return NewAppError("system error", "NS-000001", developerMessage)
 }
@@ -3,6 +3,7 @@ package apperror
 import (
 	"errors"
 	"net/http"
+	"strconv"
 )
 
 type appHandler func(http.ResponseWriter, *http.Request) error
@@ -18,6 +19,17 @@ func Middleware(h appHandler) http.HandlerFunc {
 					w.Write(ErrNotFound.Marshal())
 					return
 				}
+				if errors.Is(err, ErrEmailTaken) {
+					w.WriteHeader(http.StatusConflict)
+					w.Write(ErrEmailTaken.Marshal())
+					return
+				}
+				if appErr.RetryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(appErr.RetryAfter))
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write(appErr.Marshal())
+					return
+				}
 				err := err.(*AppError)
 				w.WriteHeader(http.StatusBadRequest)
 				w.Write(err.Marshal())
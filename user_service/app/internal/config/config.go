@@ -4,6 +4,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
 	"sync"
+	"time"
 )
 
 type Config struct {
@@ -13,15 +14,73 @@ type Config struct {
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
 		Port   string `yaml:"port" env-default:"8080"`
 	}
+	Storage struct {
+		Type string `yaml:"type" env-default:"mongodb"`
+	} `yaml:"storage"`
 	MongoDB struct {
-		Host       string `yaml:"host" env-required:"true"`
-		Port       string `yaml:"port" env-required:"true"`
+		Host       string `yaml:"host"`
+		Port       string `yaml:"port"`
 		Username   string `yaml:"username"`
 		Password   string `yaml:"password"`
-		AuthDB     string `yaml:"auth_db" env-required:"true"`
-		Database   string `yaml:"database" env-required:"true"`
-		Collection string `yaml:"collection" env-required:"true"`
-	} `yaml:"mongodb" env-required:"true"`
+		AuthDB     string `yaml:"auth_db"`
+		Database   string `yaml:"database"`
+		Collection string `yaml:"collection"`
+		// AttemptsCollection keeps failed sign ins for throttling
+		AttemptsCollection string `yaml:"attempts_collection" env-default:"login_attempts"`
+		// DeletionsCollection keeps account deletions, also after the users are gone
+		DeletionsCollection string `yaml:"deletions_collection" env-default:"deletions"`
+	} `yaml:"mongodb"`
+	Password struct {
+		Algorithm  string `yaml:"algorithm" env-default:"bcrypt"`
+		BcryptCost int    `yaml:"bcrypt_cost" env-default:"12"`
+		Argon2id   struct {
+			Time       uint32 `yaml:"time" env-default:"3"`
+			MemoryKiB  uint32 `yaml:"memory_kib" env-default:"65536"`
+			Threads    uint8  `yaml:"threads" env-default:"2"`
+			KeyLength  uint32 `yaml:"key_length" env-default:"32"`
+			SaltLength uint32 `yaml:"salt_length" env-default:"16"`
+		} `yaml:"argon2id"`
+		MinLength int `yaml:"min_length" env-default:"8"`
+		MaxLength int `yaml:"max_length" env-default:"64"`
+		// BreachedFile lists refused passwords one per line, no check when it is empty
+		BreachedFile string `yaml:"breached_file"`
+	} `yaml:"password"`
+	Throttle struct {
+		Accounts ThrottleLimits `yaml:"accounts"`
+		Clients  ThrottleLimits `yaml:"clients"`
+	} `yaml:"throttle"`
+	Mailer struct {
+		Type string `yaml:"type" env-default:"log"`
+		From string `yaml:"from" env-default:"notes@localhost"`
+		// File collects messages of the log mailer, they are only logged when it is empty
+		File string `yaml:"file"`
+		SMTP struct {
+			Host     string `yaml:"host"`
+			Port     string `yaml:"port" env-default:"25"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+		} `yaml:"smtp"`
+	} `yaml:"mailer"`
+	Verification struct {
+		// URL is the public address verification links point to
+		URL string        `yaml:"url" env-default:"http://localhost:10000/api/verify"`
+		TTL time.Duration `yaml:"ttl" env-default:"24h"`
+	} `yaml:"verification"`
+	PasswordReset struct {
+		// URL is the page that asks for a new password, the reset token is added to its query
+		URL string        `yaml:"url" env-default:"http://localhost:10000/reset-password"`
+		TTL time.Duration `yaml:"ttl" env-default:"1h"`
+	} `yaml:"password_reset"`
+}
+
+// ThrottleLimits tune failed sign in handling of accounts or client addresses
+type ThrottleLimits struct {
+	FreeAttempts int           `yaml:"free_attempts"`
+	BaseDelay    time.Duration `yaml:"base_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay"`
+	LockoutAfter int           `yaml:"lockout_after"`
+	Lockout      time.Duration `yaml:"lockout"`
+	Window       time.Duration `yaml:"window"`
 }
 
 var instance *Config
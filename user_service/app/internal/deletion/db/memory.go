@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/deletion"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ deletion.Storage = &memoryDB{}
+
+// memoryDB keeps deletions in process memory, they are lost on restart together with the users
+type memoryDB struct {
+	mu        sync.RWMutex
+	deletions map[string]deletion.Deletion
+}
+
+func NewMemoryStorage() deletion.Storage {
+	return &memoryDB{deletions: make(map[string]deletion.Deletion)}
+}
+
+func (s *memoryDB) Create(ctx context.Context, d deletion.Deletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deletions[d.UserUUID]; ok {
+		return deletion.ErrExists
+	}
+	s.deletions[d.UserUUID] = copyDeletion(d)
+
+	return nil
+}
+
+func (s *memoryDB) Find(ctx context.Context, userUUID string) (deletion.Deletion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deletions[userUUID]
+	if !ok {
+		return deletion.Deletion{}, apperror.ErrNotFound
+	}
+	return copyDeletion(d), nil
+}
+
+func (s *memoryDB) FindByStatus(ctx context.Context, status deletion.Status) (deletions []deletion.Deletion, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, d := range s.deletions {
+		if d.Status == status {
+			deletions = append(deletions, copyDeletion(d))
+		}
+	}
+	sort.Slice(deletions, func(i, j int) bool { return deletions[i].RequestedAt.Before(deletions[j].RequestedAt) })
+
+	return deletions, nil
+}
+
+func (s *memoryDB) UpdateStep(ctx context.Context, userUUID string, step deletion.Step, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deletions[userUUID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	d = copyDeletion(d)
+	for i := range d.Steps {
+		if d.Steps[i].Name == step.Name {
+			d.Steps[i] = step
+		}
+	}
+	d.UpdatedAt = now
+	s.deletions[userUUID] = d
+
+	return nil
+}
+
+func (s *memoryDB) Finish(ctx context.Context, userUUID string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deletions[userUUID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	d.Status = deletion.StatusDone
+	d.UpdatedAt = now
+	d.FinishedAt = &now
+	s.deletions[userUUID] = d
+
+	return nil
+}
+
+func copyDeletion(d deletion.Deletion) deletion.Deletion {
+	d.Steps = append([]deletion.Step{}, d.Steps...)
+	return d
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/deletion"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var _ deletion.Storage = &db{}
+
+type db struct {
+	collection *mongo.Collection
+	logger     logging.Logger
+}
+
+func NewStorage(storage *mongo.Database, collection string, logger logging.Logger) deletion.Storage {
+	return &db{
+		collection: storage.Collection(collection),
+		logger:     logger,
+	}
+}
+
+func (s *db) Create(ctx context.Context, d deletion.Deletion) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.collection.InsertOne(ctx, d); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return deletion.ErrExists
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	return nil
+}
+
+func (s *db) Find(ctx context.Context, userUUID string) (d deletion.Deletion, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result := s.collection.FindOne(ctx, bson.M{"_id": userUUID})
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return d, apperror.ErrNotFound
+		}
+		return d, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = result.Decode(&d); err != nil {
+		return d, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+
+	return d, nil
+}
+
+func (s *db) FindByStatus(ctx context.Context, status deletion.Status) (deletions []deletion.Deletion, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "requested_at", Value: 1}})
+	cursor, err := s.collection.Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cursor.All(ctx, &deletions); err != nil {
+		return nil, fmt.Errorf("failed to decode documents. error: %w", err)
+	}
+
+	return deletions, nil
+}
+
+func (s *db) UpdateStep(ctx context.Context, userUUID string, step deletion.Step, now time.Time) error {
+	return s.updateOne(ctx, bson.M{"_id": userUUID, "steps.name": step.Name},
+		bson.M{"$set": bson.M{"steps.$": step, "updated_at": now}})
+}
+
+func (s *db) Finish(ctx context.Context, userUUID string, now time.Time) error {
+	return s.updateOne(ctx, bson.M{"_id": userUUID},
+		bson.M{"$set": bson.M{"status": deletion.StatusDone, "updated_at": now, "finished_at": now}})
+}
+
+func (s *db) updateOne(ctx context.Context, filter, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
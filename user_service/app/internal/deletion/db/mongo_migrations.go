@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations returns the schema history of the account deletions collection. Versions
+// continue the ones of the other collections, all of them share the migrations history.
+func MongoMigrations(collection string) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     6,
+			Description: "index account deletions by status",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "status", Value: 1}, {Key: "requested_at", Value: 1}},
+					Options: options.Index().SetName("status_requested_at"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "status_requested_at")
+				return err
+			},
+		},
+	}
+}
@@ -0,0 +1,103 @@
+package deletion
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"net/http"
+)
+
+const (
+	deletionsURL = "/api/deletions"
+	deletionURL  = "/api/deletions/:uuid"
+)
+
+type Handler struct {
+	Logger          logging.Logger
+	DeletionService Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodPost, deletionsURL, apperror.Middleware(h.StartDeletion))
+	router.HandlerFunc(http.MethodGet, deletionsURL, apperror.Middleware(h.GetRunningDeletions))
+	router.HandlerFunc(http.MethodGet, deletionURL, apperror.Middleware(h.GetDeletion))
+	router.HandlerFunc(http.MethodPatch, deletionURL, apperror.Middleware(h.ReportStep))
+}
+
+// StartDeletion answers 201 for a new deletion and 200 with the one in progress otherwise
+func (h *Handler) StartDeletion(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var dto StartDeletionDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	d, created, err := h.DeletionService.Start(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", deletionsURL, d.UserUUID))
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	return writeJSON(w, status, d)
+}
+
+// GetRunningDeletions lists the unfinished deletions, the only status that can be asked for
+func (h *Handler) GetRunningDeletions(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	if status := r.URL.Query().Get("status"); status != string(StatusRunning) {
+		return apperror.BadRequestError(fmt.Sprintf("status query parameter must be %s", StatusRunning))
+	}
+	deletions, err := h.DeletionService.Running(r.Context())
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, deletions)
+}
+
+func (h *Handler) GetDeletion(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+	d, err := h.DeletionService.Get(r.Context(), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, d)
+}
+
+func (h *Handler) ReportStep(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto ReportStepDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+	dto.UserUUID = params.ByName("uuid")
+
+	d, err := h.DeletionService.ReportStep(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, d)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+	return nil
+}
@@ -0,0 +1,77 @@
+package deletion
+
+import (
+	"time"
+)
+
+type Status string
+
+const (
+	StatusRunning Status = "running"
+	// StatusDone is set once every step is done and the user is removed
+	StatusDone Status = "done"
+)
+
+// Deletion removes an account and everything its user keeps in other services. The gateway runs
+// the steps and reports each of them, the record survives the user so the outcome can be looked up.
+type Deletion struct {
+	UserUUID    string     `json:"user_uuid" bson:"_id"`
+	Status      Status     `json:"status" bson:"status"`
+	Steps       []Step     `json:"steps" bson:"steps"`
+	RequestedAt time.Time  `json:"requested_at" bson:"requested_at"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// Step removes the data of the user from one service
+type Step struct {
+	Name     string `json:"name" bson:"name"`
+	Done     bool   `json:"done" bson:"done"`
+	Attempts int    `json:"attempts" bson:"attempts"`
+	// Error is the failure of the last attempt, cleared when the step is done
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+func (d Deletion) step(name string) (Step, bool) {
+	for _, s := range d.Steps {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Step{}, false
+}
+
+func (d Deletion) stepsDone() bool {
+	for _, s := range d.Steps {
+		if !s.Done {
+			return false
+		}
+	}
+	return true
+}
+
+type StartDeletionDTO struct {
+	UserUUID string   `json:"user_uuid"`
+	Steps    []string `json:"steps"`
+}
+
+// ReportStepDTO records an attempt of a step, it failed when Error is set
+type ReportStepDTO struct {
+	UserUUID string `json:"-"`
+	Step     string `json:"step"`
+	Error    string `json:"error,omitempty"`
+}
+
+func NewDeletion(dto StartDeletionDTO, now time.Time) Deletion {
+	steps := make([]Step, 0, len(dto.Steps))
+	for _, name := range dto.Steps {
+		steps = append(steps, Step{Name: name})
+	}
+	return Deletion{
+		UserUUID:    dto.UserUUID,
+		Status:      StatusRunning,
+		Steps:       steps,
+		RequestedAt: now,
+		UpdatedAt:   now,
+	}
+}
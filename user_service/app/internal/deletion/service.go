@@ -0,0 +1,155 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"time"
+)
+
+var _ Service = &service{}
+
+type service struct {
+	storage Storage
+	users   user.Service
+	logger  logging.Logger
+}
+
+func NewService(storage Storage, users user.Service, logger logging.Logger) Service {
+	return &service{
+		storage: storage,
+		users:   users,
+		logger:  logger,
+	}
+}
+
+type Service interface {
+	// Start disables the user and records the deletion. It returns whether the deletion was created,
+	// a deletion of the user that exists already is returned as it is.
+	Start(ctx context.Context, dto StartDeletionDTO) (Deletion, bool, error)
+	Get(ctx context.Context, userUUID string) (Deletion, error)
+	// Running returns the unfinished deletions, the gateway resumes them
+	Running(ctx context.Context) ([]Deletion, error)
+	// ReportStep records an attempt of a step. Once every step is done the user is removed and the
+	// deletion is done.
+	ReportStep(ctx context.Context, dto ReportStepDTO) (Deletion, error)
+}
+
+func (s *service) Start(ctx context.Context, dto StartDeletionDTO) (Deletion, bool, error) {
+	if dto.UserUUID == "" {
+		return Deletion{}, false, apperror.BadRequestError("user uuid is required")
+	}
+	if len(dto.Steps) == 0 {
+		return Deletion{}, false, apperror.BadRequestError("deletion steps are required")
+	}
+	seen := make(map[string]bool, len(dto.Steps))
+	for _, name := range dto.Steps {
+		if name == "" || seen[name] {
+			return Deletion{}, false, apperror.BadRequestError(fmt.Sprintf("step names must be unique and not empty, got %q", name))
+		}
+		seen[name] = true
+	}
+
+	existing, err := s.storage.Find(ctx, dto.UserUUID)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, apperror.ErrNotFound) {
+		return Deletion{}, false, fmt.Errorf("failed to find deletion. error: %w", err)
+	}
+
+	// sign in stops before anything is removed, a half deleted account must not be used
+	if err = s.users.Disable(ctx, dto.UserUUID); err != nil {
+		return Deletion{}, false, err
+	}
+
+	d := NewDeletion(dto, time.Now().UTC())
+	if err = s.storage.Create(ctx, d); err != nil {
+		if errors.Is(err, ErrExists) {
+			existing, err = s.storage.Find(ctx, dto.UserUUID)
+			return existing, false, err
+		}
+		return Deletion{}, false, fmt.Errorf("failed to create deletion. error: %w", err)
+	}
+	s.logger.Infof("deletion of user %s started", d.UserUUID)
+
+	return d, true, nil
+}
+
+func (s *service) Get(ctx context.Context, userUUID string) (Deletion, error) {
+	d, err := s.storage.Find(ctx, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return d, err
+		}
+		return d, fmt.Errorf("failed to find deletion. error: %w", err)
+	}
+	return d, nil
+}
+
+func (s *service) Running(ctx context.Context) ([]Deletion, error) {
+	deletions, err := s.storage.FindByStatus(ctx, StatusRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find running deletions. error: %w", err)
+	}
+	if deletions == nil {
+		deletions = []Deletion{}
+	}
+	return deletions, nil
+}
+
+func (s *service) ReportStep(ctx context.Context, dto ReportStepDTO) (Deletion, error) {
+	d, err := s.Get(ctx, dto.UserUUID)
+	if err != nil {
+		return d, err
+	}
+	step, ok := d.step(dto.Step)
+	if !ok {
+		return d, apperror.BadRequestError(fmt.Sprintf("deletion has no step %q", dto.Step))
+	}
+	// reports may come late or twice when the gateway retries, a done step stays done
+	if d.Status == StatusDone || step.Done {
+		return d, nil
+	}
+
+	step.Attempts++
+	step.Error = dto.Error
+	step.Done = dto.Error == ""
+	now := time.Now().UTC()
+	if err = s.storage.UpdateStep(ctx, d.UserUUID, step, now); err != nil {
+		return d, fmt.Errorf("failed to update deletion step. error: %w", err)
+	}
+	for i := range d.Steps {
+		if d.Steps[i].Name == step.Name {
+			d.Steps[i] = step
+		}
+	}
+	d.UpdatedAt = now
+	if !step.Done {
+		s.logger.Warnf("deletion of user %s failed at step %s: %s", d.UserUUID, step.Name, step.Error)
+	}
+
+	if d.stepsDone() {
+		return s.finish(ctx, d, now)
+	}
+	return d, nil
+}
+
+// finish removes the user once the data in other services is gone
+func (s *service) finish(ctx context.Context, d Deletion, now time.Time) (Deletion, error) {
+	if err := s.users.Delete(ctx, d.UserUUID); err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return d, err
+	}
+	if err := s.storage.Finish(ctx, d.UserUUID, now); err != nil {
+		return d, fmt.Errorf("failed to finish deletion. error: %w", err)
+	}
+	d.Status = StatusDone
+	d.UpdatedAt = now
+	d.FinishedAt = &now
+	s.logger.Infof("deletion of user %s finished", d.UserUUID)
+
+	return d, nil
+}
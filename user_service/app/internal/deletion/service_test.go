@@ -0,0 +1,104 @@
+package deletion_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/deletion"
+	deletiondb "gitlab.konstweb.ru/ow/arch/notes/user_service/internal/deletion/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/mail"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/password"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	userdb "gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+func newServices(t *testing.T) (deletion.Service, user.Service) {
+	t.Helper()
+	hasher, err := password.NewHasher(password.AlgorithmBcrypt, bcrypt.MinCost, password.Argon2Params{})
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+	mailer := mail.NewLogMailer("", "notes@localhost", testLogger)
+	users, err := user.NewService(userdb.NewMemoryStorage(testLogger), hasher, password.NewPolicy(6, 64), mailer,
+		user.VerificationSettings{URL: "http://localhost/api/verify", TTL: time.Hour},
+		user.VerificationSettings{URL: "http://localhost/reset-password", TTL: time.Hour}, testLogger)
+	if err != nil {
+		t.Fatalf("new user service: %v", err)
+	}
+	return deletion.NewService(deletiondb.NewMemoryStorage(), users, testLogger), users
+}
+
+func TestDeletionRemovesUserAfterAllSteps(t *testing.T) {
+	ctx := context.Background()
+	deletions, users := newServices(t)
+	uuid, err := users.Create(ctx, user.CreateUserDTO{Email: "alice@example.com", Password: "secret", RepeatPassword: "secret"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	d, created, err := deletions.Start(ctx, deletion.StartDeletionDTO{UserUUID: uuid, Steps: []string{"notes", "tags"}})
+	if err != nil || !created || d.Status != deletion.StatusRunning || len(d.Steps) != 2 {
+		t.Fatalf("start = %+v, %t, %v", d, created, err)
+	}
+	if _, err = users.GetByEmailAndPassword(ctx, "alice@example.com", "secret"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("user being deleted signed in, error %v", err)
+	}
+	if _, created, err = deletions.Start(ctx, deletion.StartDeletionDTO{UserUUID: uuid, Steps: []string{"other"}}); err != nil || created {
+		t.Fatalf("second start created %t, error %v; want the running deletion", created, err)
+	}
+
+	d, err = deletions.ReportStep(ctx, deletion.ReportStepDTO{UserUUID: uuid, Step: "notes", Error: "note_service is down"})
+	if err != nil || d.Steps[0].Done || d.Steps[0].Attempts != 1 || d.Steps[0].Error == "" {
+		t.Fatalf("failed step = %+v, %v", d.Steps[0], err)
+	}
+	for _, step := range []string{"notes", "tags"} {
+		if d, err = deletions.ReportStep(ctx, deletion.ReportStepDTO{UserUUID: uuid, Step: step}); err != nil {
+			t.Fatalf("report %s: %v", step, err)
+		}
+	}
+	if d.Status != deletion.StatusDone || d.FinishedAt == nil || d.Steps[0].Attempts != 2 || d.Steps[0].Error != "" {
+		t.Fatalf("finished deletion = %+v", d)
+	}
+	if _, err = users.GetOne(ctx, uuid); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("user is still there, error %v", err)
+	}
+	if running, err := deletions.Running(ctx); err != nil || len(running) != 0 {
+		t.Fatalf("running deletions = %+v, %v", running, err)
+	}
+	if d, err = deletions.Get(ctx, uuid); err != nil || d.Status != deletion.StatusDone {
+		t.Fatalf("deletion outlives the user = %+v, %v", d, err)
+	}
+}
+
+func TestDeletionRejectsUnknownUsersAndSteps(t *testing.T) {
+	ctx := context.Background()
+	deletions, users := newServices(t)
+
+	_, _, err := deletions.Start(ctx, deletion.StartDeletionDTO{UserUUID: "5f0c8b7e9d3a4b2c1d0e0f10", Steps: []string{"notes"}})
+	if !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("start for a missing user: %v", err)
+	}
+
+	uuid, err := users.Create(ctx, user.CreateUserDTO{Email: "bob@example.com", Password: "secret", RepeatPassword: "secret"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, _, err = deletions.Start(ctx, deletion.StartDeletionDTO{UserUUID: uuid, Steps: []string{"notes", "notes"}}); err == nil {
+		t.Fatal("repeated step names accepted")
+	}
+	if _, _, err = deletions.Start(ctx, deletion.StartDeletionDTO{UserUUID: uuid, Steps: []string{"notes"}}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	var appErr *apperror.AppError
+	if _, err = deletions.ReportStep(ctx, deletion.ReportStepDTO{UserUUID: uuid, Step: "files"}); !errors.As(err, &appErr) || appErr.Code != "NS-000002" {
+		t.Fatalf("unknown step: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrExists is returned by Create when the user is being deleted already
+var ErrExists = errors.New("deletion exists")
+
+type Storage interface {
+	Create(ctx context.Context, d Deletion) error
+	Find(ctx context.Context, userUUID string) (Deletion, error)
+	// FindByStatus returns the deletions with the status ordered by request time
+	FindByStatus(ctx context.Context, status Status) ([]Deletion, error)
+	// UpdateStep replaces the step with the same name
+	UpdateStep(ctx context.Context, userUUID string, step Step, now time.Time) error
+	Finish(ctx context.Context, userUUID string, now time.Time) error
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"os"
+	"sync"
+	"time"
+)
+
+var _ Mailer = &logMailer{}
+
+// logMailer is for local runs, nothing is delivered. Messages go to the log and are appended
+// to a file when path is set, so links from them can be opened by hand.
+type logMailer struct {
+	mu     sync.Mutex
+	path   string
+	from   string
+	logger logging.Logger
+}
+
+func NewLogMailer(path, from string, logger logging.Logger) Mailer {
+	return &logMailer{path: path, from: from, logger: logger}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Infof("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	if m.path == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open mail file. error: %w", err)
+	}
+	defer f.Close()
+	if _, err = fmt.Fprintf(f, "Date: %s\r\n%s\r\n\r\n", time.Now().Format(time.RFC1123Z), compose(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to write mail file. error: %w", err)
+	}
+	return nil
+}
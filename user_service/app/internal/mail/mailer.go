@@ -0,0 +1,17 @@
+package mail
+
+import (
+	"context"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages to users
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
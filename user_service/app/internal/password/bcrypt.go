@@ -0,0 +1,43 @@
+package password
+
+import (
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxBytes is how much of a password bcrypt takes into account
+const bcryptMaxBytes = 72
+
+type bcryptHasher struct {
+	cost int
+}
+
+func (h bcryptHasher) validate() error {
+	if h.cost < bcrypt.MinCost || h.cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost %d is out of range %d..%d", h.cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+func (h bcryptHasher) hash(password string) (string, error) {
+	if len(password) > bcryptMaxBytes {
+		return "", ErrTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password due to error %w", err)
+	}
+	return string(hash), nil
+}
+
+func (h bcryptHasher) compare(hash, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (h bcryptHasher) needsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
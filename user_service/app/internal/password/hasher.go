@@ -0,0 +1,79 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMismatch = errors.New("password does not match")
+	ErrTooLong  = errors.New("password is too long for the hash algorithm")
+)
+
+// Hasher hashes passwords with the configured algorithm. It compares passwords with hashes of
+// every supported algorithm, so stored hashes keep working after the configuration changes.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) error
+	// NeedsRehash tells whether the hash was made by another algorithm or with other parameters
+	NeedsRehash(hash string) bool
+}
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+type hasher struct {
+	algorithm string
+	bcrypt    bcryptHasher
+	argon2id  argon2idHasher
+}
+
+// NewHasher returns a hasher using algorithm for new hashes
+func NewHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (Hasher, error) {
+	h := &hasher{
+		algorithm: algorithm,
+		bcrypt:    bcryptHasher{cost: bcryptCost},
+		argon2id:  argon2idHasher{params: argon2Params},
+	}
+	switch algorithm {
+	case AlgorithmBcrypt:
+		if err := h.bcrypt.validate(); err != nil {
+			return nil, err
+		}
+	case AlgorithmArgon2id:
+		if err := h.argon2id.validate(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+	return h, nil
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmArgon2id {
+		return h.argon2id.hash(password)
+	}
+	return h.bcrypt.hash(password)
+}
+
+func (h *hasher) Compare(hash, password string) error {
+	if isArgon2id(hash) {
+		return h.argon2id.compare(hash, password)
+	}
+	return h.bcrypt.compare(hash, password)
+}
+
+func (h *hasher) NeedsRehash(hash string) bool {
+	if h.algorithm == AlgorithmArgon2id {
+		return !isArgon2id(hash) || h.argon2id.needsRehash(hash)
+	}
+	return isArgon2id(hash) || h.bcrypt.needsRehash(hash)
+}
+
+func isArgon2id(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
@@ -0,0 +1,103 @@
+package password_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/password"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var fastArgon2 = password.Argon2Params{Time: 1, Memory: 1024, Threads: 1, KeyLength: 16, SaltLength: 8}
+
+func newHasher(t *testing.T, algorithm string, cost int, params password.Argon2Params) password.Hasher {
+	t.Helper()
+	h, err := password.NewHasher(algorithm, cost, params)
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+	return h
+}
+
+func TestHashAndCompare(t *testing.T) {
+	for _, h := range []password.Hasher{
+		newHasher(t, password.AlgorithmBcrypt, bcrypt.MinCost, fastArgon2),
+		newHasher(t, password.AlgorithmArgon2id, bcrypt.MinCost, fastArgon2),
+	} {
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+		if err = h.Compare(hash, "correct horse"); err != nil {
+			t.Errorf("compare %s: %v", hash, err)
+		}
+		if err = h.Compare(hash, "wrong horse"); err != password.ErrMismatch {
+			t.Errorf("compare a wrong password with %s: %v", hash, err)
+		}
+		if h.NeedsRehash(hash) {
+			t.Errorf("fresh hash %s needs rehash", hash)
+		}
+	}
+
+	if _, err := password.NewHasher("md5", 10, fastArgon2); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+	if _, err := newHasher(t, password.AlgorithmBcrypt, bcrypt.MinCost, fastArgon2).Hash(strings.Repeat("a", 73)); err != password.ErrTooLong {
+		t.Errorf("hash of 73 bytes with bcrypt: %v", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := newHasher(t, password.AlgorithmBcrypt, bcrypt.MinCost, fastArgon2)
+	weakHash, _ := weak.Hash("secret")
+
+	stronger := newHasher(t, password.AlgorithmBcrypt, bcrypt.MinCost+1, fastArgon2)
+	if !stronger.NeedsRehash(weakHash) {
+		t.Error("hash of a lower cost does not need rehash")
+	}
+
+	argon := newHasher(t, password.AlgorithmArgon2id, bcrypt.MinCost, fastArgon2)
+	if !argon.NeedsRehash(weakHash) {
+		t.Error("bcrypt hash does not need rehash for argon2id")
+	}
+	if err := argon.Compare(weakHash, "secret"); err != nil {
+		t.Errorf("argon2id hasher does not compare bcrypt hashes: %v", err)
+	}
+
+	argonHash, _ := argon.Hash("secret")
+	tuned := fastArgon2
+	tuned.Time = 2
+	if !newHasher(t, password.AlgorithmArgon2id, bcrypt.MinCost, tuned).NeedsRehash(argonHash) {
+		t.Error("argon2id hash of other parameters does not need rehash")
+	}
+	if !weak.NeedsRehash(argonHash) {
+		t.Error("argon2id hash does not need rehash for bcrypt")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(file, []byte("password1\n Qwerty123 \n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := password.NewPolicy(8, 12)
+	if err := p.LoadBreached(file); err != nil {
+		t.Fatalf("load breached: %v", err)
+	}
+
+	for pwd, ok := range map[string]bool{
+		"":              false,
+		"short":         false,
+		"long enough":   true,
+		"пароль-ок":     true,
+		"far too long!": false,
+		"password1":     false,
+		"QWERTY123":     false,
+	} {
+		if err := p.Check(pwd); (err == nil) != ok {
+			t.Errorf("check %q = %v, want ok %v", pwd, err, ok)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Policy decides which passwords users may choose
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// breached holds lower cased passwords known from leaks
+	breached map[string]struct{}
+}
+
+func NewPolicy(minLength, maxLength int) *Policy {
+	return &Policy{MinLength: minLength, MaxLength: maxLength}
+}
+
+// LoadBreached reads the breached passwords from a file with one password per line
+func (p *Policy) LoadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached passwords file. error: %w", err)
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached passwords file. error: %w", err)
+	}
+	p.breached = breached
+
+	return nil
+}
+
+// Check returns a message for the user when the password is refused, lengths count characters
+func (p *Policy) Check(password string) error {
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters long", p.MaxLength)
+	}
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return fmt.Errorf("password is known from data breaches, choose another one")
+	}
+	return nil
+}
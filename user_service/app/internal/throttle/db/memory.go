@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle"
+	"sync"
+	"time"
+)
+
+var _ throttle.Storage = &memoryDB{}
+
+// memoryDB keeps sign in attempts in process memory, expired ones are dropped on access
+type memoryDB struct {
+	mu       sync.Mutex
+	attempts map[string]throttle.Attempts
+}
+
+func NewMemoryStorage() throttle.Storage {
+	return &memoryDB{attempts: make(map[string]throttle.Attempts)}
+}
+
+func (s *memoryDB) Find(ctx context.Context, key string) (throttle.Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.find(key, time.Now())
+	if !ok {
+		return a, apperror.ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *memoryDB) AddFailure(ctx context.Context, key string, now time.Time, window, retention time.Duration) (throttle.Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.find(key, now)
+	if !ok || now.Sub(a.LastFailure) > window {
+		a.Failures = 0
+	}
+	a.Key = key
+	a.Failures++
+	a.LastFailure = now
+	if expires := now.Add(retention); expires.After(a.ExpiresAt) {
+		a.ExpiresAt = expires
+	}
+	s.attempts[key] = a
+
+	return a, nil
+}
+
+func (s *memoryDB) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attempts[key]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	a.LockedUntil = until
+	if until.After(a.ExpiresAt) {
+		a.ExpiresAt = until
+	}
+	s.attempts[key] = a
+
+	return nil
+}
+
+func (s *memoryDB) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.attempts[key]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(s.attempts, key)
+
+	return nil
+}
+
+func (s *memoryDB) find(key string, now time.Time) (throttle.Attempts, bool) {
+	a, ok := s.attempts[key]
+	if ok && now.After(a.ExpiresAt) {
+		delete(s.attempts, key)
+		return throttle.Attempts{}, false
+	}
+	return a, ok
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var _ throttle.Storage = &db{}
+
+type db struct {
+	collection *mongo.Collection
+	logger     logging.Logger
+}
+
+func NewStorage(storage *mongo.Database, collection string, logger logging.Logger) throttle.Storage {
+	return &db{
+		collection: storage.Collection(collection),
+		logger:     logger,
+	}
+}
+
+func (s *db) Find(ctx context.Context, key string) (a throttle.Attempts, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// the TTL monitor runs once a minute, expired documents may still be there
+	filter := bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}
+	result := s.collection.FindOne(ctx, filter)
+	if err = result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return a, apperror.ErrNotFound
+		}
+		return a, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = result.Decode(&a); err != nil {
+		return a, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+
+	return a, nil
+}
+
+func (s *db) AddFailure(ctx context.Context, key string, now time.Time, window, retention time.Duration) (a throttle.Attempts, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// one pipeline update keeps concurrent failures from being lost
+	stale := bson.M{"$or": bson.A{
+		bson.M{"$lt": bson.A{"$last_failure", now.Add(-window)}},
+		bson.M{"$lt": bson.A{"$expires_at", now}},
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures": bson.M{"$cond": bson.A{
+				stale, 1, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			}},
+			"last_failure": now,
+			"expires_at":   bson.M{"$max": bson.A{"$expires_at", now.Add(retention)}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	result := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts)
+	if err = result.Err(); err != nil {
+		return a, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = result.Decode(&a); err != nil {
+		return a, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+
+	return a, nil
+}
+
+func (s *db) Lock(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"locked_until": until,
+			"expires_at":   bson.M{"$max": bson.A{"$expires_at", until}},
+		}}},
+	}
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": key}, update)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *db) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
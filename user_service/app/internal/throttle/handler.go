@@ -0,0 +1,33 @@
+package throttle
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"net/http"
+)
+
+const lockoutsURL = "/api/lockouts"
+
+type Handler struct {
+	Logger   logging.Logger
+	Throttle Service
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodDelete, lockoutsURL, apperror.Middleware(h.Unlock))
+}
+
+// Unlock forgets failed sign ins of the email and of the ip given in the query
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	email, ip := r.URL.Query().Get("email"), r.URL.Query().Get("ip")
+	if err := h.Throttle.Unlock(r.Context(), email, ip); err != nil {
+		return err
+	}
+	h.Logger.Infof("sign in lockout of email %q and ip %q removed", email, ip)
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
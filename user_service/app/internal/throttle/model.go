@@ -0,0 +1,57 @@
+package throttle
+
+import (
+	"time"
+)
+
+// Attempts is the history of failed sign ins of one account or one client address
+type Attempts struct {
+	Key         string    `json:"key" bson:"_id"`
+	Failures    int       `json:"failures" bson:"failures"`
+	LastFailure time.Time `json:"last_failure" bson:"last_failure"`
+	LockedUntil time.Time `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	// ExpiresAt is when the history is not needed any more and may be dropped
+	ExpiresAt time.Time `json:"-" bson:"expires_at"`
+}
+
+// Limits tune throttling of one kind of key. After FreeAttempts failures every next attempt
+// waits BaseDelay, doubled with each failure up to MaxDelay. LockoutAfter failures lock the key
+// for Lockout. Failures older than Window are forgotten.
+type Limits struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	Lockout      time.Duration
+	Window       time.Duration
+}
+
+// wait returns how long the key has to wait at now before the next attempt
+func (l Limits) wait(a Attempts, now time.Time) time.Duration {
+	if now.Before(a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+	if now.Sub(a.LastFailure) > l.Window || a.Failures <= l.FreeAttempts {
+		return 0
+	}
+
+	delay := l.BaseDelay
+	for i := l.FreeAttempts + 1; i < a.Failures && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.MaxDelay {
+		delay = l.MaxDelay
+	}
+	if next := a.LastFailure.Add(delay); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// retention is how long a failure matters
+func (l Limits) retention() time.Duration {
+	if l.Lockout > l.Window {
+		return l.Lockout
+	}
+	return l.Window
+}
@@ -0,0 +1,115 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"strings"
+	"time"
+)
+
+var _ Service = &service{}
+
+type service struct {
+	storage  Storage
+	accounts Limits
+	clients  Limits
+	logger   logging.Logger
+}
+
+// NewService throttles sign ins per account with accounts limits and per client address
+// with clients limits
+func NewService(storage Storage, accounts, clients Limits, logger logging.Logger) Service {
+	return &service{
+		storage:  storage,
+		accounts: accounts,
+		clients:  clients,
+		logger:   logger,
+	}
+}
+
+type Service interface {
+	// Wait returns how long the client has to wait before it may try to sign in to the account,
+	// zero when it may try now
+	Wait(ctx context.Context, email, clientIP string) (time.Duration, error)
+	Failed(ctx context.Context, email, clientIP string) error
+	// Succeeded forgets failures of the account. Failures of the address stay, one known
+	// password must not clear guesses at other accounts.
+	Succeeded(ctx context.Context, email string) error
+	// Unlock forgets failures of the account and of the address, empty ones are skipped
+	Unlock(ctx context.Context, email, clientIP string) error
+}
+
+func (s *service) Wait(ctx context.Context, email, clientIP string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range s.keys(email, clientIP) {
+		a, err := s.storage.Find(ctx, k.key)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			return 0, fmt.Errorf("failed to find sign in attempts. error: %w", err)
+		}
+		if w := k.limits.wait(a, now); w > wait {
+			wait = w
+		}
+	}
+	return wait, nil
+}
+
+func (s *service) Failed(ctx context.Context, email, clientIP string) error {
+	now := time.Now()
+	for _, k := range s.keys(email, clientIP) {
+		a, err := s.storage.AddFailure(ctx, k.key, now, k.limits.Window, k.limits.retention())
+		if err != nil {
+			return fmt.Errorf("failed to count sign in failure. error: %w", err)
+		}
+		if k.limits.LockoutAfter > 0 && a.Failures >= k.limits.LockoutAfter {
+			s.logger.Warnf("%s locked out after %d failed sign ins", k.key, a.Failures)
+			if err = s.storage.Lock(ctx, k.key, now.Add(k.limits.Lockout)); err != nil {
+				return fmt.Errorf("failed to lock out. error: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *service) Succeeded(ctx context.Context, email string) error {
+	return s.forget(ctx, s.keys(email, ""))
+}
+
+func (s *service) Unlock(ctx context.Context, email, clientIP string) error {
+	keys := s.keys(email, clientIP)
+	if len(keys) == 0 {
+		return apperror.BadRequestError("email or ip is required")
+	}
+	return s.forget(ctx, keys)
+}
+
+func (s *service) forget(ctx context.Context, keys []limitedKey) error {
+	for _, k := range keys {
+		err := s.storage.Delete(ctx, k.key)
+		if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("failed to forget sign in attempts. error: %w", err)
+		}
+	}
+	return nil
+}
+
+type limitedKey struct {
+	key    string
+	limits Limits
+}
+
+func (s *service) keys(email, clientIP string) (keys []limitedKey) {
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		keys = append(keys, limitedKey{key: "email:" + email, limits: s.accounts})
+	}
+	if clientIP != "" {
+		keys = append(keys, limitedKey{key: "ip:" + clientIP, limits: s.clients})
+	}
+	return keys
+}
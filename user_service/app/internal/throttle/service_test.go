@@ -0,0 +1,99 @@
+package throttle_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+func newService() throttle.Service {
+	accounts := throttle.Limits{FreeAttempts: 2, BaseDelay: time.Minute, MaxDelay: 4 * time.Minute,
+		LockoutAfter: 6, Lockout: time.Hour, Window: time.Hour}
+	clients := throttle.Limits{FreeAttempts: 4, BaseDelay: time.Minute, MaxDelay: time.Minute,
+		LockoutAfter: 100, Lockout: time.Hour, Window: time.Hour}
+	return throttle.NewService(db.NewMemoryStorage(), accounts, clients, testLogger)
+}
+
+func fail(t *testing.T, s throttle.Service, email, ip string, times int) {
+	t.Helper()
+	for i := 0; i < times; i++ {
+		if err := s.Failed(context.Background(), email, ip); err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+	}
+}
+
+func wait(t *testing.T, s throttle.Service, email, ip string) time.Duration {
+	t.Helper()
+	w, err := s.Wait(context.Background(), email, ip)
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	return w
+}
+
+func TestProgressiveDelayAndLockout(t *testing.T) {
+	ctx := context.Background()
+	s := newService()
+
+	fail(t, s, "Alice@example.com", "", 2)
+	if w := wait(t, s, "alice@example.com", "10.0.0.1"); w != 0 {
+		t.Fatalf("wait after free failures = %v", w)
+	}
+
+	// delays double from the base up to the maximum
+	for failures, want := range map[int]time.Duration{3: time.Minute, 4: 2 * time.Minute, 5: 4 * time.Minute} {
+		s := newService()
+		fail(t, s, "alice@example.com", "", failures)
+		if w := wait(t, s, "alice@example.com", ""); w <= want-time.Second || w > want {
+			t.Errorf("wait after %d failures = %v, want about %v", failures, w, want)
+		}
+	}
+
+	fail(t, s, "alice@example.com", "", 4)
+	if w := wait(t, s, "alice@example.com", ""); w <= 59*time.Minute {
+		t.Fatalf("wait after lockout = %v, want about an hour", w)
+	}
+	if w := wait(t, s, "bob@example.com", ""); w != 0 {
+		t.Fatalf("another account waits %v", w)
+	}
+
+	if err := s.Unlock(ctx, "alice@example.com", ""); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if w := wait(t, s, "alice@example.com", ""); w != 0 {
+		t.Fatalf("wait after unlock = %v", w)
+	}
+	if err := s.Unlock(ctx, "", ""); err == nil {
+		t.Fatal("unlock without email and ip accepted")
+	}
+}
+
+func TestClientAddressThrottledAcrossAccounts(t *testing.T) {
+	ctx := context.Background()
+	s := newService()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		fail(t, s, email, "10.0.0.1", 1)
+	}
+	if w := wait(t, s, "f@example.com", "10.0.0.1"); w == 0 {
+		t.Fatal("address guessing across accounts is not delayed")
+	}
+	if w := wait(t, s, "f@example.com", "10.0.0.2"); w != 0 {
+		t.Fatalf("another address waits %v", w)
+	}
+
+	if err := s.Succeeded(ctx, "f@example.com"); err != nil {
+		t.Fatalf("succeeded: %v", err)
+	}
+	if w := wait(t, s, "a@example.com", "10.0.0.1"); w == 0 {
+		t.Fatal("a successful sign in cleared failures of the address")
+	}
+}
@@ -0,0 +1,15 @@
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+type Storage interface {
+	Find(ctx context.Context, key string) (Attempts, error)
+	// AddFailure counts a failure at now, the count starts over when the previous failure is
+	// older than window. It returns the updated history.
+	AddFailure(ctx context.Context, key string, now time.Time, window, retention time.Duration) (Attempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,325 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+)
+
+var _ user.Storage = &memoryDB{}
+
+// memoryDB keeps users in process memory. uuids are object id hexes, as in the mongo storage.
+type memoryDB struct {
+	mu     sync.RWMutex
+	users  map[string]user.User
+	logger logging.Logger
+}
+
+func NewMemoryStorage(logger logging.Logger) user.Storage {
+	return &memoryDB{
+		users:  make(map[string]user.User),
+		logger: logger,
+	}
+}
+
+func (s *memoryDB) Create(ctx context.Context, u user.User) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(u.Email, "") {
+		return "", apperror.ErrEmailTaken
+	}
+	u.UUID = primitive.NewObjectID().Hex()
+	s.users[u.UUID] = u
+
+	return u.UUID, nil
+}
+
+// emailTaken tells whether a user other than the excluded one has the email, the lock is held by the caller
+func (s *memoryDB) emailTaken(email, excludedUUID string) bool {
+	for uuid, u := range s.users {
+		if u.Email == email && uuid != excludedUUID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryDB) FindAll(ctx context.Context) ([]user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]user.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+func (s *memoryDB) FindOne(ctx context.Context, uuid string) (u user.User, err error) {
+	if _, err = primitive.ObjectIDFromHex(uuid); err != nil {
+		return u, fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[uuid]
+	if !ok {
+		return u, apperror.ErrNotFound
+	}
+
+	return u, nil
+}
+
+func (s *memoryDB) FindByEmail(ctx context.Context, email string) (u user.User, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u = range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return user.User{}, apperror.ErrNotFound
+}
+
+func (s *memoryDB) FindByVerificationToken(ctx context.Context, tokenHash string) (u user.User, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u = range s.users {
+		if u.Verification != nil && u.Verification.TokenHash == tokenHash {
+			return u, nil
+		}
+	}
+
+	return user.User{}, apperror.ErrNotFound
+}
+
+func (s *memoryDB) SetVerification(ctx context.Context, uuid string, verification user.Verification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Verification = &verification
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) MarkVerified(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Verified = true
+	stored.Verification = nil
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) ChangeEmail(ctx context.Context, uuid, email string, verification user.Verification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	if s.emailTaken(email, uuid) {
+		return apperror.ErrEmailTaken
+	}
+	stored.Email = email
+	stored.Verified = false
+	stored.Verification = &verification
+	stored.PasswordReset = nil
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) FindByPasswordResetToken(ctx context.Context, tokenHash string) (u user.User, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u = range s.users {
+		if u.PasswordReset != nil && u.PasswordReset.TokenHash == tokenHash {
+			return u, nil
+		}
+	}
+
+	return user.User{}, apperror.ErrNotFound
+}
+
+func (s *memoryDB) SetPasswordReset(ctx context.Context, uuid string, reset user.Verification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.PasswordReset = &reset
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) ResetPassword(ctx context.Context, uuid, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Password = passwordHash
+	stored.PasswordReset = nil
+	stored.Verified = true
+	stored.Verification = nil
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) SetTOTP(ctx context.Context, uuid string, totp user.TOTP, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.TOTP = &totp
+	stored.TOTPEnabled = enabled
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) UseTOTPStep(ctx context.Context, uuid string, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok || stored.TOTP == nil || stored.TOTP.LastStep >= step {
+		return apperror.ErrNotFound
+	}
+	totp := *stored.TOTP
+	totp.LastStep = step
+	stored.TOTP = &totp
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) UseRecoveryCode(ctx context.Context, uuid, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok || stored.TOTP == nil {
+		return apperror.ErrNotFound
+	}
+	totp := *stored.TOTP
+	totp.RecoveryCodes = nil
+	for _, c := range stored.TOTP.RecoveryCodes {
+		if c != codeHash {
+			totp.RecoveryCodes = append(totp.RecoveryCodes, c)
+		}
+	}
+	if len(totp.RecoveryCodes) == len(stored.TOTP.RecoveryCodes) {
+		return apperror.ErrNotFound
+	}
+	stored.TOTP = &totp
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) DisableTOTP(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.TOTP = nil
+	stored.TOTPEnabled = false
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Disable(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[uuid]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	stored.Disabled = true
+	stored.Verification = nil
+	stored.PasswordReset = nil
+	s.users[uuid] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Update(ctx context.Context, u user.User) error {
+	if _, err := primitive.ObjectIDFromHex(u.UUID); err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[u.UUID]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	if u.Email != "" {
+		if s.emailTaken(u.Email, u.UUID) {
+			return apperror.ErrEmailTaken
+		}
+		stored.Email = u.Email
+	}
+	if u.Password != "" {
+		stored.Password = u.Password
+	}
+	s.users[u.UUID] = stored
+
+	return nil
+}
+
+func (s *memoryDB) Delete(ctx context.Context, uuid string) error {
+	if _, err := primitive.ObjectIDFromHex(uuid); err != nil {
+		return fmt.Errorf("failed to convet objectid to hex. error: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[uuid]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(s.users, uuid)
+
+	return nil
+}
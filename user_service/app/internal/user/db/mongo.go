@@ -32,6 +32,9 @@ func (s *db) Create(ctx context.Context, user user.User) (string, error) {
 	defer cancel()
 	result, err := s.collection.InsertOne(nCtx, user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", apperror.ErrEmailTaken
+		}
 		return "", fmt.Errorf("failed to execute query. error: %w", err)
 	}
 
@@ -42,6 +45,21 @@ func (s *db) Create(ctx context.Context, user user.User) (string, error) {
 	return "", fmt.Errorf("failed to convet objectid to hex")
 }
 
+func (s *db) FindAll(ctx context.Context) (users []user.User, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cursor, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode documents. error: %w", err)
+	}
+
+	return users, nil
+}
+
 func (s *db) FindOne(ctx context.Context, uuid string) (u user.User, err error) {
 	objectID, err := primitive.ObjectIDFromHex(uuid)
 	if err != nil {
@@ -88,6 +106,138 @@ func (s *db) FindByEmail(ctx context.Context, email string) (u user.User, err er
 	return u, nil
 }
 
+func (s *db) FindByVerificationToken(ctx context.Context, tokenHash string) (u user.User, err error) {
+	return s.findOneBy(ctx, bson.M{"verification.token_hash": tokenHash})
+}
+
+func (s *db) FindByPasswordResetToken(ctx context.Context, tokenHash string) (u user.User, err error) {
+	return s.findOneBy(ctx, bson.M{"password_reset.token_hash": tokenHash})
+}
+
+func (s *db) findOneBy(ctx context.Context, filter bson.M) (u user.User, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result := s.collection.FindOne(ctx, filter)
+	err = result.Err()
+	if err != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return u, apperror.ErrNotFound
+		}
+		return u, fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if err = result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode document. error: %w", err)
+	}
+
+	return u, nil
+}
+
+func (s *db) SetVerification(ctx context.Context, uuid string, verification user.Verification) error {
+	return s.updateOne(ctx, uuid, bson.M{"$set": bson.M{"verification": verification}})
+}
+
+func (s *db) MarkVerified(ctx context.Context, uuid string) error {
+	return s.updateOne(ctx, uuid, bson.M{
+		"$set":   bson.M{"verified": true},
+		"$unset": bson.M{"verification": ""},
+	})
+}
+
+func (s *db) ChangeEmail(ctx context.Context, uuid, email string, verification user.Verification) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+	update := bson.M{
+		"$set":   bson.M{"email": email, "verification": verification},
+		"$unset": bson.M{"verified": "", "password_reset": ""},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperror.ErrEmailTaken
+		}
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *db) Disable(ctx context.Context, uuid string) error {
+	return s.updateOne(ctx, uuid, bson.M{
+		"$set":   bson.M{"disabled": true},
+		"$unset": bson.M{"verification": "", "password_reset": ""},
+	})
+}
+
+func (s *db) SetPasswordReset(ctx context.Context, uuid string, reset user.Verification) error {
+	return s.updateOne(ctx, uuid, bson.M{"$set": bson.M{"password_reset": reset}})
+}
+
+func (s *db) ResetPassword(ctx context.Context, uuid, passwordHash string) error {
+	return s.updateOne(ctx, uuid, bson.M{
+		"$set":   bson.M{"password": passwordHash, "verified": true},
+		"$unset": bson.M{"password_reset": "", "verification": ""},
+	})
+}
+
+func (s *db) SetTOTP(ctx context.Context, uuid string, totp user.TOTP, enabled bool) error {
+	return s.updateOne(ctx, uuid, bson.M{"$set": bson.M{"totp": totp, "totp_enabled": enabled}})
+}
+
+func (s *db) UseTOTPStep(ctx context.Context, uuid string, step int64) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+	return s.updateMatching(ctx, bson.M{"_id": objectID, "totp.last_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"totp.last_step": step}})
+}
+
+func (s *db) UseRecoveryCode(ctx context.Context, uuid, codeHash string) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+	return s.updateMatching(ctx, bson.M{"_id": objectID, "totp.recovery_codes": codeHash},
+		bson.M{"$pull": bson.M{"totp.recovery_codes": codeHash}})
+}
+
+func (s *db) DisableTOTP(ctx context.Context, uuid string) error {
+	return s.updateOne(ctx, uuid, bson.M{"$unset": bson.M{"totp": "", "totp_enabled": ""}})
+}
+
+func (s *db) updateOne(ctx context.Context, uuid string, update bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to convert hex to objectid. error: %w", err)
+	}
+
+	return s.updateMatching(ctx, bson.M{"_id": objectID}, update)
+}
+
+// updateMatching applies update to the user matching filter, ErrNotFound when nothing matched
+func (s *db) updateMatching(ctx context.Context, filter, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to execute query. error: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return apperror.ErrNotFound
+	}
+
+	return nil
+}
+
 func (s *db) Update(ctx context.Context, user user.User) error {
 	objectID, err := primitive.ObjectIDFromHex(user.UUID)
 	if err != nil {
@@ -116,6 +266,9 @@ func (s *db) Update(ctx context.Context, user user.User) error {
 	defer cancel()
 	result, err := s.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apperror.ErrEmailTaken
+		}
 		return fmt.Errorf("failed to execute query. error: %w", err)
 	}
 	if result.MatchedCount == 0 {
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb/migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrations returns the schema history of the users collection
+func MongoMigrations(collection string, logger logging.Logger) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     1,
+			Description: "index users by email",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "email")
+				return err
+			},
+		},
+		{
+			Version:     2,
+			Description: "verify existing users and index verification tokens",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				// accounts created before email verification stay usable
+				_, err := db.Collection(collection).UpdateMany(ctx,
+					bson.M{"verified": bson.M{"$exists": false}, "verification": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"verified": true}})
+				if err != nil {
+					return err
+				}
+				_, err = db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "verification.token_hash", Value: 1}},
+					Options: options.Index().SetName("verification_token_hash").SetSparse(true),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "verification_token_hash")
+				return err
+			},
+		},
+		{
+			Version:     3,
+			Description: "index password reset tokens",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "password_reset.token_hash", Value: 1}},
+					Options: options.Index().SetName("password_reset_token_hash").SetSparse(true),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection(collection).Indexes().DropOne(ctx, "password_reset_token_hash")
+				return err
+			},
+		},
+		{
+			Version:     5,
+			Description: "normalise emails and make them unique",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return EnsureUniqueEmails(ctx, db.Collection(collection), logger)
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				// normalised emails stay, they are valid without the unique index too
+				unique, err := hasIndex(ctx, db.Collection(collection), "email_unique")
+				if err != nil || !unique {
+					return err
+				}
+				_, err = db.Collection(collection).Indexes().DropOne(ctx, "email_unique")
+				if err != nil {
+					return err
+				}
+				_, err = db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email"),
+				})
+				return err
+			},
+		},
+	}
+}
+
+// EnsureUniqueEmails rewrites stored emails to their normal form and replaces the email index with
+// a unique one. Emails that do not normalise are logged and kept as they are. While users share an
+// email the unique index is left out and the duplicates are logged, `app duplicates` lists them to
+// be merged or removed by hand, the next start creates the index once they are gone.
+func EnsureUniqueEmails(ctx context.Context, collection *mongo.Collection, logger logging.Logger) error {
+	unique, err := hasIndex(ctx, collection, "email_unique")
+	if err != nil || unique {
+		return err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var users []user.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		email, err := user.NormalizeEmail(u.Email)
+		if err != nil {
+			logger.Warnf("email %q of user %s does not normalise and is kept as is. error: %v", u.Email, u.UUID, err)
+			continue
+		}
+		if email == u.Email {
+			continue
+		}
+		objectID, err := primitive.ObjectIDFromHex(u.UUID)
+		if err != nil {
+			return err
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"email": email}})
+		if err != nil {
+			return err
+		}
+	}
+
+	if duplicates := user.DuplicateEmails(users); len(duplicates) > 0 {
+		for _, d := range duplicates {
+			uuids := make([]string, 0, len(d.Users))
+			for _, u := range d.Users {
+				uuids = append(uuids, u.UUID)
+			}
+			logger.Warnf("email %q belongs to users %v", d.Email, uuids)
+		}
+		logger.Warnf("%d emails belong to several users, emails stay without a unique index until `app duplicates` reports none", len(duplicates))
+		return nil
+	}
+
+	if _, err = collection.Indexes().DropOne(ctx, "email"); err != nil {
+		return err
+	}
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName("email_unique").SetUnique(true),
+	})
+	return err
+}
+
+// hasIndex tells whether the collection has an index with the name
+func hasIndex(ctx context.Context, collection *mongo.Collection, name string) (bool, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list indexes. error: %w", err)
+	}
+	var indexes []bson.M
+	if err = cursor.All(ctx, &indexes); err != nil {
+		return false, fmt.Errorf("failed to decode index. error: %w", err)
+	}
+	for _, index := range indexes {
+		if index["name"] == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"golang.org/x/net/idna"
+	"sort"
+	"strings"
+)
+
+// NormalizeEmail trims and lower cases the email and converts an internationalised domain to
+// its ASCII form, so every spelling of one address is stored the same way
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid email %q", email)
+	}
+
+	domain, err := idna.Lookup.ToASCII(email[at+1:])
+	if err != nil {
+		return "", fmt.Errorf("invalid email domain %q. error: %w", email[at+1:], err)
+	}
+	return email[:at+1] + domain, nil
+}
+
+// findByEmail looks the user up by the normal form of the email, an invalid email has no user
+func (s service) findByEmail(ctx context.Context, email string) (User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return User{}, apperror.ErrNotFound
+	}
+	return s.storage.FindByEmail(ctx, email)
+}
+
+// DuplicateEmail is an address several users share after normalisation
+type DuplicateEmail struct {
+	Email string
+	Users []User
+}
+
+// DuplicateEmails groups users by normalised email and returns groups of more than one user,
+// ordered by email. Emails that do not normalise are compared as they are.
+func DuplicateEmails(users []User) []DuplicateEmail {
+	byEmail := make(map[string][]User)
+	for _, u := range users {
+		email, err := NormalizeEmail(u.Email)
+		if err != nil {
+			email = u.Email
+		}
+		byEmail[email] = append(byEmail[email], u)
+	}
+
+	var duplicates []DuplicateEmail
+	for email, group := range byEmail {
+		if len(group) > 1 {
+			duplicates = append(duplicates, DuplicateEmail{Email: email, Users: group})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Email < duplicates[j].Email })
+
+	return duplicates
+}
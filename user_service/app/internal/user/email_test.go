@@ -0,0 +1,63 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	for email, want := range map[string]string{
+		"  Alice@Example.COM ":        "alice@example.com",
+		"bob@Bücher.example":          "bob@xn--bcher-kva.example",
+		"carol@xn--bcher-kva.example": "carol@xn--bcher-kva.example",
+	} {
+		got, err := user.NormalizeEmail(email)
+		if err != nil || got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, %v, want %q", email, got, err, want)
+		}
+	}
+	for _, email := range []string{"", "alice", "@example.com", "alice@"} {
+		if _, err := user.NormalizeEmail(email); err == nil {
+			t.Errorf("NormalizeEmail(%q) accepted an invalid email", email)
+		}
+	}
+}
+
+func TestCreateRejectsTakenEmail(t *testing.T) {
+	s, _ := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+
+	if _, err := createUserErr(s, " ALICE@example.com", "secret"); !errors.Is(err, apperror.ErrEmailTaken) {
+		t.Fatalf("want email taken for another spelling, got %v", err)
+	}
+
+	u, err := s.GetOne(context.Background(), uuid)
+	if err != nil || u.Email != "alice@example.com" {
+		t.Fatalf("want the normalised email stored, got %q, %v", u.Email, err)
+	}
+	if _, err = s.GetByEmailAndPassword(context.Background(), "Alice@Example.com", "secret"); err != nil {
+		t.Fatalf("sign in with another spelling: %v", err)
+	}
+
+	bob := createUser(t, s, "bob@example.com")
+	err = s.Update(context.Background(), user.UpdateUserDTO{UUID: bob, Email: "alice@EXAMPLE.com", OldPassword: "secret", NewPassword: "secret"})
+	if !errors.Is(err, apperror.ErrEmailTaken) {
+		t.Fatalf("want email taken on update, got %v", err)
+	}
+}
+
+func TestDuplicateEmails(t *testing.T) {
+	duplicates := user.DuplicateEmails([]user.User{
+		{UUID: "1", Email: "alice@example.com"},
+		{UUID: "2", Email: "bob@example.com"},
+		{UUID: "3", Email: "Alice@Example.com "},
+	})
+	if len(duplicates) != 1 || duplicates[0].Email != "alice@example.com" || len(duplicates[0].Users) != 2 {
+		t.Fatalf("want alice shared by two users, got %+v", duplicates)
+	}
+}
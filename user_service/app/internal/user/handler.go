@@ -2,21 +2,32 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
 	"net/http"
 )
 
 const (
-	usersURL = "/api/users"
-	userURL  = "/api/users/:uuid"
+	usersURL         = "/api/users"
+	userURL          = "/api/users/:uuid"
+	verificationsURL = "/api/verifications"
+	passwordResetURL = "/api/password-resets"
+	totpURL          = "/api/users/:uuid/totp"
+	totpVerifyURL    = "/api/users/:uuid/totp/verify"
+	totpDisableURL   = "/api/users/:uuid/totp/disable"
+
+	// clientIPHeader carries the address the gateway got the sign in or the second factor from
+	clientIPHeader = "X-Client-IP"
 )
 
 type Handler struct {
 	Logger      logging.Logger
 	UserService Service
+	Throttle    throttle.Service
 }
 
 func (h *Handler) Register(router *httprouter.Router) {
@@ -25,6 +36,14 @@ func (h *Handler) Register(router *httprouter.Router) {
 	router.HandlerFunc(http.MethodGet, userURL, apperror.Middleware(h.GetUser))
 	router.HandlerFunc(http.MethodPatch, userURL, apperror.Middleware(h.PartiallyUpdateUser))
 	router.HandlerFunc(http.MethodDelete, userURL, apperror.Middleware(h.DeleteUser))
+	router.HandlerFunc(http.MethodGet, verificationsURL, apperror.Middleware(h.Verify))
+	router.HandlerFunc(http.MethodPost, verificationsURL, apperror.Middleware(h.ResendVerification))
+	router.HandlerFunc(http.MethodPost, passwordResetURL, apperror.Middleware(h.ForgotPassword))
+	router.HandlerFunc(http.MethodPut, passwordResetURL, apperror.Middleware(h.ResetPassword))
+	router.HandlerFunc(http.MethodPost, totpURL, apperror.Middleware(h.EnrollTOTP))
+	router.HandlerFunc(http.MethodPut, totpURL, apperror.Middleware(h.ConfirmTOTP))
+	router.HandlerFunc(http.MethodPost, totpVerifyURL, apperror.Middleware(h.VerifyTOTP))
+	router.HandlerFunc(http.MethodPost, totpDisableURL, apperror.Middleware(h.DisableTOTP))
 }
 
 func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) error {
@@ -57,10 +76,31 @@ func (h *Handler) GetUserByEmailAndPassword(w http.ResponseWriter, r *http.Reque
 		return apperror.BadRequestError("invalid query parameters email or password")
 	}
 
+	clientIP := r.Header.Get(clientIPHeader)
+	wait, err := h.Throttle.Wait(r.Context(), email, clientIP)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return apperror.TooManyRequestsError("too many failed sign in attempts", wait)
+	}
+
 	user, err := h.UserService.GetByEmailAndPassword(r.Context(), email, password)
 	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			if tErr := h.Throttle.Failed(r.Context(), email, clientIP); tErr != nil {
+				h.Logger.Error(tErr)
+			}
+		}
 		return err
 	}
+	// with the second factor on the failures are forgotten once a code passes, a known password
+	// must not give code guesses a fresh start
+	if !user.TOTPEnabled {
+		if err = h.Throttle.Succeeded(r.Context(), email); err != nil {
+			h.Logger.Error(err)
+		}
+	}
 
 	userBytes, err := json.Marshal(user)
 	if err != nil {
@@ -129,3 +169,193 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+// Verify activates the account the token of the query was sent for
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := h.UserService.Verify(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// ResendVerification mails a new verification link, it answers the same for unknown emails
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var dto ResendVerificationDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	err := h.UserService.ResendVerification(r.Context(), dto.Email)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+// ForgotPassword mails a reset token, it answers the same for unknown emails
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var dto ForgotPasswordDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	err := h.UserService.ForgotPassword(r.Context(), dto.Email)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+// ResetPassword sets a new password by a reset token and answers with the user it belongs to
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var dto ResetPasswordDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	user, err := h.UserService.ResetPassword(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+
+	userBytes, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("failed to marshall user. error: %w", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(userBytes)
+
+	return nil
+}
+
+// EnrollTOTP answers with a new secret and its otpauth URI
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	enrollment, err := h.UserService.EnrollTOTP(r.Context(), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+
+	enrollmentBytes, err := json.Marshal(enrollment)
+	if err != nil {
+		return fmt.Errorf("failed to marshall enrollment. error: %w", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(enrollmentBytes)
+
+	return nil
+}
+
+// ConfirmTOTP enables the second factor and answers with the recovery codes
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto TOTPCodeDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	codes, err := h.UserService.ConfirmTOTP(r.Context(), params.ByName("uuid"), dto.Code)
+	if err != nil {
+		return err
+	}
+
+	codesBytes, err := json.Marshal(RecoveryCodes{Codes: codes})
+	if err != nil {
+		return fmt.Errorf("failed to marshall recovery codes. error: %w", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(codesBytes)
+
+	return nil
+}
+
+// VerifyTOTP checks the second factor of a sign in. Wrong codes are failed sign ins of the account
+// and of the address, a right one forgets the failures of the account.
+func (h *Handler) VerifyTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto TOTPCodeDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	u, err := h.UserService.GetOne(r.Context(), params.ByName("uuid"))
+	if err != nil {
+		return err
+	}
+	clientIP := r.Header.Get(clientIPHeader)
+	wait, err := h.Throttle.Wait(r.Context(), u.Email, clientIP)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return apperror.TooManyRequestsError("too many failed sign in attempts", wait)
+	}
+
+	err = h.UserService.VerifyTOTP(r.Context(), u.UUID, dto.Code)
+	if err != nil {
+		if errors.Is(err, errInvalidCode) || errors.Is(err, errCodeUsed) {
+			if tErr := h.Throttle.Failed(r.Context(), u.Email, clientIP); tErr != nil {
+				h.Logger.Error(tErr)
+			}
+		}
+		return err
+	}
+	if err = h.Throttle.Succeeded(r.Context(), u.Email); err != nil {
+		h.Logger.Error(err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.Context().Value(httprouter.ParamsKey).(httprouter.Params)
+
+	var dto DisableTOTPDTO
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		return apperror.BadRequestError("invalid JSON scheme. check swagger API")
+	}
+
+	err := h.UserService.DisableTOTP(r.Context(), params.ByName("uuid"), dto)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -1,31 +1,72 @@
 package user
 
 import (
-	"fmt"
-	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 type User struct {
 	UUID     string `json:"uuid" bson:"_id,omitempty"`
 	Email    string `json:"email" bson:"email,omitempty"`
 	Password string `json:"-" bson:"password,omitempty"`
+	// Verified is set once the user opens the link sent to the email
+	Verified      bool          `json:"verified" bson:"verified,omitempty"`
+	Verification  *Verification `json:"-" bson:"verification,omitempty"`
+	PasswordReset *Verification `json:"-" bson:"password_reset,omitempty"`
+	// TOTPEnabled asks for a one-time code after the password on sign in
+	TOTPEnabled bool  `json:"totp_enabled" bson:"totp_enabled,omitempty"`
+	TOTP        *TOTP `json:"-" bson:"totp,omitempty"`
+	// Disabled users are being deleted, they can not sign in any more
+	Disabled bool `json:"disabled,omitempty" bson:"disabled,omitempty"`
 }
 
-func (u *User) CheckPassword(password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	if err != nil {
-		return fmt.Errorf("password does not match")
-	}
-	return nil
+// TOTP is the authenticator app of the user, pending until the first code confirms it
+type TOTP struct {
+	Secret string `bson:"secret"`
+	// LastStep is the time step of the last accepted code, every code is accepted once
+	LastStep int64 `bson:"last_step"`
+	// RecoveryCodes are hashes of the unused recovery codes
+	RecoveryCodes []string `bson:"recovery_codes,omitempty"`
 }
 
-func (u *User) GeneratePasswordHash() error {
-	pwd, err := generatePasswordHash(u.Password)
-	if err != nil {
-		return err
-	}
-	u.Password = pwd
-	return nil
+// Verification is a pending check of a mailed token, an email verification or a password reset.
+// Only a hash of the token is stored.
+type Verification struct {
+	TokenHash string    `bson:"token_hash"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+type ResendVerificationDTO struct {
+	Email string `json:"email"`
+}
+
+// TOTPEnrollment is what the authenticator app needs, URI is for the QR code
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TOTPCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// DisableTOTPDTO re-authenticates the user, Code is a current one or a recovery code
+type DisableTOTPDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordDTO struct {
+	Token          string `json:"token"`
+	Password       string `json:"password"`
+	RepeatPassword string `json:"repeat_password"`
 }
 
 type CreateUserDTO struct {
@@ -37,7 +78,6 @@ type CreateUserDTO struct {
 type UpdateUserDTO struct {
 	UUID        string `json:"uuid,omitempty" bson:"_id,omitempty"`
 	Email       string `json:"email,omitempty" bson:"email,omitempty"`
-	Password    string `json:"password,omitempty" bson:"password,omitempty"`
 	OldPassword string `json:"old_password,omitempty" bson:"-"`
 	NewPassword string `json:"new_password,omitempty" bson:"-"`
 }
@@ -48,19 +88,3 @@ func NewUser(dto CreateUserDTO) User {
 		Password: dto.Password,
 	}
 }
-
-func UpdatedUser(dto UpdateUserDTO) User {
-	return User{
-		UUID:     dto.UUID,
-		Email:    dto.Email,
-		Password: dto.Password,
-	}
-}
-
-func generatePasswordHash(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password due to error %w", err)
-	}
-	return string(hash), nil
-}
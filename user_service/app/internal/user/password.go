@@ -0,0 +1,84 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/mail"
+	"time"
+)
+
+const passwordResetSubject = "Reset your password"
+
+// ForgotPassword mails a password reset token. Unknown emails are ignored so the answer does
+// not tell whether an account exists.
+func (s service) ForgotPassword(ctx context.Context, email string) error {
+	if email == "" {
+		return apperror.BadRequestError("email is required")
+	}
+
+	u, err := s.findByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find user by email. error: %w", err)
+	}
+	if u.Disabled {
+		return nil
+	}
+
+	// a new request replaces the previous token
+	token, reset, err := newToken(s.passwordReset.TTL)
+	if err != nil {
+		return err
+	}
+	if err = s.storage.SetPasswordReset(ctx, u.UUID, reset); err != nil {
+		return fmt.Errorf("failed to save password reset. error: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: passwordResetSubject,
+		Body: fmt.Sprintf("Open the link to set a new password:\n\n%s\n\n"+
+			"The link is valid for %s and works once. Ignore this mail if you did not ask for it.",
+			tokenLink(s.passwordReset.URL, token), s.passwordReset.TTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send password reset. error: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets the password of the user the token was mailed to and returns the user
+func (s service) ResetPassword(ctx context.Context, dto ResetPasswordDTO) (u User, err error) {
+	if dto.Token == "" {
+		return u, apperror.BadRequestError("reset token is required")
+	}
+	if dto.Password != dto.RepeatPassword {
+		return u, apperror.BadRequestError("password does not match repeat password")
+	}
+
+	u, err = s.storage.FindByPasswordResetToken(ctx, hashToken(dto.Token))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return u, apperror.BadRequestError("invalid or expired reset token")
+		}
+		return u, fmt.Errorf("failed to find user by reset token. error: %w", err)
+	}
+	if u.PasswordReset == nil || time.Now().After(u.PasswordReset.ExpiresAt) {
+		return u, apperror.BadRequestError("invalid or expired reset token")
+	}
+
+	hash, err := s.hashPassword(dto.Password)
+	if err != nil {
+		return u, err
+	}
+	if err = s.storage.ResetPassword(ctx, u.UUID, hash); err != nil {
+		return u, fmt.Errorf("failed to reset password. error: %w", err)
+	}
+
+	u.Password, u.PasswordReset, u.Verified = hash, nil, true
+	return u, nil
+}
@@ -0,0 +1,130 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/password"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	s, box := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+
+	if err := s.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("forgot password of an unknown email: %v", err)
+	}
+	if len(box.sent) != 1 {
+		t.Fatalf("mail sent for an unknown email: %+v", box.sent)
+	}
+
+	if err := s.ForgotPassword(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("forgot password: %v", err)
+	}
+	token := box.lastToken(t)
+
+	dto := user.ResetPasswordDTO{Token: token, Password: "renewed", RepeatPassword: "other"}
+	if _, err := s.ResetPassword(ctx, dto); err == nil {
+		t.Fatal("mismatching passwords accepted")
+	}
+	dto.RepeatPassword = "renewed"
+	u, err := s.ResetPassword(ctx, dto)
+	if err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if u.UUID != uuid || !u.Verified {
+		t.Fatalf("reset user = %+v", u)
+	}
+	if _, err := s.ResetPassword(ctx, dto); err == nil {
+		t.Fatal("reset token used twice")
+	}
+
+	if _, err := s.GetByEmailAndPassword(ctx, "alice@example.com", "secret"); err == nil {
+		t.Fatal("old password still works")
+	}
+	if _, err := s.GetByEmailAndPassword(ctx, "alice@example.com", "renewed"); err != nil {
+		t.Fatalf("sign in with the new password: %v", err)
+	}
+}
+
+func TestResetPasswordExpired(t *testing.T) {
+	ctx := context.Background()
+	s, box := newService(t, -time.Minute)
+	createUser(t, s, "bob@example.com")
+
+	if err := s.ForgotPassword(ctx, "bob@example.com"); err != nil {
+		t.Fatalf("forgot password: %v", err)
+	}
+	dto := user.ResetPasswordDTO{Token: box.lastToken(t), Password: "renewed", RepeatPassword: "renewed"}
+	if _, err := s.ResetPassword(ctx, dto); err == nil {
+		t.Fatal("expired reset token accepted")
+	}
+}
+
+func TestRehashOnSignIn(t *testing.T) {
+	ctx := context.Background()
+	storage := db.NewMemoryStorage(testLogger)
+	newSvc := func(cost int) user.Service {
+		hasher, err := password.NewHasher(password.AlgorithmBcrypt, cost, password.Argon2Params{})
+		if err != nil {
+			t.Fatalf("new hasher: %v", err)
+		}
+		s, err := user.NewService(storage, hasher, password.NewPolicy(6, 64), &outbox{},
+			user.VerificationSettings{}, user.VerificationSettings{}, testLogger)
+		if err != nil {
+			t.Fatalf("new service: %v", err)
+		}
+		return s
+	}
+
+	uuid := createUser(t, newSvc(bcrypt.MinCost), "alice@example.com")
+	if _, err := createUserErr(newSvc(bcrypt.MinCost), "bob@example.com", "short"); err == nil {
+		t.Fatal("password shorter than the policy accepted")
+	}
+
+	stronger := newSvc(bcrypt.MinCost + 1)
+	if _, err := stronger.GetByEmailAndPassword(ctx, "alice@example.com", "secret"); err != nil {
+		t.Fatalf("sign in: %v", err)
+	}
+	u, err := stronger.GetOne(ctx, uuid)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(u.Password)); cost != bcrypt.MinCost+1 {
+		t.Fatalf("cost after sign in = %d, want %d", cost, bcrypt.MinCost+1)
+	}
+	if _, err := stronger.GetByEmailAndPassword(ctx, "alice@example.com", "secret"); err != nil {
+		t.Fatalf("sign in after rehash: %v", err)
+	}
+}
+
+func TestUpdateRequiresOldPassword(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+
+	for _, dto := range []user.UpdateUserDTO{
+		{UUID: uuid, OldPassword: "guessed", NewPassword: "guessed"},
+		{UUID: uuid, NewPassword: "changed"},
+		{UUID: uuid, Email: "mallory@example.com"},
+	} {
+		if err := s.Update(ctx, dto); err == nil {
+			t.Fatalf("update %+v without the current password accepted", dto)
+		}
+	}
+	if _, err := s.GetByEmailAndPassword(ctx, "alice@example.com", "secret"); err != nil {
+		t.Fatalf("sign in after refused updates: %v", err)
+	}
+
+	if err := s.Update(ctx, user.UpdateUserDTO{UUID: uuid, OldPassword: "secret", NewPassword: "changed"}); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+	if _, err := s.GetByEmailAndPassword(ctx, "alice@example.com", "changed"); err != nil {
+		t.Fatalf("sign in with the new password: %v", err)
+	}
+}
@@ -5,21 +5,33 @@ import (
 	"errors"
 	"fmt"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/mail"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/password"
 	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var _ Service = &service{}
 
 type service struct {
-	storage Storage
-	logger  logging.Logger
+	storage       Storage
+	hasher        password.Hasher
+	policy        *password.Policy
+	mailer        mail.Mailer
+	verification  VerificationSettings
+	passwordReset VerificationSettings
+	logger        logging.Logger
 }
 
-func NewService(userStorage Storage, logger logging.Logger) (Service, error) {
+func NewService(userStorage Storage, hasher password.Hasher, policy *password.Policy, mailer mail.Mailer,
+	verification, passwordReset VerificationSettings, logger logging.Logger) (Service, error) {
 	return &service{
-		storage: userStorage,
-		logger:  logger,
+		storage:       userStorage,
+		hasher:        hasher,
+		policy:        policy,
+		mailer:        mailer,
+		verification:  verification,
+		passwordReset: passwordReset,
+		logger:        logger,
 	}, nil
 }
 
@@ -29,35 +41,58 @@ type Service interface {
 	GetOne(ctx context.Context, uuid string) (User, error)
 	Update(ctx context.Context, dto UpdateUserDTO) error
 	Delete(ctx context.Context, uuid string) error
+	// Disable keeps the user from signing in while the account is deleted
+	Disable(ctx context.Context, uuid string) error
+	Verify(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, dto ResetPasswordDTO) (User, error)
+	EnrollTOTP(ctx context.Context, uuid string) (TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, uuid, code string) ([]string, error)
+	VerifyTOTP(ctx context.Context, uuid, code string) error
+	DisableTOTP(ctx context.Context, uuid string, dto DisableTOTPDTO) error
 }
 
 func (s service) Create(ctx context.Context, dto CreateUserDTO) (userUUID string, err error) {
 	if dto.Password != dto.RepeatPassword {
 		return userUUID, apperror.BadRequestError("password does not match repeat password")
 	}
+	if dto.Email, err = NormalizeEmail(dto.Email); err != nil {
+		return userUUID, apperror.BadRequestError(err.Error())
+	}
 
 	user := NewUser(dto)
 
-	err = user.GeneratePasswordHash()
+	user.Password, err = s.hashPassword(dto.Password)
 	if err != nil {
-		s.logger.Errorf("failed to create user due to error %v", err)
-		return
+		return userUUID, err
 	}
 
+	token, verification, err := newToken(s.verification.TTL)
+	if err != nil {
+		return userUUID, err
+	}
+	user.Verification = &verification
+
 	userUUID, err = s.storage.Create(ctx, user)
 
 	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
+		if errors.Is(err, apperror.ErrNotFound) || errors.Is(err, apperror.ErrEmailTaken) {
 			return userUUID, err
 		}
 		return userUUID, fmt.Errorf("failed to create user. error: %w", err)
 	}
 
+	// the account exists already, a lost mail is fixed by asking for the link again
+	if err = s.sendVerification(ctx, user.Email, token); err != nil {
+		s.logger.Error(err)
+	}
+
 	return userUUID, nil
 }
 
-func (s service) GetByEmailAndPassword(ctx context.Context, email, password string) (u User, err error) {
-	u, err = s.storage.FindByEmail(ctx, email)
+func (s service) GetByEmailAndPassword(ctx context.Context, email, plain string) (u User, err error) {
+	u, err = s.findByEmail(ctx, email)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -66,13 +101,44 @@ func (s service) GetByEmailAndPassword(ctx context.Context, email, password stri
 		return u, fmt.Errorf("failed to find user by email. error: %w", err)
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+	if err = s.hasher.Compare(u.Password, plain); err != nil || u.Disabled {
 		return u, apperror.ErrNotFound
 	}
 
+	// the plain password is at hand only now, hashes of weaker settings are upgraded on sign in
+	if s.hasher.NeedsRehash(u.Password) {
+		s.rehash(ctx, u, plain)
+	}
+
 	return u, nil
 }
 
+func (s service) rehash(ctx context.Context, u User, plain string) {
+	hash, err := s.hasher.Hash(plain)
+	if err != nil {
+		s.logger.Errorf("failed to rehash password of user %s due to error %v", u.UUID, err)
+		return
+	}
+	if err = s.storage.Update(ctx, User{UUID: u.UUID, Password: hash}); err != nil {
+		s.logger.Errorf("failed to store rehashed password of user %s due to error %v", u.UUID, err)
+	}
+}
+
+// hashPassword enforces the password policy and hashes the password
+func (s service) hashPassword(plain string) (string, error) {
+	if err := s.policy.Check(plain); err != nil {
+		return "", apperror.BadRequestError(err.Error())
+	}
+	hash, err := s.hasher.Hash(plain)
+	if err != nil {
+		if errors.Is(err, password.ErrTooLong) {
+			return "", apperror.BadRequestError(err.Error())
+		}
+		return "", fmt.Errorf("failed to hash password. error: %w", err)
+	}
+	return hash, nil
+}
+
 func (s service) GetOne(ctx context.Context, uuid string) (u User, err error) {
 	u, err = s.storage.FindOne(ctx, uuid)
 
@@ -85,36 +151,70 @@ func (s service) GetOne(ctx context.Context, uuid string) (u User, err error) {
 	return u, nil
 }
 
+// Update changes the email or the password, either of them is proven with the current password
 func (s service) Update(ctx context.Context, dto UpdateUserDTO) error {
-	var updatedUser User
-	if dto.OldPassword != dto.NewPassword || dto.NewPassword == "" {
-		user, err := s.GetOne(ctx, dto.UUID)
-		if err != nil {
+	user, err := s.GetOne(ctx, dto.UUID)
+	if err != nil {
+		return err
+	}
+	if err = s.hasher.Compare(user.Password, dto.OldPassword); err != nil {
+		return apperror.BadRequestError("old password does not match current password")
+	}
+
+	// the new password is checked against the policy before anything is stored
+	var hash string
+	if dto.NewPassword != "" {
+		if hash, err = s.hashPassword(dto.NewPassword); err != nil {
 			return err
 		}
+	}
 
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(dto.OldPassword))
+	if dto.Email != "" {
+		email, err := NormalizeEmail(dto.Email)
 		if err != nil {
-			return apperror.BadRequestError("old password does not match current password")
+			return apperror.BadRequestError(err.Error())
+		}
+		if err = s.changeEmail(ctx, user, email); err != nil {
+			return err
 		}
-
-		dto.Password = dto.NewPassword
 	}
 
-	updatedUser = UpdatedUser(dto)
+	// an empty new password leaves the stored one as it is
+	if hash == "" {
+		return nil
+	}
+	err = s.storage.Update(ctx, User{UUID: dto.UUID, Password: hash})
 
-	err := updatedUser.GeneratePasswordHash()
 	if err != nil {
-		return fmt.Errorf("failed to update user. error %w", err)
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to update user. error: %w", err)
 	}
+	return nil
+}
 
-	err = s.storage.Update(ctx, updatedUser)
+// changeEmail stores a new email unverified and mails it a verification link, the same way Create does
+func (s service) changeEmail(ctx context.Context, u User, email string) error {
+	if u.Email == email {
+		return nil
+	}
 
+	token, verification, err := newToken(s.verification.TTL)
 	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
+		return err
+	}
+	err = s.storage.ChangeEmail(ctx, u.UUID, email, verification)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) || errors.Is(err, apperror.ErrEmailTaken) {
 			return err
 		}
-		return fmt.Errorf("failed to update user. error: %w", err)
+		return fmt.Errorf("failed to change email of user. error: %w", err)
+	}
+
+	// the email is changed already, a lost mail is fixed by asking for the link again
+	if err = s.sendVerification(ctx, email, token); err != nil {
+		s.logger.Error(err)
 	}
 	return nil
 }
@@ -130,3 +230,15 @@ func (s service) Delete(ctx context.Context, uuid string) error {
 	}
 	return err
 }
+
+func (s service) Disable(ctx context.Context, uuid string) error {
+	err := s.storage.Disable(ctx, uuid)
+
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to disable user. error: %w", err)
+	}
+	return nil
+}
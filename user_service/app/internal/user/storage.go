@@ -5,9 +5,34 @@ import (
 )
 
 type Storage interface {
+	// Create and Update return apperror.ErrEmailTaken when another user has the email
 	Create(ctx context.Context, user User) (string, error)
+	// FindAll returns every user, for maintenance tools only
+	FindAll(ctx context.Context) ([]User, error)
 	FindByEmail(ctx context.Context, email string) (User, error)
 	FindOne(ctx context.Context, uuid string) (User, error)
+	FindByVerificationToken(ctx context.Context, tokenHash string) (User, error)
+	SetVerification(ctx context.Context, uuid string, verification Verification) error
+	// MarkVerified sets the user verified and drops the pending verification
+	MarkVerified(ctx context.Context, uuid string) error
+	// ChangeEmail stores the email unverified with a new pending verification and drops a pending
+	// password reset mailed to the old one. ErrEmailTaken when another user has the email.
+	ChangeEmail(ctx context.Context, uuid, email string, verification Verification) error
+	FindByPasswordResetToken(ctx context.Context, tokenHash string) (User, error)
+	SetPasswordReset(ctx context.Context, uuid string, reset Verification) error
+	// ResetPassword stores the password hash, drops the pending reset and verifies the user,
+	// the reset token proved the email is theirs
+	ResetPassword(ctx context.Context, uuid, passwordHash string) error
+	// SetTOTP replaces the authenticator of the user and turns the second factor on or off
+	SetTOTP(ctx context.Context, uuid string, totp TOTP, enabled bool) error
+	// UseTOTPStep records an accepted code, ErrNotFound means a code of the step or a later
+	// one was accepted already
+	UseTOTPStep(ctx context.Context, uuid string, step int64) error
+	// UseRecoveryCode removes the recovery code, ErrNotFound means there is no such code
+	UseRecoveryCode(ctx context.Context, uuid, codeHash string) error
+	DisableTOTP(ctx context.Context, uuid string) error
+	// Disable keeps the user from signing in and drops pending mailed tokens
+	Disable(ctx context.Context, uuid string) error
 	Update(ctx context.Context, user User) error
 	Delete(ctx context.Context, uuid string) error
 }
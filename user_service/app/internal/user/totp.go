@@ -0,0 +1,182 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer        = "Notes"
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+)
+
+var totpOpts = totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// EnrollTOTP generates a new secret. The second factor stays off until ConfirmTOTP.
+func (s service) EnrollTOTP(ctx context.Context, uuid string) (e TOTPEnrollment, err error) {
+	u, err := s.GetOne(ctx, uuid)
+	if err != nil {
+		return e, err
+	}
+	if u.TOTPEnabled {
+		return e, apperror.BadRequestError("two-factor authentication is enabled already")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: u.Email,
+		Period:      totpPeriod,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return e, fmt.Errorf("failed to generate totp secret. error: %w", err)
+	}
+	if err = s.storage.SetTOTP(ctx, uuid, TOTP{Secret: key.Secret()}, false); err != nil {
+		return e, fmt.Errorf("failed to save totp secret. error: %w", err)
+	}
+
+	return TOTPEnrollment{Secret: key.Secret(), URI: key.URL()}, nil
+}
+
+// ConfirmTOTP turns the second factor on with the first code of the app and returns
+// recovery codes, they are shown only this once
+func (s service) ConfirmTOTP(ctx context.Context, uuid, code string) (codes []string, err error) {
+	u, err := s.GetOne(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, apperror.BadRequestError("two-factor authentication is enabled already")
+	}
+	if u.TOTP == nil {
+		return nil, apperror.BadRequestError("two-factor authentication is not enrolled")
+	}
+	step, ok := matchTOTP(u.TOTP.Secret, code, time.Now())
+	if !ok {
+		return nil, apperror.BadRequestError("invalid code")
+	}
+
+	confirmed := TOTP{Secret: u.TOTP.Secret, LastStep: step}
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		confirmed.RecoveryCodes = append(confirmed.RecoveryCodes, hashToken(normalizeRecoveryCode(code)))
+	}
+	if err = s.storage.SetTOTP(ctx, uuid, confirmed, true); err != nil {
+		return nil, fmt.Errorf("failed to enable totp. error: %w", err)
+	}
+
+	return codes, nil
+}
+
+// VerifyTOTP checks the second factor of a sign in, a recovery code is accepted as well
+func (s service) VerifyTOTP(ctx context.Context, uuid, code string) error {
+	u, err := s.GetOne(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled || u.TOTP == nil {
+		return apperror.BadRequestError("two-factor authentication is not enabled")
+	}
+	return s.useCode(ctx, u, code)
+}
+
+// DisableTOTP turns the second factor off, the user proves who they are with the password and a code
+func (s service) DisableTOTP(ctx context.Context, uuid string, dto DisableTOTPDTO) error {
+	u, err := s.GetOne(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled || u.TOTP == nil {
+		return apperror.BadRequestError("two-factor authentication is not enabled")
+	}
+	if err = s.hasher.Compare(u.Password, dto.Password); err != nil {
+		return apperror.BadRequestError("password does not match current password")
+	}
+	if err = s.useCode(ctx, u, dto.Code); err != nil {
+		return err
+	}
+
+	if err = s.storage.DisableTOTP(ctx, uuid); err != nil {
+		return fmt.Errorf("failed to disable totp. error: %w", err)
+	}
+	return nil
+}
+
+var (
+	// errInvalidCode and errCodeUsed are wrong guesses of the second factor, they are throttled
+	// like wrong passwords
+	errInvalidCode = apperror.BadRequestError("invalid code")
+	errCodeUsed    = apperror.BadRequestError("code was used already")
+)
+
+func (s service) useCode(ctx context.Context, u User, code string) error {
+	if step, ok := matchTOTP(u.TOTP.Secret, code, time.Now()); ok {
+		err := s.storage.UseTOTPStep(ctx, u.UUID, step)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return errCodeUsed
+			}
+			return fmt.Errorf("failed to use totp code. error: %w", err)
+		}
+		return nil
+	}
+
+	err := s.storage.UseRecoveryCode(ctx, u.UUID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return errInvalidCode
+		}
+		return fmt.Errorf("failed to use recovery code. error: %w", err)
+	}
+	s.logger.Infof("user %s signed in with a recovery code", u.UUID)
+	return nil
+}
+
+// matchTOTP returns the time step of the code, a step before and after now are accepted
+// for clock drift
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpOpts.Digits.Length() {
+		return 0, false
+	}
+	for _, skew := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, t, totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return t.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCode returns a code like ABCDE-FGHIJ
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code. error: %w", err)
+	}
+	code := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,133 @@
+package user_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pquerna/otp/totp"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle"
+	throttledb "gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+)
+
+func TestTOTP(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+
+	enrollment, err := s.EnrollTOTP(ctx, uuid)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if enrollment.Secret == "" || enrollment.URI == "" {
+		t.Fatalf("enrollment = %+v", enrollment)
+	}
+	if u, _ := s.GetOne(ctx, uuid); u.TOTPEnabled {
+		t.Fatal("second factor enabled before confirmation")
+	}
+	if _, err := s.ConfirmTOTP(ctx, uuid, "000000"); err == nil {
+		t.Fatal("wrong code confirmed the enrollment")
+	}
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	recovery, err := s.ConfirmTOTP(ctx, uuid, code)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if len(recovery) != 10 {
+		t.Fatalf("recovery codes = %v", recovery)
+	}
+	if u, _ := s.GetOne(ctx, uuid); !u.TOTPEnabled {
+		t.Fatal("second factor is not enabled")
+	}
+
+	// the confirming code is spent, codes are accepted once
+	if err := s.VerifyTOTP(ctx, uuid, code); err == nil {
+		t.Fatal("code accepted twice")
+	}
+	if err := s.VerifyTOTP(ctx, uuid, recovery[0]); err != nil {
+		t.Fatalf("verify with a recovery code: %v", err)
+	}
+	if err := s.VerifyTOTP(ctx, uuid, recovery[0]); err == nil {
+		t.Fatal("recovery code accepted twice")
+	}
+
+	if err := s.DisableTOTP(ctx, uuid, user.DisableTOTPDTO{Password: "wrong!", Code: recovery[1]}); err == nil {
+		t.Fatal("disabled with a wrong password")
+	}
+	if err := s.DisableTOTP(ctx, uuid, user.DisableTOTPDTO{Password: "secret", Code: "123456"}); err == nil {
+		t.Fatal("disabled with a wrong code")
+	}
+	if err := s.DisableTOTP(ctx, uuid, user.DisableTOTPDTO{Password: "secret", Code: recovery[1]}); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if u, _ := s.GetOne(ctx, uuid); u.TOTPEnabled || u.TOTP != nil {
+		t.Fatalf("second factor left after disable: %+v", u)
+	}
+}
+
+func TestWrongCodesAreThrottledLikeWrongPasswords(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+	enrollment, err := s.EnrollTOTP(ctx, uuid)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	code, _ := totp.GenerateCode(enrollment.Secret, time.Now())
+	if _, err = s.ConfirmTOTP(ctx, uuid, code); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	accounts := throttle.Limits{FreeAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Minute,
+		LockoutAfter: 100, Lockout: time.Hour, Window: time.Hour}
+	clients := throttle.Limits{FreeAttempts: 100, BaseDelay: time.Minute, MaxDelay: time.Minute,
+		LockoutAfter: 100, Lockout: time.Hour, Window: time.Hour}
+	h := user.Handler{Logger: testLogger, UserService: s,
+		Throttle: throttle.NewService(throttledb.NewMemoryStorage(), accounts, clients, testLogger)}
+	router := httprouter.New()
+	h.Register(router)
+
+	send := func(r *http.Request, ip string) int {
+		r.Header.Set("X-Client-IP", ip)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+	verify := func(code, ip string) int {
+		body := strings.NewReader(`{"code":"` + code + `"}`)
+		return send(httptest.NewRequest(http.MethodPost, "/api/users/"+uuid+"/totp/verify", body), ip)
+	}
+	signIn := func(ip string) int {
+		query := url.Values{"email": {"alice@example.com"}, "password": {"secret"}}.Encode()
+		return send(httptest.NewRequest(http.MethodGet, "/api/users?"+query, nil), ip)
+	}
+
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if status := verify("000000", ip); status != http.StatusBadRequest {
+			t.Fatalf("wrong code %d answered %d", i, status)
+		}
+	}
+	// the password is known, signing in again must not forget the wrong codes
+	if status := signIn("10.0.0.3"); status != http.StatusOK {
+		t.Fatalf("sign in answered %d", status)
+	}
+	if status := verify("000000", "10.0.0.3"); status != http.StatusBadRequest {
+		t.Fatalf("third wrong code answered %d", status)
+	}
+	if status := verify("000000", "10.0.0.4"); status != http.StatusTooManyRequests {
+		t.Fatalf("code after three wrong ones answered %d, want 429", status)
+	}
+	if status := signIn("10.0.0.5"); status != http.StatusTooManyRequests {
+		t.Fatalf("sign in after three wrong codes answered %d, want 429", status)
+	}
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/apperror"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/mail"
+	"net/url"
+	"time"
+)
+
+const verificationSubject = "Confirm your email"
+
+// VerificationSettings tells where links of mailed tokens point to and how long they are valid
+type VerificationSettings struct {
+	URL string
+	TTL time.Duration
+}
+
+func (s service) Verify(ctx context.Context, token string) error {
+	if token == "" {
+		return apperror.BadRequestError("verification token is required")
+	}
+
+	u, err := s.storage.FindByVerificationToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.BadRequestError("invalid or expired verification token")
+		}
+		return fmt.Errorf("failed to find user by verification token. error: %w", err)
+	}
+	if u.Verification == nil || time.Now().After(u.Verification.ExpiresAt) {
+		return apperror.BadRequestError("invalid or expired verification token")
+	}
+
+	if err = s.storage.MarkVerified(ctx, u.UUID); err != nil {
+		return fmt.Errorf("failed to verify user. error: %w", err)
+	}
+	return nil
+}
+
+// ResendVerification sends a new link to an unverified user. Unknown and verified emails
+// are ignored so the answer does not tell whether an account exists.
+func (s service) ResendVerification(ctx context.Context, email string) error {
+	if email == "" {
+		return apperror.BadRequestError("email is required")
+	}
+
+	u, err := s.findByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find user by email. error: %w", err)
+	}
+	if u.Verified || u.Disabled {
+		return nil
+	}
+
+	token, verification, err := newToken(s.verification.TTL)
+	if err != nil {
+		return err
+	}
+	if err = s.storage.SetVerification(ctx, u.UUID, verification); err != nil {
+		return fmt.Errorf("failed to save verification. error: %w", err)
+	}
+	return s.sendVerification(ctx, u.Email, token)
+}
+
+func newToken(ttl time.Duration) (token string, v Verification, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", v, fmt.Errorf("failed to generate token. error: %w", err)
+	}
+	token = hex.EncodeToString(b)
+
+	return token, Verification{
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+func (s service) sendVerification(ctx context.Context, email, token string) error {
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: verificationSubject,
+		Body: fmt.Sprintf("Open the link to confirm your email:\n\n%s\n\nThe link is valid for %s.",
+			tokenLink(s.verification.URL, token), s.verification.TTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification. error: %w", err)
+	}
+	return nil
+}
+
+func tokenLink(base, token string) string {
+	return fmt.Sprintf("%s?token=%s", base, url.QueryEscape(token))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package user_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/mail"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/password"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testLogger = logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+
+// outbox keeps sent messages instead of delivering them
+type outbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+// lastToken takes the token from the link of the last message
+func (o *outbox) lastToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.sent) == 0 {
+		t.Fatal("nothing was sent")
+	}
+	body := o.sent[len(o.sent)-1].Body
+	start := strings.Index(body, "http://")
+	if start < 0 {
+		t.Fatalf("no link in %q", body)
+	}
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func newService(t *testing.T, ttl time.Duration) (user.Service, *outbox) {
+	t.Helper()
+	box := &outbox{}
+	hasher, err := password.NewHasher(password.AlgorithmBcrypt, bcrypt.MinCost, password.Argon2Params{})
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+	s, err := user.NewService(db.NewMemoryStorage(testLogger), hasher, password.NewPolicy(6, 64), box,
+		user.VerificationSettings{URL: "http://localhost/api/verify", TTL: ttl},
+		user.VerificationSettings{URL: "http://localhost/reset-password", TTL: ttl}, testLogger)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	return s, box
+}
+
+func createUserErr(s user.Service, email, pwd string) (string, error) {
+	return s.Create(context.Background(), user.CreateUserDTO{Email: email, Password: pwd, RepeatPassword: pwd})
+}
+
+func createUser(t *testing.T, s user.Service, email string) string {
+	t.Helper()
+	uuid, err := createUserErr(s, email, "secret")
+	if err != nil {
+		t.Fatalf("create %s: %v", email, err)
+	}
+	return uuid
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	s, box := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+
+	u, err := s.GetByEmailAndPassword(ctx, "alice@example.com", "secret")
+	if err != nil {
+		t.Fatalf("sign in: %v", err)
+	}
+	if u.Verified {
+		t.Fatal("new user is verified")
+	}
+	if box.sent[0].To != "alice@example.com" {
+		t.Fatalf("mail sent to %q", box.sent[0].To)
+	}
+
+	token := box.lastToken(t)
+	if err := s.Verify(ctx, "bogus"); err == nil {
+		t.Fatal("bogus token accepted")
+	}
+	if err := s.Verify(ctx, token); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if u, _ = s.GetOne(ctx, uuid); !u.Verified {
+		t.Fatal("user is not verified")
+	}
+	if err := s.Verify(ctx, token); err == nil {
+		t.Fatal("token accepted twice")
+	}
+
+	if err := s.ResendVerification(ctx, "alice@example.com"); err != nil || len(box.sent) != 1 {
+		t.Fatalf("resend to a verified user: %v, %d mails", err, len(box.sent))
+	}
+	if err := s.ResendVerification(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("resend to an unknown email: %v", err)
+	}
+}
+
+func TestVerifyExpiredAndResent(t *testing.T) {
+	ctx := context.Background()
+	s, box := newService(t, -time.Minute)
+	uuid := createUser(t, s, "bob@example.com")
+
+	expired := box.lastToken(t)
+	if err := s.Verify(ctx, expired); err == nil {
+		t.Fatal("expired token accepted")
+	}
+
+	if err := s.ResendVerification(ctx, "bob@example.com"); err != nil {
+		t.Fatalf("resend: %v", err)
+	}
+	if box.lastToken(t) == expired {
+		t.Fatal("the same token was sent again")
+	}
+	if u, _ := s.GetOne(ctx, uuid); u.Verified {
+		t.Fatal("user verified by an expired token")
+	}
+}
+
+func TestChangedEmailIsVerifiedAgain(t *testing.T) {
+	ctx := context.Background()
+	s, box := newService(t, time.Hour)
+	uuid := createUser(t, s, "alice@example.com")
+	if err := s.Verify(ctx, box.lastToken(t)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	err := s.Update(ctx, user.UpdateUserDTO{UUID: uuid, Email: "Alice@Example.org", OldPassword: "secret", NewPassword: "secret"})
+	if err != nil {
+		t.Fatalf("update email: %v", err)
+	}
+	u, _ := s.GetOne(ctx, uuid)
+	if u.Email != "alice@example.org" || u.Verified {
+		t.Fatalf("want the new email unverified, got %q verified %v", u.Email, u.Verified)
+	}
+	if len(box.sent) != 2 || box.sent[1].To != "alice@example.org" {
+		t.Fatalf("want a verification mailed to the new email, got %+v", box.sent)
+	}
+
+	if err = s.Verify(ctx, box.lastToken(t)); err != nil {
+		t.Fatalf("verify new email: %v", err)
+	}
+	if u, _ = s.GetOne(ctx, uuid); !u.Verified {
+		t.Fatal("new email is not verified")
+	}
+
+	err = s.Update(ctx, user.UpdateUserDTO{UUID: uuid, Email: "alice@example.org", OldPassword: "secret", NewPassword: "secret"})
+	if err != nil {
+		t.Fatalf("update with the same email: %v", err)
+	}
+	if u, _ = s.GetOne(ctx, uuid); !u.Verified || len(box.sent) != 2 {
+		t.Fatalf("the same email was verified again, verified %v, %d mails", u.Verified, len(box.sent))
+	}
+}
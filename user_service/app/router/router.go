@@ -0,0 +1,167 @@
+// Package router builds the routed handler of the user service. The service binary serves it on its
+// own listener, the all-in-one binary mounts it next to the other services in one process.
+package router
+
+import (
+	"context"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/config"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/deletion"
+	deletiondb "gitlab.konstweb.ru/ow/arch/notes/user_service/internal/deletion/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/mail"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/password"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle"
+	throttledb "gitlab.konstweb.ru/ow/arch/notes/user_service/internal/throttle/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/internal/user/db"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/logging"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/metric"
+	mongo "gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb"
+	"gitlab.konstweb.ru/ow/arch/notes/user_service/pkg/mongodb/migrate"
+	"net/http"
+)
+
+// Config is the config of the service, Storage.Type chooses where users are kept
+type Config = config.Config
+
+// New builds the handler of every route of the service on the storage of cfg
+func New(ctx context.Context, cfg *Config, logger logging.Logger) (http.Handler, error) {
+	router := httprouter.New()
+
+	metricHandler := metric.Handler{Logger: logger}
+	metricHandler.Register(router)
+
+	storages, err := newStorage(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	hasher, policy, err := newPasswords(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	mailer, err := newMailer(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	verification := user.VerificationSettings{URL: cfg.Verification.URL, TTL: cfg.Verification.TTL}
+	passwordReset := user.VerificationSettings{URL: cfg.PasswordReset.URL, TTL: cfg.PasswordReset.TTL}
+	userService, err := user.NewService(storages.users, hasher, policy, mailer, verification, passwordReset, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	loginThrottle := throttle.NewService(storages.attempts,
+		throttleLimits(cfg.Throttle.Accounts), throttleLimits(cfg.Throttle.Clients), logger)
+
+	usersHandler := user.Handler{
+		Logger:      logger,
+		UserService: userService,
+		Throttle:    loginThrottle,
+	}
+	usersHandler.Register(router)
+
+	throttleHandler := throttle.Handler{Logger: logger, Throttle: loginThrottle}
+	throttleHandler.Register(router)
+
+	deletionHandler := deletion.Handler{
+		Logger:          logger,
+		DeletionService: deletion.NewService(storages.deletions, userService, logger),
+	}
+	deletionHandler.Register(router)
+
+	return router, nil
+}
+
+type storages struct {
+	users     user.Storage
+	attempts  throttle.Storage
+	deletions deletion.Storage
+}
+
+func newStorage(ctx context.Context, cfg *Config, logger logging.Logger) (s storages, err error) {
+	switch cfg.Storage.Type {
+	case "mongodb":
+		mongoClient, err := mongo.NewClient(ctx, cfg.MongoDB.Host, cfg.MongoDB.Port,
+			cfg.MongoDB.Username, cfg.MongoDB.Password, cfg.MongoDB.Database, cfg.MongoDB.AuthDB)
+		if err != nil {
+			return s, err
+		}
+		logger.Info("apply mongodb migrations")
+		err = migrate.NewMigrator(mongoClient, MongoMigrations(cfg, logger), logger).Up(ctx)
+		if err != nil {
+			return s, err
+		}
+		// migration 5 leaves the unique email index out while emails are shared
+		err = db.EnsureUniqueEmails(ctx, mongoClient.Collection(cfg.MongoDB.Collection), logger)
+		if err != nil {
+			return s, err
+		}
+		return storages{
+			users:     db.NewStorage(mongoClient, cfg.MongoDB.Collection, logger),
+			attempts:  throttledb.NewStorage(mongoClient, cfg.MongoDB.AttemptsCollection, logger),
+			deletions: deletiondb.NewStorage(mongoClient, cfg.MongoDB.DeletionsCollection, logger),
+		}, nil
+	case "memory":
+		logger.Warn("users are kept in memory and will be lost on restart")
+		return storages{
+			users:     db.NewMemoryStorage(logger),
+			attempts:  throttledb.NewMemoryStorage(),
+			deletions: deletiondb.NewMemoryStorage(),
+		}, nil
+	default:
+		return s, fmt.Errorf("unknown storage type %q", cfg.Storage.Type)
+	}
+}
+
+// MongoMigrations is the schema history of the whole service database
+func MongoMigrations(cfg *Config, logger logging.Logger) []migrate.Migration {
+	migrations := append(db.MongoMigrations(cfg.MongoDB.Collection, logger), throttledb.MongoMigrations(cfg.MongoDB.AttemptsCollection)...)
+	return append(migrations, deletiondb.MongoMigrations(cfg.MongoDB.DeletionsCollection)...)
+}
+
+func throttleLimits(l config.ThrottleLimits) throttle.Limits {
+	return throttle.Limits{
+		FreeAttempts: l.FreeAttempts,
+		BaseDelay:    l.BaseDelay,
+		MaxDelay:     l.MaxDelay,
+		LockoutAfter: l.LockoutAfter,
+		Lockout:      l.Lockout,
+		Window:       l.Window,
+	}
+}
+
+func newPasswords(cfg *Config, logger logging.Logger) (password.Hasher, *password.Policy, error) {
+	hasher, err := password.NewHasher(cfg.Password.Algorithm, cfg.Password.BcryptCost, password.Argon2Params{
+		Time:       cfg.Password.Argon2id.Time,
+		Memory:     cfg.Password.Argon2id.MemoryKiB,
+		Threads:    cfg.Password.Argon2id.Threads,
+		KeyLength:  cfg.Password.Argon2id.KeyLength,
+		SaltLength: cfg.Password.Argon2id.SaltLength,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy := password.NewPolicy(cfg.Password.MinLength, cfg.Password.MaxLength)
+	if cfg.Password.BreachedFile != "" {
+		if err = policy.LoadBreached(cfg.Password.BreachedFile); err != nil {
+			return nil, nil, err
+		}
+		logger.Infof("breached passwords loaded from %s", cfg.Password.BreachedFile)
+	}
+	return hasher, policy, nil
+}
+
+func newMailer(cfg *Config, logger logging.Logger) (mail.Mailer, error) {
+	switch cfg.Mailer.Type {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.Mailer.SMTP.Host, cfg.Mailer.SMTP.Port,
+			cfg.Mailer.SMTP.Username, cfg.Mailer.SMTP.Password, cfg.Mailer.From), nil
+	case "log":
+		logger.Warn("mails are not delivered, they are written to the log")
+		return mail.NewLogMailer(cfg.Mailer.File, cfg.Mailer.From, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer type %q", cfg.Mailer.Type)
+	}
+}